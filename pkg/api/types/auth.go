@@ -0,0 +1,21 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+// BasicAuth is the username and password sent using the HTTP Basic Authentication scheme.
+type BasicAuth struct {
+	Username string `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password string `mapstructure:"password,omitempty" json:"-"`
+}
+
+// Authorization sets the HTTP Authorization header to `<type> <credentials>`.
+type Authorization struct {
+	Type        string `mapstructure:"type,omitempty" json:"type,omitempty"`
+	Credentials string `mapstructure:"credentials,omitempty" json:"-"`
+}
@@ -12,6 +12,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -69,6 +70,11 @@ type APIErrors struct {
 	Errors []string `json:"errors,omitempty"`
 }
 
+var (
+	errAlreadyExists = errors.New("already exists")
+	errInUse         = errors.New("in use")
+)
+
 func (a *App) handleConfigTargetsGet(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -110,7 +116,12 @@ func (a *App) handleConfigTargetsPost(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
 		return
 	}
-	a.AddTargetConfig(tc)
+	if !a.targetConfigExists(tc.Name) {
+		a.AddTargetConfig(tc)
+		a.configLock.Lock()
+		a.apiTargets[tc.Name] = struct{}{}
+		a.configLock.Unlock()
+	}
 }
 
 func (a *App) handleConfigTargetsSubscriptions(w http.ResponseWriter, r *http.Request) {
@@ -159,34 +170,271 @@ func (a *App) handleConfigTargetsDelete(w http.ResponseWriter, r *http.Request)
 		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
 		return
 	}
+	a.configLock.Lock()
+	delete(a.apiTargets, id)
+	a.configLock.Unlock()
 }
 
 func (a *App) handleConfigSubscriptions(w http.ResponseWriter, r *http.Request) {
-	a.handlerCommonGet(w, a.Config.Subscriptions)
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	handleConfigItemGet(w, r, "subscription", a.Config.Subscriptions)
+}
+
+func (a *App) handleConfigSubscriptionsPost(w http.ResponseWriter, r *http.Request) {
+	name, cfg, err := decodeConfigItem(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	sc, err := a.Config.DecodeSubscriptionConfig(name, cfg)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	a.handleConfigChange(w, r, http.StatusCreated, func() error {
+		return a.CreateSubscription(a.ctx, sc)
+	})
+}
+
+func (a *App) handleConfigSubscriptionsPut(w http.ResponseWriter, r *http.Request) {
+	name, cfg, err := decodeConfigItemUpdate(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	sc, err := a.Config.DecodeSubscriptionConfig(name, cfg)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	a.handleConfigChange(w, r, http.StatusOK, func() error {
+		return a.UpdateSubscription(a.ctx, sc)
+	})
+}
+
+func (a *App) handleConfigSubscriptionsDelete(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	a.handleConfigChange(w, r, http.StatusOK, func() error {
+		return a.DeleteSubscription(a.ctx, id)
+	})
 }
 
 func (a *App) handleConfigOutputs(w http.ResponseWriter, r *http.Request) {
-	a.handlerCommonGet(w, a.Config.Outputs)
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	handleConfigItemGet(w, r, "output", a.Config.Outputs)
 }
 
-func (a *App) handleConfigClustering(w http.ResponseWriter, r *http.Request) {
-	a.handlerCommonGet(w, a.Config.Clustering)
+func (a *App) handleConfigOutputsPost(w http.ResponseWriter, r *http.Request) {
+	name, cfg, err := decodeConfigItem(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	a.handleConfigChange(w, r, http.StatusCreated, func() error {
+		return a.CreateOutput(a.ctx, name, cfg)
+	})
 }
 
-func (a *App) handleConfigAPIServer(w http.ResponseWriter, r *http.Request) {
-	a.handlerCommonGet(w, a.Config.APIServer)
+func (a *App) handleConfigOutputsPut(w http.ResponseWriter, r *http.Request) {
+	name, cfg, err := decodeConfigItemUpdate(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	a.handleConfigChange(w, r, http.StatusOK, func() error {
+		return a.UpdateOutput(a.ctx, name, cfg)
+	})
 }
 
-func (a *App) handleConfigGNMIServer(w http.ResponseWriter, r *http.Request) {
-	a.handlerCommonGet(w, a.Config.GnmiServer)
+func (a *App) handleConfigOutputsDelete(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	a.handleConfigChange(w, r, http.StatusOK, func() error {
+		return a.DeleteOutputConfig(id)
+	})
 }
 
 func (a *App) handleConfigInputs(w http.ResponseWriter, r *http.Request) {
-	a.handlerCommonGet(w, a.Config.Inputs)
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	handleConfigItemGet(w, r, "input", a.Config.Inputs)
+}
+
+func (a *App) handleConfigInputsPost(w http.ResponseWriter, r *http.Request) {
+	name, cfg, err := decodeConfigItem(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	a.handleConfigChange(w, r, http.StatusCreated, func() error {
+		return a.CreateInput(a.ctx, name, cfg)
+	})
+}
+
+func (a *App) handleConfigInputsPut(w http.ResponseWriter, r *http.Request) {
+	name, cfg, err := decodeConfigItemUpdate(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	a.handleConfigChange(w, r, http.StatusOK, func() error {
+		return a.UpdateInput(a.ctx, name, cfg)
+	})
+}
+
+func (a *App) handleConfigInputsDelete(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	a.handleConfigChange(w, r, http.StatusOK, func() error {
+		return a.DeleteInputConfig(id)
+	})
 }
 
 func (a *App) handleConfigProcessors(w http.ResponseWriter, r *http.Request) {
-	a.handlerCommonGet(w, a.Config.Processors)
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	handleConfigItemGet(w, r, "processor", a.Config.Processors)
+}
+
+func (a *App) handleConfigProcessorsPost(w http.ResponseWriter, r *http.Request) {
+	name, cfg, err := decodeConfigItem(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	a.handleConfigChange(w, r, http.StatusCreated, func() error {
+		return a.CreateProcessor(name, cfg)
+	})
+}
+
+func (a *App) handleConfigProcessorsPut(w http.ResponseWriter, r *http.Request) {
+	name, cfg, err := decodeConfigItemUpdate(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	a.handleConfigChange(w, r, http.StatusOK, func() error {
+		return a.UpdateProcessor(a.ctx, name, cfg)
+	})
+}
+
+func (a *App) handleConfigProcessorsDelete(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	a.handleConfigChange(w, r, http.StatusOK, func() error {
+		return a.DeleteProcessor(id)
+	})
+}
+
+// handleConfigChange runs a configuration change while holding the
+// config reload semaphore and writes the resulting status code.
+func (a *App) handleConfigChange(w http.ResponseWriter, r *http.Request, code int, change func() error) {
+	err := a.sem.Acquire(r.Context(), 1)
+	if err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer a.sem.Release(1)
+	err = change()
+	switch {
+	case err == nil:
+		w.WriteHeader(code)
+	case errors.Is(err, errNotFound):
+		writeAPIError(w, http.StatusNotFound, err)
+	case errors.Is(err, errAlreadyExists), errors.Is(err, errInUse):
+		writeAPIError(w, http.StatusConflict, err)
+	default:
+		writeAPIError(w, http.StatusBadRequest, err)
+	}
+}
+
+// handleConfigItemGet writes the whole config section m or,
+// if the request has an id, the single item with that name.
+func handleConfigItemGet[V any](w http.ResponseWriter, r *http.Request, kind string, m map[string]V) {
+	id := mux.Vars(r)["id"]
+	var err error
+	if id == "" {
+		err = json.NewEncoder(w).Encode(m)
+	} else {
+		item, ok := m[id]
+		if !ok {
+			writeAPIError(w, http.StatusNotFound, fmt.Errorf("%s %q %w", kind, id, errNotFound))
+			return
+		}
+		err = json.NewEncoder(w).Encode(item)
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// decodeConfigItem reads a JSON object from the request body and
+// returns it without its mandatory "name" field.
+func decodeConfigItem(r *http.Request) (string, map[string]interface{}, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Body.Close()
+	cfg := make(map[string]interface{})
+	err = json.Unmarshal(body, &cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	name, _ := cfg["name"].(string)
+	if name == "" {
+		return "", nil, errors.New("missing name")
+	}
+	delete(cfg, "name")
+	return name, cfg, nil
+}
+
+// decodeConfigItemUpdate reads a JSON object from the request body and
+// returns it along with the id from the request path.
+// If the object has a "name" field, it must match the id.
+func decodeConfigItemUpdate(r *http.Request) (string, map[string]interface{}, error) {
+	id := mux.Vars(r)["id"]
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Body.Close()
+	cfg := make(map[string]interface{})
+	err = json.Unmarshal(body, &cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	if name, ok := cfg["name"]; ok && name != id {
+		return "", nil, fmt.Errorf("name %v does not match %q", name, id)
+	}
+	delete(cfg, "name")
+	return id, cfg, nil
+}
+
+// writeAPIError writes the status code and the error as APIErrors,
+// joined errors are returned as separate entries.
+func writeAPIError(w http.ResponseWriter, code int, err error) {
+	apiErrs := APIErrors{}
+	if jerr, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range jerr.Unwrap() {
+			apiErrs.Errors = append(apiErrs.Errors, e.Error())
+		}
+	} else {
+		apiErrs.Errors = []string{err.Error()}
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(apiErrs)
+}
+
+func (a *App) handleConfigClustering(w http.ResponseWriter, r *http.Request) {
+	a.handlerCommonGet(w, a.Config.Clustering)
+}
+
+func (a *App) handleConfigAPIServer(w http.ResponseWriter, r *http.Request) {
+	a.handlerCommonGet(w, a.Config.APIServer)
+}
+
+func (a *App) handleConfigGNMIServer(w http.ResponseWriter, r *http.Request) {
+	a.handlerCommonGet(w, a.Config.GnmiServer)
 }
 
 func (a *App) handleConfig(w http.ResponseWriter, r *http.Request) {
@@ -255,6 +503,7 @@ type clusterMember struct {
 	Name                  string   `json:"name,omitempty"`
 	APIEndpoint           string   `json:"api-endpoint,omitempty"`
 	IsLeader              bool     `json:"is-leader,omitempty"`
+	Draining              bool     `json:"draining,omitempty"`
 	NumberOfLockedTargets int      `json:"number-of-locked-nodes"`
 	LockedTargets         []string `json:"locked-targets,omitempty"`
 }
@@ -302,11 +551,18 @@ func (a *App) handleClusteringGet(w http.ResponseWriter, r *http.Request) {
 		}
 		instanceNodes[v] = append(instanceNodes[v], name)
 	}
+	draining, err := a.drainingInstances(ctx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
 	resp.Members = make([]clusterMember, len(services))
 	for i, s := range services {
 		resp.Members[i].APIEndpoint = s.Address
 		resp.Members[i].Name = strings.TrimSuffix(s.ID, "-api")
 		resp.Members[i].IsLeader = resp.Leader == resp.Members[i].Name
+		_, resp.Members[i].Draining = draining[resp.Members[i].Name]
 		resp.Members[i].NumberOfLockedTargets = len(instanceNodes[resp.Members[i].Name])
 		resp.Members[i].LockedTargets = instanceNodes[resp.Members[i].Name]
 	}
@@ -335,6 +591,23 @@ func (a *App) handleAdminShutdown(w http.ResponseWriter, r *http.Request) {
 	a.Cfn()
 }
 
+func (a *App) handleAdminReload(w http.ResponseWriter, r *http.Request) {
+	a.Logger.Printf("reloading config due to user request")
+	diff, err := a.ReloadConfig(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	b, err := json.Marshal(diff)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	w.Write(b)
+}
+
 func (a *App) handleClusteringMembersGet(w http.ResponseWriter, r *http.Request) {
 	if a.Config.Clustering == nil {
 		return
@@ -374,6 +647,12 @@ func (a *App) handleClusteringMembersGet(w http.ResponseWriter, r *http.Request)
 		}
 		instanceNodes[v] = append(instanceNodes[v], name)
 	}
+	draining, err := a.drainingInstances(ctx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
 	members := make([]clusterMember, len(services))
 	for i, s := range services {
 		scheme := "http://"
@@ -385,6 +664,7 @@ func (a *App) handleClusteringMembersGet(w http.ResponseWriter, r *http.Request)
 		members[i].APIEndpoint = fmt.Sprintf("%s%s", scheme, s.Address)
 		members[i].Name = strings.TrimSuffix(s.ID, "-api")
 		members[i].IsLeader = leader[leaderKey] == members[i].Name
+		_, members[i].Draining = draining[members[i].Name]
 		members[i].NumberOfLockedTargets = len(instanceNodes[members[i].Name])
 		members[i].LockedTargets = instanceNodes[members[i].Name]
 	}
@@ -464,6 +744,34 @@ func (a *App) handleClusteringLeaderGet(w http.ResponseWriter, r *http.Request)
 	w.Write(b)
 }
 
+func (a *App) handleClusteringRebalancePost(w http.ResponseWriter, r *http.Request) {
+	if a.Config.Clustering == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{"clustering is not enabled"}})
+		return
+	}
+	a.Logger.Printf("rebalancing targets due to user request")
+	moves, err := a.rebalanceTargets(r.Context())
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, errNotLeader) {
+			code = http.StatusConflict
+		} else if !a.consistentHashing() {
+			code = http.StatusBadRequest
+		}
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	b, err := json.Marshal(moves)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	w.Write(b)
+}
+
 func headersMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-Type", "application/json")
@@ -20,7 +20,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/fsnotify/fsnotify"
 	"github.com/fullstorydev/grpcurl"
 	"github.com/gorilla/mux"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
@@ -73,6 +72,8 @@ type App struct {
 	Targets       map[string]*target.Target
 	targetsChan   chan *target.Target
 	activeTargets map[string]struct{}
+	// targets created through the API, kept on config reload
+	apiTargets    map[string]struct{}
 	targetsLockFn map[string]context.CancelFunc
 	rootDesc      desc.Descriptor
 	// end collector
@@ -81,6 +82,14 @@ type App struct {
 	// api
 	apiServices map[string]*lockers.Service
 	isLeader    bool
+	// consistent hash ring of the cluster instances
+	hashRing *hashRing
+	// signals the leader that the cluster members changed
+	rebalanceCh chan struct{}
+	// serializes the leader targets dispatching and rebalancing
+	dispatchLock *sync.Mutex
+	// set when this instance is in drain mode
+	drain *drain
 	// prometheus registry
 	reg *prometheus.Registry
 	//
@@ -108,6 +117,9 @@ type App struct {
 	tunTargetCfn  map[tunnel.Target]context.CancelFunc
 	// processors plugin manager
 	pm *plugin_manager.PluginManager
+	// subscribe command, used to re-read the subscriptions
+	// configuration when the config is reloaded.
+	subscribeCmd *cobra.Command
 }
 
 func New() *App {
@@ -127,10 +139,13 @@ func New() *App {
 		Inputs:        make(map[string]inputs.Input),
 		targetsChan:   make(chan *target.Target),
 		activeTargets: make(map[string]struct{}),
+		apiTargets:    make(map[string]struct{}),
 		targetsLockFn: make(map[string]context.CancelFunc),
 		//
 		router:        mux.NewRouter(),
 		apiServices:   make(map[string]*lockers.Service),
+		rebalanceCh:   make(chan struct{}, 1),
+		dispatchLock:  new(sync.Mutex),
 		Logger:        log.New(io.Discard, "[gnmic] ", log.LstdFlags|log.Lmsgprefix),
 		out:           os.Stdout,
 		PromptHistory: make([]string, 0, 128),
@@ -201,6 +216,7 @@ func (a *App) InitGlobalFlags() {
 	a.RootCmd.PersistentFlags().StringArrayVarP(&a.Config.GlobalFlags.File, "file", "", nil, "YANG file(s)")
 	a.RootCmd.PersistentFlags().StringArrayVarP(&a.Config.GlobalFlags.Dir, "dir", "", nil, "YANG dir(s)")
 	a.RootCmd.PersistentFlags().StringArrayVarP(&a.Config.GlobalFlags.Exclude, "exclude", "", nil, "YANG module names to be excluded")
+	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.ValidatePaths, "validate-paths", "", false, "validate the request paths against the YANG schema loaded with --file and --dir before sending RPCs")
 
 	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.UseTunnelServer, "use-tunnel-server", "", false, "use tunnel server to dial targets")
 	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.AuthScheme, "auth-scheme", "", "", "authentication scheme to use for the target's username/password")
@@ -347,90 +363,6 @@ func (a *App) createCollectorDialOpts() {
 	a.dialOpts = opts
 }
 
-func (a *App) watchConfig() {
-	a.Logger.Printf("watching config...")
-	a.Config.FileConfig.OnConfigChange(a.loadTargets)
-	a.Config.FileConfig.WatchConfig()
-}
-
-func (a *App) loadTargets(e fsnotify.Event) {
-	a.Logger.Printf("got config change notification: %v", e)
-	ctx, cancel := context.WithCancel(a.ctx)
-	defer cancel()
-	err := a.sem.Acquire(ctx, 1)
-	if err != nil {
-		a.Logger.Printf("failed to acquire target loading semaphore: %v", err)
-		return
-	}
-	defer a.sem.Release(1)
-	switch e.Op {
-	case fsnotify.Write, fsnotify.Create:
-		newTargets, err := a.Config.GetTargets()
-		if err != nil && !errors.Is(err, config.ErrNoTargetsFound) {
-			a.Logger.Printf("failed getting targets from new config: %v", err)
-			return
-		}
-		if !a.inCluster() {
-			currentTargets := a.Targets
-			// delete targets
-			for n := range currentTargets {
-				if _, ok := newTargets[n]; !ok {
-					if a.Config.Debug {
-						a.Logger.Printf("target %q deleted from config", n)
-					}
-					err = a.DeleteTarget(a.ctx, n)
-					if err != nil {
-						a.Logger.Printf("failed to delete target %q: %v", n, err)
-					}
-				}
-			}
-			// add targets
-			for n, tc := range newTargets {
-				if _, ok := currentTargets[n]; !ok {
-					if a.Config.Debug {
-						a.Logger.Printf("target %q added to config", n)
-					}
-					a.AddTargetConfig(tc)
-					a.wg.Add(1)
-					go a.TargetSubscribeStream(a.ctx, tc)
-				}
-			}
-			return
-		}
-		// in a cluster
-		if !a.isLeader {
-			return
-		}
-		// in cluster && leader
-		dist, err := a.getTargetToInstanceMapping()
-		if err != nil {
-			a.Logger.Printf("failed to get target to instance mapping: %v", err)
-			return
-		}
-		// delete targets
-		for t := range dist {
-			if _, ok := newTargets[t]; !ok {
-				err = a.deleteTarget(ctx, t)
-				if err != nil {
-					a.Logger.Printf("failed to delete target %q: %v", t, err)
-					continue
-				}
-			}
-		}
-		// add new targets to cluster
-		a.configLock.Lock()
-		for _, tc := range newTargets {
-			if _, ok := dist[tc.Name]; !ok {
-				err = a.dispatchTarget(a.ctx, tc)
-				if err != nil {
-					a.Logger.Printf("failed to add target %q: %v", tc.Name, err)
-				}
-			}
-		}
-		a.configLock.Unlock()
-	}
-}
-
 func (a *App) startAPIServer() {
 	if a.Config.APIServer == nil {
 		return
@@ -18,6 +18,7 @@ import (
 	"net"
 	"net/http"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -36,6 +37,7 @@ const (
 var (
 	errNoMoreSuitableServices = errors.New("no more suitable services for this target")
 	errNotFound               = errors.New("not found")
+	errNotLeader              = errors.New("not the cluster leader")
 )
 
 func (a *App) InitLocker() error {
@@ -154,6 +156,7 @@ START:
 		a.Logger.Printf("leader done waiting, starting loader and dispatching targets")
 		go a.startLoader(ctx)
 		go a.dispatchTargets(ctx)
+		go a.watchRebalance(ctx)
 	}()
 
 	doneCh, errCh := a.locker.KeepLock(a.ctx, leaderKey)
@@ -206,6 +209,8 @@ START:
 func (a *App) updateServices(srvs []*lockers.Service) {
 	a.configLock.Lock()
 	defer a.configLock.Unlock()
+	instances := a.instanceNames()
+	defer a.membersUpdated(instances)
 
 	numNewSrv := len(srvs)
 	numCurrentSrv := len(a.apiServices)
@@ -262,9 +267,12 @@ func (a *App) dispatchTargets(ctx context.Context) {
 			}
 			var err error
 			//a.m.RLock()
+			a.dispatchLock.Lock()
 			dctx, cancel := context.WithTimeout(ctx, a.Config.Clustering.TargetsWatchTimer)
+			// draining instances are not assigned new targets
+			draining := a.drainingServices(dctx)
 			for _, tc := range a.Config.Targets {
-				err = a.dispatchTarget(dctx, tc)
+				err = a.dispatchTarget(dctx, tc, draining)
 				if err != nil {
 					a.Logger.Printf("failed to dispatch target %q: %v", tc.Name, err)
 				}
@@ -282,6 +290,8 @@ func (a *App) dispatchTargets(ctx context.Context) {
 			}
 			//a.m.RUnlock()
 			cancel()
+			a.migrateDrainedTargets(ctx, draining)
+			a.dispatchLock.Unlock()
 			select {
 			case <-ctx.Done():
 				return
@@ -292,7 +302,9 @@ func (a *App) dispatchTargets(ctx context.Context) {
 	}
 }
 
-func (a *App) dispatchTarget(ctx context.Context, tc *types.TargetConfig) error {
+// dispatchTarget assigns the target to an instance if it is not locked.
+// The draining services are not selected.
+func (a *App) dispatchTarget(ctx context.Context, tc *types.TargetConfig, draining []string) error {
 	if a.Config.Debug {
 		a.Logger.Printf("checking if %q is locked", tc.Name)
 	}
@@ -308,9 +320,9 @@ func (a *App) dispatchTarget(ctx context.Context, tc *types.TargetConfig) error
 		return nil
 	}
 	a.Logger.Printf("dispatching target %q", tc.Name)
-	denied := make([]string, 0)
+	denied := slices.Clone(draining)
 SELECTSERVICE:
-	service, err := a.selectService(tc.Tags, denied...)
+	service, err := a.selectTargetService(tc, denied...)
 	if err != nil {
 		return err
 	}
@@ -382,6 +394,9 @@ func (a *App) selectService(tags []string, denied ...string) (*lockers.Service,
 		return nil, errNotFound
 	case 1:
 		for _, s := range a.apiServices {
+			if slices.Contains(denied, s.ID) {
+				return nil, errNoMoreSuitableServices
+			}
 			return s, nil
 		}
 	default:
@@ -396,6 +411,12 @@ func (a *App) selectService(tags []string, denied ...string) (*lockers.Service,
 				matchingInstances = append(matchingInstances, strings.TrimSuffix(n, "-api"))
 			}
 		}
+		matchingInstances = slices.DeleteFunc(matchingInstances, func(n string) bool {
+			return slices.Contains(denied, n+"-api")
+		})
+		if len(matchingInstances) == 0 {
+			return nil, errNoMoreSuitableServices
+		}
 		if len(matchingInstances) == 1 {
 			return a.apiServices[fmt.Sprintf("%s-api", matchingInstances[0])], nil
 		}
@@ -680,3 +701,88 @@ func (a *App) unassignTarget(ctx context.Context, name string, serviceID string)
 	}
 	return nil
 }
+
+// moveTarget hands a target over from instance "from" to another instance:
+// the target is unassigned from its current instance, and once its lock is released
+// it is assigned to instance "to", or dispatched again if "to" is empty or does not take it.
+// It returns the name of the instance the target was moved to.
+func (a *App) moveTarget(ctx context.Context, tc *types.TargetConfig, from, to string, draining []string) (string, error) {
+	a.Logger.Printf("[cluster-leader] moving target %q from %q", tc.Name, from)
+	err := a.unassignTarget(ctx, tc.Name, from+"-api")
+	if err != nil {
+		return "", fmt.Errorf("failed to unassign target %q from %q: %v", tc.Name, from, err)
+	}
+	key := a.targetLockKey(tc.Name)
+	err = a.waitUnlocked(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to move target %q: %v", tc.Name, err)
+	}
+	if to != "" {
+		err = a.assignTargetTo(ctx, tc, to)
+		if err == nil {
+			a.Logger.Printf("[cluster-leader] target %q moved from %q to %q", tc.Name, from, to)
+			return to, nil
+		}
+		a.Logger.Printf("[cluster-leader] failed to assign target %q to %q, dispatching it: %v", tc.Name, to, err)
+	}
+	err = a.dispatchTarget(ctx, tc, draining)
+	if err != nil {
+		return "", fmt.Errorf("failed to dispatch target %q: %v", tc.Name, err)
+	}
+	values, err := a.locker.List(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	a.Logger.Printf("[cluster-leader] target %q moved from %q to %q", tc.Name, from, values[key])
+	return values[key], nil
+}
+
+// assignTargetTo assigns a target to an instance and waits for the instance to lock it.
+// The target is unassigned from the instance if it does not lock it in time.
+func (a *App) assignTargetTo(ctx context.Context, tc *types.TargetConfig, instance string) error {
+	a.configLock.RLock()
+	service, ok := a.apiServices[instance+"-api"]
+	a.configLock.RUnlock()
+	if !ok {
+		return errNotFound
+	}
+	err := a.assignTarget(ctx, tc, service)
+	if err != nil {
+		return err
+	}
+	key := a.targetLockKey(tc.Name)
+	wctx, cancel := context.WithTimeout(ctx, a.Config.Clustering.TargetAssignmentTimeout)
+	defer cancel()
+	for {
+		values, err := a.locker.List(wctx, key)
+		if err == nil && values[key] == instance {
+			return nil
+		}
+		select {
+		case <-wctx.Done():
+			uerr := a.unassignTarget(ctx, tc.Name, service.ID)
+			if uerr != nil {
+				a.Logger.Printf("failed to unassign target %q from %q: %v", tc.Name, service.ID, uerr)
+			}
+			return fmt.Errorf("lock %q not acquired by %q: %w", key, instance, wctx.Err())
+		case <-time.After(lockWaitTime):
+		}
+	}
+}
+
+// waitUnlocked waits for the key lock to be released by its previous owner.
+func (a *App) waitUnlocked(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, a.Config.Clustering.TargetAssignmentTimeout)
+	defer cancel()
+	for {
+		locked, err := a.locker.IsLocked(ctx, key)
+		if err == nil && !locked {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("lock %q not released: %w", key, ctx.Err())
+		case <-time.After(lockWaitTime):
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
+	"github.com/openconfig/gnmic/pkg/lockers"
+)
+
+type targetMove struct {
+	Target string `json:"target,omitempty"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+}
+
+func (a *App) consistentHashing() bool {
+	return a.Config.Clustering != nil && a.Config.Clustering.Distribution == config.DistributionConsistentHash
+}
+
+// selectTargetService selects the service a target is assigned to
+// based on the configured distribution strategy.
+func (a *App) selectTargetService(tc *types.TargetConfig, denied ...string) (*lockers.Service, error) {
+	if a.consistentHashing() {
+		return a.selectHashedService(tc.Name, tc.Tags, denied...)
+	}
+	return a.selectService(tc.Tags, denied...)
+}
+
+// selectHashedService selects the service of the instance the target name hashes to,
+// among the instances with the highest tags match.
+// It reads the hash ring built by membersUpdated and must be called without the config lock held.
+func (a *App) selectHashedService(name string, tags []string, denied ...string) (*lockers.Service, error) {
+	a.configLock.RLock()
+	numServices := len(a.apiServices)
+	ring := a.hashRing
+	candidates := a.candidateInstances(tags, denied...)
+	a.configLock.RUnlock()
+	if numServices == 0 || ring == nil {
+		return nil, errNotFound
+	}
+	if len(candidates) == 0 {
+		return nil, errNoMoreSuitableServices
+	}
+	load, err := a.getInstancesLoad(candidates...)
+	if err != nil {
+		return nil, err
+	}
+	instance := ring.place(name, candidates, load, a.Config.Clustering.LoadFactor)
+	a.Logger.Printf("selected service name: %s", instance)
+	a.configLock.RLock()
+	srv, ok := a.apiServices[instance+"-api"]
+	a.configLock.RUnlock()
+	if ok {
+		return srv, nil
+	}
+	return nil, errNotFound
+}
+
+// candidateInstances returns the sorted names of the instances with the highest tags match,
+// excluding the denied services.
+func (a *App) candidateInstances(tags []string, denied ...string) []string {
+	var instances []string
+	tagCount := a.getInstancesTagsMatches(tags)
+	if len(tagCount) > 0 {
+		instances = a.getHighestTagsMatches(tagCount)
+	} else {
+		instances = a.instanceNames()
+	}
+	candidates := make([]string, 0, len(instances))
+	for _, inst := range instances {
+		if slices.Contains(denied, inst+"-api") {
+			continue
+		}
+		candidates = append(candidates, inst)
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// instanceNames returns the sorted names of the registered instances.
+func (a *App) instanceNames() []string {
+	names := make([]string, 0, len(a.apiServices))
+	for n := range a.apiServices {
+		names = append(names, strings.TrimSuffix(n, "-api"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// membersUpdated rebuilds the hash ring if the cluster members changed
+// and notifies the leader that the targets need to be rebalanced.
+// It is called with the config lock held.
+func (a *App) membersUpdated(prev []string) {
+	instances := a.instanceNames()
+	if a.hashRing != nil && slices.Equal(prev, instances) {
+		return
+	}
+	a.hashRing = newHashRing(instances...)
+	if !a.isLeader || !a.consistentHashing() || len(instances) == 0 {
+		return
+	}
+	select {
+	case a.rebalanceCh <- struct{}{}:
+	default:
+		// a rebalance is already pending
+	}
+}
+
+// watchRebalance rebalances the targets when the cluster members change.
+// It runs on the leader as long as ctx is not done.
+func (a *App) watchRebalance(ctx context.Context) {
+	if !a.consistentHashing() {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.rebalanceCh:
+			// give the new members time to settle
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(a.Config.Clustering.LeaderWaitTimer):
+			}
+			moves, err := a.rebalanceTargets(ctx)
+			if err != nil {
+				a.Logger.Printf("[cluster-leader] targets rebalance failed: %v", err)
+				continue
+			}
+			a.Logger.Printf("[cluster-leader] targets rebalance moved %d target(s)", len(moves))
+		}
+	}
+}
+
+// rebalanceTargets moves the locked targets whose consistent hash placement changed
+// since they were assigned, e.g: after an instance joined the cluster.
+// The targets are walked in order and placed using the current load,
+// only the targets that would be placed on a different instance are moved to it.
+func (a *App) rebalanceTargets(ctx context.Context) ([]*targetMove, error) {
+	if !a.consistentHashing() {
+		return nil, fmt.Errorf("rebalancing requires the %q distribution", config.DistributionConsistentHash)
+	}
+	if !a.isLeader {
+		return nil, errNotLeader
+	}
+	a.dispatchLock.Lock()
+	defer a.dispatchLock.Unlock()
+
+	locks, err := a.getTargetToInstanceMapping()
+	if err != nil {
+		return nil, err
+	}
+	load := make(map[string]int)
+	names := make([]string, 0, len(locks))
+	for name, instance := range locks {
+		load[instance]++
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	a.configLock.RLock()
+	ring := a.hashRing
+	a.configLock.RUnlock()
+	if ring == nil {
+		return nil, errNotFound
+	}
+
+	draining := a.drainingServices(ctx)
+	moves := make([]*targetMove, 0)
+	for _, name := range names {
+		a.configLock.RLock()
+		tc, ok := a.Config.Targets[name]
+		_, registered := a.apiServices[locks[name]+"-api"]
+		var candidates []string
+		if ok {
+			candidates = a.candidateInstances(tc.Tags, draining...)
+		}
+		a.configLock.RUnlock()
+		// unknown target or its instance left the cluster,
+		// it will be dispatched again once its lock expires.
+		// targets of draining instances are migrated separately.
+		if !ok || !registered || slices.Contains(draining, locks[name]+"-api") {
+			continue
+		}
+		from := locks[name]
+		load[from]--
+		to := ring.place(name, candidates, load, a.Config.Clustering.LoadFactor)
+		if to == "" || to == from {
+			load[from]++
+			continue
+		}
+		to, err = a.moveTarget(ctx, tc, from, to, draining)
+		if err != nil {
+			a.Logger.Printf("[cluster-leader] %v", err)
+			continue
+		}
+		load[to]++
+		moves = append(moves, &targetMove{Target: name, From: from, To: to})
+	}
+	return moves, nil
+}
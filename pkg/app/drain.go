@@ -0,0 +1,346 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/cobra"
+
+	"github.com/openconfig/gnmic/pkg/lockers"
+)
+
+// drain holds the state of an instance in drain mode.
+type drain struct {
+	cancel context.CancelFunc
+}
+
+// startDrain puts this instance in drain mode.
+// A drain key is locked and maintained until the drain is stopped
+// or the instance stops, the cluster leader does not assign targets
+// to draining instances and moves their targets to the other instances.
+func (a *App) startDrain() error {
+	a.configLock.RLock()
+	draining := a.drain != nil
+	a.configLock.RUnlock()
+	if draining {
+		return nil
+	}
+	key := a.drainLockKey(a.Config.Clustering.InstanceName)
+	lctx, lcancel := context.WithTimeout(a.ctx, a.Config.Clustering.TargetAssignmentTimeout)
+	defer lcancel()
+	ok, err := a.locker.Lock(lctx, key, []byte(a.Config.Clustering.InstanceName))
+	if err != nil {
+		return fmt.Errorf("failed to acquire drain lock %q: %v", key, err)
+	}
+	if !ok {
+		return fmt.Errorf("failed to acquire drain lock %q", key)
+	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	d := &drain{cancel: cancel}
+	a.configLock.Lock()
+	if a.drain != nil {
+		// started concurrently
+		a.configLock.Unlock()
+		cancel()
+		return nil
+	}
+	a.drain = d
+	a.configLock.Unlock()
+	a.Logger.Printf("instance %q is draining", a.Config.Clustering.InstanceName)
+	go func() {
+		defer cancel()
+		doneCh, errCh := a.locker.KeepLock(ctx, key)
+		select {
+		case <-ctx.Done():
+			// wait for KeepLock to return
+			select {
+			case <-errCh:
+			case <-doneCh:
+			}
+			return
+		case <-doneCh:
+			a.Logger.Printf("drain lock %q removed", key)
+		case err := <-errCh:
+			a.Logger.Printf("failed to maintain drain lock %q: %v", key, err)
+		}
+		a.configLock.Lock()
+		if a.drain == d {
+			a.drain = nil
+		}
+		a.configLock.Unlock()
+	}()
+	return nil
+}
+
+// stopDrain takes this instance out of drain mode,
+// the leader can assign targets to it again.
+func (a *App) stopDrain(ctx context.Context) error {
+	a.configLock.Lock()
+	d := a.drain
+	a.drain = nil
+	a.configLock.Unlock()
+	if d == nil {
+		return nil
+	}
+	d.cancel()
+	a.Logger.Printf("instance %q is no longer draining", a.Config.Clustering.InstanceName)
+	return a.locker.Unlock(ctx, a.drainLockKey(a.Config.Clustering.InstanceName))
+}
+
+// drainingInstances returns the names of the instances in drain mode.
+func (a *App) drainingInstances(ctx context.Context) (map[string]struct{}, error) {
+	locks, err := a.locker.List(ctx, fmt.Sprintf("gnmic/%s/drain", a.Config.Clustering.ClusterName))
+	if err != nil {
+		return nil, err
+	}
+	instances := make(map[string]struct{}, len(locks))
+	for _, instance := range locks {
+		instances[instance] = struct{}{}
+	}
+	return instances, nil
+}
+
+// drainingServices returns the API services IDs of the instances in drain mode.
+func (a *App) drainingServices(ctx context.Context) []string {
+	instances, err := a.drainingInstances(ctx)
+	if err != nil {
+		a.Logger.Printf("failed to get draining instances: %v", err)
+		return nil
+	}
+	ids := make([]string, 0, len(instances))
+	for instance := range instances {
+		ids = append(ids, instance+"-api")
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// migrateDrainedTargets moves the targets locked by the draining services
+// to the other instances, one target at a time.
+// A target is kept on its draining instance if no other instance can take it over.
+func (a *App) migrateDrainedTargets(ctx context.Context, draining []string) {
+	if len(draining) == 0 {
+		return
+	}
+	locks, err := a.getTargetToInstanceMapping()
+	if err != nil {
+		a.Logger.Printf("[cluster-leader] failed to get targets locks: %v", err)
+		return
+	}
+	names := make([]string, 0)
+	for name, instance := range locks {
+		for _, id := range draining {
+			if id == instance+"-api" {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	a.Logger.Printf("[cluster-leader] migrating %d target(s) from draining instances", len(names))
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return
+		}
+		a.configLock.RLock()
+		tc, ok := a.Config.Targets[name]
+		a.configLock.RUnlock()
+		if !ok {
+			continue
+		}
+		_, err = a.selectTargetService(tc, draining...)
+		if err != nil {
+			a.Logger.Printf("[cluster-leader] no instance available to take over target %q: %v", name, err)
+			continue
+		}
+		_, err = a.moveTarget(ctx, tc, locks[name], "", draining)
+		if err != nil {
+			a.Logger.Printf("[cluster-leader] %v", err)
+		}
+	}
+}
+
+func (a *App) handleClusteringMemberDrainPost(w http.ResponseWriter, r *http.Request) {
+	a.handleClusteringMemberDrain(w, r, true)
+}
+
+func (a *App) handleClusteringMemberDrainDelete(w http.ResponseWriter, r *http.Request) {
+	a.handleClusteringMemberDrain(w, r, false)
+}
+
+// handleClusteringMemberDrain starts or stops the drain mode of a cluster member.
+// Requests targeting another member are forwarded to it.
+func (a *App) handleClusteringMemberDrain(w http.ResponseWriter, r *http.Request, start bool) {
+	if a.Config.Clustering == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{"clustering is not enabled"}})
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if id != a.Config.Clustering.InstanceName {
+		code, body, err := a.forwardToMember(r.Context(), id, r.Method, r.URL.Path)
+		if err != nil {
+			code = http.StatusInternalServerError
+			if errors.Is(err, errNotFound) {
+				code = http.StatusNotFound
+				err = fmt.Errorf("member %q not found", id)
+			}
+			w.WriteHeader(code)
+			json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+			return
+		}
+		w.WriteHeader(code)
+		w.Write(body)
+		return
+	}
+	var err error
+	if start {
+		err = a.startDrain()
+	} else {
+		err = a.stopDrain(r.Context())
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+}
+
+// forwardToMember sends an API request to the cluster member id,
+// it returns the response status code and body.
+func (a *App) forwardToMember(ctx context.Context, id, method, path string) (int, []byte, error) {
+	services, err := a.locker.GetServices(ctx, fmt.Sprintf("%s-%s", a.Config.Clustering.ClusterName, apiServiceName), nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, s := range services {
+		if s.ID != id+"-api" {
+			continue
+		}
+		client, scheme := serviceHTTPClient(s)
+		req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s://%s%s", scheme, s.Address, path), nil)
+		if err != nil {
+			return 0, nil, err
+		}
+		rsp, err := client.Do(req)
+		if err != nil {
+			return 0, nil, err
+		}
+		defer rsp.Body.Close()
+		body, err := io.ReadAll(rsp.Body)
+		if err != nil {
+			return 0, nil, err
+		}
+		return rsp.StatusCode, body, nil
+	}
+	return 0, nil, errNotFound
+}
+
+func serviceHTTPClient(s *lockers.Service) (*http.Client, string) {
+	scheme := "http"
+	client := &http.Client{
+		Timeout: defaultHTTPClientTimeout,
+	}
+	for _, t := range s.Tags {
+		if strings.HasPrefix(t, "protocol=") {
+			scheme = strings.Split(t, "=")[1]
+			break
+		}
+	}
+	if scheme == "https" {
+		client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+		}
+	}
+	return client, scheme
+}
+
+// cluster command
+
+func (a *App) ClusterDrainRunE(cmd *cobra.Command, args []string) error {
+	err := a.clusterMemberDrainRequest(http.MethodPost, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "member %q is draining\n", args[0])
+	return nil
+}
+
+func (a *App) ClusterResumeRunE(cmd *cobra.Command, args []string) error {
+	err := a.clusterMemberDrainRequest(http.MethodDelete, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "member %q resumed\n", args[0])
+	return nil
+}
+
+// clusterMemberDrainRequest sends a drain request for member to the
+// gNMIc API server set with --api or under api-server in the config file.
+func (a *App) clusterMemberDrainRequest(method, member string) error {
+	err := a.Config.GetAPIServer()
+	if err != nil {
+		return err
+	}
+	if a.Config.APIServer == nil {
+		return errors.New("missing gnmic API address, set it with --api")
+	}
+	scheme := "http"
+	client := &http.Client{
+		Timeout: defaultHTTPClientTimeout,
+	}
+	if a.Config.APIServer.TLS != nil {
+		scheme = "https"
+		client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: a.Config.SkipVerify,
+			},
+		}
+	}
+	addr := a.Config.APIServer.Address
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	url := fmt.Sprintf("%s://%s/api/v1/cluster/members/%s/drain", scheme, addr, member)
+	ctx, cancel := context.WithTimeout(a.Context(), defaultHTTPClientTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return err
+	}
+	rsp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode == http.StatusOK {
+		return nil
+	}
+	apiErrs := new(APIErrors)
+	err = json.NewDecoder(rsp.Body).Decode(apiErrs)
+	if err != nil || len(apiErrs.Errors) == 0 {
+		return fmt.Errorf("%s %s: status code=%d", method, url, rsp.StatusCode)
+	}
+	return fmt.Errorf("%s %s: status code=%d: %s", method, url, rsp.StatusCode, strings.Join(apiErrs.Errors, ", "))
+}
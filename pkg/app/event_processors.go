@@ -0,0 +1,161 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"slices"
+	"sort"
+
+	"github.com/openconfig/gnmic/pkg/config"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+// CreateProcessor validates and adds a new event processor configuration.
+func (a *App) CreateProcessor(name string, cfg map[string]interface{}) error {
+	err := a.validateProcessorConfig(name, cfg)
+	if err != nil {
+		return err
+	}
+	a.configLock.Lock()
+	defer a.configLock.Unlock()
+	if _, ok := a.Config.Processors[name]; ok {
+		return fmt.Errorf("processor %q %w", name, errAlreadyExists)
+	}
+	a.Config.Processors[name] = cfg
+	return nil
+}
+
+// UpdateProcessor validates and replaces an existing event processor configuration,
+// then restarts the outputs and inputs using it.
+func (a *App) UpdateProcessor(ctx context.Context, name string, cfg map[string]interface{}) error {
+	err := a.validateProcessorConfig(name, cfg)
+	if err != nil {
+		return err
+	}
+	a.configLock.Lock()
+	if _, ok := a.Config.Processors[name]; !ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("processor %q %w", name, errNotFound)
+	}
+	a.Config.Processors[name] = cfg
+	procs := changedProcessors(a.Config, &config.Diff{
+		Processors: &config.SectionDiff{Updated: []string{name}},
+		Actions:    new(config.SectionDiff),
+	})
+	outs := usingProcessors(a.Config.Outputs, procs, new(config.SectionDiff))
+	ins := usingProcessors(a.Config.Inputs, procs, new(config.SectionDiff))
+	tcs := maps.Clone(a.Config.Targets)
+	a.configLock.Unlock()
+
+	for _, n := range outs {
+		a.Logger.Printf("restarting output %q", n)
+		err = a.DeleteOutput(n)
+		if err != nil {
+			a.Logger.Printf("%v", err)
+		}
+		a.InitOutput(ctx, n, tcs)
+	}
+	// inputs writing to the restarted outputs are restarted as well.
+	for _, n := range a.inputsUsingOutputs(outs...) {
+		if !slices.Contains(ins, n) {
+			ins = append(ins, n)
+		}
+	}
+	a.restartInputs(ctx, ins, tcs)
+	return nil
+}
+
+// DeleteProcessor removes an event processor configuration.
+// A processor used by an output, an input or another processor cannot be deleted.
+func (a *App) DeleteProcessor(name string) error {
+	a.configLock.Lock()
+	defer a.configLock.Unlock()
+	if _, ok := a.Config.Processors[name]; !ok {
+		return fmt.Errorf("processor %q %w", name, errNotFound)
+	}
+	refs := map[string]struct{}{name: {}}
+	users := make([]string, 0)
+	for n, cfg := range a.Config.Outputs {
+		if referencesAny(cfg["event-processors"], refs) {
+			users = append(users, fmt.Sprintf("output %q", n))
+		}
+	}
+	for n, cfg := range a.Config.Inputs {
+		if referencesAny(cfg["event-processors"], refs) {
+			users = append(users, fmt.Sprintf("input %q", n))
+		}
+	}
+	for n, pcfg := range a.Config.Processors {
+		for _, v := range pcfg {
+			cfg, ok := v.(map[string]interface{})
+			if ok && referencesAny(cfg["processors"], refs) {
+				users = append(users, fmt.Sprintf("processor %q", n))
+			}
+		}
+	}
+	if len(users) > 0 {
+		sort.Strings(users)
+		return fmt.Errorf("processor %q %w: %v", name, errInUse, users)
+	}
+	delete(a.Config.Processors, name)
+	return nil
+}
+
+// validateProcessorConfig checks the processor type and
+// makes sure the processor can be initialized with the given config.
+func (a *App) validateProcessorConfig(name string, cfg map[string]interface{}) error {
+	err := a.Config.ValidateProcessorConfig(cfg)
+	if err != nil {
+		return err
+	}
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	ps := make(map[string]map[string]interface{}, len(a.Config.Processors)+1)
+	for n, p := range a.Config.Processors {
+		ps[n] = p
+	}
+	ps[name] = cfg
+	_, err = formatters.MakeEventProcessors(
+		log.New(io.Discard, "", 0),
+		[]string{name},
+		ps,
+		a.Config.Targets,
+		a.Config.Actions,
+	)
+	return err
+}
+
+// checkProcessorsExist returns an error for each name in the
+// event processors list v that is not a configured processor.
+func (a *App) checkProcessorsExist(v interface{}) []error {
+	var names []string
+	switch v := v.(type) {
+	case []string:
+		names = v
+	case []interface{}:
+		for _, n := range v {
+			if s, ok := n.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	errs := make([]error, 0)
+	for _, n := range names {
+		if _, ok := a.Config.Processors[n]; !ok {
+			errs = append(errs, fmt.Errorf("unknown event processor %q", n))
+		}
+	}
+	return errs
+}
@@ -54,6 +54,12 @@ func (a *App) GetRun(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed getting targets config: %v", err)
 	}
+	if a.Config.ValidatePaths {
+		err = a.validateGetPaths(targetsConfig)
+		if err != nil {
+			return fmt.Errorf("invalid get paths:\n%v", err)
+		}
+	}
 	_, err = a.Config.GetActions()
 	if err != nil {
 		return fmt.Errorf("failed reading actions config: %v", err)
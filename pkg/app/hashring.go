@@ -0,0 +1,100 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// number of points each instance has on the hash ring,
+// more points give a more even distribution.
+const hashRingReplicas = 128
+
+// hashRing places the cluster instances on a consistent hash ring.
+type hashRing struct {
+	points []uint64
+	owners map[uint64]string
+}
+
+func newHashRing(instances ...string) *hashRing {
+	r := &hashRing{
+		points: make([]uint64, 0, len(instances)*hashRingReplicas),
+		owners: make(map[uint64]string, len(instances)*hashRingReplicas),
+	}
+	for _, inst := range instances {
+		for i := 0; i < hashRingReplicas; i++ {
+			h := hashKey(inst + "#" + strconv.Itoa(i))
+			if _, ok := r.owners[h]; ok {
+				continue
+			}
+			r.owners[h] = inst
+			r.points = append(r.points, h)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// place returns the instance the key is assigned to, using consistent hashing with bounded loads:
+// the ring is walked clockwise starting at the key hash, the first candidate instance
+// with a load below the capacity is selected.
+// The capacity is the average load of the candidates (including the key being placed)
+// multiplied by loadFactor.
+// If all candidates are at capacity, the first candidate on the ring is returned.
+func (r *hashRing) place(key string, candidates []string, load map[string]int, loadFactor float64) string {
+	if len(candidates) == 0 || len(r.points) == 0 {
+		return ""
+	}
+	allowed := make(map[string]struct{}, len(candidates))
+	total := 1
+	for _, c := range candidates {
+		allowed[c] = struct{}{}
+		total += load[c]
+	}
+	capacity := int(math.Ceil(loadFactor * float64(total) / float64(len(candidates))))
+
+	h := hashKey(key)
+	start := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	first := ""
+	seen := make(map[string]struct{}, len(candidates))
+	for i := 0; i < len(r.points) && len(seen) < len(allowed); i++ {
+		inst := r.owners[r.points[(start+i)%len(r.points)]]
+		if _, ok := allowed[inst]; !ok {
+			continue
+		}
+		if _, ok := seen[inst]; ok {
+			continue
+		}
+		seen[inst] = struct{}{}
+		if first == "" {
+			first = inst
+		}
+		if load[inst] < capacity {
+			return inst
+		}
+	}
+	return first
+}
+
+// hashKey returns the FNV-1a hash of s, mixed with the murmur3 finalizer
+// to spread similar keys (e.g: instance-1, instance-2) over the ring.
+func hashKey(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,94 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func placeAll(r *hashRing, keys, candidates []string, loadFactor float64) (map[string]string, map[string]int) {
+	placement := make(map[string]string, len(keys))
+	load := make(map[string]int)
+	for _, k := range keys {
+		inst := r.place(k, candidates, load, loadFactor)
+		placement[k] = inst
+		load[inst]++
+	}
+	return placement, load
+}
+
+func testKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("target%d", i)
+	}
+	return keys
+}
+
+func TestHashRingBoundedLoad(t *testing.T) {
+	instances := []string{"gnmic1", "gnmic2", "gnmic3", "gnmic4"}
+	keys := testKeys(1000)
+	for _, lf := range []float64{1, 1.25, 2} {
+		t.Run(fmt.Sprintf("load-factor=%v", lf), func(t *testing.T) {
+			r := newHashRing(instances...)
+			_, load := placeAll(r, keys, instances, lf)
+			capacity := int(math.Ceil(lf * float64(len(keys)) / float64(len(instances))))
+			total := 0
+			for inst, l := range load {
+				if l > capacity {
+					t.Errorf("instance %q load %d is above capacity %d", inst, l, capacity)
+				}
+				total += l
+			}
+			if total != len(keys) {
+				t.Errorf("expected %d placed keys, got %d", len(keys), total)
+			}
+		})
+	}
+}
+
+func TestHashRingMinimalMoves(t *testing.T) {
+	keys := testKeys(1000)
+	instances := []string{"gnmic1", "gnmic2", "gnmic3"}
+	before, _ := placeAll(newHashRing(instances...), keys, instances, math.MaxInt32)
+
+	instances = append(instances, "gnmic4")
+	after, _ := placeAll(newHashRing(instances...), keys, instances, math.MaxInt32)
+	moved := 0
+	for k, inst := range after {
+		if inst == before[k] {
+			continue
+		}
+		moved++
+		if inst != "gnmic4" {
+			t.Errorf("key %q moved from %q to %q, expected to move to the new instance only", k, before[k], inst)
+		}
+	}
+	// ideally 1/4 of the keys move to the new instance
+	if moved == 0 || moved > len(keys)/2 {
+		t.Errorf("unexpected number of moved keys: %d", moved)
+	}
+	t.Logf("moved %d/%d keys", moved, len(keys))
+}
+
+func TestHashRingCandidates(t *testing.T) {
+	r := newHashRing("gnmic1", "gnmic2", "gnmic3")
+	candidates := []string{"gnmic2"}
+	placement, _ := placeAll(r, testKeys(100), candidates, 1.25)
+	for k, inst := range placement {
+		if inst != "gnmic2" {
+			t.Errorf("key %q placed on %q, expected %q", k, inst, "gnmic2")
+		}
+	}
+	if inst := r.place("target", nil, nil, 1.25); inst != "" {
+		t.Errorf("expected no placement without candidates, got %q", inst)
+	}
+}
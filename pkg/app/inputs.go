@@ -10,6 +10,9 @@ package app
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"maps"
 
 	"github.com/openconfig/gnmic/pkg/api/types"
 	"github.com/openconfig/gnmic/pkg/inputs"
@@ -26,6 +29,11 @@ func (a *App) InitInput(ctx context.Context, name string, tcs map[string]*types.
 			a.Logger.Printf("starting input type %s", inputType)
 			if initializer, ok := inputs.Inputs[inputType.(string)]; ok {
 				in := initializer()
+				// the input keeps the outputs running when it starts,
+				// it is restarted when they are re-initialized.
+				a.operLock.RLock()
+				outs := maps.Clone(a.Outputs)
+				a.operLock.RUnlock()
 				go func() {
 					err := in.Start(ctx, name, cfg,
 						inputs.WithLogger(a.Logger),
@@ -36,7 +44,7 @@ func (a *App) InitInput(ctx context.Context, name string, tcs map[string]*types.
 							a.Config.Actions,
 						),
 						inputs.WithName(a.Config.InstanceName),
-						inputs.WithOutputs(a.Outputs),
+						inputs.WithOutputs(outs),
 					)
 					if err != nil {
 						a.Logger.Printf("failed to init input type %q: %v", inputType, err)
@@ -55,3 +63,88 @@ func (a *App) InitInputs(ctx context.Context) {
 		a.InitInput(ctx, name, a.Config.Targets)
 	}
 }
+
+func (a *App) DeleteInput(name string) error {
+	if a.Inputs == nil {
+		return nil
+	}
+	a.operLock.Lock()
+	defer a.operLock.Unlock()
+	in, ok := a.Inputs[name]
+	if !ok {
+		return fmt.Errorf("input %q does not exist", name)
+	}
+	err := in.Close()
+	if err != nil {
+		a.Logger.Printf("failed to close input %q: %v", name, err)
+	}
+	delete(a.Inputs, name)
+	return nil
+}
+
+// CreateInput validates and adds a new input configuration, then starts it.
+func (a *App) CreateInput(ctx context.Context, name string, cfg map[string]interface{}) error {
+	err := a.validateInputConfig(cfg)
+	if err != nil {
+		return err
+	}
+	a.configLock.Lock()
+	if _, ok := a.Config.Inputs[name]; ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("input %q %w", name, errAlreadyExists)
+	}
+	a.Config.Inputs[name] = cfg
+	tcs := maps.Clone(a.Config.Targets)
+	a.configLock.Unlock()
+	a.InitInput(ctx, name, tcs)
+	return nil
+}
+
+// UpdateInput validates and replaces an existing input configuration,
+// then restarts the input.
+func (a *App) UpdateInput(ctx context.Context, name string, cfg map[string]interface{}) error {
+	err := a.validateInputConfig(cfg)
+	if err != nil {
+		return err
+	}
+	a.configLock.Lock()
+	if _, ok := a.Config.Inputs[name]; !ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("input %q %w", name, errNotFound)
+	}
+	a.Config.Inputs[name] = cfg
+	tcs := maps.Clone(a.Config.Targets)
+	a.configLock.Unlock()
+	err = a.DeleteInput(name)
+	if err != nil {
+		a.Logger.Printf("%v", err)
+	}
+	a.InitInput(ctx, name, tcs)
+	return nil
+}
+
+// DeleteInputConfig stops an input and removes its configuration.
+func (a *App) DeleteInputConfig(name string) error {
+	a.configLock.Lock()
+	if _, ok := a.Config.Inputs[name]; !ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("input %q %w", name, errNotFound)
+	}
+	delete(a.Config.Inputs, name)
+	a.configLock.Unlock()
+	err := a.DeleteInput(name)
+	if err != nil {
+		a.Logger.Printf("%v", err)
+	}
+	return nil
+}
+
+func (a *App) validateInputConfig(cfg map[string]interface{}) error {
+	errs := make([]error, 0)
+	err := a.Config.ValidateInputConfig(cfg)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, a.checkProcessorsExist(cfg["event-processors"])...)
+	return errors.Join(errs...)
+}
@@ -77,11 +77,11 @@ START:
 			// clustered, dispatch
 			a.configLock.Lock()
 			a.Config.Targets[add.Name] = add
-			err = a.dispatchTarget(ctx, add)
+			a.configLock.Unlock()
+			err = a.dispatchTarget(ctx, add, a.drainingServices(ctx))
 			if err != nil {
 				a.Logger.Printf("failed dispatching target %q: %v", add.Name, err)
 			}
-			a.configLock.Unlock()
 		}
 	}
 	a.Logger.Printf("target loader stopped")
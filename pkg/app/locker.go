@@ -19,3 +19,7 @@ func (a *App) targetLockKey(s string) string {
 	}
 	return fmt.Sprintf("gnmic/%s/targets/%s", a.Config.Clustering.ClusterName, s)
 }
+
+func (a *App) drainLockKey(instance string) string {
+	return fmt.Sprintf("gnmic/%s/drain/%s", a.Config.Clustering.ClusterName, instance)
+}
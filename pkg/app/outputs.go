@@ -10,11 +10,17 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"sort"
 	"sync"
 
 	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
 	"github.com/openconfig/gnmic/pkg/outputs"
+	"github.com/openconfig/gnmic/pkg/outputs/buffer"
 )
 
 func (a *App) InitOutput(ctx context.Context, name string, tcs map[string]*types.TargetConfig) {
@@ -24,11 +30,16 @@ func (a *App) InitOutput(ctx context.Context, name string, tcs map[string]*types
 		return
 	}
 	wg := new(sync.WaitGroup)
+	var buffered bool
+	var initErr error
 	if cfg, ok := a.Config.Outputs[name]; ok {
 		if outType, ok := cfg["type"]; ok {
 			a.Logger.Printf("starting output type %s", outType)
 			if initializer, ok := outputs.Outputs[outType.(string)]; ok {
 				out := initializer()
+				if _, buffered = cfg["buffer"]; buffered {
+					out = buffer.NewOutput(out)
+				}
 				wg.Add(1)
 				go func() {
 					defer wg.Done()
@@ -47,6 +58,7 @@ func (a *App) InitOutput(ctx context.Context, name string, tcs map[string]*types
 					)
 					if err != nil {
 						a.Logger.Printf("failed to init output type %q: %v", outType, err)
+						initErr = err
 					}
 				}()
 				a.operLock.Lock()
@@ -56,6 +68,12 @@ func (a *App) InitOutput(ctx context.Context, name string, tcs map[string]*types
 		}
 	}
 	wg.Wait()
+	// a buffered output that failed to initialize drops all the messages written to it.
+	if buffered && initErr != nil {
+		a.operLock.Lock()
+		delete(a.Outputs, name)
+		a.operLock.Unlock()
+	}
 }
 
 func (a *App) InitOutputs(ctx context.Context) {
@@ -98,3 +116,116 @@ func (a *App) DeleteOutput(name string) error {
 	delete(a.Outputs, name)
 	return nil
 }
+
+// CreateOutput validates and adds a new output configuration, then starts it.
+func (a *App) CreateOutput(ctx context.Context, name string, cfg map[string]interface{}) error {
+	err := a.validateOutputConfig(cfg)
+	if err != nil {
+		return err
+	}
+	a.configLock.Lock()
+	if _, ok := a.Config.Outputs[name]; ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("output %q %w", name, errAlreadyExists)
+	}
+	a.Config.Outputs[name] = cfg
+	tcs := maps.Clone(a.Config.Targets)
+	a.configLock.Unlock()
+	a.InitOutput(ctx, name, tcs)
+	// inputs without an outputs list write to all outputs.
+	a.restartInputs(ctx, a.inputsUsingOutputs(name), tcs)
+	return nil
+}
+
+// UpdateOutput validates and replaces an existing output configuration,
+// then restarts the output.
+func (a *App) UpdateOutput(ctx context.Context, name string, cfg map[string]interface{}) error {
+	err := a.validateOutputConfig(cfg)
+	if err != nil {
+		return err
+	}
+	a.configLock.Lock()
+	if _, ok := a.Config.Outputs[name]; !ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("output %q %w", name, errNotFound)
+	}
+	a.Config.Outputs[name] = cfg
+	tcs := maps.Clone(a.Config.Targets)
+	a.configLock.Unlock()
+	err = a.DeleteOutput(name)
+	if err != nil {
+		a.Logger.Printf("%v", err)
+	}
+	a.InitOutput(ctx, name, tcs)
+	a.restartInputs(ctx, a.inputsUsingOutputs(name), tcs)
+	return nil
+}
+
+// DeleteOutputConfig stops an output and removes its configuration.
+// An output referenced by a subscription or a target cannot be deleted.
+func (a *App) DeleteOutputConfig(name string) error {
+	a.configLock.Lock()
+	if _, ok := a.Config.Outputs[name]; !ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("output %q %w", name, errNotFound)
+	}
+	users := make([]string, 0)
+	for sn, sc := range a.Config.Subscriptions {
+		if slices.Contains(sc.Outputs, name) {
+			users = append(users, fmt.Sprintf("subscription %q", sn))
+		}
+	}
+	for tn, tc := range a.Config.Targets {
+		if slices.Contains(tc.Outputs, name) {
+			users = append(users, fmt.Sprintf("target %q", tn))
+		}
+	}
+	if len(users) > 0 {
+		a.configLock.Unlock()
+		sort.Strings(users)
+		return fmt.Errorf("output %q %w: %v", name, errInUse, users)
+	}
+	delete(a.Config.Outputs, name)
+	tcs := maps.Clone(a.Config.Targets)
+	a.configLock.Unlock()
+	err := a.DeleteOutput(name)
+	if err != nil {
+		a.Logger.Printf("%v", err)
+	}
+	a.restartInputs(a.ctx, a.inputsUsingOutputs(name), tcs)
+	return nil
+}
+
+// inputsUsingOutputs returns the names of the inputs writing to any of the outputs.
+func (a *App) inputsUsingOutputs(names ...string) []string {
+	outs := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		outs[n] = struct{}{}
+	}
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	return usingOutputs(a.Config.Inputs, outs, new(config.SectionDiff))
+}
+
+// restartInputs stops and starts the named inputs,
+// they pick up the output instances currently running.
+func (a *App) restartInputs(ctx context.Context, names []string, tcs map[string]*types.TargetConfig) {
+	for _, n := range names {
+		a.Logger.Printf("restarting input %q", n)
+		err := a.DeleteInput(n)
+		if err != nil {
+			a.Logger.Printf("%v", err)
+		}
+		a.InitInput(ctx, n, tcs)
+	}
+}
+
+func (a *App) validateOutputConfig(cfg map[string]interface{}) error {
+	errs := make([]error, 0)
+	err := a.Config.ValidateOutputConfig(cfg)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, a.checkProcessorsExist(cfg["event-processors"])...)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,40 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/openconfig/gnmic/pkg/outputs/file"
+)
+
+func TestInitOutputBufferFailure(t *testing.T) {
+	a := New()
+	a.Config.Outputs["out1"] = map[string]interface{}{
+		"type":      "file",
+		"file-type": "stdout",
+		"buffer":    map[string]interface{}{"directory": t.TempDir()},
+	}
+	a.Config.Outputs["out2"] = map[string]interface{}{
+		"type":      "file",
+		"file-type": "stdout",
+	}
+	a.InitOutput(context.Background(), "out1", a.Config.Targets)
+	a.InitOutput(context.Background(), "out2", a.Config.Targets)
+	defer a.DeleteOutput("out2")
+	// the file output does not report the delivery of messages,
+	// its buffer fails to initialize.
+	if _, ok := a.Outputs["out1"]; ok {
+		t.Errorf("buffered output registered after failing to initialize")
+	}
+	if _, ok := a.Outputs["out2"]; !ok {
+		t.Errorf("output not registered")
+	}
+}
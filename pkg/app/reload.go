@@ -0,0 +1,428 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
+)
+
+// ReloadConfig re-reads the configuration file and applies the changes
+// to the running outputs, inputs, processors, subscriptions and targets.
+// ctx bounds the time spent waiting for a concurrent reload and reading the file,
+// the re-initialized outputs, inputs and targets run under the App context.
+func (a *App) ReloadConfig(ctx context.Context) (*config.Diff, error) {
+	err := a.sem.Acquire(ctx, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire config reload semaphore: %w", err)
+	}
+	defer a.sem.Release(1)
+	err = a.Config.ReloadFile(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return a.applyConfig()
+}
+
+func (a *App) watchConfig() {
+	a.Logger.Printf("watching config...")
+	a.Config.FileConfig.OnConfigChange(a.configFileChanged)
+	a.Config.FileConfig.WatchConfig()
+}
+
+// configFileChanged is called by the config file watcher,
+// the file content is already (re)read at this point.
+func (a *App) configFileChanged(e fsnotify.Event) {
+	a.Logger.Printf("got config change notification: %v", e)
+	switch e.Op {
+	case fsnotify.Write, fsnotify.Create:
+		ctx, cancel := context.WithCancel(a.ctx)
+		defer cancel()
+		err := a.sem.Acquire(ctx, 1)
+		if err != nil {
+			a.Logger.Printf("failed to acquire config reload semaphore: %v", err)
+			return
+		}
+		defer a.sem.Release(1)
+		_, err = a.applyConfig()
+		if err != nil {
+			a.Logger.Printf("failed to reload config: %v", err)
+		}
+	}
+}
+
+func (a *App) watchSIGHUP() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-sigCh:
+			a.Logger.Printf("received SIGHUP, reloading config...")
+			_, err := a.ReloadConfig(a.ctx)
+			if err != nil {
+				a.Logger.Printf("failed to reload config: %v", err)
+			}
+		}
+	}
+}
+
+// applyConfig compares the reloadable sections of the configuration file
+// with the running configuration and applies the differences.
+// It assumes that the reload semaphore is acquired.
+func (a *App) applyConfig() (*config.Diff, error) {
+	newCfg, err := a.Config.ReadReloadable(a.subscribeCmd)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	a.configLock.Lock()
+	a.keepAPITargets(newCfg)
+	diff := a.Config.Diff(newCfg)
+	a.configLock.Unlock()
+	if diff.IsEmpty() {
+		a.Logger.Printf("config reload: no changes detected")
+		return diff, nil
+	}
+	a.Logger.Printf("config reload: applying changes: %s", diff)
+
+	procs := changedProcessors(newCfg, diff)
+	reinitOutputs := usingProcessors(newCfg.Outputs, procs, diff.Outputs)
+	reinitInputs := usingProcessors(newCfg.Inputs, procs, diff.Inputs)
+	// inputs keep the output instances they were started with,
+	// they are restarted to write to the closed and re-initialized outputs.
+	outs := make(map[string]struct{})
+	for _, n := range append(append(diff.Outputs.Changed(), diff.Outputs.Added...), reinitOutputs...) {
+		outs[n] = struct{}{}
+	}
+	for _, n := range usingOutputs(newCfg.Inputs, outs, diff.Inputs) {
+		if !slices.Contains(reinitInputs, n) {
+			reinitInputs = append(reinitInputs, n)
+		}
+	}
+
+	a.configLock.Lock()
+	a.Config.Processors = newCfg.Processors
+	a.Config.Actions = newCfg.Actions
+	a.Config.Outputs = newCfg.Outputs
+	a.Config.Inputs = newCfg.Inputs
+	a.Config.Subscriptions = newCfg.Subscriptions
+	tcs := maps.Clone(a.Config.Targets)
+	a.configLock.Unlock()
+
+	// outputs
+	for _, name := range append(diff.Outputs.Changed(), reinitOutputs...) {
+		a.Logger.Printf("config reload: closing output %q", name)
+		err = a.DeleteOutput(name)
+		if err != nil {
+			a.Logger.Printf("config reload: %v", err)
+		}
+	}
+	for _, name := range append(diff.Outputs.NewOrUpdated(), reinitOutputs...) {
+		a.Logger.Printf("config reload: initializing output %q", name)
+		a.InitOutput(a.ctx, name, tcs)
+	}
+	// inputs
+	for _, name := range append(diff.Inputs.Changed(), reinitInputs...) {
+		a.Logger.Printf("config reload: closing input %q", name)
+		err = a.DeleteInput(name)
+		if err != nil {
+			a.Logger.Printf("config reload: %v", err)
+		}
+	}
+	for _, name := range append(diff.Inputs.NewOrUpdated(), reinitInputs...) {
+		a.Logger.Printf("config reload: initializing input %q", name)
+		a.InitInput(a.ctx, name, tcs)
+	}
+	// targets
+	a.applyTargetsConfig(newCfg.Targets, diff)
+	// subscriptions
+	if !diff.Subscriptions.IsEmpty() {
+		// targets updated in this reload were already restarted
+		subs := append(diff.Subscriptions.Changed(), diff.Subscriptions.Added...)
+		for _, name := range a.targetsUsingSubscriptions(subs, diff.Targets.NewOrUpdated()) {
+			a.Logger.Printf("config reload: restarting target %q", name)
+			err = a.restartTarget(a.ctx, name)
+			if err != nil {
+				a.Logger.Printf("config reload: failed to restart target %q: %v", name, err)
+			}
+		}
+	}
+	return diff, nil
+}
+
+// keepAPITargets adds the targets created through the API to the new configuration,
+// so that a reload does not delete them. A target defined in the configuration file
+// with the same name takes over the one created through the API.
+// It is called with the config lock held.
+func (a *App) keepAPITargets(newCfg *config.Config) {
+	for name := range a.apiTargets {
+		if _, ok := newCfg.Targets[name]; ok {
+			delete(a.apiTargets, name)
+			continue
+		}
+		tc, ok := a.Config.Targets[name]
+		if !ok {
+			delete(a.apiTargets, name)
+			continue
+		}
+		if newCfg.Targets == nil {
+			newCfg.Targets = make(map[string]*types.TargetConfig)
+		}
+		a.Logger.Printf("config reload: keeping target %q created through the API", name)
+		newCfg.Targets[name] = tc
+	}
+}
+
+// applyTargetsConfig adds, deletes and updates targets based on the config diff.
+// In a cluster only the leader applies the targets changes.
+// Targets are not reconciled when they are discovered by a loader or
+// registered with the tunnel server.
+func (a *App) applyTargetsConfig(newTargets map[string]*types.TargetConfig, diff *config.Diff) {
+	if diff.Targets.IsEmpty() {
+		return
+	}
+	if len(a.Config.Loader) > 0 || a.Config.UseTunnelServer {
+		a.Logger.Printf("config reload: targets are managed by the loader or tunnel server, ignoring targets changes")
+		return
+	}
+	if !a.inCluster() {
+		for _, n := range diff.Targets.Changed() {
+			if a.Config.Debug {
+				a.Logger.Printf("target %q deleted from config", n)
+			}
+			err := a.DeleteTarget(a.ctx, n)
+			if err != nil {
+				a.Logger.Printf("failed to delete target %q: %v", n, err)
+			}
+		}
+		for _, n := range diff.Targets.NewOrUpdated() {
+			tc := newTargets[n]
+			if a.Config.Debug {
+				a.Logger.Printf("target %q added to config", n)
+			}
+			a.AddTargetConfig(tc)
+			a.wg.Add(1)
+			go a.subscribeStream(a.ctx, tc)
+		}
+		return
+	}
+	// in a cluster
+	if !a.isLeader {
+		return
+	}
+	for _, n := range diff.Targets.Changed() {
+		err := a.deleteTarget(a.ctx, n)
+		if err != nil {
+			a.Logger.Printf("failed to delete target %q: %v", n, err)
+		}
+	}
+	draining := a.drainingServices(a.ctx)
+	for _, n := range diff.Targets.NewOrUpdated() {
+		tc := newTargets[n]
+		a.configLock.Lock()
+		a.Config.Targets[n] = tc
+		a.configLock.Unlock()
+		err := a.dispatchTarget(a.ctx, tc, draining)
+		if err != nil {
+			a.Logger.Printf("failed to add target %q: %v", n, err)
+		}
+	}
+}
+
+// targetsUsingSubscriptions returns the names of the running targets
+// that use one of the subscriptions in subs.
+// Targets listed in skip are ignored.
+func (a *App) targetsUsingSubscriptions(subs []string, skip []string) []string {
+	skipped := make(map[string]struct{}, len(skip))
+	for _, n := range skip {
+		skipped[n] = struct{}{}
+	}
+	a.operLock.RLock()
+	defer a.operLock.RUnlock()
+	names := make([]string, 0, len(a.Targets))
+	for n, t := range a.Targets {
+		if _, ok := skipped[n]; ok {
+			continue
+		}
+		for _, sub := range subs {
+			if targetUsesSubscription(t.Config, sub) {
+				names = append(names, n)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// restartTarget stops a running target and subscribes to it again
+// using its current target and subscriptions configuration.
+func (a *App) restartTarget(ctx context.Context, name string) error {
+	a.configLock.RLock()
+	tc, ok := a.Config.Targets[name]
+	a.configLock.RUnlock()
+	if !ok {
+		return fmt.Errorf("target %q does not exist", name)
+	}
+	err := a.stopTarget(ctx, name)
+	if err != nil {
+		return err
+	}
+	a.wg.Add(1)
+	go a.subscribeStream(ctx, tc)
+	return nil
+}
+
+// targetUsesSubscription returns true if the target config references
+// the subscription or has no explicit subscriptions.
+func targetUsesSubscription(tc *types.TargetConfig, sub string) bool {
+	if len(tc.Subscriptions) == 0 {
+		return true
+	}
+	for _, s := range tc.Subscriptions {
+		if s == sub {
+			return true
+		}
+	}
+	return false
+}
+
+// changedProcessors returns the names of the processors that were added, deleted
+// or updated, as well as the processors referencing them or referencing a changed action.
+func changedProcessors(newCfg *config.Config, diff *config.Diff) map[string]struct{} {
+	procs := make(map[string]struct{})
+	for _, n := range append(diff.Processors.Changed(), diff.Processors.Added...) {
+		procs[n] = struct{}{}
+	}
+	acts := make(map[string]struct{})
+	for _, n := range append(diff.Actions.Changed(), diff.Actions.Added...) {
+		acts[n] = struct{}{}
+	}
+	for {
+		numChanged := len(procs)
+		for name, pcfg := range newCfg.Processors {
+			if _, ok := procs[name]; ok {
+				continue
+			}
+			for _, v := range pcfg {
+				cfg, ok := v.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if referencesAny(cfg["actions"], acts) || referencesAny(cfg["processors"], procs) {
+					procs[name] = struct{}{}
+				}
+			}
+		}
+		if len(procs) == numChanged {
+			return procs
+		}
+	}
+}
+
+// usingProcessors returns the names of the unchanged outputs or inputs
+// whose event-processors list references one of the processors in procs.
+func usingProcessors(cfgs map[string]map[string]interface{}, procs map[string]struct{}, sd *config.SectionDiff) []string {
+	if len(procs) == 0 {
+		return nil
+	}
+	skip := make(map[string]struct{})
+	for _, n := range append(sd.Changed(), sd.Added...) {
+		skip[n] = struct{}{}
+	}
+	names := make([]string, 0)
+	for name, cfg := range cfgs {
+		if _, ok := skip[name]; ok {
+			continue
+		}
+		if referencesAny(cfg["event-processors"], procs) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// usingOutputs returns the names of the inputs in cfgs writing to one of the outputs in outs,
+// skipping the inputs changed or added according to sd.
+// An input without an outputs list writes to all the outputs.
+func usingOutputs(cfgs map[string]map[string]interface{}, outs map[string]struct{}, sd *config.SectionDiff) []string {
+	if len(outs) == 0 {
+		return nil
+	}
+	skip := make(map[string]struct{})
+	for _, n := range append(sd.Changed(), sd.Added...) {
+		skip[n] = struct{}{}
+	}
+	names := make([]string, 0)
+	for name, cfg := range cfgs {
+		if _, ok := skip[name]; ok {
+			continue
+		}
+		if isEmptyList(cfg["outputs"]) || referencesAny(cfg["outputs"], outs) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// isEmptyList checks if v is a missing or empty list.
+func isEmptyList(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(v) == 0
+	case []string:
+		return len(v) == 0
+	}
+	return false
+}
+
+// referencesAny checks if the list v contains one of the names in refs.
+// The list items are either names or maps with a "name" field.
+func referencesAny(v interface{}, refs map[string]struct{}) bool {
+	if len(refs) == 0 {
+		return false
+	}
+	var items []interface{}
+	switch v := v.(type) {
+	case []interface{}:
+		items = v
+	case []string:
+		for _, s := range v {
+			items = append(items, s)
+		}
+	default:
+		return false
+	}
+	for _, item := range items {
+		var name string
+		switch item := item.(type) {
+		case string:
+			name = item
+		case map[string]interface{}:
+			name, _ = item["name"].(string)
+		}
+		if _, ok := refs[name]; ok {
+			return true
+		}
+	}
+	return false
+}
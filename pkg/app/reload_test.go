@@ -0,0 +1,72 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/openconfig/gnmic/pkg/config"
+)
+
+func TestUsingOutputs(t *testing.T) {
+	inputs := map[string]map[string]interface{}{
+		"in1": {"type": "nats", "outputs": []interface{}{"out1"}},
+		"in2": {"type": "nats", "outputs": []interface{}{"out2", "out3"}},
+		"in3": {"type": "kafka"},
+		"in4": {"type": "kafka", "outputs": []string{"out1"}},
+		"in5": {"type": "mqtt", "outputs": []interface{}{"out4"}},
+	}
+	tests := []struct {
+		name string
+		outs []string
+		sd   *config.SectionDiff
+		want []string
+	}{
+		{
+			name: "none",
+			sd:   new(config.SectionDiff),
+			want: []string{},
+		},
+		{
+			name: "referenced",
+			outs: []string{"out1", "out3"},
+			sd:   new(config.SectionDiff),
+			// in3 writes to all outputs
+			want: []string{"in1", "in2", "in3", "in4"},
+		},
+		{
+			name: "skip_changed_inputs",
+			outs: []string{"out1"},
+			sd:   &config.SectionDiff{Updated: []string{"in1"}, Added: []string{"in3"}},
+			want: []string{"in4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outs := make(map[string]struct{})
+			for _, n := range tt.outs {
+				outs[n] = struct{}{}
+			}
+			got := usingOutputs(inputs, outs, tt.sd)
+			if got == nil {
+				got = []string{}
+			}
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -26,7 +26,10 @@ func (a *App) routes() {
 func (a *App) clusterRoutes(r *mux.Router) {
 	r.HandleFunc("/cluster", a.handleClusteringGet).Methods(http.MethodGet)
 	r.HandleFunc("/cluster/members", a.handleClusteringMembersGet).Methods(http.MethodGet)
+	r.HandleFunc("/cluster/members/{id}/drain", a.handleClusteringMemberDrainPost).Methods(http.MethodPost)
+	r.HandleFunc("/cluster/members/{id}/drain", a.handleClusteringMemberDrainDelete).Methods(http.MethodDelete)
 	r.HandleFunc("/cluster/leader", a.handleClusteringLeaderGet).Methods(http.MethodGet)
+	r.HandleFunc("/cluster/rebalance", a.handleClusteringRebalancePost).Methods(http.MethodPost)
 }
 
 func (a *App) configRoutes(r *mux.Router) {
@@ -40,12 +43,28 @@ func (a *App) configRoutes(r *mux.Router) {
 	r.HandleFunc("/config/targets/{id}/subscriptions", a.handleConfigTargetsSubscriptions).Methods(http.MethodPatch)
 	// config/subscriptions
 	r.HandleFunc("/config/subscriptions", a.handleConfigSubscriptions).Methods(http.MethodGet)
+	r.HandleFunc("/config/subscriptions/{id}", a.handleConfigSubscriptions).Methods(http.MethodGet)
+	r.HandleFunc("/config/subscriptions", a.handleConfigSubscriptionsPost).Methods(http.MethodPost)
+	r.HandleFunc("/config/subscriptions/{id}", a.handleConfigSubscriptionsPut).Methods(http.MethodPut)
+	r.HandleFunc("/config/subscriptions/{id}", a.handleConfigSubscriptionsDelete).Methods(http.MethodDelete)
 	// config/outputs
 	r.HandleFunc("/config/outputs", a.handleConfigOutputs).Methods(http.MethodGet)
+	r.HandleFunc("/config/outputs/{id}", a.handleConfigOutputs).Methods(http.MethodGet)
+	r.HandleFunc("/config/outputs", a.handleConfigOutputsPost).Methods(http.MethodPost)
+	r.HandleFunc("/config/outputs/{id}", a.handleConfigOutputsPut).Methods(http.MethodPut)
+	r.HandleFunc("/config/outputs/{id}", a.handleConfigOutputsDelete).Methods(http.MethodDelete)
 	// config/inputs
 	r.HandleFunc("/config/inputs", a.handleConfigInputs).Methods(http.MethodGet)
+	r.HandleFunc("/config/inputs/{id}", a.handleConfigInputs).Methods(http.MethodGet)
+	r.HandleFunc("/config/inputs", a.handleConfigInputsPost).Methods(http.MethodPost)
+	r.HandleFunc("/config/inputs/{id}", a.handleConfigInputsPut).Methods(http.MethodPut)
+	r.HandleFunc("/config/inputs/{id}", a.handleConfigInputsDelete).Methods(http.MethodDelete)
 	// config/processors
 	r.HandleFunc("/config/processors", a.handleConfigProcessors).Methods(http.MethodGet)
+	r.HandleFunc("/config/processors/{id}", a.handleConfigProcessors).Methods(http.MethodGet)
+	r.HandleFunc("/config/processors", a.handleConfigProcessorsPost).Methods(http.MethodPost)
+	r.HandleFunc("/config/processors/{id}", a.handleConfigProcessorsPut).Methods(http.MethodPut)
+	r.HandleFunc("/config/processors/{id}", a.handleConfigProcessorsDelete).Methods(http.MethodDelete)
 	// config/clustering
 	r.HandleFunc("/config/clustering", a.handleConfigClustering).Methods(http.MethodGet)
 	// config/api-server
@@ -68,4 +87,5 @@ func (a *App) healthRoutes(r *mux.Router) {
 
 func (a *App) adminRoutes(r *mux.Router) {
 	r.HandleFunc("/admin/shutdown", a.handleAdminShutdown).Methods(http.MethodPost)
+	r.HandleFunc("/admin/reload", a.handleAdminReload).Methods(http.MethodPost)
 }
@@ -59,6 +59,12 @@ func (a *App) SetRunE(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed reading set request files: %v", err)
 	}
+	if a.Config.ValidatePaths {
+		err = a.validateSetPaths(targetsConfig)
+		if err != nil {
+			return fmt.Errorf("invalid set request:\n%v", err)
+		}
+	}
 	numTargets := len(a.Config.Targets)
 	a.errCh = make(chan error, numTargets*2)
 	a.wg.Add(numTargets)
@@ -51,11 +51,20 @@ func (a *App) SubscribeRunE(cmd *cobra.Command, args []string) error {
 	if a.PromptMode {
 		return a.SubscribeRunPrompt(cmd, args)
 	}
+	if a.Config.LocalFlags.SubscribeDryRun {
+		return a.validateConfigAndReport(cmd)
+	}
 	//
 	subCfg, err := a.Config.GetSubscriptions(cmd)
 	if err != nil {
 		return fmt.Errorf("failed reading subscriptions config: %v", err)
 	}
+	if a.Config.ValidatePaths {
+		err = a.validateSubscriptionsPaths(subCfg)
+		if err != nil {
+			return fmt.Errorf("invalid subscription paths:\n%v", err)
+		}
+	}
 
 	err = a.readConfigs()
 	if err != nil {
@@ -127,9 +136,11 @@ func (a *App) SubscribeRunE(cmd *cobra.Command, args []string) error {
 	go a.startCluster()
 	a.startIO()
 
+	a.subscribeCmd = cmd
 	if a.Config.LocalFlags.SubscribeWatchConfig {
 		go a.watchConfig()
 	}
+	go a.watchSIGHUP()
 
 	for range a.ctx.Done() {
 		return a.ctx.Err()
@@ -183,6 +194,7 @@ func (a *App) InitSubscribeFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.SubscribeHistoryStart, "history-start", "", "", "sets the start time in a historical range subscription, nanoseconds since Unix epoch or RFC3339 format")
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.SubscribeHistoryEnd, "history-end", "", "", "sets the end time in a historical range subscription, nanoseconds since Unix epoch or RFC3339 format")
 	cmd.Flags().Uint32VarP(&a.Config.LocalFlags.SubscribeDepth, "depth", "", 0, "depth extension value")
+	cmd.Flags().BoolVarP(&a.Config.LocalFlags.SubscribeDryRun, "dry-run", "", false, "validate the configuration and exit without subscribing")
 	//
 	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
 		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
@@ -0,0 +1,101 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+)
+
+// CreateSubscription adds a new subscription configuration and restarts
+// the running targets that should use it.
+func (a *App) CreateSubscription(ctx context.Context, sc *types.SubscriptionConfig) error {
+	err := a.validateSubscriptionConfig(sc)
+	if err != nil {
+		return err
+	}
+	a.configLock.Lock()
+	if _, ok := a.Config.Subscriptions[sc.Name]; ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("subscription %q %w", sc.Name, errAlreadyExists)
+	}
+	a.Config.Subscriptions[sc.Name] = sc
+	a.configLock.Unlock()
+	a.restartTargets(ctx, a.targetsUsingSubscriptions([]string{sc.Name}, nil))
+	return nil
+}
+
+// UpdateSubscription replaces an existing subscription configuration and
+// restarts the running targets using it.
+func (a *App) UpdateSubscription(ctx context.Context, sc *types.SubscriptionConfig) error {
+	err := a.validateSubscriptionConfig(sc)
+	if err != nil {
+		return err
+	}
+	a.configLock.Lock()
+	if _, ok := a.Config.Subscriptions[sc.Name]; !ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("subscription %q %w", sc.Name, errNotFound)
+	}
+	a.Config.Subscriptions[sc.Name] = sc
+	a.configLock.Unlock()
+	a.restartTargets(ctx, a.targetsUsingSubscriptions([]string{sc.Name}, nil))
+	return nil
+}
+
+// DeleteSubscription removes a subscription configuration and restarts
+// the running targets that were using it.
+// A subscription explicitly referenced by a target cannot be deleted.
+func (a *App) DeleteSubscription(ctx context.Context, name string) error {
+	a.configLock.Lock()
+	if _, ok := a.Config.Subscriptions[name]; !ok {
+		a.configLock.Unlock()
+		return fmt.Errorf("subscription %q %w", name, errNotFound)
+	}
+	users := make([]string, 0)
+	for tn, tc := range a.Config.Targets {
+		if len(tc.Subscriptions) > 0 && targetUsesSubscription(tc, name) {
+			users = append(users, fmt.Sprintf("target %q", tn))
+		}
+	}
+	if len(users) > 0 {
+		a.configLock.Unlock()
+		sort.Strings(users)
+		return fmt.Errorf("subscription %q %w: %v", name, errInUse, users)
+	}
+	delete(a.Config.Subscriptions, name)
+	a.configLock.Unlock()
+	a.restartTargets(ctx, a.targetsUsingSubscriptions([]string{name}, nil))
+	return nil
+}
+
+func (a *App) validateSubscriptionConfig(sc *types.SubscriptionConfig) error {
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	errs := make([]error, 0)
+	for _, o := range sc.Outputs {
+		if _, ok := a.Config.Outputs[o]; !ok {
+			errs = append(errs, fmt.Errorf("unknown output %q", o))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (a *App) restartTargets(ctx context.Context, names []string) {
+	for _, name := range names {
+		a.Logger.Printf("restarting target %q", name)
+		err := a.restartTarget(ctx, name)
+		if err != nil {
+			a.Logger.Printf("failed to restart target %q: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/openconfig/gnmic/pkg/actions"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/loaders"
+	"github.com/openconfig/gnmic/pkg/outputs"
+	"github.com/openconfig/gnmic/pkg/outputs/buffer"
+)
+
+func (a *App) ConfigValidatePreRunE(cmd *cobra.Command, _ []string) error {
+	a.Config.SetLocalFlagsFromFile(cmd)
+	return a.initPluginManager()
+}
+
+func (a *App) ConfigValidateRunE(cmd *cobra.Command, _ []string) error {
+	return a.validateConfigAndReport(cmd)
+}
+
+// validateConfigAndReport validates the configuration and
+// prints the diagnostics to stderr.
+func (a *App) validateConfigAndReport(cmd *cobra.Command) error {
+	diags := a.ValidateConfig(cmd.Context(), cmd)
+	cfgFile := a.Config.FileConfig.ConfigFileUsed()
+	if len(diags) == 0 {
+		fmt.Fprintf(os.Stderr, "configuration file %q is valid\n", cfgFile)
+		return nil
+	}
+	for _, d := range diags {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", cfgFile, d)
+	}
+	return fmt.Errorf("found %d configuration error(s)", len(diags))
+}
+
+// ValidateConfig reads all the configuration sections and checks the references between them.
+// It then initializes the event processors, actions, outputs and loader
+// and parses the subscriptions without connecting to any external system.
+func (a *App) ValidateConfig(ctx context.Context, cmd *cobra.Command) []*config.Diagnostic {
+	diags := make([]*config.Diagnostic, 0)
+	add := func(err error, path ...string) {
+		if err != nil {
+			diags = append(diags, &config.Diagnostic{Path: path, Err: err})
+		}
+	}
+	// read the configuration sections
+	_, err := a.Config.GetSubscriptions(cmd)
+	add(err, "subscriptions")
+	_, err = a.Config.GetTargets()
+	if !errors.Is(err, config.ErrNoTargetsFound) {
+		add(err, "targets")
+	}
+	_, err = a.Config.GetOutputs()
+	add(err, "outputs")
+	_, err = a.Config.GetInputs()
+	add(err, "inputs")
+	_, err = a.Config.GetActions()
+	add(err, "actions")
+	_, err = a.Config.GetEventProcessors()
+	add(err, "processors")
+	add(a.Config.GetClustering(), "clustering")
+	add(a.Config.GetGNMIServer(), "gnmi-server")
+	add(a.Config.GetAPIServer(), "api-server")
+	add(a.Config.GetTunnelServer(), "tunnel-server")
+	add(a.Config.GetLoader(), "loader")
+
+	diags = append(diags, a.Config.ValidateReferences()...)
+
+	discard := log.New(io.Discard, "", 0)
+	// subscriptions
+	for _, name := range config.SortedKeys(a.Config.Subscriptions) {
+		_, err = a.Config.CreateSubscribeRequest(a.Config.Subscriptions[name], &types.TargetConfig{})
+		add(err, "subscriptions", name)
+	}
+	// event processors: compiles jq expressions, starlark scripts, templates,...
+	for _, name := range config.SortedKeys(a.Config.Processors) {
+		_, err = formatters.MakeEventProcessors(discard, []string{name},
+			a.Config.Processors, a.Config.Targets, a.Config.Actions)
+		add(err, "processors", name)
+	}
+	// actions
+	for _, name := range config.SortedKeys(a.Config.Actions) {
+		acfg := a.Config.Actions[name]
+		aType, _ := acfg["type"].(string)
+		in, ok := actions.Actions[aType]
+		if !ok {
+			continue
+		}
+		err = in().Init(acfg, actions.WithLogger(discard), actions.WithTargets(a.Config.Targets))
+		add(err, "actions", name)
+	}
+	// outputs: initialized in dry run mode, they do not connect to their destination.
+	for _, name := range config.SortedKeys(a.Config.Outputs) {
+		ocfg := a.Config.Outputs[name]
+		oType, _ := ocfg["type"].(string)
+		in, ok := outputs.Outputs[oType]
+		if !ok {
+			continue
+		}
+		out := in()
+		if _, ok := ocfg["buffer"]; ok {
+			out = buffer.NewOutput(out)
+		}
+		err = out.Init(ctx, name, ocfg,
+			outputs.WithLogger(discard),
+			outputs.WithEventProcessors(
+				a.Config.Processors,
+				discard,
+				a.Config.Targets,
+				a.Config.Actions,
+			),
+			outputs.WithName(a.Config.InstanceName),
+			outputs.WithClusterName(a.Config.ClusterName),
+			outputs.WithTargetsConfig(a.Config.Targets),
+			outputs.WithDryRun(),
+		)
+		add(err, "outputs", name)
+	}
+	// loader, the docker loader is not initialized since it connects to the docker daemon.
+	if ldType, ok := a.Config.Loader["type"].(string); ok && ldType != "docker" {
+		if in, ok := loaders.Loaders[ldType]; ok {
+			err = in().Init(ctx, a.Config.Loader, discard,
+				loaders.WithActions(a.Config.Actions),
+				loaders.WithTargetsDefaults(a.Config.SetTargetConfigDefaults),
+			)
+			add(err, "loader")
+		}
+	}
+
+	err = a.Config.SetDiagnosticsLines(ctx, diags)
+	if err != nil {
+		a.Logger.Printf("failed to read config file lines: %v", err)
+	}
+	return diags
+}
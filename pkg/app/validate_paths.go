@@ -0,0 +1,521 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/goyang/pkg/yang"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
+)
+
+// loadValidationSchema loads the YANG schema used by --validate-paths
+// from the files and directories set with --file and --dir.
+func (a *App) loadValidationSchema() error {
+	if len(a.Config.GlobalFlags.File) == 0 {
+		return errors.New("--validate-paths requires the YANG files to be set with --file and optionally --dir")
+	}
+	// already loaded, e.g: in prompt mode
+	if len(a.SchemaTree.Dir) > 0 {
+		return nil
+	}
+	err := a.yangFilesPreProcessing()
+	if err != nil {
+		return err
+	}
+	return a.generateYangSchema(a.Config.GlobalFlags.File, a.Config.GlobalFlags.Exclude)
+}
+
+// validateSubscriptionsPaths checks the prefix and paths of each subscription against the YANG schema.
+func (a *App) validateSubscriptionsPaths(subs map[string]*types.SubscriptionConfig) error {
+	err := a.loadValidationSchema()
+	if err != nil {
+		return err
+	}
+	errs := make([]error, 0)
+	for _, name := range config.SortedKeys(subs) {
+		req, err := a.Config.CreateSubscribeRequest(subs[name], &types.TargetConfig{})
+		if err != nil {
+			return err
+		}
+		prefix := req.GetSubscribe().GetPrefix()
+		for _, s := range req.GetSubscribe().GetSubscription() {
+			_, err = validateSchemaPath(a.SchemaTree, prefix, s.GetPath())
+			if err != nil {
+				errs = append(errs, fmt.Errorf("subscription %q: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateGetPaths checks the Get request prefix and paths against the YANG schema.
+// Targets with identical Get requests, e.g. the same encoding, are validated once.
+func (a *App) validateGetPaths(tcs map[string]*types.TargetConfig) error {
+	err := a.loadValidationSchema()
+	if err != nil {
+		return err
+	}
+	errs := make([]error, 0)
+	validated := make([]*gnmi.GetRequest, 0, 1)
+OUTER:
+	for _, name := range config.SortedKeys(tcs) {
+		req, err := a.Config.CreateGetRequest(tcs[name])
+		if err != nil {
+			return err
+		}
+		for _, vreq := range validated {
+			if proto.Equal(req, vreq) {
+				continue OUTER
+			}
+		}
+		validated = append(validated, req)
+		for _, p := range req.GetPath() {
+			_, err = validateSchemaPath(a.SchemaTree, req.GetPrefix(), p)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateSetPaths checks the Set requests paths and values against the YANG schema.
+// The updated, replaced and deleted nodes must be config nodes.
+func (a *App) validateSetPaths(tcs map[string]*types.TargetConfig) error {
+	err := a.loadValidationSchema()
+	if err != nil {
+		return err
+	}
+	errs := make([]error, 0)
+	for _, name := range config.SortedKeys(tcs) {
+		reqs, err := a.Config.CreateSetRequest(name)
+		if err != nil {
+			return fmt.Errorf("target %q: failed to create set request: %v", name, err)
+		}
+		for _, req := range reqs {
+			for _, p := range req.GetDelete() {
+				_, err = validateSchemaConfigPath(a.SchemaTree, req.GetPrefix(), p)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("target %q: delete: %w", name, err))
+				}
+			}
+			for _, ops := range []struct {
+				name string
+				upds []*gnmi.Update
+			}{
+				{name: "replace", upds: req.GetReplace()},
+				{name: "update", upds: req.GetUpdate()},
+				{name: "union-replace", upds: req.GetUnionReplace()},
+			} {
+				for _, upd := range ops.upds {
+					e, err := validateSchemaConfigPath(a.SchemaTree, req.GetPrefix(), upd.GetPath())
+					if err == nil && e != nil {
+						err = validateSchemaTypedValue(e, upd.GetPath(), upd.GetVal())
+					}
+					if err != nil {
+						errs = append(errs, fmt.Errorf("target %q: %s: %w", name, ops.name, err))
+					}
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateSchemaConfigPath validates the path and checks that it points to a config node.
+func validateSchemaConfigPath(root *yang.Entry, prefix, p *gnmi.Path) (*yang.Entry, error) {
+	e, err := validateSchemaPath(root, prefix, p)
+	if err != nil {
+		return nil, err
+	}
+	if e != nil && isState(e) {
+		return nil, fmt.Errorf("path %q: is a state (config false) node", xpath(path.PathElems(prefix, p)))
+	}
+	return e, nil
+}
+
+// validateSchemaPath walks the schema tree following the prefix and path elements,
+// it checks the elements names, the keys names and the keys values.
+// It returns the schema entry the path points to,
+// or nil if the path contains a wildcard element name.
+func validateSchemaPath(root *yang.Entry, prefix, p *gnmi.Path) (*yang.Entry, error) {
+	elems := path.PathElems(prefix, p)
+	e := root
+	for i, pe := range elems {
+		name := stripPrefix(pe.GetName())
+		if name == "*" || name == "..." {
+			return nil, nil
+		}
+		child := schemaChild(e, name)
+		if child == nil {
+			err := fmt.Errorf("path %q: unknown element %q", xpath(elems), pe.GetName())
+			if s := suggestPath(root, elems); s != "" {
+				err = fmt.Errorf("%w, did you mean %q?", err, s)
+			}
+			return nil, err
+		}
+		if len(pe.GetKey()) > 0 {
+			if !child.IsList() {
+				return nil, fmt.Errorf("path %q: element %q is not a list, it cannot have keys", xpath(elems), xpath(elems[:i+1]))
+			}
+			keyNames := strings.Fields(child.Key)
+			for k, v := range pe.GetKey() {
+				kn := stripPrefix(k)
+				if !strInList(kn, keyNames) {
+					return nil, fmt.Errorf("path %q: unknown key %q for list %q, expected one of %q", xpath(elems), k, xpath(elems[:i+1]), keyNames)
+				}
+				if v == "*" {
+					continue
+				}
+				ke := schemaChild(child, kn)
+				if ke == nil || ke.Type == nil {
+					continue
+				}
+				err := checkLeafValue(ke.Type, v)
+				if err != nil {
+					return nil, fmt.Errorf("path %q: key %q: %w", xpath(elems), k, err)
+				}
+			}
+		}
+		e = child
+	}
+	return e, nil
+}
+
+// validateSchemaTypedValue checks the value set for the schema entry e.
+func validateSchemaTypedValue(e *yang.Entry, p *gnmi.Path, tv *gnmi.TypedValue) error {
+	var v interface{}
+	switch tv := tv.GetValue().(type) {
+	case *gnmi.TypedValue_JsonVal:
+		return validateSchemaJSONValue(e, p, tv.JsonVal)
+	case *gnmi.TypedValue_JsonIetfVal:
+		return validateSchemaJSONValue(e, p, tv.JsonIetfVal)
+	case *gnmi.TypedValue_StringVal:
+		v = tv.StringVal
+	case *gnmi.TypedValue_IntVal:
+		v = json.Number(strconv.FormatInt(tv.IntVal, 10))
+	case *gnmi.TypedValue_UintVal:
+		v = json.Number(strconv.FormatUint(tv.UintVal, 10))
+	case *gnmi.TypedValue_BoolVal:
+		v = tv.BoolVal
+	case *gnmi.TypedValue_DoubleVal:
+		v = json.Number(strconv.FormatFloat(tv.DoubleVal, 'f', -1, 64))
+	default:
+		// ascii, bytes, proto,... values are not validated
+		return nil
+	}
+	return validateSchemaValue(e, xpath(p.GetElem()), v)
+}
+
+func validateSchemaJSONValue(e *yang.Entry, p *gnmi.Path, b []byte) error {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	err := dec.Decode(&v)
+	if err != nil {
+		return fmt.Errorf("path %q: invalid JSON value: %w", xpath(p.GetElem()), err)
+	}
+	return validateSchemaValue(e, xpath(p.GetElem()), v)
+}
+
+// validateSchemaValue checks a decoded JSON value against the schema entry e,
+// containers and lists fields must be known children, leaves values must match their type.
+func validateSchemaValue(e *yang.Entry, p string, v interface{}) error {
+	switch {
+	case e.IsLeaf():
+		s, ok := scalarString(v)
+		if !ok {
+			return fmt.Errorf("path %q: leaf expects a scalar value, got %T", p, v)
+		}
+		return wrapPathErr(p, checkLeafValue(e.Type, s))
+	case e.IsLeafList():
+		items, ok := v.([]interface{})
+		if !ok {
+			items = []interface{}{v}
+		}
+		for _, item := range items {
+			s, ok := scalarString(item)
+			if !ok {
+				return fmt.Errorf("path %q: leaf-list expects scalar values, got %T", p, item)
+			}
+			err := checkLeafValue(e.Type, s)
+			if err != nil {
+				return wrapPathErr(p, err)
+			}
+		}
+		return nil
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return validateSchemaChildren(e, p, v)
+	case []interface{}:
+		if !e.IsList() {
+			return fmt.Errorf("path %q: container expects an object, got a list", p)
+		}
+		for _, item := range v {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("path %q: list expects objects, got %T", p, item)
+			}
+			err := validateSchemaChildren(e, p, m)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("path %q: expects an object, got %T", p, v)
+	}
+}
+
+func validateSchemaChildren(e *yang.Entry, p string, m map[string]interface{}) error {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		child := schemaChild(e, stripPrefix(k))
+		cp := p + "/" + k
+		if child == nil {
+			err := fmt.Errorf("path %q: unknown field %q", cp, k)
+			if s := closest(schemaChildrenNames(e), stripPrefix(k)); s != "" {
+				err = fmt.Errorf("%w, did you mean %q?", err, s)
+			}
+			return err
+		}
+		err := validateSchemaValue(child, cp, m[k])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkLeafValue checks that s is a valid value for the YANG type t.
+// String patterns and lengths are not checked.
+func checkLeafValue(t *yang.YangType, s string) error {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind {
+	case yang.Yint8, yang.Yint16, yang.Yint32, yang.Yint64,
+		yang.Yuint8, yang.Yuint16, yang.Yuint32, yang.Yuint64:
+		n, err := yang.ParseInt(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a valid %s", s, t.Kind)
+		}
+		return checkRange(t, s, n)
+	case yang.Ydecimal64:
+		n, err := yang.ParseDecimal(s, uint8(t.FractionDigits))
+		if err != nil {
+			return fmt.Errorf("%q is not a valid %s", s, t.Kind)
+		}
+		return checkRange(t, s, n)
+	case yang.Ybool:
+		if s != "true" && s != "false" {
+			return fmt.Errorf("%q is not a valid boolean", s)
+		}
+	case yang.Yenum:
+		if t.Enum != nil && !t.Enum.IsDefined(s) {
+			return fmt.Errorf("%q is not a valid enum value, expected one of %q", s, t.Enum.Names())
+		}
+	case yang.Yidentityref:
+		if t.IdentityBase == nil {
+			return nil
+		}
+		name := stripPrefix(s)
+		identities := make([]string, 0, len(t.IdentityBase.Values))
+		for _, id := range t.IdentityBase.Values {
+			if id.Name == name {
+				return nil
+			}
+			identities = append(identities, id.Name)
+		}
+		return fmt.Errorf("%q is not a valid identity, expected one of %q", s, identities)
+	case yang.Yunion:
+		for _, mt := range t.Type {
+			if checkLeafValue(mt, s) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q does not match any of the union types", s)
+	}
+	return nil
+}
+
+func checkRange(t *yang.YangType, s string, n yang.Number) error {
+	if !t.Range.Contains(yang.YangRange{{Min: n, Max: n}}) {
+		return fmt.Errorf("%q is out of range %s", s, t.Range)
+	}
+	return nil
+}
+
+// schemaChild returns the child schema entry of e named name.
+// The root entry children are the top level nodes of all the modules,
+// choice and case nodes are transparent.
+func schemaChild(e *yang.Entry, name string) *yang.Entry {
+	if e.Annotation["root"] == true {
+		for _, m := range config.SortedKeys(e.Dir) {
+			if c := schemaChild(e.Dir[m], name); c != nil {
+				return c
+			}
+		}
+		return nil
+	}
+	if c, ok := e.Dir[name]; ok && !c.IsChoice() && !c.IsCase() {
+		return c
+	}
+	for _, c := range e.Dir {
+		if c.IsChoice() || c.IsCase() {
+			if cc := schemaChild(c, name); cc != nil {
+				return cc
+			}
+		}
+	}
+	return nil
+}
+
+func schemaChildrenNames(e *yang.Entry) []string {
+	names := make([]string, 0, len(e.Dir))
+	for n, c := range e.Dir {
+		if e.Annotation["root"] == true || c.IsChoice() || c.IsCase() {
+			names = append(names, schemaChildrenNames(c)...)
+			continue
+		}
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// suggestPath returns the closest valid path to elems,
+// each unknown element is replaced with the closest child name.
+func suggestPath(root *yang.Entry, elems []*gnmi.PathElem) string {
+	e := root
+	suggested := make([]*gnmi.PathElem, 0, len(elems))
+	for _, pe := range elems {
+		name := stripPrefix(pe.GetName())
+		if name == "*" || name == "..." {
+			suggested = append(suggested, pe)
+			break
+		}
+		child := schemaChild(e, name)
+		if child == nil {
+			name = closest(schemaChildrenNames(e), name)
+			if name == "" {
+				break
+			}
+			child = schemaChild(e, name)
+		}
+		spe := &gnmi.PathElem{Name: name}
+		if child.IsList() {
+			spe.Key = make(map[string]string)
+			for _, k := range strings.Fields(child.Key) {
+				spe.Key[k] = "*"
+			}
+			for k, v := range pe.GetKey() {
+				if _, ok := spe.Key[stripPrefix(k)]; ok {
+					spe.Key[stripPrefix(k)] = v
+				}
+			}
+		}
+		suggested = append(suggested, spe)
+		e = child
+	}
+	if len(suggested) == 0 {
+		return ""
+	}
+	return xpath(suggested)
+}
+
+// closest returns the name in names with the smallest edit distance to s.
+func closest(names []string, s string) string {
+	best := ""
+	bestDist := -1
+	for _, n := range names {
+		d := editDistance(n, s)
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = n, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func scalarString(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case nil:
+		return "", true
+	}
+	return "", false
+}
+
+func stripPrefix(name string) string {
+	_, n := getPrefixElem(name)
+	return n
+}
+
+func xpath(elems []*gnmi.PathElem) string {
+	return "/" + path.GnmiPathToXPath(&gnmi.Path{Elem: elems}, false)
+}
+
+func wrapPathErr(p string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("path %q: %w", p, err)
+}
+
+func strInList(s string, l []string) bool {
+	for _, ls := range l {
+		if ls == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,224 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/goyang/pkg/yang"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+)
+
+const validatePathsTestModule = `
+module test {
+  namespace "urn:test";
+  prefix t;
+
+  identity speed;
+  identity speed-1g { base speed; }
+  identity speed-10g { base speed; }
+
+  container interfaces {
+    list interface {
+      key "name";
+      leaf name { type string; }
+      container config {
+        leaf mtu {
+          type uint16 { range "64..9000"; }
+        }
+        leaf enabled { type boolean; }
+        leaf speed { type identityref { base speed; } }
+        leaf mode {
+          type enumeration {
+            enum access;
+            enum trunk;
+          }
+        }
+      }
+      container state {
+        config false;
+        leaf oper-status { type string; }
+      }
+    }
+  }
+  container system {
+    list vlan {
+      key "id";
+      leaf id { type uint16 { range "1..4094"; } }
+    }
+  }
+}
+`
+
+func validatePathsTestSchema(t *testing.T) *yang.Entry {
+	ms := yang.NewModules()
+	err := ms.Parse(validatePathsTestModule, "test.yang")
+	if err != nil {
+		t.Fatalf("failed to parse YANG module: %v", err)
+	}
+	if errs := ms.Process(); len(errs) > 0 {
+		t.Fatalf("failed to process YANG module: %v", errs)
+	}
+	root := buildRootEntry()
+	root.Dir["test"] = yang.ToEntry(ms.Modules["test"])
+	return root
+}
+
+var validateSchemaPathTestSet = map[string]struct {
+	path   string
+	config bool
+	err    string
+}{
+	"valid": {
+		path: "/interfaces/interface[name=ethernet-1/1]/config/mtu",
+	},
+	"valid_prefixed": {
+		path: "/t:interfaces/t:interface/state",
+	},
+	"wildcard": {
+		path: "/interfaces/*/config",
+	},
+	"wildcard_key": {
+		path: "/system/vlan[id=*]",
+	},
+	"unknown_elem": {
+		path: "/interfaces/interface[name=e1]/confg/mtu",
+		err:  `did you mean "/interfaces/interface[name=e1]/config/mtu"?`,
+	},
+	"unknown_top_level_elem": {
+		path: "/sytem/vlan",
+		err:  `did you mean "/system/vlan[id=*]"?`,
+	},
+	"unknown_key": {
+		path: "/interfaces/interface[id=e1]",
+		err:  `unknown key "id"`,
+	},
+	"keys_on_container": {
+		path: "/system[id=1]",
+		err:  "is not a list",
+	},
+	"key_out_of_range": {
+		path: "/system/vlan[id=5000]",
+		err:  "out of range",
+	},
+	"key_not_a_number": {
+		path: "/system/vlan[id=foo]",
+		err:  "is not a valid uint16",
+	},
+	"config_path": {
+		path:   "/interfaces/interface[name=e1]/config",
+		config: true,
+	},
+	"state_path": {
+		path:   "/interfaces/interface[name=e1]/state/oper-status",
+		config: true,
+		err:    "config false",
+	},
+}
+
+func TestValidateSchemaPath(t *testing.T) {
+	root := validatePathsTestSchema(t)
+	for name, tc := range validateSchemaPathTestSet {
+		t.Run(name, func(t *testing.T) {
+			p, err := path.ParsePath(tc.path)
+			if err != nil {
+				t.Fatalf("failed to parse path: %v", err)
+			}
+			if tc.config {
+				_, err = validateSchemaConfigPath(root, nil, p)
+			} else {
+				_, err = validateSchemaPath(root, nil, p)
+			}
+			checkValidationErr(t, err, tc.err)
+		})
+	}
+}
+
+var validateSchemaTypedValueTestSet = map[string]struct {
+	path string
+	val  *gnmi.TypedValue
+	err  string
+}{
+	"valid_json": {
+		path: "/interfaces/interface[name=e1]/config",
+		val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonVal{JsonVal: []byte(`{"mtu": 1500, "enabled": true, "mode": "trunk", "speed": "t:speed-10g"}`)}},
+	},
+	"valid_list": {
+		path: "/system",
+		val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonIetfVal{JsonIetfVal: []byte(`{"vlan": [{"id": 1}, {"id": 10}]}`)}},
+	},
+	"unknown_field": {
+		path: "/interfaces/interface[name=e1]/config",
+		val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonVal{JsonVal: []byte(`{"mut": 1500}`)}},
+		err:  `unknown field "mut", did you mean "mtu"?`,
+	},
+	"mtu_out_of_range": {
+		path: "/interfaces/interface[name=e1]/config/mtu",
+		val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 10000}},
+		err:  "out of range",
+	},
+	"invalid_enum": {
+		path: "/interfaces/interface[name=e1]/config/mode",
+		val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: "hybrid"}},
+		err:  "not a valid enum value",
+	},
+	"invalid_identity": {
+		path: "/interfaces/interface[name=e1]/config/speed",
+		val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: "speed-100g"}},
+		err:  "not a valid identity",
+	},
+	"invalid_bool": {
+		path: "/interfaces/interface[name=e1]/config",
+		val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonVal{JsonVal: []byte(`{"enabled": "yes"}`)}},
+		err:  "not a valid boolean",
+	},
+	"object_for_leaf": {
+		path: "/interfaces/interface[name=e1]/config/mtu",
+		val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonVal{JsonVal: []byte(`{"value": 1500}`)}},
+		err:  "expects a scalar value",
+	},
+}
+
+func TestValidateSchemaTypedValue(t *testing.T) {
+	root := validatePathsTestSchema(t)
+	for name, tc := range validateSchemaTypedValueTestSet {
+		t.Run(name, func(t *testing.T) {
+			p, err := path.ParsePath(tc.path)
+			if err != nil {
+				t.Fatalf("failed to parse path: %v", err)
+			}
+			e, err := validateSchemaConfigPath(root, nil, p)
+			if err != nil {
+				t.Fatalf("unexpected path error: %v", err)
+			}
+			err = validateSchemaTypedValue(e, p, tc.val)
+			checkValidationErr(t, err, tc.err)
+		})
+	}
+}
+
+func checkValidationErr(t *testing.T, err error, expected string) {
+	t.Helper()
+	if expected == "" {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Errorf("expected error containing %q, got nil", expected)
+		return
+	}
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected error containing %q, got: %v", expected, err)
+	}
+}
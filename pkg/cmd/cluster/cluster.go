@@ -0,0 +1,47 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cluster
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/openconfig/gnmic/pkg/app"
+)
+
+// New returns the cluster command tree.
+func New(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cluster",
+		Short: "manage a gnmic cluster through its API",
+	}
+	cmd.AddCommand(newDrainCmd(gApp), newResumeCmd(gApp))
+	return cmd
+}
+
+func newDrainCmd(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "drain <member>",
+		Short:        "put a cluster member in drain mode, its targets are moved to the other members",
+		Args:         cobra.ExactArgs(1),
+		RunE:         gApp.ClusterDrainRunE,
+		SilenceUsage: true,
+	}
+	return cmd
+}
+
+func newResumeCmd(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "resume <member>",
+		Short:        "take a cluster member out of drain mode",
+		Args:         cobra.ExactArgs(1),
+		RunE:         gApp.ClusterResumeRunE,
+		SilenceUsage: true,
+	}
+	return cmd
+}
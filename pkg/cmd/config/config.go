@@ -0,0 +1,39 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/openconfig/gnmic/pkg/app"
+)
+
+// New returns the config command tree.
+func New(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "manage gnmic configuration",
+	}
+	cmd.AddCommand(newValidateCmd(gApp))
+	return cmd
+}
+
+func newValidateCmd(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "validate",
+		Short:   "validate the configuration file",
+		PreRunE: gApp.ConfigValidatePreRunE,
+		RunE:    gApp.ConfigValidateRunE,
+		PostRun: func(cmd *cobra.Command, args []string) {
+			gApp.CleanupPlugins()
+		},
+		SilenceUsage: true,
+	}
+	return cmd
+}
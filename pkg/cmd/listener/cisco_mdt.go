@@ -0,0 +1,424 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package listener
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+// mdtDialoutServer is the server side of the Cisco MDT gRPC dial-out service:
+//
+//	package mdt_dialout;
+//	service gRPCMdtDialout {
+//	  rpc MdtDialout(stream MdtDialoutArgs) returns (stream MdtDialoutArgs);
+//	}
+type mdtDialoutServer interface {
+	mdtDialout(grpc.ServerStream) error
+}
+
+var mdtDialoutServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mdt_dialout.gRPCMdtDialout",
+	HandlerType: (*mdtDialoutServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "MdtDialout",
+			Handler:       mdtDialoutHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "mdt_grpc_dialout.proto",
+}
+
+func mdtDialoutHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(mdtDialoutServer).mdtDialout(stream)
+}
+
+// rawFrame is a gRPC message that is not decoded by the codec.
+type rawFrame []byte
+
+// rawCodec passes rawFrame messages as is and
+// delegates all other messages to the wrapped codec.
+type rawCodec struct {
+	encoding.Codec
+}
+
+func (c rawCodec) Marshal(v interface{}) ([]byte, error) {
+	if f, ok := v.(*rawFrame); ok {
+		return *f, nil
+	}
+	return c.Codec.Marshal(v)
+}
+
+func (c rawCodec) Unmarshal(data []byte, v interface{}) error {
+	if f, ok := v.(*rawFrame); ok {
+		// data is not guaranteed to stay valid after this call returns
+		*f = append((*f)[:0], data...)
+		return nil
+	}
+	return c.Codec.Unmarshal(data, v)
+}
+
+// mdtDialout receives the Cisco MDT telemetry messages sent by the node.
+// Only the self-describing (GPB-KV) encoding is supported,
+// each row is converted to a gNMI notification targeted at the node-id.
+func (s *dialoutTelemetryServer) mdtDialout(stream grpc.ServerStream) error {
+	baseMeta, _ := s.streamMeta(stream.Context())
+	chunks := new(bytes.Buffer)
+	for {
+		frame := new(rawFrame)
+		err := stream.RecvMsg(frame)
+		if err != nil {
+			if err != io.EOF {
+				s.gApp.Logger.Printf("MDT dialout receive error: %v", err)
+			}
+			return nil
+		}
+		args, err := decodeMdtDialoutArgs(*frame)
+		if err != nil {
+			s.gApp.Logger.Printf("failed to decode MDT dialout message from %s: %v", baseMeta["source"], err)
+			continue
+		}
+		if args.errors != "" {
+			s.gApp.Logger.Printf("MDT dialout error from %s: %s", baseMeta["source"], args.errors)
+		}
+		data := args.data
+		// messages bigger than the node's max message size are split
+		// in multiple chunks, totalSize is the size of the reassembled message.
+		if args.totalSize > 0 {
+			chunks.Write(args.data)
+			if chunks.Len() < int(args.totalSize) {
+				continue
+			}
+			if chunks.Len() > int(args.totalSize) {
+				s.gApp.Logger.Printf("discarding MDT chunked message from %s: got %d bytes, expected %d",
+					baseMeta["source"], chunks.Len(), args.totalSize)
+				chunks.Reset()
+				continue
+			}
+			data = chunks.Bytes()
+		}
+		if len(data) > 0 {
+			s.handleMdtTelemetry(data, baseMeta)
+		}
+		chunks.Reset()
+	}
+}
+
+func (s *dialoutTelemetryServer) handleMdtTelemetry(data []byte, baseMeta outputs.Meta) {
+	tm, err := decodeMdtTelemetry(data)
+	if err != nil {
+		s.gApp.Logger.Printf("failed to decode MDT telemetry message from %s: %v", baseMeta["source"], err)
+		return
+	}
+	if tm.compact {
+		s.gApp.Logger.Printf("received MDT compact GPB message from %s for path %q: only the self-describing-gpb encoding is supported",
+			baseMeta["source"], tm.encodingPath)
+		return
+	}
+	outMeta := make(outputs.Meta, len(baseMeta)+2)
+	for k, v := range baseMeta {
+		outMeta[k] = v
+	}
+	if tm.subscriptionID != "" {
+		outMeta["subscription-name"] = tm.subscriptionID
+	}
+	if tm.nodeID != "" {
+		outMeta["system-name"] = tm.nodeID
+	}
+	for _, rsp := range tm.subscribeResponses() {
+		s.write(rsp, outMeta)
+	}
+}
+
+// mdtDialoutArgs is the message exchanged on the MDT dial-out stream:
+//
+//	message MdtDialoutArgs {
+//	  int64 ReqId = 1;
+//	  bytes data = 2;
+//	  string errors = 3;
+//	  int32 totalSize = 4;
+//	}
+type mdtDialoutArgs struct {
+	reqID     int64
+	data      []byte
+	errors    string
+	totalSize int32
+}
+
+// mdtTelemetry is the subset of the Cisco telemetry.proto Telemetry message used by gNMIc.
+type mdtTelemetry struct {
+	nodeID         string
+	subscriptionID string
+	encodingPath   string
+	msgTimestamp   uint64
+	gpbkv          []*mdtField
+	// compact is true if the message has the compact GPB encoding (data_gpb),
+	// decoding it requires the per-path proto definitions.
+	compact bool
+}
+
+// mdtField is a Cisco telemetry.proto TelemetryField,
+// value is nil for containers.
+type mdtField struct {
+	timestamp uint64
+	name      string
+	delete    bool
+	value     *gnmi.TypedValue
+	fields    []*mdtField
+}
+
+func decodeMdtDialoutArgs(b []byte) (*mdtDialoutArgs, error) {
+	args := new(mdtDialoutArgs)
+	err := walkMessage(b, func(num protowire.Number, v []byte, u uint64) error {
+		switch num {
+		case 1:
+			args.reqID = int64(u)
+		case 2:
+			args.data = v
+		case 3:
+			args.errors = string(v)
+		case 4:
+			args.totalSize = int32(u)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+func decodeMdtTelemetry(b []byte) (*mdtTelemetry, error) {
+	tm := new(mdtTelemetry)
+	err := walkMessage(b, func(num protowire.Number, v []byte, u uint64) error {
+		switch num {
+		case 1: // node_id_str
+			tm.nodeID = string(v)
+		case 3: // subscription_id_str
+			tm.subscriptionID = string(v)
+		case 6: // encoding_path
+			tm.encodingPath = string(v)
+		case 10: // msg_timestamp
+			tm.msgTimestamp = u
+		case 11: // data_gpbkv
+			f, err := decodeMdtField(v)
+			if err != nil {
+				return err
+			}
+			tm.gpbkv = append(tm.gpbkv, f)
+		case 12: // data_gpb
+			tm.compact = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tm, nil
+}
+
+func decodeMdtField(b []byte) (*mdtField, error) {
+	f := new(mdtField)
+	err := walkMessage(b, func(num protowire.Number, v []byte, u uint64) error {
+		switch num {
+		case 1:
+			f.timestamp = u
+		case 2:
+			f.name = string(v)
+		case 3:
+			f.delete = u != 0
+		case 4:
+			f.value = &gnmi.TypedValue{Value: &gnmi.TypedValue_BytesVal{BytesVal: v}}
+		case 5:
+			f.value = &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: string(v)}}
+		case 6:
+			f.value = &gnmi.TypedValue{Value: &gnmi.TypedValue_BoolVal{BoolVal: u != 0}}
+		case 7, 8:
+			f.value = &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: u}}
+		case 9:
+			f.value = &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: int64(int32(protowire.DecodeZigZag(u)))}}
+		case 10:
+			f.value = &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: protowire.DecodeZigZag(u)}}
+		case 11:
+			f.value = &gnmi.TypedValue{Value: &gnmi.TypedValue_DoubleVal{DoubleVal: math.Float64frombits(u)}}
+		case 12:
+			f.value = &gnmi.TypedValue{Value: &gnmi.TypedValue_DoubleVal{DoubleVal: float64(math.Float32frombits(uint32(u)))}}
+		case 15:
+			sf, err := decodeMdtField(v)
+			if err != nil {
+				return err
+			}
+			f.fields = append(f.fields, sf)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// walkMessage calls fn for each field of the protobuf encoded message b.
+// v is set for length delimited fields, u for varint and fixed size fields.
+func walkMessage(b []byte, fn func(num protowire.Number, v []byte, u uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		var v []byte
+		var u uint64
+		switch typ {
+		case protowire.VarintType:
+			u, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var u32 uint32
+			u32, n = protowire.ConsumeFixed32(b)
+			u = uint64(u32)
+		case protowire.Fixed64Type:
+			u, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("field %d: %w", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+		err := fn(num, v, u)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subscribeResponses converts each GPB-KV row to a gNMI notification.
+// The prefix is built from the encoding path and the row keys,
+// the updates from the row content leaves.
+func (tm *mdtTelemetry) subscribeResponses() []*gnmi.SubscribeResponse {
+	rsps := make([]*gnmi.SubscribeResponse, 0, len(tm.gpbkv))
+	for _, row := range tm.gpbkv {
+		ts := row.timestamp
+		if ts == 0 {
+			ts = tm.msgTimestamp
+		}
+		prefix := mdtPrefix(tm.encodingPath)
+		prefix.Target = tm.nodeID
+		notif := &gnmi.Notification{
+			// MDT timestamps are in milliseconds
+			Timestamp: int64(ts) * 1000000,
+			Prefix:    prefix,
+		}
+		for _, f := range row.fields {
+			switch f.name {
+			case "keys":
+				if len(prefix.Elem) == 0 {
+					prefix.Elem = append(prefix.Elem, new(gnmi.PathElem))
+				}
+				last := prefix.Elem[len(prefix.Elem)-1]
+				last.Key = make(map[string]string)
+				for _, k := range leaves(f, nil) {
+					last.Key[k.path[len(k.path)-1].Name] = typedValueString(k.value)
+				}
+			case "content":
+				for _, l := range leaves(f, nil) {
+					notif.Update = append(notif.Update, &gnmi.Update{
+						Path: &gnmi.Path{Elem: l.path},
+						Val:  l.value,
+					})
+				}
+			}
+		}
+		if row.delete {
+			notif.Delete = append(notif.Delete, new(gnmi.Path))
+		}
+		if len(notif.Update) == 0 && len(notif.Delete) == 0 {
+			continue
+		}
+		rsps = append(rsps, &gnmi.SubscribeResponse{
+			Response: &gnmi.SubscribeResponse_Update{Update: notif},
+		})
+	}
+	return rsps
+}
+
+// mdtPrefix converts an encoding path like
+// Cisco-IOS-XR-infra-statsd-oper:infra-statistics/interfaces/interface/latest/generic-counters
+// to a gNMI path, the YANG module name becomes the path origin.
+func mdtPrefix(encodingPath string) *gnmi.Path {
+	p := new(gnmi.Path)
+	origin, path, found := strings.Cut(encodingPath, ":")
+	if found && !strings.Contains(origin, "/") {
+		p.Origin = origin
+	} else {
+		path = encodingPath
+	}
+	for _, e := range strings.Split(strings.Trim(path, "/"), "/") {
+		if e == "" {
+			continue
+		}
+		p.Elem = append(p.Elem, &gnmi.PathElem{Name: e})
+	}
+	return p
+}
+
+type mdtLeaf struct {
+	path  []*gnmi.PathElem
+	value *gnmi.TypedValue
+}
+
+// leaves returns the leaf fields under f with their path relative to f.
+func leaves(f *mdtField, parent []*gnmi.PathElem) []*mdtLeaf {
+	ls := make([]*mdtLeaf, 0, len(f.fields))
+	for _, sf := range f.fields {
+		p := make([]*gnmi.PathElem, 0, len(parent)+1)
+		p = append(p, parent...)
+		p = append(p, &gnmi.PathElem{Name: sf.name})
+		if sf.value != nil {
+			ls = append(ls, &mdtLeaf{path: p, value: sf.value})
+			continue
+		}
+		ls = append(ls, leaves(sf, p)...)
+	}
+	return ls
+}
+
+func typedValueString(tv *gnmi.TypedValue) string {
+	switch v := tv.GetValue().(type) {
+	case *gnmi.TypedValue_StringVal:
+		return v.StringVal
+	case *gnmi.TypedValue_UintVal:
+		return strconv.FormatUint(v.UintVal, 10)
+	case *gnmi.TypedValue_IntVal:
+		return strconv.FormatInt(v.IntVal, 10)
+	case *gnmi.TypedValue_BoolVal:
+		return strconv.FormatBool(v.BoolVal)
+	case *gnmi.TypedValue_DoubleVal:
+		return strconv.FormatFloat(v.DoubleVal, 'f', -1, 64)
+	case *gnmi.TypedValue_BytesVal:
+		return string(v.BytesVal)
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package listener
+
+import (
+	"testing"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendVarint(b []byte, num protowire.Number, u uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, u)
+}
+
+func mdtContainer(name string, fields ...[]byte) []byte {
+	b := appendString(nil, 2, name)
+	for _, f := range fields {
+		b = appendBytes(b, 15, f)
+	}
+	return b
+}
+
+func TestMdtTelemetryToSubscribeResponses(t *testing.T) {
+	keys := mdtContainer("keys",
+		appendString(appendString(nil, 2, "interface-name"), 5, "GigabitEthernet0/0/0/0"),
+	)
+	content := mdtContainer("content",
+		appendVarint(appendString(nil, 2, "bytes-received"), 8, 42),
+		mdtContainer("state",
+			appendVarint(appendString(nil, 2, "drops"), 10, protowire.EncodeZigZag(-3)),
+		),
+	)
+	row := appendVarint(nil, 1, 1700000000000)
+	row = appendBytes(row, 15, keys)
+	row = appendBytes(row, 15, content)
+
+	tmb := appendString(nil, 1, "xr-1")
+	tmb = appendString(tmb, 3, "sub1")
+	tmb = appendString(tmb, 6, "Cisco-IOS-XR-infra-statsd-oper:infra-statistics/interfaces/interface/latest/generic-counters")
+	tmb = appendBytes(tmb, 11, row)
+
+	args, err := decodeMdtDialoutArgs(appendBytes(appendVarint(nil, 1, 7), 2, tmb))
+	if err != nil {
+		t.Fatalf("failed to decode MdtDialoutArgs: %v", err)
+	}
+	if args.reqID != 7 {
+		t.Errorf("unexpected ReqId: %d", args.reqID)
+	}
+	tm, err := decodeMdtTelemetry(args.data)
+	if err != nil {
+		t.Fatalf("failed to decode Telemetry: %v", err)
+	}
+	if tm.nodeID != "xr-1" || tm.subscriptionID != "sub1" || tm.compact {
+		t.Errorf("unexpected telemetry header: %+v", tm)
+	}
+	rsps := tm.subscribeResponses()
+	if len(rsps) != 1 {
+		t.Fatalf("expected 1 response, got %d", len(rsps))
+	}
+	expected := &gnmi.Notification{
+		Timestamp: 1700000000000 * 1000000,
+		Prefix: &gnmi.Path{
+			Origin: "Cisco-IOS-XR-infra-statsd-oper",
+			Target: "xr-1",
+			Elem: []*gnmi.PathElem{
+				{Name: "infra-statistics"},
+				{Name: "interfaces"},
+				{Name: "interface"},
+				{Name: "latest"},
+				{Name: "generic-counters", Key: map[string]string{"interface-name": "GigabitEthernet0/0/0/0"}},
+			},
+		},
+		Update: []*gnmi.Update{
+			{
+				Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "bytes-received"}}},
+				Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 42}},
+			},
+			{
+				Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "state"}, {Name: "drops"}}},
+				Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: -3}},
+			},
+		},
+	}
+	if !proto.Equal(rsps[0].GetUpdate(), expected) {
+		t.Errorf("unexpected notification:\ngot: %v\nexp: %v", rsps[0].GetUpdate(), expected)
+	}
+}
+
+func TestMdtPrefix(t *testing.T) {
+	tests := map[string]*gnmi.Path{
+		"Cisco-IOS-XR-shellutil-oper:system-time/uptime": {
+			Origin: "Cisco-IOS-XR-shellutil-oper",
+			Elem:   []*gnmi.PathElem{{Name: "system-time"}, {Name: "uptime"}},
+		},
+		"openconfig-interfaces:interfaces/interface": {
+			Origin: "openconfig-interfaces",
+			Elem:   []*gnmi.PathElem{{Name: "interfaces"}, {Name: "interface"}},
+		},
+		"/system/uptime": {
+			Elem: []*gnmi.PathElem{{Name: "system"}, {Name: "uptime"}},
+		},
+	}
+	for in, exp := range tests {
+		t.Run(in, func(t *testing.T) {
+			got := mdtPrefix(in)
+			if !proto.Equal(got, exp) {
+				t.Errorf("got %v, expected %v", got, exp)
+			}
+		})
+	}
+}
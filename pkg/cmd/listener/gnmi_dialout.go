@@ -0,0 +1,85 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package listener
+
+import (
+	"io"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/grpc"
+
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+// gnmiDialoutServer is the server side of the gNMI dial-out service:
+//
+//	package gnmi_dialout;
+//	service gNMIDialOut {
+//	  rpc Publish(stream gnmi.SubscribeResponse) returns (stream PublishResponse);
+//	}
+type gnmiDialoutServer interface {
+	gnmiDialoutPublish(grpc.ServerStream) error
+}
+
+var gnmiDialoutServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gnmi_dialout.gNMIDialOut",
+	HandlerType: (*gnmiDialoutServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Publish",
+			Handler:       gnmiDialoutPublishHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "gnmi_dialout.proto",
+}
+
+func gnmiDialoutPublishHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(gnmiDialoutServer).gnmiDialoutPublish(stream)
+}
+
+// gnmiDialoutPublish receives the gNMI SubscribeResponses sent by the node.
+// The subscription name and the node name are read from the
+// subscription-name and system-name http2 headers if present,
+// the node name defaults to the target set in the notifications prefix.
+func (s *dialoutTelemetryServer) gnmiDialoutPublish(stream grpc.ServerStream) error {
+	outMeta, md := s.streamMeta(stream.Context())
+	if sn := md.Get("subscription-name"); len(sn) > 0 {
+		outMeta["subscription-name"] = sn[0]
+	}
+	if systemName := md.Get("system-name"); len(systemName) > 0 {
+		outMeta["system-name"] = systemName[0]
+	}
+	for {
+		subResp := new(gnmi.SubscribeResponse)
+		err := stream.RecvMsg(subResp)
+		if err != nil {
+			if err != io.EOF {
+				s.gApp.Logger.Printf("gNMI dialout receive error: %v", err)
+			}
+			return nil
+		}
+		switch resp := subResp.Response.(type) {
+		case *gnmi.SubscribeResponse_Update:
+			if _, ok := outMeta["system-name"]; !ok && resp.Update.GetPrefix().GetTarget() != "" {
+				// outMeta is shared with the outputs still writing the previous messages
+				m := make(outputs.Meta, len(outMeta)+1)
+				for k, v := range outMeta {
+					m[k] = v
+				}
+				m["system-name"] = resp.Update.GetPrefix().GetTarget()
+				outMeta = m
+			}
+			s.write(subResp, outMeta)
+		case *gnmi.SubscribeResponse_SyncResponse:
+			s.gApp.Logger.Printf("received sync response=%+v from %s", resp.SyncResponse, outMeta["source"])
+		}
+	}
+}
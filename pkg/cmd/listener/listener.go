@@ -28,6 +28,7 @@ import (
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 
@@ -146,8 +147,12 @@ func New(gApp *app.App) *cobra.Command {
 				opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
 			}
 
+			// Cisco MDT dial-out messages are decoded by the server itself.
+			opts = append(opts, grpc.ForceServerCodec(rawCodec{encoding.GetCodec("proto")}))
 			server.grpcServer = grpc.NewServer(opts...)
 			nokiasros.RegisterDialoutTelemetryServer(server.grpcServer, server)
+			server.grpcServer.RegisterService(&gnmiDialoutServiceDesc, server)
+			server.grpcServer.RegisterService(&mdtDialoutServiceDesc, server)
 
 			if gApp.Config.LocalFlags.ListenPrometheusAddress != "" {
 				grpc_prometheus.Register(server.grpcServer)
@@ -191,25 +196,7 @@ type dialoutTelemetryServer struct {
 }
 
 func (s *dialoutTelemetryServer) Publish(stream nokiasros.DialoutTelemetry_PublishServer) error {
-	peer, ok := peer.FromContext(stream.Context())
-	if ok && s.gApp.Config.Debug {
-		b, err := json.Marshal(peer)
-		if err != nil {
-			s.gApp.Logger.Printf("failed to marshal peer data: %v", err)
-		} else {
-			s.gApp.Logger.Printf("received Publish RPC from peer=%s", string(b))
-		}
-	}
-	md, ok := metadata.FromIncomingContext(stream.Context())
-	if ok && s.gApp.Config.Debug {
-		b, err := json.Marshal(md)
-		if err != nil {
-			s.gApp.Logger.Printf("failed to marshal context metadata: %v", err)
-		} else {
-			s.gApp.Logger.Printf("received http2_header=%s", string(b))
-		}
-	}
-	outMeta := outputs.Meta{}
+	outMeta, md := s.streamMeta(stream.Context())
 	if sn, ok := md["subscription-name"]; ok {
 		if len(sn) > 0 {
 			outMeta["subscription-name"] = sn[0]
@@ -217,7 +204,6 @@ func (s *dialoutTelemetryServer) Publish(stream nokiasros.DialoutTelemetry_Publi
 	} else {
 		s.gApp.Logger.Println("could not find subscription-name in http2 headers")
 	}
-	outMeta["source"] = peer.Addr.String()
 	if systemName, ok := md["system-name"]; ok {
 		if len(systemName) > 0 {
 			outMeta["system-name"] = systemName[0]
@@ -260,9 +246,7 @@ func (s *dialoutTelemetryServer) Publish(stream nokiasros.DialoutTelemetry_Publi
 					}
 				}
 			}
-			for _, o := range s.Outputs {
-				go o.Write(s.ctx, subResp, outMeta)
-			}
+			s.write(subResp, outMeta)
 
 		case *gnmi.SubscribeResponse_SyncResponse:
 			s.gApp.Logger.Printf("received sync response=%+v from %s", resp.SyncResponse, outMeta["source"])
@@ -270,3 +254,38 @@ func (s *dialoutTelemetryServer) Publish(stream nokiasros.DialoutTelemetry_Publi
 	}
 	return nil
 }
+
+// streamMeta returns the output metadata common to all the dial-out services
+// as well as the stream http2 headers.
+func (s *dialoutTelemetryServer) streamMeta(ctx context.Context) (outputs.Meta, metadata.MD) {
+	outMeta := outputs.Meta{}
+	pr, ok := peer.FromContext(ctx)
+	if ok {
+		outMeta["source"] = pr.Addr.String()
+		if s.gApp.Config.Debug {
+			b, err := json.Marshal(pr)
+			if err != nil {
+				s.gApp.Logger.Printf("failed to marshal peer data: %v", err)
+			} else {
+				method, _ := grpc.Method(ctx)
+				s.gApp.Logger.Printf("received %s RPC from peer=%s", method, string(b))
+			}
+		}
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok && s.gApp.Config.Debug {
+		b, err := json.Marshal(md)
+		if err != nil {
+			s.gApp.Logger.Printf("failed to marshal context metadata: %v", err)
+		} else {
+			s.gApp.Logger.Printf("received http2_header=%s", string(b))
+		}
+	}
+	return outMeta, md
+}
+
+func (s *dialoutTelemetryServer) write(rsp *gnmi.SubscribeResponse, meta outputs.Meta) {
+	for _, o := range s.Outputs {
+		go o.Write(s.ctx, rsp, meta)
+	}
+}
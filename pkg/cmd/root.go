@@ -20,6 +20,8 @@ import (
 
 	"github.com/openconfig/gnmic/pkg/app"
 	"github.com/openconfig/gnmic/pkg/cmd/capabilities"
+	"github.com/openconfig/gnmic/pkg/cmd/cluster"
+	"github.com/openconfig/gnmic/pkg/cmd/config"
 	"github.com/openconfig/gnmic/pkg/cmd/diff"
 	"github.com/openconfig/gnmic/pkg/cmd/generate"
 	"github.com/openconfig/gnmic/pkg/cmd/get"
@@ -68,6 +70,8 @@ func newRootCmd() *cobra.Command {
 
 	// Subcommands
 	gApp.RootCmd.AddCommand(capabilities.New(gApp))
+	gApp.RootCmd.AddCommand(cluster.New(gApp))
+	gApp.RootCmd.AddCommand(config.New(gApp))
 	gApp.RootCmd.AddCommand(get.New(gApp))
 	gApp.RootCmd.AddCommand(getset.New(gApp))
 	gApp.RootCmd.AddCommand(listener.New(gApp))
@@ -9,6 +9,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"time"
 
@@ -20,6 +21,17 @@ const (
 	defaultTargetAssignmentTimeout = 10 * time.Second
 	defaultServicesWatchTimer      = 1 * time.Minute
 	defaultLeaderWaitTimer         = 5 * time.Second
+	defaultLoadFactor              = 1.25
+)
+
+// targets distribution strategies
+const (
+	// DistributionLoad assigns each target to the instance with the highest tags match
+	// and the lowest number of locked targets.
+	DistributionLoad = "load"
+	// DistributionConsistentHash assigns each target to an instance using
+	// consistent hashing with bounded loads.
+	DistributionConsistentHash = "consistent-hash"
 )
 
 type clustering struct {
@@ -31,6 +43,8 @@ type clustering struct {
 	TargetAssignmentTimeout time.Duration          `mapstructure:"target-assignment-timeout,omitempty" json:"target-assignment-timeout,omitempty" yaml:"target-assignment-timeout,omitempty"`
 	LeaderWaitTimer         time.Duration          `mapstructure:"leader-wait-timer,omitempty" json:"leader-wait-timer,omitempty" yaml:"leader-wait-timer,omitempty"`
 	Tags                    []string               `mapstructure:"tags,omitempty" json:"tags,omitempty" yaml:"tags,omitempty"`
+	Distribution            string                 `mapstructure:"distribution,omitempty" json:"distribution,omitempty" yaml:"distribution,omitempty"`
+	LoadFactor              float64                `mapstructure:"load-factor,omitempty" json:"load-factor,omitempty" yaml:"load-factor,omitempty"`
 	Locker                  map[string]interface{} `mapstructure:"locker,omitempty" json:"locker,omitempty" yaml:"locker,omitempty"`
 }
 
@@ -50,7 +64,15 @@ func (c *Config) GetClustering() error {
 	for i := range c.Clustering.Tags {
 		c.Clustering.Tags[i] = os.ExpandEnv(c.Clustering.Tags[i])
 	}
+	c.Clustering.Distribution = os.ExpandEnv(c.FileConfig.GetString("clustering/distribution"))
+	c.Clustering.LoadFactor = c.FileConfig.GetFloat64("clustering/load-factor")
 	c.setClusteringDefaults()
+	switch c.Clustering.Distribution {
+	case DistributionLoad, DistributionConsistentHash:
+	default:
+		return fmt.Errorf("unknown clustering distribution %q, must be one of %q", c.Clustering.Distribution,
+			[]string{DistributionLoad, DistributionConsistentHash})
+	}
 	return c.getLocker()
 }
 
@@ -86,4 +108,11 @@ func (c *Config) setClusteringDefaults() {
 	if c.Clustering.LeaderWaitTimer <= defaultLeaderWaitTimer {
 		c.Clustering.LeaderWaitTimer = defaultLeaderWaitTimer
 	}
+	if c.Clustering.Distribution == "" {
+		c.Clustering.Distribution = DistributionLoad
+	}
+	// a load factor below 1 would not leave room for all the targets
+	if c.Clustering.LoadFactor < 1 {
+		c.Clustering.LoadFactor = defaultLoadFactor
+	}
 }
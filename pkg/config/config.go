@@ -115,6 +115,7 @@ type GlobalFlags struct {
 	File             []string      `mapstructure:"file,omitempty" json:"file,omitempty" yaml:"file,omitempty"`
 	Dir              []string      `mapstructure:"dir,omitempty" json:"dir,omitempty" yaml:"dir,omitempty"`
 	Exclude          []string      `mapstructure:"exclude,omitempty" json:"exclude,omitempty" yaml:"exclude,omitempty"`
+	ValidatePaths    bool          `mapstructure:"validate-paths,omitempty" json:"validate-paths,omitempty" yaml:"validate-paths,omitempty"`
 	Token            string        `mapstructure:"token,omitempty" json:"token,omitempty" yaml:"token,omitempty"`
 	UseTunnelServer  bool          `mapstructure:"use-tunnel-server,omitempty" json:"use-tunnel-server,omitempty" yaml:"use-tunnel-server,omitempty"`
 	AuthScheme       string        `mapstructure:"auth-scheme,omitempty" json:"auth-scheme,omitempty" yaml:"auth-scheme,omitempty"`
@@ -191,6 +192,7 @@ type LocalFlags struct {
 	SubscribeHistoryStart      string        `mapstructure:"subscribe-history-start,omitempty" json:"subscribe-history-start,omitempty" yaml:"subscribe-history-start,omitempty"`
 	SubscribeHistoryEnd        string        `mapstructure:"subscribe-history-end,omitempty" json:"subscribe-history-end,omitempty" yaml:"subscribe-history-end,omitempty"`
 	SubscribeDepth             uint32        `mapstructure:"subscribe-depth,omitempty" yaml:"subscribe-depth,omitempty" json:"subscribe-depth,omitempty"`
+	SubscribeDryRun            bool          `mapstructure:"subscribe-dry-run,omitempty" yaml:"subscribe-dry-run,omitempty" json:"subscribe-dry-run,omitempty"`
 	// Path
 	PathPathType   string `mapstructure:"path-path-type,omitempty" json:"path-path-type,omitempty" yaml:"path-path-type,omitempty"`
 	PathWithDescr  bool   `mapstructure:"path-descr,omitempty" json:"path-descr,omitempty" yaml:"path-descr,omitempty"`
@@ -285,7 +287,7 @@ func New() *Config {
 		nil,
 		nil,
 		nil,
-		nil,
+		make(map[string]map[string]interface{}),
 		nil,
 		log.New(io.Discard, configLogPrefix, utils.DefaultLoggingFlags),
 		nil,
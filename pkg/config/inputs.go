@@ -9,6 +9,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/openconfig/gnmic/pkg/inputs"
@@ -58,3 +59,23 @@ func (c *Config) GetInputs() (map[string]map[string]interface{}, error) {
 	}
 	return c.Inputs, nil
 }
+
+// ValidateInputConfig checks the type of a single input configuration
+// and sets its format to the global one if it is not set.
+func (c *Config) ValidateInputConfig(cfg map[string]interface{}) error {
+	inputType, ok := cfg["type"].(string)
+	if !ok || inputType == "" {
+		return errors.New("missing input 'type'")
+	}
+	if !strInlist(inputType, inputs.InputTypes) {
+		return fmt.Errorf("unknown input type: %q", inputType)
+	}
+	if _, ok := inputs.Inputs[inputType]; !ok {
+		return fmt.Errorf("input type %q is not available", inputType)
+	}
+	if format, ok := cfg["format"]; !ok || format == "" {
+		cfg["format"] = c.FileConfig.GetString("format")
+	}
+	expandMapEnv(cfg)
+	return nil
+}
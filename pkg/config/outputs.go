@@ -10,6 +10,7 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 
@@ -80,6 +81,26 @@ func (c *Config) GetOutputs() (map[string]map[string]interface{}, error) {
 	return filteredOutputs, nil
 }
 
+// ValidateOutputConfig checks the type of a single output configuration
+// and sets its format to the global one if it is not set.
+func (c *Config) ValidateOutputConfig(cfg map[string]interface{}) error {
+	outType, ok := cfg["type"].(string)
+	if !ok || outType == "" {
+		return errors.New("missing output 'type'")
+	}
+	if _, ok := outputs.OutputTypes[outType]; !ok {
+		return fmt.Errorf("unknown output type: %q", outType)
+	}
+	if _, ok := outputs.Outputs[outType]; !ok {
+		return fmt.Errorf("output type %q is not available", outType)
+	}
+	if format, ok := cfg["format"]; !ok || format == "" {
+		cfg["format"] = c.FileConfig.GetString("format")
+	}
+	expandMapEnv(cfg, "msg-template", "target-template")
+	return nil
+}
+
 func convert(i interface{}) interface{} {
 	switch x := i.(type) {
 	case map[interface{}]interface{}:
@@ -102,3 +102,57 @@ func TestGetOutputs(t *testing.T) {
 		})
 	}
 }
+
+var validateOutputConfigTestSet = map[string]struct {
+	in     map[string]interface{}
+	out    map[string]interface{}
+	hasErr bool
+}{
+	"valid": {
+		in: map[string]interface{}{
+			"type":      "file",
+			"file-type": "stdout",
+		},
+		out: map[string]interface{}{
+			"type":      "file",
+			"file-type": "stdout",
+			"format":    "",
+		},
+	},
+	"missing_type": {
+		in: map[string]interface{}{
+			"file-type": "stdout",
+		},
+		hasErr: true,
+	},
+	"unknown_type": {
+		in: map[string]interface{}{
+			"type": "foo",
+		},
+		hasErr: true,
+	},
+}
+
+func TestValidateOutputConfig(t *testing.T) {
+	for name, data := range validateOutputConfigTestSet {
+		t.Run(name, func(t *testing.T) {
+			cfg := New()
+			err := cfg.ValidateOutputConfig(data.in)
+			if data.hasErr {
+				if err == nil {
+					t.Errorf("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if !reflect.DeepEqual(data.in, data.out) {
+				t.Logf("exp value: %+v", data.out)
+				t.Logf("got value: %+v", data.in)
+				t.Fail()
+			}
+		})
+	}
+}
@@ -56,6 +56,23 @@ func (c *Config) validateProcessorConfig(pcfg map[string]interface{}) error {
 	return nil
 }
 
+// ValidateProcessorConfig checks that a single processor configuration
+// has exactly one known processor type.
+func (c *Config) ValidateProcessorConfig(pcfg map[string]interface{}) error {
+	if len(pcfg) != 1 {
+		return fmt.Errorf("a processor must have exactly one type, got %d", len(pcfg))
+	}
+	err := c.validateProcessorConfig(pcfg)
+	if err != nil {
+		return err
+	}
+	for n, p := range pcfg {
+		pcfg[n] = convert(p)
+	}
+	expandMapEnv(pcfg, "expression", "condition")
+	return nil
+}
+
 func strInlist(s string, ls []string) bool {
 	for _, ss := range ls {
 		if ss == s {
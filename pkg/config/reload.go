@@ -0,0 +1,176 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	gfile "github.com/openconfig/gnmic/pkg/file"
+)
+
+// Diff holds the names of the added, deleted and updated
+// items of each configuration section that can be reloaded at runtime.
+type Diff struct {
+	Targets       *SectionDiff `json:"targets,omitempty"`
+	Subscriptions *SectionDiff `json:"subscriptions,omitempty"`
+	Outputs       *SectionDiff `json:"outputs,omitempty"`
+	Inputs        *SectionDiff `json:"inputs,omitempty"`
+	Processors    *SectionDiff `json:"processors,omitempty"`
+	Actions       *SectionDiff `json:"actions,omitempty"`
+}
+
+type SectionDiff struct {
+	Added   []string `json:"added,omitempty"`
+	Deleted []string `json:"deleted,omitempty"`
+	Updated []string `json:"updated,omitempty"`
+}
+
+// ReloadFile re-reads the configuration file used by the running instance.
+func (c *Config) ReloadFile(ctx context.Context) error {
+	cfgFile := c.FileConfig.ConfigFileUsed()
+	if cfgFile == "" {
+		return errors.New("no configuration file in use")
+	}
+	configBytes, err := gfile.ReadFile(ctx, cfgFile)
+	if err != nil {
+		return err
+	}
+	return c.FileConfig.ReadConfig(bytes.NewBuffer(configBytes))
+}
+
+// ReadReloadable builds a new Config from the current file configuration content.
+// It reads only the sections that can be changed at runtime:
+// targets, subscriptions, outputs, inputs, processors and actions.
+// Subscriptions defined using the --path flag are carried over as is.
+func (c *Config) ReadReloadable(cmd *cobra.Command) (*Config, error) {
+	nc := New()
+	nc.GlobalFlags = c.GlobalFlags
+	nc.LocalFlags = c.LocalFlags
+	nc.FileConfig = c.FileConfig
+	nc.logger = c.logger
+
+	var err error
+	if len(c.LocalFlags.SubscribePath) > 0 {
+		for n, sc := range c.Subscriptions {
+			nc.Subscriptions[n] = sc
+		}
+	} else {
+		_, err = nc.GetSubscriptions(cmd)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = nc.GetTargets()
+	if err != nil && !errors.Is(err, ErrNoTargetsFound) {
+		return nil, err
+	}
+	_, err = nc.GetOutputs()
+	if err != nil {
+		return nil, err
+	}
+	_, err = nc.GetInputs()
+	if err != nil {
+		return nil, err
+	}
+	_, err = nc.GetActions()
+	if err != nil {
+		return nil, err
+	}
+	_, err = nc.GetEventProcessors()
+	if err != nil {
+		return nil, err
+	}
+	return nc, nil
+}
+
+// Diff compares c with nc and returns the changes
+// needed to go from c to nc.
+func (c *Config) Diff(nc *Config) *Diff {
+	return &Diff{
+		Targets:       diffSection(c.Targets, nc.Targets),
+		Subscriptions: diffSection(c.Subscriptions, nc.Subscriptions),
+		Outputs:       diffSection(c.Outputs, nc.Outputs),
+		Inputs:        diffSection(c.Inputs, nc.Inputs),
+		Processors:    diffSection(c.Processors, nc.Processors),
+		Actions:       diffSection(c.Actions, nc.Actions),
+	}
+}
+
+func (d *Diff) IsEmpty() bool {
+	return d.Targets.IsEmpty() &&
+		d.Subscriptions.IsEmpty() &&
+		d.Outputs.IsEmpty() &&
+		d.Inputs.IsEmpty() &&
+		d.Processors.IsEmpty() &&
+		d.Actions.IsEmpty()
+}
+
+func (d *Diff) String() string {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (sd *SectionDiff) IsEmpty() bool {
+	return sd == nil || len(sd.Added)+len(sd.Deleted)+len(sd.Updated) == 0
+}
+
+// Changed returns the names of the deleted and updated items.
+func (sd *SectionDiff) Changed() []string {
+	if sd == nil {
+		return nil
+	}
+	changed := make([]string, 0, len(sd.Deleted)+len(sd.Updated))
+	changed = append(changed, sd.Deleted...)
+	changed = append(changed, sd.Updated...)
+	return changed
+}
+
+// NewOrUpdated returns the names of the added and updated items.
+func (sd *SectionDiff) NewOrUpdated() []string {
+	if sd == nil {
+		return nil
+	}
+	items := make([]string, 0, len(sd.Added)+len(sd.Updated))
+	items = append(items, sd.Added...)
+	items = append(items, sd.Updated...)
+	return items
+}
+
+func diffSection[V any](cur, next map[string]V) *SectionDiff {
+	sd := new(SectionDiff)
+	for n, cv := range cur {
+		nv, ok := next[n]
+		if !ok {
+			sd.Deleted = append(sd.Deleted, n)
+			continue
+		}
+		if !reflect.DeepEqual(cv, nv) {
+			sd.Updated = append(sd.Updated, n)
+		}
+	}
+	for n := range next {
+		if _, ok := cur[n]; !ok {
+			sd.Added = append(sd.Added, n)
+		}
+	}
+	sort.Strings(sd.Added)
+	sort.Strings(sd.Deleted)
+	sort.Strings(sd.Updated)
+	return sd
+}
@@ -0,0 +1,197 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+var configDiffTestSet = map[string]struct {
+	old  []byte
+	new  []byte
+	diff *Diff
+}{
+	"no_change": {
+		old: []byte(`
+outputs:
+  output1:
+    type: file
+    file-type: stdout
+`),
+		new: []byte(`
+outputs:
+  output1:
+    type: file
+    file-type: stdout
+`),
+		diff: &Diff{
+			Targets:       &SectionDiff{},
+			Subscriptions: &SectionDiff{},
+			Outputs:       &SectionDiff{},
+			Inputs:        &SectionDiff{},
+			Processors:    &SectionDiff{},
+			Actions:       &SectionDiff{},
+		},
+	},
+	"outputs_changes": {
+		old: []byte(`
+outputs:
+  output1:
+    type: file
+    file-type: stdout
+  output2:
+    type: nats
+`),
+		new: []byte(`
+outputs:
+  output1:
+    type: file
+    file-type: stderr
+  output3:
+    type: kafka
+`),
+		diff: &Diff{
+			Targets:       &SectionDiff{},
+			Subscriptions: &SectionDiff{},
+			Outputs: &SectionDiff{
+				Added:   []string{"output3"},
+				Deleted: []string{"output2"},
+				Updated: []string{"output1"},
+			},
+			Inputs:     &SectionDiff{},
+			Processors: &SectionDiff{},
+			Actions:    &SectionDiff{},
+		},
+	},
+	"subscriptions_and_processors_changes": {
+		old: []byte(`
+outputs:
+  output1:
+    type: file
+    file-type: stdout
+subscriptions:
+  sub1:
+    paths:
+      - /interface
+    sample-interval: 10s
+  sub2:
+    paths:
+      - /system
+processors:
+  proc1:
+    event-drop:
+      value-names:
+        - ".*"
+`),
+		new: []byte(`
+outputs:
+  output1:
+    type: file
+    file-type: stdout
+subscriptions:
+  sub1:
+    paths:
+      - /interface
+    sample-interval: 5s
+  sub2:
+    paths:
+      - /system
+processors:
+  proc1:
+    event-drop:
+      value-names:
+        - "^foo$"
+  proc2:
+    event-delete:
+      tag-names:
+        - bar
+`),
+		diff: &Diff{
+			Targets: &SectionDiff{},
+			Subscriptions: &SectionDiff{
+				Updated: []string{"sub1"},
+			},
+			Outputs: &SectionDiff{},
+			Inputs:  &SectionDiff{},
+			Processors: &SectionDiff{
+				Added:   []string{"proc2"},
+				Updated: []string{"proc1"},
+			},
+			Actions: &SectionDiff{},
+		},
+	},
+	"actions_changes": {
+		old: []byte(`
+actions:
+  act1:
+    type: http
+    url: http://localhost:8080
+  act2:
+    type: http
+    url: http://localhost:8081
+`),
+		new: []byte(`
+actions:
+  act1:
+    type: http
+    url: http://localhost:9090
+  act3:
+    type: http
+    url: http://localhost:8082
+`),
+		diff: &Diff{
+			Targets:       &SectionDiff{},
+			Subscriptions: &SectionDiff{},
+			Outputs:       &SectionDiff{},
+			Inputs:        &SectionDiff{},
+			Processors:    &SectionDiff{},
+			Actions: &SectionDiff{
+				Added:   []string{"act3"},
+				Deleted: []string{"act2"},
+				Updated: []string{"act1"},
+			},
+		},
+	},
+}
+
+func TestConfigDiff(t *testing.T) {
+	for name, data := range configDiffTestSet {
+		t.Run(name, func(t *testing.T) {
+			cfg := New()
+			cfg.FileConfig.SetConfigType("yaml")
+			err := cfg.FileConfig.ReadConfig(bytes.NewBuffer(data.old))
+			if err != nil {
+				t.Fatalf("failed reading old config: %v", err)
+			}
+			cfg, err = cfg.ReadReloadable(nil)
+			if err != nil {
+				t.Fatalf("failed building old config: %v", err)
+			}
+			err = cfg.FileConfig.ReadConfig(bytes.NewBuffer(data.new))
+			if err != nil {
+				t.Fatalf("failed reading new config: %v", err)
+			}
+			ncfg, err := cfg.ReadReloadable(nil)
+			if err != nil {
+				t.Fatalf("failed building new config: %v", err)
+			}
+			diff := cfg.Diff(ncfg)
+			t.Logf("exp value: %s", data.diff)
+			t.Logf("got value: %s", diff)
+			if !reflect.DeepEqual(diff, data.diff) {
+				t.Fail()
+			}
+			if diff.IsEmpty() != data.diff.IsEmpty() {
+				t.Fail()
+			}
+		})
+	}
+}
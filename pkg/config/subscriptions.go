@@ -174,6 +174,23 @@ func (c *Config) decodeSubscriptionConfig(sn string, s any, cmd *cobra.Command)
 	return sub, nil
 }
 
+// DecodeSubscriptionConfig decodes a single subscription configuration
+// and checks that a valid SubscribeRequest can be built from it.
+func (c *Config) DecodeSubscriptionConfig(name string, s any) (*types.SubscriptionConfig, error) {
+	if name == "" {
+		return nil, fmt.Errorf("%w: missing subscription name", ErrConfig)
+	}
+	sc, err := c.decodeSubscriptionConfig(name, s, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: subscription %s: %v", ErrConfig, name, err)
+	}
+	_, err = c.CreateSubscribeRequest(sc, &types.TargetConfig{})
+	if err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
 func (c *Config) setSubscriptionFieldsFromFlags(sub *types.SubscriptionConfig, cmd *cobra.Command) error {
 	if sub.SampleInterval == nil && flagIsSet(cmd, "sample-interval") {
 		sub.SampleInterval = &c.LocalFlags.SubscribeSampleInterval
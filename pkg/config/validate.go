@@ -0,0 +1,235 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	gfile "github.com/openconfig/gnmic/pkg/file"
+)
+
+// Diagnostic is a configuration error.
+// Path is the location of the invalid item in the configuration,
+// Line is its line number in the configuration file, 0 if unknown.
+type Diagnostic struct {
+	Path []string
+	Line int
+	Err  error
+}
+
+func (d *Diagnostic) String() string {
+	sb := new(strings.Builder)
+	if d.Line > 0 {
+		sb.WriteString("line ")
+		sb.WriteString(strconv.Itoa(d.Line))
+		sb.WriteString(": ")
+	}
+	if len(d.Path) > 0 {
+		sb.WriteString(strings.Join(d.Path, "."))
+		sb.WriteString(": ")
+	}
+	sb.WriteString(d.Err.Error())
+	return sb.String()
+}
+
+// ValidateReferences checks that the targets, subscriptions, outputs,
+// inputs, processors, actions and loader referenced by name
+// in the loaded configuration sections are defined.
+func (c *Config) ValidateReferences() []*Diagnostic {
+	diags := make([]*Diagnostic, 0)
+	unknown := func(kind, name string, path ...string) {
+		diags = append(diags, &Diagnostic{
+			Path: path,
+			Err:  fmt.Errorf("unknown %s %q", kind, name),
+		})
+	}
+	// outputs and inputs ignored because of a missing or unknown type
+	for name := range c.FileConfig.GetStringMap("outputs") {
+		if _, ok := c.Outputs[name]; !ok {
+			diags = append(diags, &Diagnostic{
+				Path: []string{"outputs", name},
+				Err:  fmt.Errorf("output is ignored: missing or unknown type"),
+			})
+		}
+	}
+	for _, name := range SortedKeys(c.Targets) {
+		tc := c.Targets[name]
+		for i, s := range tc.Subscriptions {
+			if _, ok := c.Subscriptions[s]; !ok {
+				unknown("subscription", s, "targets", name, "subscriptions", strconv.Itoa(i))
+			}
+		}
+		for i, o := range tc.Outputs {
+			if _, ok := c.Outputs[o]; !ok {
+				unknown("output", o, "targets", name, "outputs", strconv.Itoa(i))
+			}
+		}
+	}
+	for _, name := range SortedKeys(c.Subscriptions) {
+		for i, o := range c.Subscriptions[name].Outputs {
+			if _, ok := c.Outputs[o]; !ok {
+				unknown("output", o, "subscriptions", name, "outputs", strconv.Itoa(i))
+			}
+		}
+	}
+	for _, name := range SortedKeys(c.Outputs) {
+		for i, p := range refNames(c.Outputs[name]["event-processors"]) {
+			if _, ok := c.Processors[p]; !ok {
+				unknown("event processor", p, "outputs", name, "event-processors", strconv.Itoa(i))
+			}
+		}
+	}
+	for _, name := range SortedKeys(c.Inputs) {
+		for i, p := range refNames(c.Inputs[name]["event-processors"]) {
+			if _, ok := c.Processors[p]; !ok {
+				unknown("event processor", p, "inputs", name, "event-processors", strconv.Itoa(i))
+			}
+		}
+		for i, o := range refNames(c.Inputs[name]["outputs"]) {
+			if _, ok := c.Outputs[o]; !ok {
+				unknown("output", o, "inputs", name, "outputs", strconv.Itoa(i))
+			}
+		}
+	}
+	for _, name := range SortedKeys(c.Processors) {
+		for typ, v := range c.Processors[name] {
+			pcfg, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for i, p := range refNames(pcfg["processors"]) {
+				if _, ok := c.Processors[p]; !ok {
+					unknown("event processor", p, "processors", name, typ, "processors", strconv.Itoa(i))
+				}
+			}
+			for i, a := range refNames(pcfg["actions"]) {
+				if _, ok := c.Actions[a]; !ok {
+					unknown("action", a, "processors", name, typ, "actions", strconv.Itoa(i))
+				}
+			}
+		}
+	}
+	for _, k := range []string{"on-add", "on-delete"} {
+		for i, a := range refNames(c.Loader[k]) {
+			if _, ok := c.Actions[a]; !ok {
+				unknown("action", a, "loader", k, strconv.Itoa(i))
+			}
+		}
+	}
+	return diags
+}
+
+// SetDiagnosticsLines sets the line number of each diagnostic
+// based on its path in the configuration file.
+// Line numbers are only available for YAML configuration files.
+func (c *Config) SetDiagnosticsLines(ctx context.Context, diags []*Diagnostic) error {
+	cfgFile := c.FileConfig.ConfigFileUsed()
+	switch filepath.Ext(cfgFile) {
+	case ".yaml", ".yml":
+	default:
+		return nil
+	}
+	b, err := gfile.ReadFile(ctx, cfgFile)
+	if err != nil {
+		return err
+	}
+	root := new(yaml.Node)
+	err = yaml.Unmarshal(b, root)
+	if err != nil {
+		return err
+	}
+	for _, d := range diags {
+		d.Line = nodeLine(root, d.Path)
+	}
+	return nil
+}
+
+// nodeLine returns the line of the deepest node matching path.
+// Map keys are matched ignoring case since the configuration keys are lower cased when read.
+func nodeLine(n *yaml.Node, path []string) int {
+	line := 0
+	for n != nil {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			if len(n.Content) == 0 {
+				return line
+			}
+			n = n.Content[0]
+			continue
+		case yaml.AliasNode:
+			n = n.Alias
+			continue
+		}
+		if len(path) == 0 {
+			return line
+		}
+		var next *yaml.Node
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if strings.EqualFold(n.Content[i].Value, path[0]) {
+					line = n.Content[i].Line
+					next = n.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			idx, err := strconv.Atoi(path[0])
+			if err == nil && idx >= 0 && idx < len(n.Content) {
+				next = n.Content[idx]
+				line = next.Line
+			}
+		}
+		n = next
+		path = path[1:]
+	}
+	return line
+}
+
+// refNames returns the names in a list of references,
+// the list items are either names or maps with a name field.
+func refNames(v interface{}) []string {
+	var items []interface{}
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		items = v
+	default:
+		return nil
+	}
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		switch item := item.(type) {
+		case string:
+			names = append(names, item)
+		case map[string]interface{}:
+			name, _ := item["name"].(string)
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// SortedKeys returns the keys of m in ascending order.
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
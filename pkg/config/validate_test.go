@@ -0,0 +1,151 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+var validateReferencesTestSet = map[string]struct {
+	in    []byte
+	diags []string
+}{
+	"valid": {
+		in: []byte(`
+targets:
+  router1:
+    address: 10.0.0.1
+    subscriptions:
+      - sub1
+    outputs:
+      - out1
+subscriptions:
+  sub1:
+    paths:
+      - /interface
+outputs:
+  out1:
+    type: file
+    file-type: stdout
+    event-processors:
+      - proc1
+processors:
+  proc1:
+    event-trigger:
+      actions:
+        - act1
+actions:
+  act1:
+    type: http
+    url: http://localhost:8080
+`),
+	},
+	"unknown_references": {
+		in: []byte(`
+targets:
+  router1:
+    address: 10.0.0.1
+    subscriptions:
+      - sub2
+    outputs:
+      - out2
+subscriptions:
+  sub1:
+    paths:
+      - /interface
+    outputs:
+      - out3
+outputs:
+  out1:
+    type: file
+    file-type: stdout
+    event-processors:
+      - proc2
+  out4:
+    file-type: stdout
+processors:
+  proc1:
+    event-trigger:
+      actions:
+        - act2
+`),
+		diags: []string{
+			`outputs.out4: output is ignored: missing or unknown type`,
+			`targets.router1.subscriptions.0: unknown subscription "sub2"`,
+			`targets.router1.outputs.0: unknown output "out2"`,
+			`subscriptions.sub1.outputs.0: unknown output "out3"`,
+			`outputs.out1.event-processors.0: unknown event processor "proc2"`,
+			`processors.proc1.event-trigger.actions.0: unknown action "act2"`,
+		},
+	},
+}
+
+func TestValidateReferences(t *testing.T) {
+	for name, data := range validateReferencesTestSet {
+		t.Run(name, func(t *testing.T) {
+			cfg := New()
+			cfg.FileConfig.SetConfigType("yaml")
+			err := cfg.FileConfig.ReadConfig(bytes.NewBuffer(data.in))
+			if err != nil {
+				t.Fatalf("failed reading config: %v", err)
+			}
+			cfg.GetSubscriptions(nil)
+			cfg.GetTargets()
+			cfg.GetOutputs()
+			cfg.GetActions()
+			cfg.GetEventProcessors()
+			diags := cfg.ValidateReferences()
+			got := make([]string, 0, len(diags))
+			for _, d := range diags {
+				got = append(got, d.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(data.diags, "\n") {
+				t.Errorf("unexpected diagnostics:\ngot: %q\nexp: %q", got, data.diags)
+			}
+		})
+	}
+}
+
+func TestNodeLine(t *testing.T) {
+	in := []byte(`
+outputs:
+  Out1:
+    type: file
+    event-processors:
+      - proc1
+      - proc2
+processors:
+  proc1:
+    event-drop: {}
+`)
+	root := new(yaml.Node)
+	err := yaml.Unmarshal(in, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path []string
+		line int
+	}{
+		{path: []string{"outputs", "out1"}, line: 3},
+		{path: []string{"outputs", "out1", "event-processors", "1"}, line: 7},
+		{path: []string{"outputs", "out1", "unknown"}, line: 3},
+		{path: []string{"processors", "proc1", "event-drop"}, line: 10},
+		{path: []string{"targets"}, line: 0},
+	}
+	for _, tt := range tests {
+		if line := nodeLine(root, tt.path); line != tt.line {
+			t.Errorf("path %v: got line %d, expected %d", tt.path, line, tt.line)
+		}
+	}
+}
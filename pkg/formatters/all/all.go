@@ -10,6 +10,8 @@ package all
 
 import (
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_add_tag"
+	_ "github.com/openconfig/gnmic/pkg/formatters/event_aggregate"
+	_ "github.com/openconfig/gnmic/pkg/formatters/event_alert"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_allow"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_combine"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_convert"
@@ -18,11 +20,14 @@ import (
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_delete"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_drop"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_duration_convert"
+	_ "github.com/openconfig/gnmic/pkg/formatters/event_enrich"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_extract_tags"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_group_by"
+	_ "github.com/openconfig/gnmic/pkg/formatters/event_join"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_jq"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_merge"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_override_ts"
+	_ "github.com/openconfig/gnmic/pkg/formatters/event_rate"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_rate_limit"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_starlark"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_strings"
@@ -0,0 +1,457 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	processorType = "event-aggregate"
+	loggingPrefix = "[" + processorType + "] "
+	defaultWindow = time.Minute
+)
+
+var defaultAggregations = []string{"min", "max", "mean", "sum", "count", "p50", "p95", "p99"}
+
+// aggregate buffers the values with a name matching one of the regexes
+// and emits their aggregations over a tumbling or sliding window,
+// per group of events sharing the same name and tags values.
+type aggregate struct {
+	ValueNames   []string      `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Tags         []string      `mapstructure:"tags,omitempty" json:"tags,omitempty"`
+	Window       time.Duration `mapstructure:"window,omitempty" json:"window,omitempty"`
+	Slide        time.Duration `mapstructure:"slide,omitempty" json:"slide,omitempty"`
+	Aggregations []string      `mapstructure:"aggregations,omitempty" json:"aggregations,omitempty"`
+	DropOriginal bool          `mapstructure:"drop-original,omitempty" json:"drop-original,omitempty"`
+	TTL          time.Duration `mapstructure:"ttl,omitempty" json:"ttl,omitempty"`
+	Debug        bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	valueNames []*regexp.Regexp
+	// percentiles maps the configured percentile aggregations
+	// to their rank, e.g p95 => 95
+	percentiles map[string]float64
+
+	m      sync.Mutex
+	groups map[string]*group
+	lastGC time.Time
+	logger *log.Logger
+}
+
+// group holds the buffered samples of the events sharing
+// the same name and tags values.
+type group struct {
+	name string
+	tags map[string]string
+	// next is the end timestamp of the next window to be closed.
+	next     int64
+	samples  map[string][]sample
+	lastSeen time.Time
+}
+
+type sample struct {
+	ts int64
+	v  float64
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &aggregate{
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (a *aggregate) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, a)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.valueNames = make([]*regexp.Regexp, 0, len(a.ValueNames))
+	for _, reg := range a.ValueNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		a.valueNames = append(a.valueNames, re)
+	}
+	if a.Window <= 0 {
+		a.Window = defaultWindow
+	}
+	if a.Slide <= 0 {
+		a.Slide = a.Window
+	}
+	if a.Slide > a.Window {
+		return fmt.Errorf("slide %s cannot be larger than window %s", a.Slide, a.Window)
+	}
+	if a.TTL <= 0 {
+		a.TTL = 2 * a.Window
+	}
+	if len(a.Aggregations) == 0 {
+		a.Aggregations = defaultAggregations
+	}
+	a.percentiles = make(map[string]float64)
+	for _, agg := range a.Aggregations {
+		switch agg {
+		case "min", "max", "mean", "sum", "count":
+		default:
+			if !strings.HasPrefix(agg, "p") {
+				return fmt.Errorf("unknown aggregation %q", agg)
+			}
+			p, err := strconv.ParseFloat(agg[1:], 64)
+			if err != nil || p <= 0 || p > 100 {
+				return fmt.Errorf("invalid percentile aggregation %q", agg)
+			}
+			a.percentiles[agg] = p
+		}
+	}
+	a.groups = make(map[string]*group)
+	a.lastGC = time.Now()
+	if a.logger.Writer() != io.Discard {
+		b, err := json.Marshal(a)
+		if err != nil {
+			a.logger.Printf("initialized processor '%s': %+v", processorType, a)
+			return nil
+		}
+		a.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (a *aggregate) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	now := time.Now()
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	res := make([]*formatters.EventMsg, 0, len(es))
+	aggs := make([]*formatters.EventMsg, 0)
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		values := a.numericValues(e)
+		if len(values) == 0 {
+			res = append(res, e)
+			continue
+		}
+		key, tags, ok := a.groupKey(e)
+		if !ok {
+			res = append(res, e)
+			continue
+		}
+		g, ok := a.groups[key]
+		if !ok {
+			g = &group{
+				name:    e.Name,
+				tags:    tags,
+				next:    a.windowEnd(e.Timestamp),
+				samples: make(map[string][]sample),
+			}
+			a.groups[key] = g
+		}
+		g.lastSeen = now
+		aggs = append(aggs, a.advance(g, e.Timestamp)...)
+		if e.Timestamp < g.next-int64(a.Window) {
+			a.logger.Printf("group %q: sample at %d is older than the current window, ignoring", key, e.Timestamp)
+		} else {
+			for k, v := range values {
+				g.add(k, sample{ts: e.Timestamp, v: v})
+			}
+		}
+		if !a.DropOriginal {
+			res = append(res, e)
+			continue
+		}
+		for k := range values {
+			delete(e.Values, k)
+		}
+		if len(e.Values) == 0 && len(e.Deletes) == 0 {
+			continue
+		}
+		res = append(res, e)
+	}
+	aggs = append(aggs, a.expire(now)...)
+	return append(res, aggs...)
+}
+
+func (a *aggregate) WithLogger(l *log.Logger) {
+	if a.Debug && l != nil {
+		a.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if a.Debug {
+		a.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (a *aggregate) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (a *aggregate) WithActions(act map[string]map[string]interface{}) {}
+
+func (a *aggregate) WithProcessors(procs map[string]map[string]any) {}
+
+// numericValues returns the event values matching one of the regexes,
+// converted to float64.
+// If no regex is configured, all the numeric values are returned.
+func (a *aggregate) numericValues(e *formatters.EventMsg) map[string]float64 {
+	values := make(map[string]float64)
+	for k, v := range e.Values {
+		if len(a.valueNames) > 0 && !a.matches(k) {
+			continue
+		}
+		f, err := toFloat(v)
+		if err != nil {
+			continue
+		}
+		values[k] = f
+	}
+	return values
+}
+
+func (a *aggregate) matches(name string) bool {
+	for _, re := range a.valueNames {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupKey builds the key of the group the event belongs to,
+// along with the tags of the aggregated events.
+// It returns false if the event is missing one of the configured tags.
+func (a *aggregate) groupKey(e *formatters.EventMsg) (string, map[string]string, bool) {
+	tagNames := a.Tags
+	if len(tagNames) == 0 {
+		tagNames = make([]string, 0, len(e.Tags))
+		for k := range e.Tags {
+			tagNames = append(tagNames, k)
+		}
+		sort.Strings(tagNames)
+	}
+	tags := make(map[string]string, len(tagNames))
+	sb := new(strings.Builder)
+	sb.WriteString(e.Name)
+	for _, k := range tagNames {
+		v, ok := e.Tags[k]
+		if !ok {
+			return "", nil, false
+		}
+		tags[k] = v
+		sb.WriteString(",")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(v)
+	}
+	return sb.String(), tags, true
+}
+
+// windowEnd returns the end of the first window closing after ts.
+// Windows are aligned on multiples of the slide duration.
+func (a *aggregate) windowEnd(ts int64) int64 {
+	slide := int64(a.Slide)
+	return (ts/slide + 1) * slide
+}
+
+// advance closes the group windows ending before or at ts.
+func (a *aggregate) advance(g *group, ts int64) []*formatters.EventMsg {
+	res := make([]*formatters.EventMsg, 0)
+	for ts >= g.next {
+		res = append(res, a.close(g)...)
+		if len(g.samples) == 0 {
+			g.next = a.windowEnd(ts)
+			break
+		}
+	}
+	return res
+}
+
+// close emits the aggregated event of the group next window,
+// then moves the group to the following window.
+func (a *aggregate) close(g *group) []*formatters.EventMsg {
+	res := make([]*formatters.EventMsg, 0, 1)
+	if ev := a.aggregate(g, g.next-int64(a.Window), g.next); ev != nil {
+		res = append(res, ev)
+	}
+	g.next += int64(a.Slide)
+	// drop the samples that are not part of the next window
+	start := g.next - int64(a.Window)
+	for k, ss := range g.samples {
+		i := sort.Search(len(ss), func(i int) bool { return ss[i].ts >= start })
+		if i == len(ss) {
+			delete(g.samples, k)
+			continue
+		}
+		g.samples[k] = ss[i:]
+	}
+	return res
+}
+
+// aggregate builds an event with the aggregations of the group samples
+// with a timestamp in [start, end).
+func (a *aggregate) aggregate(g *group, start, end int64) *formatters.EventMsg {
+	values := make(map[string]interface{})
+	for k, ss := range g.samples {
+		vs := make([]float64, 0, len(ss))
+		for _, s := range ss {
+			if s.ts >= start && s.ts < end {
+				vs = append(vs, s.v)
+			}
+		}
+		if len(vs) == 0 {
+			continue
+		}
+		for agg, v := range a.compute(vs) {
+			values[k+"_"+agg] = v
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	tags := make(map[string]string, len(g.tags))
+	for k, v := range g.tags {
+		tags[k] = v
+	}
+	return &formatters.EventMsg{
+		Name:      g.name,
+		Timestamp: end,
+		Tags:      tags,
+		Values:    values,
+	}
+}
+
+func (a *aggregate) compute(vs []float64) map[string]interface{} {
+	sort.Float64s(vs)
+	sum := 0.0
+	for _, v := range vs {
+		sum += v
+	}
+	res := make(map[string]interface{}, len(a.Aggregations))
+	for _, agg := range a.Aggregations {
+		switch agg {
+		case "min":
+			res[agg] = vs[0]
+		case "max":
+			res[agg] = vs[len(vs)-1]
+		case "mean":
+			res[agg] = sum / float64(len(vs))
+		case "sum":
+			res[agg] = sum
+		case "count":
+			res[agg] = len(vs)
+		default:
+			res[agg] = percentile(vs, a.percentiles[agg])
+		}
+	}
+	return res
+}
+
+// expire flushes and removes the groups that did not receive
+// an event for the configured TTL.
+func (a *aggregate) expire(now time.Time) []*formatters.EventMsg {
+	if now.Sub(a.lastGC) < a.TTL {
+		return nil
+	}
+	a.lastGC = now
+	keys := make([]string, 0)
+	for k, g := range a.groups {
+		if now.Sub(g.lastSeen) > a.TTL {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	res := make([]*formatters.EventMsg, 0)
+	for _, k := range keys {
+		g := a.groups[k]
+		for len(g.samples) > 0 {
+			res = append(res, a.close(g)...)
+		}
+		a.logger.Printf("group %q: expired", k)
+		delete(a.groups, k)
+	}
+	return res
+}
+
+// add inserts the sample s of value k,
+// keeping the samples ordered by timestamp.
+func (g *group) add(k string, s sample) {
+	ss := g.samples[k]
+	i := sort.Search(len(ss), func(i int) bool { return ss[i].ts > s.ts })
+	ss = append(ss, sample{})
+	copy(ss[i+1:], ss[i:])
+	ss[i] = s
+	g.samples[k] = ss
+}
+
+// percentile returns the nearest-rank percentile p of the sorted values vs.
+func percentile(vs []float64, p float64) float64 {
+	i := int(math.Ceil(p/100*float64(len(vs)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return vs[i]
+}
+
+func toFloat(v interface{}) (float64, error) {
+	var f float64
+	switch v := v.(type) {
+	case int:
+		f = float64(v)
+	case int8:
+		f = float64(v)
+	case int16:
+		f = float64(v)
+	case int32:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case uint:
+		f = float64(v)
+	case uint8:
+		f = float64(v)
+	case uint16:
+		f = float64(v)
+	case uint32:
+		f = float64(v)
+	case uint64:
+		f = float64(v)
+	case float32:
+		f = float64(v)
+	case float64:
+		f = v
+	case string:
+		var err error
+		f, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("unsupported value type %T", v)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid value %v", f)
+	}
+	return f, nil
+}
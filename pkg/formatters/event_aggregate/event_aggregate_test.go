@@ -0,0 +1,334 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_aggregate
+
+import (
+	"io"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processor map[string]interface{}
+	tests     []item
+}{
+	"tumbling": {
+		processor: map[string]interface{}{
+			"type":         processorType,
+			"value-names":  []string{"power$"},
+			"tags":         []string{"port"},
+			"window":       "10s",
+			"aggregations": []string{"min", "max", "mean", "count"},
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "optics",
+						Timestamp: 1e9,
+						Tags:      map[string]string{"port": "1/1", "source": "r1"},
+						Values:    map[string]interface{}{"power": 1.0, "state": "up"},
+					},
+					{
+						Name:      "optics",
+						Timestamp: 5e9,
+						Tags:      map[string]string{"port": "1/1", "source": "r1"},
+						Values:    map[string]interface{}{"power": "3"},
+					},
+					{
+						// no port tag
+						Name:      "optics",
+						Timestamp: 5e9,
+						Values:    map[string]interface{}{"power": 10},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "optics",
+						Timestamp: 1e9,
+						Tags:      map[string]string{"port": "1/1", "source": "r1"},
+						Values:    map[string]interface{}{"power": 1.0, "state": "up"},
+					},
+					{
+						Name:      "optics",
+						Timestamp: 5e9,
+						Tags:      map[string]string{"port": "1/1", "source": "r1"},
+						Values:    map[string]interface{}{"power": "3"},
+					},
+					{
+						Name:      "optics",
+						Timestamp: 5e9,
+						Values:    map[string]interface{}{"power": 10},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "optics",
+						Timestamp: 12e9,
+						Tags:      map[string]string{"port": "1/1", "source": "r1"},
+						Values:    map[string]interface{}{"power": 5.0},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "optics",
+						Timestamp: 12e9,
+						Tags:      map[string]string{"port": "1/1", "source": "r1"},
+						Values:    map[string]interface{}{"power": 5.0},
+					},
+					{
+						Name:      "optics",
+						Timestamp: 10e9,
+						Tags:      map[string]string{"port": "1/1"},
+						Values: map[string]interface{}{
+							"power_min":   1.0,
+							"power_max":   3.0,
+							"power_mean":  2.0,
+							"power_count": 2,
+						},
+					},
+				},
+			},
+			{
+				// late sample
+				input: []*formatters.EventMsg{
+					{
+						Name:      "optics",
+						Timestamp: 9e9,
+						Tags:      map[string]string{"port": "1/1"},
+						Values:    map[string]interface{}{"power": 100.0},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "optics",
+						Timestamp: 9e9,
+						Tags:      map[string]string{"port": "1/1"},
+						Values:    map[string]interface{}{"power": 100.0},
+					},
+				},
+			},
+			{
+				// skips the empty windows
+				input: []*formatters.EventMsg{
+					{
+						Name:      "optics",
+						Timestamp: 45e9,
+						Tags:      map[string]string{"port": "1/1"},
+						Values:    map[string]interface{}{"power": 1.0},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "optics",
+						Timestamp: 45e9,
+						Tags:      map[string]string{"port": "1/1"},
+						Values:    map[string]interface{}{"power": 1.0},
+					},
+					{
+						Name:      "optics",
+						Timestamp: 20e9,
+						Tags:      map[string]string{"port": "1/1"},
+						Values: map[string]interface{}{
+							"power_min":   5.0,
+							"power_max":   5.0,
+							"power_mean":  5.0,
+							"power_count": 1,
+						},
+					},
+				},
+			},
+		},
+	},
+	"sliding_drop_original": {
+		processor: map[string]interface{}{
+			"type":          processorType,
+			"window":        "10s",
+			"slide":         "5s",
+			"drop-original": true,
+			"aggregations":  []string{"sum", "p50", "p99"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 1e9,
+						Values:    map[string]interface{}{"v": 1, "state": "up"},
+					},
+					{
+						Name:      "sub1",
+						Timestamp: 3e9,
+						Values:    map[string]interface{}{"v": 2},
+					},
+					{
+						Name:      "sub1",
+						Timestamp: 6e9,
+						Values:    map[string]interface{}{"v": 4},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 1e9,
+						Values:    map[string]interface{}{"state": "up"},
+					},
+					{
+						Name:      "sub1",
+						Timestamp: 5e9,
+						Tags:      map[string]string{},
+						Values: map[string]interface{}{
+							"v_sum": 3.0,
+							"v_p50": 1.0,
+							"v_p99": 2.0,
+						},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 11e9,
+						Values:    map[string]interface{}{"v": 8},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 10e9,
+						Tags:      map[string]string{},
+						Values: map[string]interface{}{
+							"v_sum": 7.0,
+							"v_p50": 2.0,
+							"v_p99": 4.0,
+						},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 16e9,
+						Values:    map[string]interface{}{"v": 16},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 15e9,
+						Tags:      map[string]string{},
+						Values: map[string]interface{}{
+							"v_sum": 12.0,
+							"v_p50": 4.0,
+							"v_p99": 8.0,
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventAggregate(t *testing.T) {
+	for name, ts := range testset {
+		if typ, ok := ts.processor["type"]; ok {
+			t.Log("found type")
+			if pi, ok := formatters.EventProcessors[typ.(string)]; ok {
+				t.Log("found processor")
+				p := pi()
+				err := p.Init(ts.processor)
+				if err != nil {
+					t.Errorf("failed to initialize processors: %v", err)
+					return
+				}
+				t.Logf("processor: %+v", p)
+				for i, item := range ts.tests {
+					t.Run(name, func(t *testing.T) {
+						t.Logf("running test item %d", i)
+						outs := p.Apply(item.input...)
+						if !reflect.DeepEqual(outs, item.output) {
+							t.Logf("failed at %q item %d", name, i)
+							t.Logf("expected: %#v", item.output)
+							t.Logf("     got: %#v", outs)
+							t.Fail()
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestEventAggregateInitErrors(t *testing.T) {
+	cfgs := map[string]map[string]interface{}{
+		"bad_regex":       {"value-names": []string{"("}},
+		"large_slide":     {"window": "10s", "slide": "20s"},
+		"bad_aggregation": {"aggregations": []string{"median"}},
+		"bad_percentile":  {"aggregations": []string{"p101"}},
+	}
+	for name, cfg := range cfgs {
+		t.Run(name, func(t *testing.T) {
+			p := formatters.EventProcessors[processorType]()
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestEventAggregateExpire(t *testing.T) {
+	a := &aggregate{logger: log.New(io.Discard, "", 0)}
+	err := a.Init(map[string]interface{}{
+		"window":       "10s",
+		"aggregations": []string{"count"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	a.Apply(&formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 1e9,
+		Tags:      map[string]string{"source": "r1"},
+		Values:    map[string]interface{}{"v": 1},
+	})
+	outs := a.expire(now.Add(time.Minute))
+	want := []*formatters.EventMsg{
+		{
+			Name:      "sub1",
+			Timestamp: 10e9,
+			Tags:      map[string]string{"source": "r1"},
+			Values:    map[string]interface{}{"v_count": 1},
+		},
+	}
+	if !reflect.DeepEqual(outs, want) {
+		t.Errorf("expected: %#v", want)
+		t.Errorf("     got: %#v", outs)
+	}
+	if len(a.groups) != 0 {
+		t.Errorf("expired group not removed")
+	}
+}
@@ -0,0 +1,504 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/openconfig/gnmic/pkg/actions"
+	_ "github.com/openconfig/gnmic/pkg/actions/all"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	gfile "github.com/openconfig/gnmic/pkg/file"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	processorType   = "event-alert"
+	loggingPrefix   = "[" + processorType + "] "
+	defaultSeverity = "warning"
+	defaultTTL      = time.Hour
+
+	directionAbove = "above"
+	directionBelow = "below"
+
+	alertNameTag  = "alert-name"
+	alertStateTag = "alert-state"
+	severityTag   = "severity"
+	thresholdName = "threshold"
+
+	alertFiring   = "firing"
+	alertResolved = "resolved"
+)
+
+type state int
+
+const (
+	stateInactive state = iota
+	// the raise condition is met, waiting for the `for` duration.
+	statePending
+	stateFiring
+	// the clear condition is met, waiting for the `clear-for` duration.
+	stateClearing
+)
+
+// alert tracks the state of each series (event name, tags and value name)
+// with a value name matching one of the regexes, and emits an alert event
+// when a series starts firing or is resolved.
+type alert struct {
+	AlertName      string                 `mapstructure:"alert-name,omitempty" json:"alert-name,omitempty"`
+	ValueNames     []string               `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Direction      string                 `mapstructure:"direction,omitempty" json:"direction,omitempty"`
+	Raise          *float64               `mapstructure:"raise,omitempty" json:"raise,omitempty"`
+	Clear          *float64               `mapstructure:"clear,omitempty" json:"clear,omitempty"`
+	For            time.Duration          `mapstructure:"for,omitempty" json:"for,omitempty"`
+	ClearFor       time.Duration          `mapstructure:"clear-for,omitempty" json:"clear-for,omitempty"`
+	Severity       string                 `mapstructure:"severity,omitempty" json:"severity,omitempty"`
+	Labels         map[string]string      `mapstructure:"labels,omitempty" json:"labels,omitempty"`
+	RepeatInterval time.Duration          `mapstructure:"repeat-interval,omitempty" json:"repeat-interval,omitempty"`
+	DropOriginal   bool                   `mapstructure:"drop-original,omitempty" json:"drop-original,omitempty"`
+	TTL            time.Duration          `mapstructure:"ttl,omitempty" json:"ttl,omitempty"`
+	Actions        []string               `mapstructure:"actions,omitempty" json:"actions,omitempty"`
+	Vars           map[string]interface{} `mapstructure:"vars,omitempty" json:"vars,omitempty"`
+	VarsFile       string                 `mapstructure:"vars-file,omitempty" json:"vars-file,omitempty"`
+	Async          bool                   `mapstructure:"async,omitempty" json:"async,omitempty"`
+	Debug          bool                   `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	valueNames []*regexp.Regexp
+	actions    []actions.Action
+	vars       map[string]interface{}
+
+	m      sync.Mutex
+	series map[string]*series
+	lastGC time.Time
+
+	targets map[string]*types.TargetConfig
+	acts    map[string]map[string]interface{}
+	logger  *log.Logger
+}
+
+type series struct {
+	state state
+	// since is the timestamp of the first sample
+	// of the current pending or clearing state.
+	since int64
+	// notified is the timestamp of the last firing alert sent.
+	notified int64
+	lastSeen time.Time
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &alert{
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *alert) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	err = p.setDefaults()
+	if err != nil {
+		return err
+	}
+	p.valueNames = make([]*regexp.Regexp, 0, len(p.ValueNames))
+	for _, reg := range p.ValueNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		p.valueNames = append(p.valueNames, re)
+	}
+	for _, name := range p.Actions {
+		if actCfg, ok := p.acts[name]; ok {
+			err = p.initializeAction(actCfg)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		return fmt.Errorf("failed to initialize action %q: config not found", name)
+	}
+	err = p.readVars()
+	if err != nil {
+		return err
+	}
+	p.series = make(map[string]*series)
+	p.lastGC = time.Now()
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *alert) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	now := time.Now()
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.expire(now)
+
+	res := make([]*formatters.EventMsg, 0, len(es))
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		if !p.DropOriginal {
+			res = append(res, e)
+		}
+		for _, k := range p.matchingValues(e) {
+			v, err := toFloat(e.Values[k])
+			if err != nil {
+				p.logger.Printf("value %q: %v", k, err)
+				continue
+			}
+			ev := p.evaluate(e, k, v, now)
+			if ev == nil {
+				continue
+			}
+			res = append(res, ev)
+			if len(p.actions) == 0 {
+				continue
+			}
+			if p.Async {
+				go p.runActions(ev)
+			} else {
+				p.runActions(ev)
+			}
+		}
+	}
+	return res
+}
+
+func (p *alert) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *alert) WithTargets(tcs map[string]*types.TargetConfig) {
+	p.targets = tcs
+}
+
+func (p *alert) WithActions(acts map[string]map[string]interface{}) {
+	p.acts = acts
+}
+
+func (p *alert) WithProcessors(procs map[string]map[string]any) {}
+
+func (p *alert) setDefaults() error {
+	if len(p.ValueNames) == 0 {
+		return errors.New("missing value-names")
+	}
+	if p.Raise == nil {
+		return errors.New("missing raise threshold")
+	}
+	if p.Clear == nil {
+		p.Clear = p.Raise
+	}
+	switch p.Direction {
+	case "":
+		p.Direction = directionAbove
+		fallthrough
+	case directionAbove:
+		if *p.Clear > *p.Raise {
+			return fmt.Errorf("clear threshold %v cannot be higher than raise threshold %v", *p.Clear, *p.Raise)
+		}
+	case directionBelow:
+		if *p.Clear < *p.Raise {
+			return fmt.Errorf("clear threshold %v cannot be lower than raise threshold %v", *p.Clear, *p.Raise)
+		}
+	default:
+		return fmt.Errorf("unknown direction %q, must be one of %q or %q", p.Direction, directionAbove, directionBelow)
+	}
+	if p.Severity == "" {
+		p.Severity = defaultSeverity
+	}
+	if p.TTL <= 0 {
+		p.TTL = defaultTTL
+	}
+	return nil
+}
+
+// matchingValues returns the sorted names of the event values
+// matching one of the configured regexes.
+func (p *alert) matchingValues(e *formatters.EventMsg) []string {
+	names := make([]string, 0, len(e.Values))
+	for k := range e.Values {
+		for _, re := range p.valueNames {
+			if re.MatchString(k) {
+				names = append(names, k)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p *alert) raised(v float64) bool {
+	if p.Direction == directionBelow {
+		return v < *p.Raise
+	}
+	return v > *p.Raise
+}
+
+func (p *alert) cleared(v float64) bool {
+	if p.Direction == directionBelow {
+		return v >= *p.Clear
+	}
+	return v <= *p.Clear
+}
+
+// evaluate moves the series of value k to its next state,
+// it returns an alert event if the series started firing or got resolved.
+func (p *alert) evaluate(e *formatters.EventMsg, k string, v float64, now time.Time) *formatters.EventMsg {
+	key := seriesKey(e, k)
+	s, ok := p.series[key]
+	if !ok {
+		s = &series{}
+		p.series[key] = s
+	}
+	s.lastSeen = now
+	ts := e.Timestamp
+	switch s.state {
+	case stateInactive:
+		if !p.raised(v) {
+			return nil
+		}
+		s.state = statePending
+		s.since = ts
+		fallthrough
+	case statePending:
+		if !p.raised(v) {
+			p.logger.Printf("series %q: raise condition no longer met", key)
+			s.state = stateInactive
+			return nil
+		}
+		if time.Duration(ts-s.since) < p.For {
+			p.logger.Printf("series %q: pending", key)
+			return nil
+		}
+		p.logger.Printf("series %q: firing", key)
+		s.state = stateFiring
+		s.notified = ts
+		return p.alertEvent(e, k, v, alertFiring, *p.Raise)
+	case stateFiring:
+		if !p.cleared(v) {
+			if p.RepeatInterval > 0 && time.Duration(ts-s.notified) >= p.RepeatInterval {
+				s.notified = ts
+				return p.alertEvent(e, k, v, alertFiring, *p.Raise)
+			}
+			return nil
+		}
+		s.state = stateClearing
+		s.since = ts
+		fallthrough
+	case stateClearing:
+		if !p.cleared(v) {
+			p.logger.Printf("series %q: clear condition no longer met", key)
+			s.state = stateFiring
+			return nil
+		}
+		if time.Duration(ts-s.since) < p.ClearFor {
+			p.logger.Printf("series %q: clearing", key)
+			return nil
+		}
+		p.logger.Printf("series %q: resolved", key)
+		s.state = stateInactive
+		return p.alertEvent(e, k, v, alertResolved, *p.Clear)
+	}
+	return nil
+}
+
+func (p *alert) alertEvent(e *formatters.EventMsg, k string, v float64, alertState string, threshold float64) *formatters.EventMsg {
+	ev := &formatters.EventMsg{
+		Name:      e.Name,
+		Timestamp: e.Timestamp,
+		Tags:      make(map[string]string, len(e.Tags)+len(p.Labels)+3),
+		Values: map[string]interface{}{
+			k:             v,
+			thresholdName: threshold,
+		},
+	}
+	for tk, tv := range e.Tags {
+		ev.Tags[tk] = tv
+	}
+	for tk, tv := range p.Labels {
+		ev.Tags[tk] = tv
+	}
+	ev.Tags[alertNameTag] = p.AlertName
+	if p.AlertName == "" {
+		ev.Tags[alertNameTag] = k
+	}
+	ev.Tags[alertStateTag] = alertState
+	ev.Tags[severityTag] = p.Severity
+	return ev
+}
+
+// expire removes the series that did not receive a sample for the configured TTL.
+// Firing series are kept until they are resolved.
+func (p *alert) expire(now time.Time) {
+	if now.Sub(p.lastGC) < p.TTL {
+		return
+	}
+	p.lastGC = now
+	for k, s := range p.series {
+		if s.state == stateFiring || s.state == stateClearing {
+			continue
+		}
+		if now.Sub(s.lastSeen) > p.TTL {
+			p.logger.Printf("series %q: expired", k)
+			delete(p.series, k)
+		}
+	}
+}
+
+func (p *alert) initializeAction(cfg map[string]interface{}) error {
+	if len(cfg) == 0 {
+		return errors.New("missing action definition")
+	}
+	if actType, ok := cfg["type"]; ok {
+		switch actType := actType.(type) {
+		case string:
+			if in, ok := actions.Actions[actType]; ok {
+				act := in()
+				err := act.Init(cfg, actions.WithLogger(p.logger), actions.WithTargets(p.targets))
+				if err != nil {
+					return err
+				}
+				p.actions = append(p.actions, act)
+				return nil
+			}
+			return fmt.Errorf("unknown action type %q", actType)
+		default:
+			return fmt.Errorf("unexpected action field type %T", actType)
+		}
+	}
+	return errors.New("missing type field under action")
+}
+
+func (p *alert) readVars() error {
+	if p.VarsFile == "" {
+		p.vars = p.Vars
+		return nil
+	}
+	b, err := gfile.ReadFile(context.TODO(), p.VarsFile)
+	if err != nil {
+		return err
+	}
+	v := make(map[string]interface{})
+	err = yaml.Unmarshal(b, &v)
+	if err != nil {
+		return err
+	}
+	p.vars = utils.MergeMaps(v, p.Vars)
+	return nil
+}
+
+func (p *alert) runActions(e *formatters.EventMsg) {
+	actx := &actions.Context{Input: e, Env: make(map[string]interface{}), Vars: p.vars}
+	for _, act := range p.actions {
+		res, err := act.Run(context.TODO(), actx)
+		if err != nil {
+			p.logger.Printf("alert action %q failed: %+v", act.NName(), err)
+			return
+		}
+		actx.Env[act.NName()] = res
+		p.logger.Printf("action %q result: %+v", act.NName(), res)
+	}
+}
+
+func seriesKey(e *formatters.EventMsg, valueName string) string {
+	tagNames := make([]string, 0, len(e.Tags))
+	for k := range e.Tags {
+		tagNames = append(tagNames, k)
+	}
+	sort.Strings(tagNames)
+	sb := new(strings.Builder)
+	sb.WriteString(e.Name)
+	for _, k := range tagNames {
+		sb.WriteString(",")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(e.Tags[k])
+	}
+	sb.WriteString(":")
+	sb.WriteString(valueName)
+	return sb.String()
+}
+
+func toFloat(v interface{}) (float64, error) {
+	var f float64
+	switch v := v.(type) {
+	case int:
+		f = float64(v)
+	case int8:
+		f = float64(v)
+	case int16:
+		f = float64(v)
+	case int32:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case uint:
+		f = float64(v)
+	case uint8:
+		f = float64(v)
+	case uint16:
+		f = float64(v)
+	case uint32:
+		f = float64(v)
+	case uint64:
+		f = float64(v)
+	case float32:
+		f = float64(v)
+	case float64:
+		f = v
+	case string:
+		var err error
+		f, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("unsupported value type %T", v)
+	}
+	if math.IsNaN(f) {
+		return 0, fmt.Errorf("invalid value %v", f)
+	}
+	return f, nil
+}
@@ -0,0 +1,269 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_alert
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/openconfig/gnmic/pkg/actions"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+func power(ts int64, v float64) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "optics",
+		Timestamp: ts,
+		Tags:      map[string]string{"port": "1/1"},
+		Values:    map[string]interface{}{"input-power": v},
+	}
+}
+
+func powerAlert(ts int64, v float64, alertState string, threshold float64) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "optics",
+		Timestamp: ts,
+		Tags: map[string]string{
+			"port":        "1/1",
+			"team":        "optical",
+			"alert-name":  "low-rx-power",
+			"alert-state": alertState,
+			"severity":    "critical",
+		},
+		Values: map[string]interface{}{"input-power": v, "threshold": threshold},
+	}
+}
+
+var testset = map[string]struct {
+	processor map[string]interface{}
+	tests     []item
+}{
+	"below_hysteresis_for": {
+		processor: map[string]interface{}{
+			"type":          processorType,
+			"alert-name":    "low-rx-power",
+			"value-names":   []string{"input-power$"},
+			"direction":     "below",
+			"raise":         -10,
+			"clear":         -8,
+			"for":           "2s",
+			"severity":      "critical",
+			"labels":        map[string]string{"team": "optical"},
+			"drop-original": true,
+		},
+		tests: []item{
+			{
+				input:  []*formatters.EventMsg{power(0, -5)},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				// pending
+				input:  []*formatters.EventMsg{power(1e9, -11)},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input:  []*formatters.EventMsg{power(2e9, -12)},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input:  []*formatters.EventMsg{power(3e9, -12)},
+				output: []*formatters.EventMsg{powerAlert(3e9, -12, "firing", -10)},
+			},
+			{
+				// deduplicated
+				input:  []*formatters.EventMsg{power(4e9, -13)},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				// between raise and clear thresholds
+				input:  []*formatters.EventMsg{power(5e9, -9)},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input:  []*formatters.EventMsg{power(6e9, -7)},
+				output: []*formatters.EventMsg{powerAlert(6e9, -7, "resolved", -8)},
+			},
+			{
+				input:  []*formatters.EventMsg{power(7e9, -7)},
+				output: []*formatters.EventMsg{},
+			},
+		},
+	},
+	"above_pending_reset_repeat": {
+		processor: map[string]interface{}{
+			"type":            processorType,
+			"value-names":     []string{"^cpu$"},
+			"raise":           90,
+			"for":             "10s",
+			"clear-for":       "10s",
+			"repeat-interval": "20s",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 0, Values: map[string]interface{}{"cpu": 95}},
+					{Timestamp: 5e9, Values: map[string]interface{}{"cpu": 50}},
+					{Timestamp: 10e9, Values: map[string]interface{}{"cpu": "99"}},
+					{Timestamp: 20e9, Values: map[string]interface{}{"cpu": 99}},
+				},
+				output: []*formatters.EventMsg{
+					{Timestamp: 0, Values: map[string]interface{}{"cpu": 95}},
+					{Timestamp: 5e9, Values: map[string]interface{}{"cpu": 50}},
+					{Timestamp: 10e9, Values: map[string]interface{}{"cpu": "99"}},
+					{Timestamp: 20e9, Values: map[string]interface{}{"cpu": 99}},
+					{
+						Timestamp: 20e9,
+						Tags: map[string]string{
+							"alert-name":  "cpu",
+							"alert-state": "firing",
+							"severity":    "warning",
+						},
+						Values: map[string]interface{}{"cpu": 99.0, "threshold": 90.0},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{Timestamp: 30e9, Values: map[string]interface{}{"cpu": 10}},
+					{Timestamp: 35e9, Values: map[string]interface{}{"cpu": 95}},
+					{Timestamp: 40e9, Values: map[string]interface{}{"cpu": 95}},
+				},
+				output: []*formatters.EventMsg{
+					{Timestamp: 30e9, Values: map[string]interface{}{"cpu": 10}},
+					{Timestamp: 35e9, Values: map[string]interface{}{"cpu": 95}},
+					{Timestamp: 40e9, Values: map[string]interface{}{"cpu": 95}},
+					{
+						Timestamp: 40e9,
+						Tags: map[string]string{
+							"alert-name":  "cpu",
+							"alert-state": "firing",
+							"severity":    "warning",
+						},
+						Values: map[string]interface{}{"cpu": 95.0, "threshold": 90.0},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventAlert(t *testing.T) {
+	for name, ts := range testset {
+		if typ, ok := ts.processor["type"]; ok {
+			t.Log("found type")
+			if pi, ok := formatters.EventProcessors[typ.(string)]; ok {
+				t.Log("found processor")
+				p := pi()
+				err := p.Init(ts.processor)
+				if err != nil {
+					t.Errorf("failed to initialize processors: %v", err)
+					return
+				}
+				t.Logf("processor: %+v", p)
+				for i, item := range ts.tests {
+					t.Run(name, func(t *testing.T) {
+						t.Logf("running test item %d", i)
+						outs := p.Apply(item.input...)
+						if !reflect.DeepEqual(outs, item.output) {
+							t.Logf("failed at %q item %d", name, i)
+							t.Logf("expected: %#v", item.output)
+							t.Logf("     got: %#v", outs)
+							t.Fail()
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestEventAlertInitErrors(t *testing.T) {
+	cfgs := map[string]map[string]interface{}{
+		"no_value_names":  {"raise": 1},
+		"no_raise":        {"value-names": []string{".*"}},
+		"bad_direction":   {"value-names": []string{".*"}, "raise": 1, "direction": "up"},
+		"clear_above":     {"value-names": []string{".*"}, "raise": 1, "clear": 2},
+		"clear_below":     {"value-names": []string{".*"}, "raise": 1, "clear": 0, "direction": "below"},
+		"unknown_action":  {"value-names": []string{".*"}, "raise": 1, "actions": []string{"a1"}},
+		"bad_value_regex": {"value-names": []string{"("}, "raise": 1},
+	}
+	for name, cfg := range cfgs {
+		t.Run(name, func(t *testing.T) {
+			p := formatters.EventProcessors[processorType]()
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+type recorder struct {
+	m      sync.Mutex
+	inputs []interface{}
+}
+
+func (r *recorder) Init(cfg map[string]interface{}, opts ...actions.Option) error { return nil }
+
+func (r *recorder) Run(ctx context.Context, aCtx *actions.Context) (interface{}, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.inputs = append(r.inputs, aCtx.Input)
+	return nil, nil
+}
+
+func (r *recorder) NName() string { return "recorder" }
+
+func (r *recorder) WithTargets(map[string]*types.TargetConfig) {}
+
+func (r *recorder) WithLogger(*log.Logger) {}
+
+func TestEventAlertActions(t *testing.T) {
+	rec := new(recorder)
+	actions.Register("test-recorder", func() actions.Action { return rec })
+	p := formatters.EventProcessors[processorType]()
+	err := p.Init(
+		map[string]interface{}{
+			"value-names": []string{"cpu"},
+			"raise":       90,
+			"actions":     []string{"rec"},
+		},
+		formatters.WithActions(map[string]map[string]interface{}{
+			"rec": {"type": "test-recorder"},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Apply(
+		&formatters.EventMsg{Timestamp: 1, Values: map[string]interface{}{"cpu": 95}},
+		&formatters.EventMsg{Timestamp: 2, Values: map[string]interface{}{"cpu": 96}},
+		&formatters.EventMsg{Timestamp: 3, Values: map[string]interface{}{"cpu": 10}},
+	)
+	if len(rec.inputs) != 2 {
+		t.Fatalf("expected 2 action runs, got %d", len(rec.inputs))
+	}
+	for i, st := range []string{alertFiring, alertResolved} {
+		ev, ok := rec.inputs[i].(*formatters.EventMsg)
+		if !ok {
+			t.Fatalf("unexpected action input type %T", rec.inputs[i])
+		}
+		if ev.Tags[alertStateTag] != st {
+			t.Errorf("run %d: expected alert state %q, got %q", i, st, ev.Tags[alertStateTag])
+		}
+	}
+}
@@ -0,0 +1,335 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_enrich
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	gfile "github.com/openconfig/gnmic/pkg/file"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	processorType   = "event-enrich"
+	loggingPrefix   = "[" + processorType + "] "
+	defaultInterval = 30 * time.Second
+	keySeparator    = "\x00"
+
+	formatCSV  = "csv"
+	formatJSON = "json"
+	formatYAML = "yaml"
+)
+
+// enrich adds tags to the events from the matching row of a lookup table,
+// the row is selected using the values of one or more event tags.
+type enrich struct {
+	Path       string        `mapstructure:"path,omitempty" json:"path,omitempty"`
+	Format     string        `mapstructure:"format,omitempty" json:"format,omitempty"`
+	Interval   time.Duration `mapstructure:"interval,omitempty" json:"interval,omitempty"`
+	KeyTags    []string      `mapstructure:"key-tags,omitempty" json:"key-tags,omitempty"`
+	KeyColumns []string      `mapstructure:"key-columns,omitempty" json:"key-columns,omitempty"`
+	Columns    []string      `mapstructure:"columns,omitempty" json:"columns,omitempty"`
+	TagPrefix  string        `mapstructure:"tag-prefix,omitempty" json:"tag-prefix,omitempty"`
+	Overwrite  bool          `mapstructure:"overwrite,omitempty" json:"overwrite,omitempty"`
+	Debug      bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	m sync.RWMutex
+	// table maps the key columns values to the tags to add.
+	table     map[string]map[string]string
+	modTime   time.Time
+	lastCheck time.Time
+	loading   bool
+
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &enrich{
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *enrich) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	err = p.setDefaults()
+	if err != nil {
+		return err
+	}
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+		} else {
+			p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+		}
+	}
+	return p.load(context.TODO())
+}
+
+func (p *enrich) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	p.refresh()
+	p.m.RLock()
+	defer p.m.RUnlock()
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		key, ok := p.eventKey(e)
+		if !ok {
+			continue
+		}
+		row, ok := p.table[key]
+		if !ok {
+			p.logger.Printf("no lookup table entry for key %q", strings.ReplaceAll(key, keySeparator, ","))
+			continue
+		}
+		if e.Tags == nil {
+			e.Tags = make(map[string]string, len(row))
+		}
+		for k, v := range row {
+			if _, ok := e.Tags[k]; ok && !p.Overwrite {
+				continue
+			}
+			e.Tags[k] = v
+		}
+	}
+	return es
+}
+
+func (p *enrich) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *enrich) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (p *enrich) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *enrich) WithProcessors(procs map[string]map[string]any) {}
+
+func (p *enrich) setDefaults() error {
+	if p.Path == "" {
+		return errors.New("missing path")
+	}
+	if len(p.KeyTags) == 0 {
+		return errors.New("missing key-tags")
+	}
+	if len(p.KeyColumns) == 0 {
+		p.KeyColumns = p.KeyTags
+	}
+	if len(p.KeyColumns) != len(p.KeyTags) {
+		return fmt.Errorf("key-columns and key-tags must have the same length")
+	}
+	if p.Format == "" {
+		switch strings.ToLower(filepath.Ext(p.Path)) {
+		case ".csv":
+			p.Format = formatCSV
+		case ".json":
+			p.Format = formatJSON
+		default:
+			p.Format = formatYAML
+		}
+	}
+	switch p.Format {
+	case formatCSV, formatJSON, formatYAML:
+	default:
+		return fmt.Errorf("unknown format %q, must be one of %q, %q or %q", p.Format, formatCSV, formatJSON, formatYAML)
+	}
+	if p.Interval <= 0 {
+		p.Interval = defaultInterval
+	}
+	return nil
+}
+
+// eventKey builds the lookup table key from the event tags,
+// it returns false if one of the key tags is missing.
+func (p *enrich) eventKey(e *formatters.EventMsg) (string, bool) {
+	vals := make([]string, 0, len(p.KeyTags))
+	for _, t := range p.KeyTags {
+		v, ok := e.Tags[t]
+		if !ok {
+			return "", false
+		}
+		vals = append(vals, v)
+	}
+	return strings.Join(vals, keySeparator), true
+}
+
+// refresh triggers a background reload of the lookup table
+// if the configured interval elapsed since the last check.
+func (p *enrich) refresh() {
+	now := time.Now()
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.loading || now.Sub(p.lastCheck) < p.Interval {
+		return
+	}
+	p.loading = true
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), p.Interval)
+		defer cancel()
+		err := p.load(ctx)
+		if err != nil {
+			p.logger.Printf("failed to reload lookup table: %v", err)
+		}
+		p.m.Lock()
+		p.loading = false
+		p.m.Unlock()
+	}()
+}
+
+// load reads and parses the lookup table.
+// Local files are only read if their modification time changed.
+func (p *enrich) load(ctx context.Context) error {
+	p.m.Lock()
+	p.lastCheck = time.Now()
+	modTime := p.modTime
+	p.m.Unlock()
+
+	local := !strings.Contains(p.Path, "://")
+	if local {
+		fi, err := os.Stat(p.Path)
+		if err != nil {
+			return err
+		}
+		if fi.ModTime().Equal(modTime) {
+			return nil
+		}
+		modTime = fi.ModTime()
+	}
+	b, err := gfile.ReadFile(ctx, p.Path)
+	if err != nil {
+		return err
+	}
+	rows, err := p.parse(b)
+	if err != nil {
+		return fmt.Errorf("failed to parse %q: %w", p.Path, err)
+	}
+	table, err := p.buildTable(rows)
+	if err != nil {
+		return err
+	}
+	p.logger.Printf("loaded %d entries from %q", len(table), p.Path)
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.table = table
+	p.modTime = modTime
+	return nil
+}
+
+// parse decodes the lookup table bytes into a list of rows.
+func (p *enrich) parse(b []byte) ([]map[string]string, error) {
+	switch p.Format {
+	case formatCSV:
+		records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return nil, nil
+		}
+		header := records[0]
+		rows := make([]map[string]string, 0, len(records)-1)
+		for _, rec := range records[1:] {
+			row := make(map[string]string, len(header))
+			for i, col := range header {
+				row[col] = rec[i]
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	default:
+		// JSON is valid YAML
+		items := make([]map[string]interface{}, 0)
+		err := yaml.Unmarshal(b, &items)
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]map[string]string, 0, len(items))
+		for _, item := range items {
+			row := make(map[string]string, len(item))
+			for k, v := range item {
+				if v == nil {
+					continue
+				}
+				row[k] = fmt.Sprint(v)
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+}
+
+// buildTable indexes the rows by the key columns values,
+// keeping only the columns to add as tags.
+func (p *enrich) buildTable(rows []map[string]string) (map[string]map[string]string, error) {
+	isKey := make(map[string]struct{}, len(p.KeyColumns))
+	for _, c := range p.KeyColumns {
+		isKey[c] = struct{}{}
+	}
+	table := make(map[string]map[string]string, len(rows))
+	for i, row := range rows {
+		vals := make([]string, 0, len(p.KeyColumns))
+		for _, c := range p.KeyColumns {
+			v, ok := row[c]
+			if !ok {
+				return nil, fmt.Errorf("entry %d is missing key column %q", i, c)
+			}
+			vals = append(vals, v)
+		}
+		key := strings.Join(vals, keySeparator)
+		if _, ok := table[key]; ok {
+			p.logger.Printf("duplicate entry for key %q, using the last one", strings.Join(vals, ","))
+		}
+		tags := make(map[string]string)
+		if len(p.Columns) == 0 {
+			for k, v := range row {
+				if _, ok := isKey[k]; ok {
+					continue
+				}
+				tags[p.TagPrefix+k] = v
+			}
+		} else {
+			for _, c := range p.Columns {
+				if v, ok := row[c]; ok {
+					tags[p.TagPrefix+c] = v
+				}
+			}
+		}
+		table[key] = tags
+	}
+	return table, nil
+}
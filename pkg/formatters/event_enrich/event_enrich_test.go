@@ -0,0 +1,200 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_enrich
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const csvTable = `source,interface_name,site,tenant,circuit-id
+r1,ethernet-1/1,paris,acme,C-001
+r1,ethernet-1/2,paris,globex,C-002
+`
+
+const jsonTable = `[
+  {"device": "r1", "site": "paris", "rack": 12},
+  {"device": "r2", "site": "london", "rack": 3}
+]`
+
+const yamlTable = `
+- device: r1
+  site: paris
+  tenant: acme
+`
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newProcessor(t *testing.T, cfg map[string]interface{}) formatters.EventProcessor {
+	t.Helper()
+	p := formatters.EventProcessors[processorType]()
+	if err := p.Init(cfg); err != nil {
+		t.Fatalf("failed to initialize processor: %v", err)
+	}
+	return p
+}
+
+func TestEventEnrichCSV(t *testing.T) {
+	p := newProcessor(t, map[string]interface{}{
+		"path":     writeFile(t, "inventory.csv", csvTable),
+		"key-tags": []string{"source", "interface_name"},
+		"columns":  []string{"site", "circuit-id"},
+	})
+	in := []*formatters.EventMsg{
+		{
+			Name:   "sub1",
+			Tags:   map[string]string{"source": "r1", "interface_name": "ethernet-1/1", "site": "original"},
+			Values: map[string]interface{}{"in-octets": 1},
+		},
+		{
+			Name:   "sub1",
+			Tags:   map[string]string{"source": "r1", "interface_name": "ethernet-1/3"},
+			Values: map[string]interface{}{"in-octets": 1},
+		},
+		{
+			Name:   "sub1",
+			Tags:   map[string]string{"source": "r1"},
+			Values: map[string]interface{}{"in-octets": 1},
+		},
+		nil,
+	}
+	want := []*formatters.EventMsg{
+		{
+			Name: "sub1",
+			Tags: map[string]string{
+				"source":         "r1",
+				"interface_name": "ethernet-1/1",
+				"site":           "original",
+				"circuit-id":     "C-001",
+			},
+			Values: map[string]interface{}{"in-octets": 1},
+		},
+		{
+			Name:   "sub1",
+			Tags:   map[string]string{"source": "r1", "interface_name": "ethernet-1/3"},
+			Values: map[string]interface{}{"in-octets": 1},
+		},
+		{
+			Name:   "sub1",
+			Tags:   map[string]string{"source": "r1"},
+			Values: map[string]interface{}{"in-octets": 1},
+		},
+		nil,
+	}
+	got := p.Apply(in...)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected: %#v", want)
+		t.Errorf("     got: %#v", got)
+	}
+}
+
+func TestEventEnrichJSONOverwritePrefix(t *testing.T) {
+	p := newProcessor(t, map[string]interface{}{
+		"path":        writeFile(t, "inventory.json", jsonTable),
+		"key-tags":    []string{"source"},
+		"key-columns": []string{"device"},
+		"tag-prefix":  "inv_",
+		"overwrite":   true,
+	})
+	got := p.Apply(&formatters.EventMsg{
+		Tags: map[string]string{"source": "r2", "inv_site": "unknown"},
+	})
+	want := []*formatters.EventMsg{
+		{
+			Tags: map[string]string{"source": "r2", "inv_site": "london", "inv_rack": "3"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected: %#v", want)
+		t.Errorf("     got: %#v", got)
+	}
+}
+
+func TestEventEnrichReload(t *testing.T) {
+	path := writeFile(t, "inventory.yaml", yamlTable)
+	p := newProcessor(t, map[string]interface{}{
+		"path":        path,
+		"key-tags":    []string{"source"},
+		"key-columns": []string{"device"},
+		"interval":    "1ms",
+	})
+	err := os.WriteFile(path, []byte(yamlTable+"- device: r2\n  site: london\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// make sure the modification time changes
+	later := time.Now().Add(time.Second)
+	if err = os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	want := map[string]string{"source": "r2", "site": "london"}
+	for i := 0; i < 100; i++ {
+		got := p.Apply(&formatters.EventMsg{Tags: map[string]string{"source": "r2"}})
+		if reflect.DeepEqual(got[0].Tags, want) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("lookup table not reloaded")
+}
+
+func TestEventEnrichHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(jsonTable))
+	}))
+	defer srv.Close()
+	p := newProcessor(t, map[string]interface{}{
+		"path":        srv.URL + "/inventory",
+		"format":      "json",
+		"key-tags":    []string{"source"},
+		"key-columns": []string{"device"},
+		"columns":     []string{"site"},
+	})
+	got := p.Apply(&formatters.EventMsg{Tags: map[string]string{"source": "r1"}})
+	want := map[string]string{"source": "r1", "site": "paris"}
+	if !reflect.DeepEqual(got[0].Tags, want) {
+		t.Errorf("expected: %v", want)
+		t.Errorf("     got: %v", got[0].Tags)
+	}
+}
+
+func TestEventEnrichInitErrors(t *testing.T) {
+	path := writeFile(t, "inventory.csv", csvTable)
+	cfgs := map[string]map[string]interface{}{
+		"no_path":         {"key-tags": []string{"source"}},
+		"no_key_tags":     {"path": path},
+		"columns_len":     {"path": path, "key-tags": []string{"source"}, "key-columns": []string{"a", "b"}},
+		"bad_format":      {"path": path, "key-tags": []string{"source"}, "format": "xml"},
+		"missing_file":    {"path": path + ".missing", "key-tags": []string{"source"}},
+		"missing_key_col": {"path": path, "key-tags": []string{"device"}},
+	}
+	for name, cfg := range cfgs {
+		t.Run(name, func(t *testing.T) {
+			p := formatters.EventProcessors[processorType]()
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_join
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	processorType = "event-join"
+	loggingPrefix = "[" + processorType + "] "
+	defaultTTL    = 10 * time.Minute
+	keySeparator  = "\x00"
+)
+
+// join caches the latest values of the dimension events, keyed by tags values,
+// and merges them into the fact events with the same tags values.
+type join struct {
+	Dimension     *selector     `mapstructure:"dimension,omitempty" json:"dimension,omitempty"`
+	Fact          *selector     `mapstructure:"fact,omitempty" json:"fact,omitempty"`
+	KeyTags       []string      `mapstructure:"key-tags,omitempty" json:"key-tags,omitempty"`
+	AsTags        bool          `mapstructure:"as-tags,omitempty" json:"as-tags,omitempty"`
+	Overwrite     bool          `mapstructure:"overwrite,omitempty" json:"overwrite,omitempty"`
+	DropDimension bool          `mapstructure:"drop-dimension,omitempty" json:"drop-dimension,omitempty"`
+	TTL           time.Duration `mapstructure:"ttl,omitempty" json:"ttl,omitempty"`
+	Debug         bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	m      sync.Mutex
+	cache  map[string]*entry
+	lastGC time.Time
+	logger *log.Logger
+}
+
+// selector selects events by name and, for dimension events, the values to cache.
+type selector struct {
+	Names  []string `mapstructure:"names,omitempty" json:"names,omitempty"`
+	Values []string `mapstructure:"values,omitempty" json:"values,omitempty"`
+
+	names  []*regexp.Regexp
+	values []*regexp.Regexp
+}
+
+type entry struct {
+	values  map[string]interface{}
+	updated time.Time
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &join{
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *join) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.Dimension == nil || len(p.Dimension.Names) == 0 {
+		return errors.New("missing dimension names")
+	}
+	if p.Fact == nil {
+		p.Fact = new(selector)
+	}
+	if len(p.KeyTags) == 0 {
+		return errors.New("missing key-tags")
+	}
+	err = p.Dimension.init()
+	if err != nil {
+		return fmt.Errorf("dimension: %w", err)
+	}
+	err = p.Fact.init()
+	if err != nil {
+		return fmt.Errorf("fact: %w", err)
+	}
+	if p.TTL <= 0 {
+		p.TTL = defaultTTL
+	}
+	p.cache = make(map[string]*entry)
+	p.lastGC = time.Now()
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *join) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	now := time.Now()
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.expire(now)
+
+	// update the cache first so that facts can be joined
+	// with dimensions received in the same batch.
+	isDimension := make([]bool, len(es))
+	for i, e := range es {
+		if e == nil || !matchAny(p.Dimension.names, e.Name) {
+			continue
+		}
+		isDimension[i] = true
+		p.updateCache(e, now)
+	}
+	res := make([]*formatters.EventMsg, 0, len(es))
+	for i, e := range es {
+		if e == nil {
+			continue
+		}
+		if isDimension[i] {
+			if !p.DropDimension {
+				res = append(res, e)
+			}
+			continue
+		}
+		if len(p.Fact.names) == 0 || matchAny(p.Fact.names, e.Name) {
+			p.merge(e, now)
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+func (p *join) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *join) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (p *join) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *join) WithProcessors(procs map[string]map[string]any) {}
+
+func (s *selector) init() error {
+	var err error
+	s.names, err = compileRegexes(s.Names)
+	if err != nil {
+		return err
+	}
+	s.values, err = compileRegexes(s.Values)
+	return err
+}
+
+// eventKey builds the cache key from the event tags,
+// it returns false if one of the key tags is missing.
+func (p *join) eventKey(e *formatters.EventMsg) (string, bool) {
+	vals := make([]string, 0, len(p.KeyTags))
+	for _, t := range p.KeyTags {
+		v, ok := e.Tags[t]
+		if !ok {
+			return "", false
+		}
+		vals = append(vals, v)
+	}
+	return strings.Join(vals, keySeparator), true
+}
+
+// updateCache stores the dimension event values,
+// merged with the ones previously received for the same key.
+// A dimension event with deletes removes the cached entry.
+func (p *join) updateCache(e *formatters.EventMsg, now time.Time) {
+	key, ok := p.eventKey(e)
+	if !ok {
+		return
+	}
+	if len(e.Deletes) > 0 {
+		p.logger.Printf("dimension %q deleted", strings.ReplaceAll(key, keySeparator, ","))
+		delete(p.cache, key)
+		return
+	}
+	en, ok := p.cache[key]
+	if !ok {
+		en = &entry{values: make(map[string]interface{})}
+		p.cache[key] = en
+	}
+	en.updated = now
+	for k, v := range e.Values {
+		if len(p.Dimension.values) > 0 && !matchAny(p.Dimension.values, k) {
+			continue
+		}
+		en.values[k] = v
+	}
+}
+
+// merge adds the cached dimension values to the fact event.
+func (p *join) merge(e *formatters.EventMsg, now time.Time) {
+	key, ok := p.eventKey(e)
+	if !ok {
+		return
+	}
+	en, ok := p.cache[key]
+	if !ok || now.Sub(en.updated) > p.TTL {
+		p.logger.Printf("no dimension for key %q", strings.ReplaceAll(key, keySeparator, ","))
+		return
+	}
+	if p.AsTags {
+		if e.Tags == nil {
+			e.Tags = make(map[string]string, len(en.values))
+		}
+		for k, v := range en.values {
+			if _, ok := e.Tags[k]; ok && !p.Overwrite {
+				continue
+			}
+			e.Tags[k] = fmt.Sprint(v)
+		}
+		return
+	}
+	if e.Values == nil {
+		e.Values = make(map[string]interface{}, len(en.values))
+	}
+	for k, v := range en.values {
+		if _, ok := e.Values[k]; ok && !p.Overwrite {
+			continue
+		}
+		e.Values[k] = v
+	}
+}
+
+func (p *join) expire(now time.Time) {
+	if now.Sub(p.lastGC) < p.TTL {
+		return
+	}
+	p.lastGC = now
+	for k, en := range p.cache {
+		if now.Sub(en.updated) > p.TTL {
+			delete(p.cache, k)
+		}
+	}
+}
+
+func compileRegexes(exprs []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_join
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processor map[string]interface{}
+	tests     []item
+}{
+	"values": {
+		processor: map[string]interface{}{
+			"type": processorType,
+			"dimension": map[string]interface{}{
+				"names":  []string{"^intf-config$"},
+				"values": []string{"description$", "speed$"},
+			},
+			"fact": map[string]interface{}{
+				"names": []string{"^intf-counters$"},
+			},
+			"key-tags": []string{"source", "interface_name"},
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: []*formatters.EventMsg{},
+			},
+			{
+				// fact without dimension
+				input: []*formatters.EventMsg{
+					{
+						Name:   "intf-counters",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e1"},
+						Values: map[string]interface{}{"in-octets": 1},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:   "intf-counters",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e1"},
+						Values: map[string]interface{}{"in-octets": 1},
+					},
+				},
+			},
+			{
+				// dimension received after the fact in the same batch
+				input: []*formatters.EventMsg{
+					{
+						Name:   "intf-counters",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e1"},
+						Values: map[string]interface{}{"in-octets": 2},
+					},
+					{
+						Name:   "intf-config",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e1"},
+						Values: map[string]interface{}{"description": "uplink", "mtu": 9000},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:   "intf-counters",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e1"},
+						Values: map[string]interface{}{"in-octets": 2, "description": "uplink"},
+					},
+					{
+						Name:   "intf-config",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e1"},
+						Values: map[string]interface{}{"description": "uplink", "mtu": 9000},
+					},
+				},
+			},
+			{
+				// dimension values merged across events
+				input: []*formatters.EventMsg{
+					{
+						Name:   "intf-config",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e1"},
+						Values: map[string]interface{}{"speed": "100G"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:   "intf-config",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e1"},
+						Values: map[string]interface{}{"speed": "100G"},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:   "intf-counters",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e1"},
+						Values: map[string]interface{}{"in-octets": 3},
+					},
+					{
+						// other key
+						Name:   "intf-counters",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e2"},
+						Values: map[string]interface{}{"in-octets": 3},
+					},
+					{
+						// not a fact
+						Name:   "other",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e1"},
+						Values: map[string]interface{}{"in-octets": 3},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:   "intf-counters",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e1"},
+						Values: map[string]interface{}{"in-octets": 3, "description": "uplink", "speed": "100G"},
+					},
+					{
+						Name:   "intf-counters",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e2"},
+						Values: map[string]interface{}{"in-octets": 3},
+					},
+					{
+						Name:   "other",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e1"},
+						Values: map[string]interface{}{"in-octets": 3},
+					},
+				},
+			},
+			{
+				// dimension deleted
+				input: []*formatters.EventMsg{
+					{
+						Name:    "intf-config",
+						Tags:    map[string]string{"source": "r1", "interface_name": "e1"},
+						Deletes: []string{"/interface"},
+					},
+					{
+						Name:   "intf-counters",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e1"},
+						Values: map[string]interface{}{"in-octets": 4},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:    "intf-config",
+						Tags:    map[string]string{"source": "r1", "interface_name": "e1"},
+						Deletes: []string{"/interface"},
+					},
+					{
+						Name:   "intf-counters",
+						Tags:   map[string]string{"source": "r1", "interface_name": "e1"},
+						Values: map[string]interface{}{"in-octets": 4},
+					},
+				},
+			},
+		},
+	},
+	"tags_overwrite_drop_dimension": {
+		processor: map[string]interface{}{
+			"type": processorType,
+			"dimension": map[string]interface{}{
+				"names": []string{"^intf-config$"},
+			},
+			"key-tags":       []string{"source"},
+			"as-tags":        true,
+			"overwrite":      true,
+			"drop-dimension": true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:   "intf-config",
+						Tags:   map[string]string{"source": "r1"},
+						Values: map[string]interface{}{"speed": 100, "role": "spine"},
+					},
+					{
+						Name:   "intf-counters",
+						Tags:   map[string]string{"source": "r1", "role": "unknown"},
+						Values: map[string]interface{}{"in-octets": 1},
+					},
+					{
+						// missing key tag
+						Name:   "intf-counters",
+						Values: map[string]interface{}{"in-octets": 1},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:   "intf-counters",
+						Tags:   map[string]string{"source": "r1", "role": "spine", "speed": "100"},
+						Values: map[string]interface{}{"in-octets": 1},
+					},
+					{
+						Name:   "intf-counters",
+						Values: map[string]interface{}{"in-octets": 1},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventJoin(t *testing.T) {
+	for name, ts := range testset {
+		if typ, ok := ts.processor["type"]; ok {
+			t.Log("found type")
+			if pi, ok := formatters.EventProcessors[typ.(string)]; ok {
+				t.Log("found processor")
+				p := pi()
+				err := p.Init(ts.processor)
+				if err != nil {
+					t.Errorf("failed to initialize processors: %v", err)
+					return
+				}
+				t.Logf("processor: %+v", p)
+				for i, item := range ts.tests {
+					t.Run(name, func(t *testing.T) {
+						t.Logf("running test item %d", i)
+						outs := p.Apply(item.input...)
+						if !reflect.DeepEqual(outs, item.output) {
+							t.Logf("failed at %q item %d", name, i)
+							t.Logf("expected: %#v", item.output)
+							t.Logf("     got: %#v", outs)
+							t.Fail()
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestEventJoinTTL(t *testing.T) {
+	p := formatters.EventProcessors[processorType]().(*join)
+	err := p.Init(map[string]interface{}{
+		"dimension": map[string]interface{}{"names": []string{"dim"}},
+		"key-tags":  []string{"source"},
+		"ttl":       "1m",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Apply(&formatters.EventMsg{
+		Name:   "dim",
+		Tags:   map[string]string{"source": "r1"},
+		Values: map[string]interface{}{"speed": 100},
+	})
+	p.cache["r1"].updated = time.Now().Add(-2 * time.Minute)
+	outs := p.Apply(&formatters.EventMsg{
+		Name:   "fact",
+		Tags:   map[string]string{"source": "r1"},
+		Values: map[string]interface{}{"in-octets": 1},
+	})
+	if _, ok := outs[0].Values["speed"]; ok {
+		t.Errorf("expired dimension merged into fact: %v", outs[0])
+	}
+	p.lastGC = time.Now().Add(-2 * time.Minute)
+	p.expire(time.Now())
+	if len(p.cache) != 0 {
+		t.Errorf("expired dimension not removed")
+	}
+}
+
+func TestEventJoinInitErrors(t *testing.T) {
+	cfgs := map[string]map[string]interface{}{
+		"no_dimension": {"key-tags": []string{"source"}},
+		"no_key_tags":  {"dimension": map[string]interface{}{"names": []string{"dim"}}},
+		"bad_regex": {
+			"dimension": map[string]interface{}{"names": []string{"("}},
+			"key-tags":  []string{"source"},
+		},
+	}
+	for name, cfg := range cfgs {
+		t.Run(name, func(t *testing.T) {
+			p := formatters.EventProcessors[processorType]()
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,381 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_rate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	processorType = "event-rate"
+	loggingPrefix = "[" + processorType + "] "
+	defaultTTL    = 10 * time.Minute
+	modeRate      = "rate"
+	modeDelta     = "delta"
+)
+
+// rate replaces the values with a name matching one of the regexes
+// with their per-second rate or delta since the previous sample of the same series.
+type rate struct {
+	ValueNames   []string      `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Mode         string        `mapstructure:"mode,omitempty" json:"mode,omitempty"`
+	CounterSize  int           `mapstructure:"counter-size,omitempty" json:"counter-size,omitempty"`
+	Suffix       string        `mapstructure:"suffix,omitempty" json:"suffix,omitempty"`
+	KeepOriginal bool          `mapstructure:"keep-original,omitempty" json:"keep-original,omitempty"`
+	TTL          time.Duration `mapstructure:"ttl,omitempty" json:"ttl,omitempty"`
+	Debug        bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	valueNames []*regexp.Regexp
+
+	m sync.Mutex
+	// series holds the last sample of each series,
+	// keyed by event name, tags and value name.
+	series map[string]*sample
+	lastGC time.Time
+	logger *log.Logger
+}
+
+// sample is a counter value received at timestamp ts.
+// Unsigned integer counters are kept in u to avoid losing precision,
+// other numbers are kept in f.
+type sample struct {
+	ts       int64
+	u        uint64
+	f        float64
+	isFloat  bool
+	lastSeen time.Time
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &rate{
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (r *rate) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, r)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if len(r.ValueNames) == 0 {
+		return fmt.Errorf("missing value-names")
+	}
+	r.valueNames = make([]*regexp.Regexp, 0, len(r.ValueNames))
+	for _, reg := range r.ValueNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		r.valueNames = append(r.valueNames, re)
+	}
+	switch r.Mode {
+	case "":
+		r.Mode = modeRate
+	case modeRate, modeDelta:
+	default:
+		return fmt.Errorf("unknown mode %q, must be one of %q or %q", r.Mode, modeRate, modeDelta)
+	}
+	switch r.CounterSize {
+	case 0:
+		r.CounterSize = 64
+	case 32, 64:
+	default:
+		return fmt.Errorf("unsupported counter-size %d, must be 32 or 64", r.CounterSize)
+	}
+	if r.KeepOriginal && r.Suffix == "" {
+		return fmt.Errorf("keep-original requires a suffix")
+	}
+	if r.TTL <= 0 {
+		r.TTL = defaultTTL
+	}
+	r.series = make(map[string]*sample)
+	r.lastGC = time.Now()
+	if r.logger.Writer() != io.Discard {
+		b, err := json.Marshal(r)
+		if err != nil {
+			r.logger.Printf("initialized processor '%s': %+v", processorType, r)
+			return nil
+		}
+		r.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (r *rate) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	now := time.Now()
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.expire(now)
+
+	res := make([]*formatters.EventMsg, 0, len(es))
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		names := r.matchingValues(e)
+		if len(names) == 0 {
+			res = append(res, e)
+			continue
+		}
+		for _, k := range names {
+			v, ok, err := r.compute(seriesKey(e, k), e.Timestamp, e.Values[k], now)
+			if err != nil {
+				// not a counter, leave the value untouched
+				r.logger.Printf("value %q: %v", k, err)
+				continue
+			}
+			if !r.KeepOriginal {
+				delete(e.Values, k)
+			}
+			if ok {
+				e.Values[k+r.Suffix] = v
+			}
+		}
+		// all the event values were counters seen for the first time,
+		// there is nothing left to forward.
+		if len(e.Values) == 0 && len(e.Deletes) == 0 {
+			r.logger.Printf("dropping event %q with no values left", e.Name)
+			continue
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+func (r *rate) WithLogger(l *log.Logger) {
+	if r.Debug && l != nil {
+		r.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if r.Debug {
+		r.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (r *rate) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (r *rate) WithActions(act map[string]map[string]interface{}) {}
+
+func (r *rate) WithProcessors(procs map[string]map[string]any) {}
+
+// matchingValues returns the sorted names of the event values
+// matching one of the configured regexes.
+func (r *rate) matchingValues(e *formatters.EventMsg) []string {
+	names := make([]string, 0, len(e.Values))
+	for k := range e.Values {
+		for _, re := range r.valueNames {
+			if re.MatchString(k) {
+				names = append(names, k)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// compute stores the new sample of the series and returns its rate or delta.
+// ok is false when no value can be calculated: first sample of the series,
+// duplicate or out of order sample, stale series or counter reset.
+func (r *rate) compute(key string, ts int64, v interface{}, now time.Time) (interface{}, bool, error) {
+	cur, err := newSample(v)
+	if err != nil {
+		return nil, false, err
+	}
+	cur.ts = ts
+	cur.lastSeen = now
+	prev, ok := r.series[key]
+	if ok && ts == prev.ts {
+		r.logger.Printf("series %q: duplicate sample at %d", key, ts)
+		prev.lastSeen = now
+		return nil, false, nil
+	}
+	r.series[key] = cur
+	switch {
+	case !ok:
+		r.logger.Printf("series %q: first sample", key)
+		return nil, false, nil
+	case ts < prev.ts:
+		// the target restarted or its clock moved backwards
+		r.logger.Printf("series %q: timestamp went backwards from %d to %d, resetting", key, prev.ts, ts)
+		return nil, false, nil
+	case time.Duration(ts-prev.ts) > r.TTL:
+		r.logger.Printf("series %q: previous sample older than %s, resetting", key, r.TTL)
+		return nil, false, nil
+	}
+	d, ok := r.delta(prev, cur)
+	if !ok {
+		r.logger.Printf("series %q: counter reset from %v to %v", key, prev.value(), cur.value())
+		return nil, false, nil
+	}
+	if r.Mode == modeDelta {
+		return d, true, nil
+	}
+	var df float64
+	switch d := d.(type) {
+	case uint64:
+		df = float64(d)
+	case float64:
+		df = d
+	}
+	return df / (float64(ts-prev.ts) / float64(time.Second)), true, nil
+}
+
+// delta returns the increase of the counter between two samples,
+// accounting for a counter wrap.
+// It returns false if the counter was reset.
+func (r *rate) delta(prev, cur *sample) (interface{}, bool) {
+	if prev.isFloat || cur.isFloat {
+		pf, cf := prev.value64(), cur.value64()
+		if cf < pf {
+			return nil, false
+		}
+		return cf - pf, true
+	}
+	if cur.u >= prev.u {
+		return cur.u - prev.u, true
+	}
+	var max uint64 = math.MaxUint64
+	if r.CounterSize == 32 {
+		if prev.u > math.MaxUint32 || cur.u > math.MaxUint32 {
+			return nil, false
+		}
+		max = math.MaxUint32
+	}
+	// a wrap is only plausible if the counter moved by less than half its range,
+	// otherwise the counter was reset, e.g. after a target restart.
+	d := max - prev.u + cur.u + 1
+	if d > max/2 {
+		return nil, false
+	}
+	return d, true
+}
+
+func (r *rate) expire(now time.Time) {
+	if now.Sub(r.lastGC) < r.TTL {
+		return
+	}
+	r.lastGC = now
+	for k, s := range r.series {
+		if now.Sub(s.lastSeen) > r.TTL {
+			r.logger.Printf("series %q: expired", k)
+			delete(r.series, k)
+		}
+	}
+}
+
+func newSample(v interface{}) (*sample, error) {
+	switch v := v.(type) {
+	case uint:
+		return &sample{u: uint64(v)}, nil
+	case uint8:
+		return &sample{u: uint64(v)}, nil
+	case uint16:
+		return &sample{u: uint64(v)}, nil
+	case uint32:
+		return &sample{u: uint64(v)}, nil
+	case uint64:
+		return &sample{u: v}, nil
+	case int:
+		return intSample(int64(v)), nil
+	case int8:
+		return intSample(int64(v)), nil
+	case int16:
+		return intSample(int64(v)), nil
+	case int32:
+		return intSample(int64(v)), nil
+	case int64:
+		return intSample(v), nil
+	case float32:
+		return floatSample(float64(v))
+	case float64:
+		return floatSample(v)
+	case string:
+		if u, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return &sample{u: u}, nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %q as a number", v)
+		}
+		return floatSample(f)
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+func intSample(i int64) *sample {
+	if i < 0 {
+		return &sample{f: float64(i), isFloat: true}
+	}
+	return &sample{u: uint64(i)}
+}
+
+func floatSample(f float64) (*sample, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("invalid value %v", f)
+	}
+	// keep integral floats, e.g. from a JSON decoder, as unsigned integers
+	// to allow wrap detection.
+	if f >= 0 && f < math.MaxUint64 && f == math.Trunc(f) {
+		return &sample{u: uint64(f)}, nil
+	}
+	return &sample{f: f, isFloat: true}, nil
+}
+
+func (s *sample) value() interface{} {
+	if s.isFloat {
+		return s.f
+	}
+	return s.u
+}
+
+func (s *sample) value64() float64 {
+	if s.isFloat {
+		return s.f
+	}
+	return float64(s.u)
+}
+
+func seriesKey(e *formatters.EventMsg, valueName string) string {
+	tagNames := make([]string, 0, len(e.Tags))
+	for k := range e.Tags {
+		tagNames = append(tagNames, k)
+	}
+	sort.Strings(tagNames)
+	sb := new(strings.Builder)
+	sb.WriteString(e.Name)
+	for _, k := range tagNames {
+		sb.WriteString(",")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(e.Tags[k])
+	}
+	sb.WriteString(":")
+	sb.WriteString(valueName)
+	return sb.String()
+}
@@ -0,0 +1,400 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_rate
+
+import (
+	"io"
+	"log"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+var testset = map[string]struct {
+	processor map[string]interface{}
+	tests     []item
+}{
+	"rate": {
+		processor: map[string]interface{}{
+			"type":        processorType,
+			"value-names": []string{"octets$"},
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 0,
+						Tags:      map[string]string{"interface_name": "ethernet-1/1"},
+						Values:    map[string]interface{}{"in-octets": uint64(1000), "oper-state": "up"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 0,
+						Tags:      map[string]string{"interface_name": "ethernet-1/1"},
+						Values:    map[string]interface{}{"oper-state": "up"},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 2e9,
+						Tags:      map[string]string{"interface_name": "ethernet-1/1"},
+						Values:    map[string]interface{}{"in-octets": "3000"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 2e9,
+						Tags:      map[string]string{"interface_name": "ethernet-1/1"},
+						Values:    map[string]interface{}{"in-octets": 1000.0},
+					},
+				},
+			},
+			{
+				// different series
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 3e9,
+						Tags:      map[string]string{"interface_name": "ethernet-1/2"},
+						Values:    map[string]interface{}{"in-octets": uint64(10)},
+					},
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				// counter reset
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 4e9,
+						Tags:      map[string]string{"interface_name": "ethernet-1/1"},
+						Values:    map[string]interface{}{"in-octets": uint64(100)},
+					},
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 5e9,
+						Tags:      map[string]string{"interface_name": "ethernet-1/1"},
+						Values:    map[string]interface{}{"in-octets": uint64(600)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 5e9,
+						Tags:      map[string]string{"interface_name": "ethernet-1/1"},
+						Values:    map[string]interface{}{"in-octets": 500.0},
+					},
+				},
+			},
+			{
+				// timestamp going backwards
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 1e9,
+						Tags:      map[string]string{"interface_name": "ethernet-1/1"},
+						Values:    map[string]interface{}{"in-octets": uint64(700)},
+					},
+				},
+				output: []*formatters.EventMsg{},
+			},
+		},
+	},
+	"delta_suffix_keep_original": {
+		processor: map[string]interface{}{
+			"type":          processorType,
+			"value-names":   []string{"packets$"},
+			"mode":          "delta",
+			"suffix":        "_delta",
+			"keep-original": true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 1e9,
+						Values:    map[string]interface{}{"in-packets": 10.0},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 1e9,
+						Values:    map[string]interface{}{"in-packets": 10.0},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 2e9,
+						Values:    map[string]interface{}{"in-packets": 25.0},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 2e9,
+						Values:    map[string]interface{}{"in-packets": 25.0, "in-packets_delta": uint64(15)},
+					},
+				},
+			},
+			{
+				// duplicate sample
+				input: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 2e9,
+						Values:    map[string]interface{}{"in-packets": 25.0},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 2e9,
+						Values:    map[string]interface{}{"in-packets": 25.0},
+					},
+				},
+			},
+		},
+	},
+	"wrap_32": {
+		processor: map[string]interface{}{
+			"type":         processorType,
+			"value-names":  []string{".*"},
+			"mode":         "delta",
+			"counter-size": 32,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 1e9,
+						Values:    map[string]interface{}{"c": uint32(math.MaxUint32 - 9)},
+					},
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 2e9,
+						Values:    map[string]interface{}{"c": uint32(10)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Timestamp: 2e9,
+						Values:    map[string]interface{}{"c": uint64(20)},
+					},
+				},
+			},
+		},
+	},
+	"wrap_64": {
+		processor: map[string]interface{}{
+			"type":         processorType,
+			"value-names":  []string{".*"},
+			"mode":         "delta",
+			"counter-size": 64,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 1e9,
+						Values:    map[string]interface{}{"c": uint64(math.MaxUint64 - 4)},
+					},
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 2e9,
+						Values:    map[string]interface{}{"c": uint64(5)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Timestamp: 2e9,
+						Values:    map[string]interface{}{"c": uint64(10)},
+					},
+				},
+			},
+			{
+				// a 32 bit sized drop on a 64 bit counter is a reset
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 3e9,
+						Values:    map[string]interface{}{"c": uint64(1)},
+					},
+				},
+				output: []*formatters.EventMsg{},
+			},
+		},
+	},
+	"wrap_default_size": {
+		processor: map[string]interface{}{
+			"type":        processorType,
+			"value-names": []string{".*"},
+			"mode":        "delta",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 1e9,
+						Values:    map[string]interface{}{"c": uint32(math.MaxUint32 - 9)},
+					},
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				// counters are 64 bits wide by default, a 32 bit wrap is a reset
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 2e9,
+						Values:    map[string]interface{}{"c": uint32(10)},
+					},
+				},
+				output: []*formatters.EventMsg{},
+			},
+		},
+	},
+	"ttl": {
+		processor: map[string]interface{}{
+			"type":        processorType,
+			"value-names": []string{".*"},
+			"ttl":         "5s",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 1e9,
+						Values:    map[string]interface{}{"c": uint64(1)},
+					},
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 10e9,
+						Values:    map[string]interface{}{"c": uint64(2)},
+					},
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Timestamp: 11e9,
+						Values:    map[string]interface{}{"c": uint64(4)},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Timestamp: 11e9,
+						Values:    map[string]interface{}{"c": 2.0},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventRate(t *testing.T) {
+	for name, ts := range testset {
+		if typ, ok := ts.processor["type"]; ok {
+			t.Log("found type")
+			if pi, ok := formatters.EventProcessors[typ.(string)]; ok {
+				t.Log("found processor")
+				p := pi()
+				err := p.Init(ts.processor)
+				if err != nil {
+					t.Errorf("failed to initialize processors: %v", err)
+					return
+				}
+				t.Logf("processor: %+v", p)
+				for i, item := range ts.tests {
+					t.Run(name, func(t *testing.T) {
+						t.Logf("running test item %d", i)
+						outs := p.Apply(item.input...)
+						if !reflect.DeepEqual(outs, item.output) {
+							t.Logf("failed at %q item %d", name, i)
+							t.Logf("expected: %#v", item.output)
+							t.Logf("     got: %#v", outs)
+							t.Fail()
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestEventRateInitErrors(t *testing.T) {
+	cfgs := map[string]map[string]interface{}{
+		"no_value_names": {},
+		"bad_mode":       {"value-names": []string{".*"}, "mode": "avg"},
+		"bad_size":       {"value-names": []string{".*"}, "counter-size": 16},
+		"no_suffix":      {"value-names": []string{".*"}, "keep-original": true},
+	}
+	for name, cfg := range cfgs {
+		t.Run(name, func(t *testing.T) {
+			p := formatters.EventProcessors[processorType]()
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestEventRateExpire(t *testing.T) {
+	r := &rate{TTL: time.Minute, series: map[string]*sample{}, logger: log.New(io.Discard, "", 0)}
+	now := time.Now()
+	r.series["old"] = &sample{lastSeen: now.Add(-2 * time.Minute)}
+	r.series["new"] = &sample{lastSeen: now}
+	r.lastGC = now.Add(-2 * time.Minute)
+	r.expire(now)
+	if _, ok := r.series["old"]; ok {
+		t.Errorf("stale series not expired")
+	}
+	if _, ok := r.series["new"]; !ok {
+		t.Errorf("active series expired")
+	}
+}
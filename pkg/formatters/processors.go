@@ -42,6 +42,11 @@ var EventProcessorTypes = []string{
 	"event-value-tag",
 	"event-starlark",
 	"event-combine",
+	"event-rate",
+	"event-aggregate",
+	"event-alert",
+	"event-enrich",
+	"event-join",
 }
 
 type Initializer func() EventProcessor
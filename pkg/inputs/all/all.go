@@ -10,6 +10,7 @@ package all
 
 import (
 	_ "github.com/openconfig/gnmic/pkg/inputs/kafka_input"
+	_ "github.com/openconfig/gnmic/pkg/inputs/mqtt_input"
 	_ "github.com/openconfig/gnmic/pkg/inputs/nats_input"
 	_ "github.com/openconfig/gnmic/pkg/inputs/stan_input"
 )
@@ -0,0 +1,378 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mqtt_input
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/inputs"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	loggingPrefix       = "[mqtt_input] "
+	defaultAddress      = "tcp://localhost:1883"
+	defaultTopic        = "gnmic/#"
+	mqttConnectWait     = 2 * time.Second
+	defaultFormat       = "event"
+	defaultNumWorkers   = 1
+	defaultBufferSize   = 100
+	defaultKeepAlive    = 30 * time.Second
+	sharedTopicPrefix   = "$share/"
+	disconnectQuiesceMs = 250
+)
+
+func init() {
+	inputs.Register("mqtt", func() inputs.Input {
+		return &MqttInput{
+			Cfg:    &Config{},
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+			wg:     new(sync.WaitGroup),
+		}
+	})
+}
+
+// MqttInput //
+type MqttInput struct {
+	Cfg    *Config
+	ctx    context.Context
+	cfn    context.CancelFunc
+	logger *log.Logger
+
+	client  mqtt.Client
+	msgChan chan mqtt.Message
+	wg      *sync.WaitGroup
+	outputs []outputs.Output
+	evps    []formatters.EventProcessor
+}
+
+// Config //
+type Config struct {
+	Name            string           `mapstructure:"name,omitempty"`
+	Address         string           `mapstructure:"address,omitempty"`
+	ClientID        string           `mapstructure:"client-id,omitempty"`
+	Username        string           `mapstructure:"username,omitempty"`
+	Password        string           `mapstructure:"password,omitempty"`
+	TLS             *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Topic           string           `mapstructure:"topic,omitempty"`
+	Group           string           `mapstructure:"group,omitempty"`
+	QoS             byte             `mapstructure:"qos,omitempty"`
+	CleanSession    *bool            `mapstructure:"clean-session,omitempty"`
+	KeepAlive       time.Duration    `mapstructure:"keep-alive,omitempty"`
+	ConnectTimeWait time.Duration    `mapstructure:"connect-time-wait,omitempty"`
+	Format          string           `mapstructure:"format,omitempty"`
+	Debug           bool             `mapstructure:"debug,omitempty"`
+	NumWorkers      int              `mapstructure:"num-workers,omitempty"`
+	BufferSize      int              `mapstructure:"buffer-size,omitempty"`
+	Outputs         []string         `mapstructure:"outputs,omitempty"`
+	EventProcessors []string         `mapstructure:"event-processors,omitempty"`
+}
+
+// Start //
+func (m *MqttInput) Start(ctx context.Context, name string, cfg map[string]interface{}, opts ...inputs.Option) error {
+	err := outputs.DecodeConfig(cfg, m.Cfg)
+	if err != nil {
+		return err
+	}
+	if m.Cfg.Name == "" {
+		m.Cfg.Name = name
+	}
+	for _, opt := range opts {
+		if err := opt(m); err != nil {
+			return err
+		}
+	}
+	err = m.setDefaults()
+	if err != nil {
+		return err
+	}
+	m.logger.Printf("input starting with config: %+v", m.Cfg)
+	m.client, err = m.createClient()
+	if err != nil {
+		return err
+	}
+	m.ctx, m.cfn = context.WithCancel(ctx)
+	m.msgChan = make(chan mqtt.Message, m.Cfg.BufferSize)
+	m.wg.Add(m.Cfg.NumWorkers)
+	for i := 0; i < m.Cfg.NumWorkers; i++ {
+		go m.worker(m.ctx, i)
+	}
+	// the client keeps retrying to connect in the background,
+	// the subscription is (re)created by the OnConnect handler.
+	m.client.Connect()
+	return nil
+}
+
+func (m *MqttInput) worker(ctx context.Context, idx int) {
+	defer m.wg.Done()
+	workerLogPrefix := fmt.Sprintf("worker-%d", idx)
+	m.logger.Printf("%s starting", workerLogPrefix)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-m.msgChan:
+			data := msg.Payload()
+			if len(data) == 0 {
+				continue
+			}
+			if m.Cfg.Debug {
+				m.logger.Printf("received msg, topic=%s, len=%d, data=%s", msg.Topic(), len(data), string(data))
+			}
+			switch m.Cfg.Format {
+			case "event":
+				evMsgs, err := decodeEvents(data)
+				if err != nil {
+					if m.Cfg.Debug {
+						m.logger.Printf("%s failed to unmarshal event msg: %v", workerLogPrefix, err)
+					}
+					continue
+				}
+				for _, p := range m.evps {
+					evMsgs = p.Apply(evMsgs...)
+				}
+				for _, o := range m.outputs {
+					for _, ev := range evMsgs {
+						o.WriteEvent(ctx, ev)
+					}
+				}
+			case "proto":
+				protoMsg := &gnmi.SubscribeResponse{}
+				err := proto.Unmarshal(data, protoMsg)
+				if err != nil {
+					if m.Cfg.Debug {
+						m.logger.Printf("%s failed to unmarshal proto msg: %v", workerLogPrefix, err)
+					}
+					continue
+				}
+				meta := topicMeta(msg.Topic())
+				for _, o := range m.outputs {
+					o.Write(ctx, protoMsg, meta)
+				}
+			}
+		}
+	}
+}
+
+// Close //
+func (m *MqttInput) Close() error {
+	if m.cfn == nil {
+		return nil
+	}
+	if m.client != nil {
+		m.client.Disconnect(disconnectQuiesceMs)
+	}
+	m.cfn()
+	m.wg.Wait()
+	return nil
+}
+
+// SetLogger //
+func (m *MqttInput) SetLogger(logger *log.Logger) {
+	if logger != nil && m.logger != nil {
+		m.logger.SetOutput(logger.Writer())
+		m.logger.SetFlags(logger.Flags())
+	}
+}
+
+// SetOutputs //
+func (m *MqttInput) SetOutputs(outs map[string]outputs.Output) {
+	if len(m.Cfg.Outputs) == 0 {
+		for _, o := range outs {
+			m.outputs = append(m.outputs, o)
+		}
+		return
+	}
+	for _, name := range m.Cfg.Outputs {
+		if o, ok := outs[name]; ok {
+			m.outputs = append(m.outputs, o)
+		}
+	}
+}
+
+func (m *MqttInput) SetName(name string) {
+	sb := strings.Builder{}
+	if name != "" {
+		sb.WriteString(name)
+		sb.WriteString("-")
+	}
+	sb.WriteString(m.Cfg.Name)
+	sb.WriteString("-mqtt-sub")
+	m.Cfg.Name = sb.String()
+}
+
+func (m *MqttInput) SetEventProcessors(ps map[string]map[string]interface{}, logger *log.Logger, tcs map[string]*types.TargetConfig, acts map[string]map[string]interface{}) error {
+	var err error
+	m.evps, err = formatters.MakeEventProcessors(
+		logger,
+		m.Cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// helper functions
+
+func (m *MqttInput) setDefaults() error {
+	m.Cfg.Format = strings.ToLower(m.Cfg.Format)
+	if m.Cfg.Format == "" {
+		m.Cfg.Format = defaultFormat
+	}
+	if !(m.Cfg.Format == "event" || m.Cfg.Format == "proto") {
+		return fmt.Errorf("unsupported input format")
+	}
+	if m.Cfg.QoS > 2 {
+		return fmt.Errorf("invalid qos %d, expected 0, 1 or 2", m.Cfg.QoS)
+	}
+	if m.Cfg.Name == "" {
+		m.Cfg.Name = "gnmic-" + uuid.New().String()
+	}
+	if m.Cfg.ClientID == "" {
+		m.Cfg.ClientID = m.Cfg.Name
+	}
+	if m.Cfg.Address == "" {
+		m.Cfg.Address = defaultAddress
+	}
+	if m.Cfg.Topic == "" {
+		m.Cfg.Topic = defaultTopic
+	}
+	if m.Cfg.CleanSession == nil {
+		cs := true
+		m.Cfg.CleanSession = &cs
+	}
+	if m.Cfg.KeepAlive <= 0 {
+		m.Cfg.KeepAlive = defaultKeepAlive
+	}
+	if m.Cfg.ConnectTimeWait <= 0 {
+		m.Cfg.ConnectTimeWait = mqttConnectWait
+	}
+	if m.Cfg.NumWorkers <= 0 {
+		m.Cfg.NumWorkers = defaultNumWorkers
+	}
+	if m.Cfg.BufferSize <= 0 {
+		m.Cfg.BufferSize = defaultBufferSize
+	}
+	return nil
+}
+
+// subscriptionTopic returns the topic filter to subscribe to,
+// using a shared subscription if a group is set.
+func (m *MqttInput) subscriptionTopic() string {
+	if m.Cfg.Group == "" {
+		return m.Cfg.Topic
+	}
+	return sharedTopicPrefix + m.Cfg.Group + "/" + m.Cfg.Topic
+}
+
+func (m *MqttInput) createClient() (mqtt.Client, error) {
+	opts := mqtt.NewClientOptions().
+		AddBroker(m.Cfg.Address).
+		SetClientID(m.Cfg.ClientID).
+		SetCleanSession(*m.Cfg.CleanSession).
+		SetKeepAlive(m.Cfg.KeepAlive).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(m.Cfg.ConnectTimeWait).
+		SetMaxReconnectInterval(m.Cfg.ConnectTimeWait).
+		SetOrderMatters(false).
+		SetOnConnectHandler(m.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			m.logger.Printf("connection to %s lost: %v", m.Cfg.Address, err)
+		})
+	if m.Cfg.Username != "" {
+		opts.SetUsername(m.Cfg.Username)
+		opts.SetPassword(m.Cfg.Password)
+	}
+	if m.Cfg.TLS != nil {
+		tlsConfig, err := utils.NewTLSConfig(
+			m.Cfg.TLS.CaFile, m.Cfg.TLS.CertFile, m.Cfg.TLS.KeyFile, "", m.Cfg.TLS.SkipVerify,
+			false)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig != nil {
+			opts.SetTLSConfig(tlsConfig)
+		}
+	}
+	return mqtt.NewClient(opts), nil
+}
+
+// onConnect subscribes to the configured topic each time
+// the client (re)connects to the broker.
+func (m *MqttInput) onConnect(c mqtt.Client) {
+	m.logger.Printf("connected to %s", m.Cfg.Address)
+	topic := m.subscriptionTopic()
+	token := c.Subscribe(topic, m.Cfg.QoS, func(_ mqtt.Client, msg mqtt.Message) {
+		select {
+		case <-m.ctx.Done():
+		case m.msgChan <- msg:
+		}
+	})
+	go func() {
+		token.Wait()
+		if err := token.Error(); err != nil {
+			m.logger.Printf("failed to subscribe to topic %q: %v", topic, err)
+			return
+		}
+		m.logger.Printf("subscribed to topic %q", topic)
+	}()
+}
+
+// decodeEvents decodes a payload containing either a single event
+// or a list of events.
+func decodeEvents(data []byte) ([]*formatters.EventMsg, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		ev := new(formatters.EventMsg)
+		err := json.Unmarshal(data, ev)
+		if err != nil {
+			return nil, err
+		}
+		return []*formatters.EventMsg{ev}, nil
+	}
+	evMsgs := make([]*formatters.EventMsg, 0)
+	err := json.Unmarshal(data, &evMsgs)
+	if err != nil {
+		return nil, err
+	}
+	return evMsgs, nil
+}
+
+// topicMeta builds the message metadata from a topic
+// following the mqtt output default topic `gnmic/<subscription>/<target>`.
+func topicMeta(topic string) outputs.Meta {
+	meta := outputs.Meta{}
+	sections := strings.SplitN(topic, "/", 3)
+	if len(sections) == 3 {
+		meta["subscription-name"] = sections[1]
+		meta["source"] = sections[2]
+	}
+	return meta
+}
@@ -0,0 +1,281 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mqtt_input
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+func TestDecodeEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want int
+		err  bool
+	}{
+		{
+			name: "single",
+			data: `{"name":"sub1","timestamp":42,"tags":{"source":"router1"}}`,
+			want: 1,
+		},
+		{
+			name: "list",
+			data: `[{"name":"sub1","timestamp":42},{"name":"sub1","timestamp":43}]`,
+			want: 2,
+		},
+		{
+			name: "leading_spaces",
+			data: "\n  {\"name\":\"sub1\",\"timestamp\":42}",
+			want: 1,
+		},
+		{
+			name: "invalid",
+			data: `{"name":`,
+			err:  true,
+		},
+		{
+			name: "not_an_event",
+			data: `"sub1"`,
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evs, err := decodeEvents([]byte(tt.data))
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %d events", len(evs))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(evs) != tt.want {
+				t.Fatalf("got %d events, want %d", len(evs), tt.want)
+			}
+			if evs[0].Name != "sub1" || evs[0].Timestamp != 42 {
+				t.Errorf("unexpected event: %+v", evs[0])
+			}
+		})
+	}
+}
+
+func TestTopicMeta(t *testing.T) {
+	meta := topicMeta("gnmic/sub1/router1")
+	if meta["subscription-name"] != "sub1" || meta["source"] != "router1" {
+		t.Errorf("unexpected meta: %v", meta)
+	}
+	meta = topicMeta("telemetry")
+	if len(meta) != 0 {
+		t.Errorf("unexpected meta: %v", meta)
+	}
+}
+
+func TestSubscriptionTopic(t *testing.T) {
+	m := &MqttInput{Cfg: &Config{}}
+	if err := m.setDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.subscriptionTopic(); got != defaultTopic {
+		t.Errorf("got %q, want %q", got, defaultTopic)
+	}
+	m.Cfg.Group = "collectors"
+	if got := m.subscriptionTopic(); got != "$share/collectors/gnmic/#" {
+		t.Errorf("got %q, want %q", got, "$share/collectors/gnmic/#")
+	}
+}
+
+type testToken struct {
+	done chan struct{}
+}
+
+func newTestToken() *testToken {
+	t := &testToken{done: make(chan struct{})}
+	close(t.done)
+	return t
+}
+
+func (t *testToken) Wait() bool                     { return true }
+func (t *testToken) WaitTimeout(time.Duration) bool { return true }
+func (t *testToken) Done() <-chan struct{}          { return t.done }
+func (t *testToken) Error() error                   { return nil }
+
+// testClient records the subscriptions,
+// the methods not used by the input are left unimplemented.
+type testClient struct {
+	mqtt.Client
+	topic   string
+	qos     byte
+	handler mqtt.MessageHandler
+}
+
+func (c *testClient) Subscribe(topic string, qos byte, handler mqtt.MessageHandler) mqtt.Token {
+	c.topic = topic
+	c.qos = qos
+	c.handler = handler
+	return newTestToken()
+}
+
+type testMessage struct {
+	mqtt.Message
+	topic   string
+	payload []byte
+}
+
+func (m *testMessage) Topic() string   { return m.topic }
+func (m *testMessage) Payload() []byte { return m.payload }
+
+// testOutput records the messages written by the input.
+type testOutput struct {
+	outputs.Output
+	mu     sync.Mutex
+	events []*formatters.EventMsg
+	msgs   []proto.Message
+	metas  []outputs.Meta
+}
+
+func (o *testOutput) Write(_ context.Context, m proto.Message, meta outputs.Meta) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.msgs = append(o.msgs, m)
+	o.metas = append(o.metas, meta)
+}
+
+func (o *testOutput) WriteEvent(_ context.Context, ev *formatters.EventMsg) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, ev)
+}
+
+func (o *testOutput) count() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.events) + len(o.msgs)
+}
+
+func (o *testOutput) wait(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	for o.count() < n {
+		select {
+		case <-deadline:
+			t.Fatalf("timeout waiting for %d messages, got %d", n, o.count())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func newTestInput(t *testing.T, cfg *Config) (*MqttInput, *testOutput) {
+	t.Helper()
+	m := &MqttInput{
+		Cfg:    cfg,
+		logger: log.New(io.Discard, loggingPrefix, 0),
+		wg:     new(sync.WaitGroup),
+	}
+	if err := m.setDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	o := new(testOutput)
+	m.SetOutputs(map[string]outputs.Output{"test": o})
+	m.ctx, m.cfn = context.WithCancel(context.Background())
+	m.msgChan = make(chan mqtt.Message, m.Cfg.BufferSize)
+	m.wg.Add(1)
+	go m.worker(m.ctx, 0)
+	t.Cleanup(func() { m.Close() })
+	return m, o
+}
+
+func TestOnConnect(t *testing.T) {
+	m, o := newTestInput(t, &Config{
+		Topic: "telemetry/#",
+		Group: "collectors",
+		QoS:   1,
+	})
+	c := new(testClient)
+	m.onConnect(c)
+	if c.topic != "$share/collectors/telemetry/#" {
+		t.Errorf("got topic %q, want %q", c.topic, "$share/collectors/telemetry/#")
+	}
+	if c.qos != 1 {
+		t.Errorf("got qos %d, want 1", c.qos)
+	}
+	c.handler(c, &testMessage{
+		topic:   "telemetry/sub1/router1",
+		payload: []byte(`{"name":"sub1","timestamp":42}`),
+	})
+	o.wait(t, 1)
+}
+
+func TestWorkerEvent(t *testing.T) {
+	m, o := newTestInput(t, &Config{})
+	for _, payload := range []string{
+		`{"name":"sub1","timestamp":1}`,
+		``,
+		`not json`,
+		`[{"name":"sub1","timestamp":2},{"name":"sub1","timestamp":3}]`,
+	} {
+		m.msgChan <- &testMessage{topic: "gnmic/sub1/router1", payload: []byte(payload)}
+	}
+	o.wait(t, 3)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i, ev := range o.events {
+		if ev.Timestamp != int64(i+1) {
+			t.Errorf("event %d: got timestamp %d, want %d", i, ev.Timestamp, i+1)
+		}
+	}
+}
+
+func TestWorkerProto(t *testing.T) {
+	m, o := newTestInput(t, &Config{Format: "proto"})
+	rsp := &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 42,
+				Update: []*gnmi.Update{
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "counter"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1}},
+					},
+				},
+			},
+		},
+	}
+	b, err := proto.Marshal(rsp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.msgChan <- &testMessage{topic: "gnmic/sub1/router1", payload: []byte{0xff}}
+	m.msgChan <- &testMessage{topic: "gnmic/sub1/router1", payload: b}
+	o.wait(t, 1)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(o.msgs))
+	}
+	if !proto.Equal(o.msgs[0], rsp) {
+		t.Errorf("got %v, want %v", o.msgs[0], rsp)
+	}
+	if o.metas[0]["subscription-name"] != "sub1" || o.metas[0]["source"] != "router1" {
+		t.Errorf("unexpected meta: %v", o.metas[0])
+	}
+}
@@ -10,6 +10,7 @@ package all
 
 import (
 	_ "github.com/openconfig/gnmic/pkg/lockers/consul_locker"
+	_ "github.com/openconfig/gnmic/pkg/lockers/etcd_locker"
 	_ "github.com/openconfig/gnmic/pkg/lockers/k8s_locker"
 	_ "github.com/openconfig/gnmic/pkg/lockers/redis_locker"
 )
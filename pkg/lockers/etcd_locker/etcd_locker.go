@@ -0,0 +1,317 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package etcd_locker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/lockers"
+)
+
+const (
+	defaultLeaseTTL    = 10 * time.Second
+	defaultRetryTimer  = 2 * time.Second
+	defaultDialTimeout = 5 * time.Second
+	loggingPrefix      = "[etcd_locker] "
+)
+
+func init() {
+	lockers.Register("etcd", func() lockers.Locker {
+		return &etcdLocker{
+			Cfg:             &config{},
+			m:               new(sync.Mutex),
+			acquiredLocks:   make(map[string]*lock),
+			attemptingLocks: make(map[string]*lock),
+			services:        make(map[string]context.CancelFunc),
+			logger:          log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+		}
+	})
+}
+
+type etcdLocker struct {
+	Cfg             *config
+	client          *clientv3.Client
+	logger          *log.Logger
+	m               *sync.Mutex
+	acquiredLocks   map[string]*lock
+	attemptingLocks map[string]*lock
+	services        map[string]context.CancelFunc
+}
+
+type config struct {
+	Endpoints   []string         `mapstructure:"endpoints,omitempty" json:"endpoints,omitempty"`
+	Username    string           `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password    string           `mapstructure:"password,omitempty" json:"-"`
+	TLS         *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	DialTimeout time.Duration    `mapstructure:"dial-timeout,omitempty" json:"dial-timeout,omitempty"`
+	LeaseTTL    time.Duration    `mapstructure:"lease-ttl,omitempty" json:"lease-ttl,omitempty"`
+	RetryTimer  time.Duration    `mapstructure:"retry-timer,omitempty" json:"retry-timer,omitempty"`
+	Debug       bool             `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+}
+
+// lock is a key held using an etcd lease,
+// the key is deleted by etcd when the lease expires or is revoked.
+type lock struct {
+	leaseID  clientv3.LeaseID
+	doneChan chan struct{}
+}
+
+func (e *etcdLocker) Init(ctx context.Context, cfg map[string]interface{}, opts ...lockers.Option) error {
+	err := lockers.DecodeConfig(cfg, e.Cfg)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	err = e.setDefaults()
+	if err != nil {
+		return err
+	}
+	clientConfig := clientv3.Config{
+		Endpoints:   e.Cfg.Endpoints,
+		Username:    e.Cfg.Username,
+		Password:    e.Cfg.Password,
+		DialTimeout: e.Cfg.DialTimeout,
+		Context:     ctx,
+	}
+	if e.Cfg.TLS != nil {
+		clientConfig.TLS, err = utils.NewTLSConfig(
+			e.Cfg.TLS.CaFile, e.Cfg.TLS.CertFile, e.Cfg.TLS.KeyFile, "",
+			e.Cfg.TLS.SkipVerify, false)
+		if err != nil {
+			return err
+		}
+	}
+	e.client, err = clientv3.New(clientConfig)
+	if err != nil {
+		return err
+	}
+	// check the connectivity to the cluster
+	sctx, cancel := context.WithTimeout(ctx, e.Cfg.DialTimeout)
+	defer cancel()
+	_, err = e.client.Status(sctx, e.Cfg.Endpoints[0])
+	if err != nil {
+		e.client.Close()
+		return fmt.Errorf("cannot contact etcd server: %w", err)
+	}
+	b, _ := json.Marshal(e.Cfg)
+	e.logger.Printf("initialized etcd locker with cfg=%s", string(b))
+	return nil
+}
+
+// Lock tries to create the key with a new lease,
+// it returns false if the key already exists.
+func (e *etcdLocker) Lock(ctx context.Context, key string, val []byte) (bool, error) {
+	if e.Cfg.Debug {
+		e.logger.Printf("attempting to lock=%s", key)
+	}
+	lease, err := e.client.Grant(ctx, ttlSeconds(e.Cfg.LeaseTTL))
+	if err != nil {
+		return false, fmt.Errorf("failed to create lease for lock=%s: %w", key, err)
+	}
+	l := &lock{leaseID: lease.ID, doneChan: make(chan struct{})}
+	e.m.Lock()
+	e.attemptingLocks[key] = l
+	e.m.Unlock()
+	defer func() {
+		e.m.Lock()
+		defer e.m.Unlock()
+		delete(e.attemptingLocks, key)
+	}()
+
+	rsp, err := e.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(val), clientv3.WithLease(lease.ID))).
+		Commit()
+	if err != nil {
+		e.revoke(lease.ID)
+		return false, fmt.Errorf("failed to acquire lock=%s: %w", key, err)
+	}
+	if !rsp.Succeeded {
+		e.revoke(lease.ID)
+		if e.Cfg.Debug {
+			e.logger.Printf("lock already taken lock=%s", key)
+		}
+		return false, nil
+	}
+	e.m.Lock()
+	e.acquiredLocks[key] = l
+	e.m.Unlock()
+	return true, nil
+}
+
+// KeepLock renews the lock lease until ctx is done or the lock is unlocked.
+func (e *etcdLocker) KeepLock(ctx context.Context, key string) (chan struct{}, chan error) {
+	errChan := make(chan error)
+	e.m.Lock()
+	l, ok := e.acquiredLocks[key]
+	e.m.Unlock()
+	if !ok {
+		doneChan := make(chan struct{})
+		go func() {
+			errChan <- fmt.Errorf("unable to maintain lock %q: not found in acquiredlocks", key)
+			close(doneChan)
+		}()
+		return doneChan, errChan
+	}
+	go func() {
+		kctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		kaCh, err := e.client.KeepAlive(kctx, l.leaseID)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				errChan <- ctx.Err()
+				return
+			case <-l.doneChan:
+				return
+			case _, ok := <-kaCh:
+				if ok {
+					continue
+				}
+				select {
+				case <-l.doneChan:
+					return
+				default:
+				}
+				if ctx.Err() != nil {
+					errChan <- ctx.Err()
+					return
+				}
+				errChan <- fmt.Errorf("could not keep lock %q: lease expired", key)
+				return
+			}
+		}
+	}()
+	return l.doneChan, errChan
+}
+
+func (e *etcdLocker) Unlock(ctx context.Context, key string) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	if l, ok := e.acquiredLocks[key]; ok {
+		delete(e.acquiredLocks, key)
+		close(l.doneChan)
+		// revoking the lease deletes the key
+		_, err := e.client.Revoke(ctx, l.leaseID)
+		if err != nil {
+			return fmt.Errorf("failed to unlock lock %s: %w", key, err)
+		}
+	}
+	if l, ok := e.attemptingLocks[key]; ok {
+		delete(e.attemptingLocks, key)
+		_, err := e.client.Revoke(ctx, l.leaseID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *etcdLocker) IsLocked(ctx context.Context, key string) (bool, error) {
+	rsp, err := e.client.Get(ctx, key, clientv3.WithCountOnly())
+	if err != nil {
+		return false, fmt.Errorf("error during etcd query: %w", err)
+	}
+	return rsp.Count > 0, nil
+}
+
+func (e *etcdLocker) List(ctx context.Context, prefix string) (map[string]string, error) {
+	rsp, err := e.client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch from etcd: %w", err)
+	}
+	if e.Cfg.Debug {
+		e.logger.Printf("got %d keys from etcd for prefix=%s", len(rsp.Kvs), prefix)
+	}
+	data := make(map[string]string, len(rsp.Kvs))
+	for _, kv := range rsp.Kvs {
+		data[string(kv.Key)] = string(kv.Value)
+	}
+	return data, nil
+}
+
+func (e *etcdLocker) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	keys := []string{}
+	e.m.Lock()
+	for key := range e.acquiredLocks {
+		keys = append(keys, key)
+	}
+	e.m.Unlock()
+	for _, key := range keys {
+		e.Unlock(ctx, key)
+	}
+	e.Deregister("")
+	if e.client == nil {
+		return nil
+	}
+	return e.client.Close()
+}
+
+func (e *etcdLocker) SetLogger(logger *log.Logger) {
+	if logger != nil && e.logger != nil {
+		e.logger.SetOutput(logger.Writer())
+		e.logger.SetFlags(logger.Flags())
+	}
+}
+
+// helpers
+
+func (e *etcdLocker) setDefaults() error {
+	if len(e.Cfg.Endpoints) == 0 {
+		return errors.New("missing etcd endpoints")
+	}
+	if e.Cfg.LeaseTTL <= 0 {
+		e.Cfg.LeaseTTL = defaultLeaseTTL
+	}
+	if e.Cfg.RetryTimer <= 0 {
+		e.Cfg.RetryTimer = defaultRetryTimer
+	}
+	if e.Cfg.DialTimeout <= 0 {
+		e.Cfg.DialTimeout = defaultDialTimeout
+	}
+	return nil
+}
+
+func (e *etcdLocker) revoke(id clientv3.LeaseID) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.Cfg.DialTimeout)
+	defer cancel()
+	_, err := e.client.Revoke(ctx, id)
+	if err != nil && e.Cfg.Debug {
+		e.logger.Printf("failed to revoke lease %x: %v", id, err)
+	}
+}
+
+// ttlSeconds converts d to a lease TTL in seconds, etcd leases have a 1s granularity.
+func ttlSeconds(d time.Duration) int64 {
+	s := int64(math.Ceil(d.Seconds()))
+	if s < 1 {
+		return 1
+	}
+	return s
+}
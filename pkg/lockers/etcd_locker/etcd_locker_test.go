@@ -0,0 +1,231 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package etcd_locker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/embed"
+
+	"github.com/openconfig/gnmic/pkg/lockers"
+)
+
+// endpoint is the client URL of the embedded etcd server the tests run against.
+var endpoint string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "gnmic-etcd-locker")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create etcd data dir: %v\n", err)
+		os.Exit(1)
+	}
+	e, err := startEtcd(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		fmt.Fprintf(os.Stderr, "failed to start etcd: %v\n", err)
+		os.Exit(1)
+	}
+	endpoint = e.Clients[0].Addr().String()
+	rc := m.Run()
+	e.Close()
+	os.RemoveAll(dir)
+	os.Exit(rc)
+}
+
+// startEtcd starts a single member etcd server listening on random local ports.
+func startEtcd(dir string) (*embed.Etcd, error) {
+	cfg := embed.NewConfig()
+	cfg.Dir = dir
+	cfg.LogLevel = "error"
+	lcurl, _ := url.Parse("http://127.0.0.1:0")
+	lpurl, _ := url.Parse("http://127.0.0.1:0")
+	cfg.ListenClientUrls = []url.URL{*lcurl}
+	cfg.AdvertiseClientUrls = []url.URL{*lcurl}
+	cfg.ListenPeerUrls = []url.URL{*lpurl}
+	cfg.AdvertisePeerUrls = []url.URL{*lpurl}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-e.Server.ReadyNotify():
+		return e, nil
+	case err = <-e.Err():
+	case <-time.After(10 * time.Second):
+		err = errors.New("timeout waiting for etcd to be ready")
+	}
+	e.Close()
+	return nil, err
+}
+
+func newTestLocker(t *testing.T, endpoint string) lockers.Locker {
+	t.Helper()
+	l := lockers.Lockers["etcd"]()
+	err := l.Init(context.Background(), map[string]interface{}{
+		"endpoints": []string{endpoint},
+		"lease-ttl": "1s",
+	})
+	if err != nil {
+		t.Fatalf("failed to init locker: %v", err)
+	}
+	t.Cleanup(func() { l.Stop() })
+	return l
+}
+
+func TestLock(t *testing.T) {
+	l1 := newTestLocker(t, endpoint)
+	l2 := newTestLocker(t, endpoint)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key := "gnmic/cluster1/targets/target1"
+	ok, err := l1.Lock(ctx, key, []byte("gnmic1"))
+	if err != nil || !ok {
+		t.Fatalf("failed to acquire lock: ok=%v, err=%v", ok, err)
+	}
+	ok, err = l2.Lock(ctx, key, []byte("gnmic2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("lock acquired twice")
+	}
+	doneCh, errCh := l1.KeepLock(ctx, key)
+	// wait longer than the lease TTL
+	select {
+	case err := <-errCh:
+		t.Fatalf("failed to keep lock: %v", err)
+	case <-doneCh:
+		t.Fatal("lock released unexpectedly")
+	case <-time.After(3 * time.Second):
+	}
+	locked, err := l2.IsLocked(ctx, key)
+	if err != nil || !locked {
+		t.Fatalf("expected key to be locked: locked=%v, err=%v", locked, err)
+	}
+	keys, err := l2.List(ctx, "gnmic/cluster1/targets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys[key] != "gnmic1" {
+		t.Fatalf("unexpected lock list: %v", keys)
+	}
+
+	err = l1.Unlock(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-doneCh:
+	case <-time.After(time.Second):
+		t.Fatal("KeepLock done channel not closed after unlock")
+	}
+	locked, err = l2.IsLocked(ctx, key)
+	if err != nil || locked {
+		t.Fatalf("expected key to be unlocked: locked=%v, err=%v", locked, err)
+	}
+	ok, err = l2.Lock(ctx, key, []byte("gnmic2"))
+	if err != nil || !ok {
+		t.Fatalf("failed to acquire released lock: ok=%v, err=%v", ok, err)
+	}
+}
+
+func TestLockExpires(t *testing.T) {
+	l1 := newTestLocker(t, endpoint)
+	l2 := newTestLocker(t, endpoint)
+	ctx := context.Background()
+
+	key := "gnmic/cluster1/leader"
+	ok, err := l1.Lock(ctx, key, []byte("gnmic1"))
+	if err != nil || !ok {
+		t.Fatalf("failed to acquire lock: ok=%v, err=%v", ok, err)
+	}
+	// without KeepLock the lease expires after its TTL
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		locked, err := l2.IsLocked(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("lock did not expire")
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func TestServices(t *testing.T) {
+	l1 := newTestLocker(t, endpoint)
+	l2 := newTestLocker(t, endpoint)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sChan := make(chan []*lockers.Service, 10)
+	go l2.WatchServices(ctx, "cluster1-gnmic-api", []string{"cluster-name=cluster1"}, sChan, time.Minute)
+
+	go l1.Register(ctx, &lockers.ServiceRegistration{
+		ID:      "gnmic1-api",
+		Name:    "cluster1-gnmic-api",
+		Address: "10.0.0.1",
+		Port:    7890,
+		Tags:    []string{"cluster-name=cluster1", "instance-name=gnmic1"},
+		TTL:     time.Second,
+	})
+
+	waitServices := func(n int) []*lockers.Service {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case srvs := <-sChan:
+				if len(srvs) == n {
+					return srvs
+				}
+			case <-timeout:
+				t.Fatalf("timeout waiting for %d services", n)
+			}
+		}
+	}
+	srvs := waitServices(1)
+	if srvs[0].ID != "gnmic1-api" || srvs[0].Address != "10.0.0.1:7890" {
+		t.Fatalf("unexpected service: %+v", srvs[0])
+	}
+	// the registration outlives its TTL
+	time.Sleep(2500 * time.Millisecond)
+	srvs, err := l2.GetServices(ctx, "cluster1-gnmic-api", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(srvs) != 1 {
+		t.Fatalf("expected 1 service, got %d", len(srvs))
+	}
+	srvs, err = l2.GetServices(ctx, "cluster1-gnmic-api", []string{"cluster-name=cluster2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(srvs) != 0 {
+		t.Fatalf("expected 0 services, got %d", len(srvs))
+	}
+
+	err = l1.Deregister("gnmic1-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitServices(0)
+}
@@ -0,0 +1,221 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package etcd_locker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/openconfig/gnmic/pkg/lockers"
+)
+
+const (
+	defaultWatchTimeout = 1 * time.Minute
+	servicesPrefix      = "gnmic/services"
+)
+
+// etcdRegistration represents a gnmic endpoint in etcd.
+// It's serialised in the key value to allow recovering
+// it during service discovery.
+type etcdRegistration struct {
+	ID      string   `json:"id,omitempty"`
+	Address string   `json:"address,omitempty"`
+	Port    int      `json:"port,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+func serviceKey(name, id string) string {
+	return fmt.Sprintf("%s/%s/%s", servicesPrefix, name, id)
+}
+
+// Register writes the service registration under a key attached to a lease with the service TTL,
+// the lease is renewed until ctx is done or the service is deregistered.
+func (e *etcdLocker) Register(ctx context.Context, s *lockers.ServiceRegistration) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	e.m.Lock()
+	e.services[s.ID] = cancel
+	e.m.Unlock()
+
+	val, err := json.Marshal(&etcdRegistration{
+		ID:      s.ID,
+		Address: s.Address,
+		Port:    s.Port,
+		Tags:    s.Tags,
+	})
+	if err != nil {
+		return err
+	}
+	lease, err := e.client.Grant(ctx, ttlSeconds(s.TTL))
+	if err != nil {
+		return fmt.Errorf("failed to create lease for service=%s: %w", s.ID, err)
+	}
+	defer e.revoke(lease.ID)
+	if e.Cfg.Debug {
+		e.logger.Printf("registering service=%s", s.ID)
+	}
+	_, err = e.client.Put(ctx, serviceKey(s.Name, s.ID), string(val), clientv3.WithLease(lease.ID))
+	if err != nil {
+		return fmt.Errorf("failed to register service=%s: %w", s.ID, err)
+	}
+	kaCh, err := e.client.KeepAlive(ctx, lease.ID)
+	if err != nil {
+		return fmt.Errorf("failed to keep service=%s registration: %w", s.ID, err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-kaCh:
+			if ok {
+				continue
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("could not keep service=%s registration: lease expired", s.ID)
+		}
+	}
+}
+
+func (e *etcdLocker) Deregister(s string) error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	for sid, cancel := range e.services {
+		if s != "" && sid != s {
+			continue
+		}
+		if e.Cfg.Debug {
+			e.logger.Printf("deregistering service=%s", sid)
+		}
+		cancel()
+		delete(e.services, sid)
+	}
+	return nil
+}
+
+func (e *etcdLocker) GetServices(ctx context.Context, serviceName string, tags []string) ([]*lockers.Service, error) {
+	services, _, err := e.getServices(ctx, serviceName, tags)
+	return services, err
+}
+
+// WatchServices sends the list of services to sChan each time
+// a service registration changes, and every watchTimeout.
+func (e *etcdLocker) WatchServices(ctx context.Context, serviceName string, tags []string, sChan chan<- []*lockers.Service, watchTimeout time.Duration) error {
+	if watchTimeout <= 0 {
+		watchTimeout = defaultWatchTimeout
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			if e.Cfg.Debug {
+				e.logger.Printf("(re)starting watch service=%q", serviceName)
+			}
+			err := e.watch(ctx, serviceName, tags, sChan, watchTimeout)
+			if err != nil {
+				e.logger.Printf("service %q watch failed: %v", serviceName, err)
+				time.Sleep(e.Cfg.RetryTimer)
+			}
+		}
+	}
+}
+
+func (e *etcdLocker) watch(ctx context.Context, serviceName string, tags []string, sChan chan<- []*lockers.Service, watchTimeout time.Duration) error {
+	services, rev, err := e.getServices(ctx, serviceName, tags)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case sChan <- services:
+	}
+	wctx, cancel := context.WithTimeout(ctx, watchTimeout)
+	defer cancel()
+	wch := e.client.Watch(wctx, serviceKey(serviceName, ""), clientv3.WithPrefix(), clientv3.WithRev(rev+1))
+	for {
+		select {
+		case <-wctx.Done():
+			// resync the services list
+			return nil
+		case wrsp, ok := <-wch:
+			if !ok {
+				return nil
+			}
+			if err := wrsp.Err(); err != nil {
+				return err
+			}
+			if len(wrsp.Events) == 0 {
+				continue
+			}
+			services, _, err = e.getServices(wctx, serviceName, tags)
+			if err != nil {
+				return err
+			}
+			select {
+			case <-wctx.Done():
+				return nil
+			case sChan <- services:
+			}
+		}
+	}
+}
+
+// getServices returns the registered services matching tags,
+// as well as the etcd revision they were read at.
+func (e *etcdLocker) getServices(ctx context.Context, serviceName string, tags []string) ([]*lockers.Service, int64, error) {
+	rsp, err := e.client.Get(ctx, serviceKey(serviceName, ""), clientv3.WithPrefix())
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get services from etcd: %w", err)
+	}
+	services := make([]*lockers.Service, 0, len(rsp.Kvs))
+	for _, kv := range rsp.Kvs {
+		reg := new(etcdRegistration)
+		err = json.Unmarshal(kv.Value, reg)
+		if err != nil {
+			// we don't have the data we expect
+			// skip it
+			continue
+		}
+		if !matchTags(reg.Tags, tags) {
+			continue
+		}
+		services = append(services, &lockers.Service{
+			ID:      reg.ID,
+			Address: net.JoinHostPort(reg.Address, strconv.Itoa(reg.Port)),
+			Tags:    reg.Tags,
+		})
+	}
+	if e.Cfg.Debug {
+		e.logger.Printf("got %d services from etcd", len(services))
+	}
+	return services, rsp.Header.Revision, nil
+}
+
+// matchTags returns true if all the wanted tags are present in tags.
+func matchTags(tags, wantedTags []string) bool {
+	tagsMap := make(map[string]struct{}, len(tags))
+	for _, t := range tags {
+		tagsMap[t] = struct{}{}
+	}
+	for _, wt := range wantedTags {
+		if _, ok := tagsMap[wt]; !ok {
+			return false
+		}
+	}
+	return true
+}
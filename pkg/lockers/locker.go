@@ -71,6 +71,7 @@ func WithLogger(logger *log.Logger) Option {
 
 var LockerTypes = []string{
 	"consul",
+	"etcd",
 	"k8s",
 	"redis",
 }
@@ -67,6 +67,7 @@ func init() {
 type asciigraphOutput struct {
 	cfg     *cfg
 	logger  *log.Logger
+	dryRun  bool
 	eventCh chan *formatters.EventMsg
 
 	m       *sync.RWMutex
@@ -184,6 +185,9 @@ func (a *asciigraphOutput) Init(ctx context.Context, name string, cfg map[string
 	if err != nil {
 		return err
 	}
+	if a.dryRun {
+		return nil
+	}
 	//
 	go a.graph(ctx)
 	a.logger.Printf("initialized asciigraph output: %s", a.String())
@@ -273,6 +277,8 @@ func (a *asciigraphOutput) SetName(name string) {}
 
 func (a *asciigraphOutput) SetClusterName(name string) {}
 
+func (a *asciigraphOutput) SetDryRun(dryRun bool) { a.dryRun = dryRun }
+
 func (a *asciigraphOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
 
 func (a *asciigraphOutput) graph(ctx context.Context) {
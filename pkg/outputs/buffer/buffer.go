@@ -0,0 +1,386 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+// Package buffer implements a durable disk buffer placed in front of an output.
+// Messages written to the output are appended to a segment log on disk
+// and forwarded to the output in order, a message is removed from the log
+// once the output reports it delivered.
+// Only the outputs implementing outputs.DeliveryReporter can be buffered.
+package buffer
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	loggingPrefix         = "[output_buffer:%s] "
+	defaultMaxSegmentSize = 16 * 1024 * 1024
+	defaultMaxSize        = 1024 * 1024 * 1024
+	defaultRetryBackoff   = time.Second
+	maxRetryBackoff       = 30 * time.Second
+	metricsInterval       = 5 * time.Second
+	cursorInterval        = time.Second
+)
+
+// errExpired is returned when a message is older than max-age.
+var errExpired = errors.New("message expired")
+
+func init() {
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// Config is the output buffer configuration,
+// set under the output's "buffer" field.
+type Config struct {
+	// directory holding the buffer segment files,
+	// defaults to $TMPDIR/gnmic/buffer/<output-name>
+	Directory string `mapstructure:"directory,omitempty" json:"directory,omitempty"`
+	// max size in bytes of a single segment file
+	MaxSegmentSize int64 `mapstructure:"max-segment-size,omitempty" json:"max-segment-size,omitempty"`
+	// max size in bytes of the buffer, the oldest segments are dropped when it's exceeded
+	MaxSize int64 `mapstructure:"max-size,omitempty" json:"max-size,omitempty"`
+	// messages older than max-age are dropped instead of being forwarded
+	MaxAge time.Duration `mapstructure:"max-age,omitempty" json:"max-age,omitempty"`
+	// sync the segment file to disk after each write
+	Sync bool `mapstructure:"sync,omitempty" json:"sync,omitempty"`
+	// wait time before retrying a failed delivery,
+	// doubled after each failure up to 30s
+	RetryBackoff time.Duration `mapstructure:"retry-backoff,omitempty" json:"retry-backoff,omitempty"`
+}
+
+// record is the buffered form of a message written to the output,
+// either a proto message with its meta or an event message.
+type record struct {
+	Timestamp int64
+	Msg       []byte
+	Meta      outputs.Meta
+	Event     *formatters.EventMsg
+}
+
+type bufferedOutput struct {
+	out    outputs.Output
+	sink   outputs.DeliveryReporter
+	name   string
+	cfg    *Config
+	log    *segmentLog
+	logger *log.Logger
+	dryRun bool
+	reg    *prometheus.Registry
+
+	cfn context.CancelFunc
+	wg  *sync.WaitGroup
+}
+
+// NewOutput returns an output that buffers the messages written
+// to the output o on disk before forwarding them.
+func NewOutput(o outputs.Output) outputs.Output {
+	return &bufferedOutput{
+		out:    o,
+		cfg:    new(Config),
+		logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+		wg:     new(sync.WaitGroup),
+	}
+}
+
+func (b *bufferedOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	b.name = name
+	err := outputs.DecodeConfig(cfg["buffer"], b.cfg)
+	if err != nil {
+		return err
+	}
+	var ok bool
+	b.sink, ok = b.out.(outputs.DeliveryReporter)
+	if !ok {
+		return fmt.Errorf("output %q does not support a buffer: it does not report the delivery of messages", name)
+	}
+	// the options are applied to the buffer to get its logger and registry,
+	// they are passed as is to the underlying output.
+	for _, opt := range opts {
+		if err := opt(b); err != nil {
+			return err
+		}
+	}
+	b.logger.SetPrefix(fmt.Sprintf(loggingPrefix, name))
+	b.setDefaults()
+	if b.dryRun {
+		return b.out.Init(ctx, name, cfg, opts...)
+	}
+	b.log, err = openSegmentLog(b.cfg.Directory, b.cfg.MaxSegmentSize, b.cfg.MaxSize, b.cfg.Sync)
+	if err != nil {
+		return fmt.Errorf("failed to open buffer directory %q: %w", b.cfg.Directory, err)
+	}
+	b.log.onDrop = func(n int64) {
+		bufferDroppedMsgs.WithLabelValues(b.name, "max-size").Add(float64(n))
+	}
+	backlog, _ := b.log.stats()
+	if backlog > 0 {
+		b.logger.Printf("replaying %d buffered message(s)", backlog)
+	}
+	err = b.out.Init(ctx, name, cfg, opts...)
+	if err != nil {
+		b.log.Close()
+		return err
+	}
+	ctx, b.cfn = context.WithCancel(ctx)
+	b.wg.Add(2)
+	go b.forward(ctx)
+	go b.saveCursor(ctx)
+	if b.reg != nil {
+		go b.updateMetrics(ctx)
+	}
+	b.logger.Printf("initialized output buffer: %+v", b.cfg)
+	return nil
+}
+
+func (b *bufferedOutput) setDefaults() {
+	if b.cfg.Directory == "" {
+		b.cfg.Directory = filepath.Join(os.TempDir(), "gnmic", "buffer", b.name)
+	}
+	if b.cfg.MaxSegmentSize <= 0 {
+		b.cfg.MaxSegmentSize = defaultMaxSegmentSize
+	}
+	if b.cfg.MaxSize <= 0 {
+		b.cfg.MaxSize = defaultMaxSize
+	}
+	if b.cfg.RetryBackoff <= 0 {
+		b.cfg.RetryBackoff = defaultRetryBackoff
+	}
+}
+
+func (b *bufferedOutput) Write(ctx context.Context, msg proto.Message, meta outputs.Meta) {
+	if msg == nil {
+		return
+	}
+	a, err := anypb.New(msg)
+	if err != nil {
+		b.logger.Printf("failed to buffer message: %v", err)
+		return
+	}
+	bm, err := proto.Marshal(a)
+	if err != nil {
+		b.logger.Printf("failed to buffer message: %v", err)
+		return
+	}
+	b.append(&record{
+		Timestamp: time.Now().UnixNano(),
+		Msg:       bm,
+		Meta:      meta,
+	})
+}
+
+func (b *bufferedOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if ev == nil {
+		return
+	}
+	b.append(&record{
+		Timestamp: time.Now().UnixNano(),
+		Event:     ev,
+	})
+}
+
+func (b *bufferedOutput) append(r *record) {
+	if b.log == nil {
+		// Init failed, e.g: the output does not report the delivery of messages
+		bufferDroppedMsgs.WithLabelValues(b.name, "write").Inc()
+		return
+	}
+	buf := new(bytes.Buffer)
+	err := gob.NewEncoder(buf).Encode(r)
+	if err != nil {
+		b.logger.Printf("failed to encode message: %v", err)
+		bufferDroppedMsgs.WithLabelValues(b.name, "encoding").Inc()
+		return
+	}
+	err = b.log.append(buf.Bytes())
+	if err != nil {
+		b.logger.Printf("failed to buffer message: %v", err)
+		bufferDroppedMsgs.WithLabelValues(b.name, "write").Inc()
+	}
+}
+
+// forward reads the buffered messages in order and writes them to the output.
+// A message is committed once delivered, dropped or expired,
+// it stays in the buffer if ctx is done before that.
+func (b *bufferedOutput) forward(ctx context.Context) {
+	defer b.wg.Done()
+	for {
+		data, err := b.log.next(ctx)
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, errClosed) {
+				b.logger.Printf("failed to read buffered message: %v", err)
+			}
+			return
+		}
+		err = b.forwardRecord(ctx, data)
+		if err != nil {
+			return
+		}
+		b.log.commit()
+	}
+}
+
+// forwardRecord writes a record to the output, retrying until it is delivered,
+// rejected by the output or older than max-age.
+// It returns an error only if ctx is done before that.
+func (b *bufferedOutput) forwardRecord(ctx context.Context, data []byte) error {
+	r := new(record)
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(r)
+	if err != nil {
+		b.logger.Printf("failed to decode buffered message: %v", err)
+		bufferDroppedMsgs.WithLabelValues(b.name, "decoding").Inc()
+		return nil
+	}
+	var msg proto.Message
+	if r.Event == nil {
+		a := new(anypb.Any)
+		err = proto.Unmarshal(r.Msg, a)
+		if err == nil {
+			msg, err = a.UnmarshalNew()
+		}
+		if err != nil {
+			b.logger.Printf("failed to decode buffered message: %v", err)
+			bufferDroppedMsgs.WithLabelValues(b.name, "decoding").Inc()
+			return nil
+		}
+	}
+	policy := outputs.RetryPolicy{
+		MaxRetries:  -1,
+		Backoff:     b.cfg.RetryBackoff,
+		Exponential: true,
+		MaxBackoff:  maxRetryBackoff,
+	}
+	err = policy.Do(ctx, func() error {
+		if b.cfg.MaxAge > 0 && time.Since(time.Unix(0, r.Timestamp)) > b.cfg.MaxAge {
+			return outputs.Permanent(errExpired)
+		}
+		var err error
+		if r.Event != nil {
+			err = b.sink.WriteEventSync(ctx, r.Event)
+		} else {
+			err = b.sink.WriteSync(ctx, msg, r.Meta)
+		}
+		if err != nil && !outputs.IsPermanent(err) && ctx.Err() == nil {
+			b.logger.Printf("failed to forward buffered message, retrying: %v", err)
+		}
+		return err
+	})
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case errors.Is(err, errExpired):
+		bufferDroppedMsgs.WithLabelValues(b.name, "max-age").Inc()
+	default:
+		b.logger.Printf("buffered message rejected by the output: %v", err)
+		bufferDroppedMsgs.WithLabelValues(b.name, "rejected").Inc()
+	}
+	return nil
+}
+
+// saveCursor periodically persists the buffer read position,
+// limiting the number of messages replayed after a crash.
+func (b *bufferedOutput) saveCursor(ctx context.Context) {
+	defer b.wg.Done()
+	ticker := time.NewTicker(cursorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := b.log.saveCursor()
+			if err != nil {
+				b.logger.Printf("failed to save buffer cursor: %v", err)
+			}
+		}
+	}
+}
+
+func (b *bufferedOutput) updateMetrics(ctx context.Context) {
+	ticker := time.NewTicker(metricsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			msgs, size := b.log.stats()
+			bufferBacklogMsgs.WithLabelValues(b.name).Set(float64(msgs))
+			bufferBacklogBytes.WithLabelValues(b.name).Set(float64(size))
+		}
+	}
+}
+
+// Close stops forwarding messages and closes the output.
+// The messages not yet forwarded stay on disk and are replayed
+// when the output is initialized again.
+func (b *bufferedOutput) Close() error {
+	if b.cfn != nil {
+		b.cfn()
+	}
+	var err error
+	if b.log != nil {
+		err = b.log.Close()
+	}
+	b.wg.Wait()
+	return errors.Join(err, b.out.Close())
+}
+
+func (b *bufferedOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if reg == nil {
+		return
+	}
+	b.reg = reg
+	if err := registerMetrics(reg); err != nil {
+		b.logger.Printf("failed to register metrics: %v", err)
+	}
+}
+
+func (b *bufferedOutput) String() string {
+	return b.out.String()
+}
+
+// SetLogger sets the buffer logger, the underlying output
+// gets its logger from the options passed to its Init.
+func (b *bufferedOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && b.logger != nil {
+		b.logger.SetOutput(logger.Writer())
+		b.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (b *bufferedOutput) SetEventProcessors(map[string]map[string]interface{}, *log.Logger, map[string]*types.TargetConfig, map[string]map[string]interface{}) error {
+	return nil
+}
+
+func (b *bufferedOutput) SetName(string) {}
+
+func (b *bufferedOutput) SetClusterName(string) {}
+
+func (b *bufferedOutput) SetDryRun(dryRun bool) { b.dryRun = dryRun }
+
+func (b *bufferedOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
@@ -0,0 +1,207 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+package buffer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+// testOutput records the messages delivered to it,
+// it fails the deliveries while down.
+type testOutput struct {
+	outputs.Output
+	m        sync.Mutex
+	down     bool
+	attempts int
+	// timestamps of the events to reject
+	reject map[int64]bool
+	events []*formatters.EventMsg
+	msgs   []proto.Message
+}
+
+func (o *testOutput) Init(context.Context, string, map[string]interface{}, ...outputs.Option) error {
+	return nil
+}
+
+func (o *testOutput) Close() error { return nil }
+
+func (o *testOutput) WriteSync(_ context.Context, msg proto.Message, _ outputs.Meta) error {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.attempts++
+	if o.down {
+		return errors.New("unavailable")
+	}
+	o.msgs = append(o.msgs, msg)
+	return nil
+}
+
+func (o *testOutput) WriteEventSync(_ context.Context, ev *formatters.EventMsg) error {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.attempts++
+	if o.down {
+		return errors.New("unavailable")
+	}
+	if o.reject[ev.Timestamp] {
+		return outputs.Permanent(errors.New("rejected"))
+	}
+	o.events = append(o.events, ev)
+	return nil
+}
+
+func (o *testOutput) setDown(down bool) {
+	o.m.Lock()
+	defer o.m.Unlock()
+	o.down = down
+}
+
+// wait waits until cond returns true, it is called with the output locked.
+func (o *testOutput) wait(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		o.m.Lock()
+		ok := cond()
+		o.m.Unlock()
+		if ok {
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatal("timeout waiting for the output")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func newTestBuffer(t *testing.T, o outputs.Output, cfg map[string]interface{}) outputs.Output {
+	t.Helper()
+	b := NewOutput(o)
+	err := b.Init(context.Background(), "test", map[string]interface{}{"buffer": cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testEvent(ts int64) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: ts,
+		Tags:      map[string]string{"source": "router1"},
+		Values:    map[string]interface{}{"counter": ts},
+	}
+}
+
+func checkEvents(t *testing.T, evs []*formatters.EventMsg, want ...int64) {
+	t.Helper()
+	if len(evs) != len(want) {
+		t.Fatalf("got %d events, want %d", len(evs), len(want))
+	}
+	for i, ev := range evs {
+		if ev.Timestamp != want[i] {
+			t.Errorf("event %d: got timestamp %d, want %d", i, ev.Timestamp, want[i])
+		}
+	}
+}
+
+func TestBufferReplay(t *testing.T) {
+	dir := t.TempDir()
+	cfg := map[string]interface{}{
+		"directory":     dir,
+		"retry-backoff": "1ms",
+	}
+	o := &testOutput{down: true}
+	b := newTestBuffer(t, o, cfg)
+	rsp := &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true},
+	}
+	ctx := context.Background()
+	b.WriteEvent(ctx, testEvent(1))
+	b.Write(ctx, rsp, outputs.Meta{"source": "router1"})
+	b.WriteEvent(ctx, testEvent(2))
+	o.wait(t, func() bool { return o.attempts > 1 })
+	err := b.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the messages not delivered are replayed in order after a restart
+	o = new(testOutput)
+	b = newTestBuffer(t, o, cfg)
+	defer b.Close()
+	o.wait(t, func() bool { return len(o.events) == 2 && len(o.msgs) == 1 })
+	checkEvents(t, o.events, 1, 2)
+	if !proto.Equal(o.msgs[0], rsp) {
+		t.Errorf("got %v, want %v", o.msgs[0], rsp)
+	}
+}
+
+func TestBufferOutputDown(t *testing.T) {
+	o := &testOutput{down: true}
+	b := newTestBuffer(t, o, map[string]interface{}{
+		"directory":     t.TempDir(),
+		"retry-backoff": "1ms",
+	}).(*bufferedOutput)
+	defer b.Close()
+	ctx := context.Background()
+	for i := int64(1); i <= 3; i++ {
+		b.WriteEvent(ctx, testEvent(i))
+	}
+	// the first message is retried while the output is down
+	o.wait(t, func() bool { return o.attempts > 3 })
+	if msgs, _ := b.log.stats(); msgs != 3 {
+		t.Fatalf("expected a backlog of 3 messages, got %d", msgs)
+	}
+	o.setDown(false)
+	o.wait(t, func() bool { return len(o.events) == 3 })
+	checkEvents(t, o.events, 1, 2, 3)
+	if msgs, _ := b.log.stats(); msgs != 0 {
+		t.Fatalf("expected an empty backlog, got %d", msgs)
+	}
+}
+
+func TestBufferDrop(t *testing.T) {
+	o := &testOutput{down: true, reject: map[int64]bool{2: true}}
+	b := newTestBuffer(t, o, map[string]interface{}{
+		"directory":     t.TempDir(),
+		"retry-backoff": "1ms",
+		"max-age":       "100ms",
+	})
+	defer b.Close()
+	ctx := context.Background()
+	// the first event expires while the output is down
+	b.WriteEvent(ctx, testEvent(1))
+	time.Sleep(200 * time.Millisecond)
+	o.setDown(false)
+	// the second event is rejected by the output
+	b.WriteEvent(ctx, testEvent(2))
+	b.WriteEvent(ctx, testEvent(3))
+	o.wait(t, func() bool { return len(o.events) == 1 })
+	checkEvents(t, o.events, 3)
+}
+
+func TestBufferUnsupportedOutput(t *testing.T) {
+	b := NewOutput(struct{ outputs.Output }{})
+	err := b.Init(context.Background(), "test", map[string]interface{}{
+		"buffer": map[string]interface{}{"directory": t.TempDir()},
+	})
+	if err == nil {
+		t.Fatal("expected an error for an output not reporting the delivery of messages")
+	}
+}
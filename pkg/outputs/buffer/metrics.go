@@ -0,0 +1,51 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+package buffer
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var bufferBacklogMsgs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "output_buffer",
+	Name:      "backlog_messages",
+	Help:      "Number of messages waiting in the output disk buffer",
+}, []string{"output"})
+
+var bufferBacklogBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "output_buffer",
+	Name:      "backlog_bytes",
+	Help:      "Size in bytes of the messages waiting in the output disk buffer",
+}, []string{"output"})
+
+var bufferDroppedMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "output_buffer",
+	Name:      "dropped_messages_total",
+	Help:      "Number of messages dropped by the output disk buffer",
+}, []string{"output", "reason"})
+
+// registerMetrics registers the buffer metrics,
+// they are shared by all the buffered outputs.
+func registerMetrics(reg *prometheus.Registry) error {
+	for _, c := range []prometheus.Collector{
+		bufferBacklogMsgs,
+		bufferBacklogBytes,
+		bufferDroppedMsgs,
+	} {
+		err := reg.Register(c)
+		if err != nil && !errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,431 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+package buffer
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	segmentFileExt = ".seg"
+	cursorFileName = "cursor"
+	// record header: payload length (4 bytes) and payload crc32 (4 bytes)
+	recordHeaderSize = 8
+)
+
+var (
+	errClosed    = errors.New("segment log closed")
+	errCorrupted = errors.New("corrupted record")
+)
+
+type segment struct {
+	seq     uint64
+	size    int64
+	records int64
+}
+
+// segmentLog is an append only log of records stored in a directory
+// as a sequence of segment files.
+// Records are read in the order they were appended,
+// a segment file is deleted once all its records are read and committed.
+// The read position is persisted by saveCursor and when the log is closed.
+type segmentLog struct {
+	dir            string
+	maxSegmentSize int64
+	maxSize        int64
+	fsync          bool
+	// called with the number of records dropped
+	// because the log exceeded its max size.
+	onDrop func(int64)
+
+	m        *sync.Mutex
+	cond     *sync.Cond
+	closed   bool
+	segments []*segment // oldest first, records are appended to the last one
+	w        *os.File
+	// reader of the first segment
+	r          *os.File
+	readOffset int64
+	// pending is the size of the last read record,
+	// pendingSeq the segment it was read from.
+	pending    int64
+	pendingSeq uint64
+	// committed read position in the first segment
+	offset  int64
+	records int64
+	// total size of the segment files
+	size int64
+	// number of records not yet committed
+	backlog int64
+	// the read position changed since it was last persisted
+	dirty bool
+}
+
+func openSegmentLog(dir string, maxSegmentSize, maxSize int64, fsync bool) (*segmentLog, error) {
+	err := os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, err
+	}
+	l := &segmentLog{
+		dir:            dir,
+		maxSegmentSize: maxSegmentSize,
+		maxSize:        maxSize,
+		fsync:          fsync,
+		m:              new(sync.Mutex),
+	}
+	l.cond = sync.NewCond(l.m)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != segmentFileExt {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(e.Name(), segmentFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		s := &segment{seq: seq}
+		s.size, s.records, err = scanSegment(l.segmentPath(seq))
+		if err != nil {
+			return nil, err
+		}
+		l.segments = append(l.segments, s)
+		l.size += s.size
+		l.backlog += s.records
+	}
+	sort.Slice(l.segments, func(i, j int) bool {
+		return l.segments[i].seq < l.segments[j].seq
+	})
+	l.readCursor()
+	// always append to a new segment, the last segment
+	// might have been left with a partial record.
+	err = l.rotate()
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *segmentLog) segmentPath(seq uint64) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%020d%s", seq, segmentFileExt))
+}
+
+// append writes a record to the last segment, rotating it if needed.
+func (l *segmentLog) append(b []byte) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.closed {
+		return errClosed
+	}
+	recSize := int64(recordHeaderSize + len(b))
+	last := l.segments[len(l.segments)-1]
+	if last.size > 0 && last.size+recSize > l.maxSegmentSize {
+		err := l.rotate()
+		if err != nil {
+			return err
+		}
+		last = l.segments[len(l.segments)-1]
+	}
+	buf := make([]byte, recSize)
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(b)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(b))
+	copy(buf[recordHeaderSize:], b)
+	_, err := l.w.Write(buf)
+	if err != nil {
+		return err
+	}
+	if l.fsync {
+		err = l.w.Sync()
+		if err != nil {
+			return err
+		}
+	}
+	last.size += recSize
+	last.records++
+	l.size += recSize
+	l.backlog++
+	l.enforceMaxSize()
+	l.cond.Signal()
+	return nil
+}
+
+// next returns the next record to be read, blocking until one is available,
+// the log is closed or ctx is done.
+// The record is not consumed until commit is called.
+func (l *segmentLog) next(ctx context.Context) ([]byte, error) {
+	stop := context.AfterFunc(ctx, func() {
+		l.m.Lock()
+		defer l.m.Unlock()
+		l.cond.Broadcast()
+	})
+	defer stop()
+	l.m.Lock()
+	defer l.m.Unlock()
+	for {
+		if l.closed {
+			return nil, errClosed
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if l.r == nil {
+			err := l.openReader()
+			if err != nil {
+				return nil, err
+			}
+		}
+		b, err := readRecord(l.r)
+		switch {
+		case err == nil:
+			l.pending = int64(recordHeaderSize + len(b))
+			l.pendingSeq = l.segments[0].seq
+			l.readOffset += l.pending
+			return b, nil
+		case errors.Is(err, io.EOF) && len(l.segments) == 1:
+			// all records read, wait for new ones
+			l.cond.Wait()
+			continue
+		case errors.Is(err, io.EOF):
+			// segment fully read
+		default:
+			// partial or corrupted record, skip the rest of the segment
+			if l.onDrop != nil {
+				l.onDrop(l.segments[0].records - l.records)
+			}
+			if len(l.segments) == 1 {
+				err = l.rotate()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		l.backlog -= l.segments[0].records - l.records
+		err = l.removeFirst()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// commit marks the record returned by the last call to next as consumed.
+func (l *segmentLog) commit() {
+	l.m.Lock()
+	defer l.m.Unlock()
+	// the segment might have been dropped in the meantime
+	if l.pending == 0 || len(l.segments) == 0 || l.pendingSeq != l.segments[0].seq {
+		l.pending = 0
+		return
+	}
+	l.offset += l.pending
+	l.records++
+	l.backlog--
+	l.pending = 0
+	l.dirty = true
+}
+
+// saveCursor persists the read position if it changed since it was last persisted.
+func (l *segmentLog) saveCursor() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.closed || !l.dirty || len(l.segments) == 0 {
+		return nil
+	}
+	return l.writeCursor()
+}
+
+// stats returns the number of records and bytes not yet committed.
+func (l *segmentLog) stats() (int64, int64) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.backlog, l.size - l.offset
+}
+
+// Close persists the read position and closes the segment files.
+func (l *segmentLog) Close() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	l.cond.Broadcast()
+	var errs []error
+	if len(l.segments) > 0 {
+		errs = append(errs, l.writeCursor())
+	}
+	if l.r != nil {
+		errs = append(errs, l.r.Close())
+	}
+	if l.w != nil {
+		errs = append(errs, l.w.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// rotate closes the current write segment and creates a new one.
+func (l *segmentLog) rotate() error {
+	if l.w != nil {
+		err := l.w.Close()
+		if err != nil {
+			return err
+		}
+	}
+	var seq uint64 = 1
+	if len(l.segments) > 0 {
+		seq = l.segments[len(l.segments)-1].seq + 1
+	}
+	f, err := os.OpenFile(l.segmentPath(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	l.w = f
+	l.segments = append(l.segments, &segment{seq: seq})
+	return nil
+}
+
+// enforceMaxSize drops the oldest segments until the log size
+// is below its max size. The segment being written is never dropped.
+func (l *segmentLog) enforceMaxSize() {
+	for l.maxSize > 0 && l.size > l.maxSize && len(l.segments) > 1 {
+		dropped := l.segments[0].records - l.records
+		l.backlog -= dropped
+		if l.onDrop != nil {
+			l.onDrop(dropped)
+		}
+		err := l.removeFirst()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// removeFirst deletes the first segment file and resets the read position.
+func (l *segmentLog) removeFirst() error {
+	if l.r != nil {
+		l.r.Close()
+		l.r = nil
+	}
+	s := l.segments[0]
+	l.segments = l.segments[1:]
+	l.size -= s.size
+	l.offset, l.readOffset, l.records = 0, 0, 0
+	l.pending = 0
+	l.dirty = true
+	err := os.Remove(l.segmentPath(s.seq))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *segmentLog) openReader() error {
+	f, err := os.Open(l.segmentPath(l.segments[0].seq))
+	if err != nil {
+		return err
+	}
+	_, err = f.Seek(l.offset, io.SeekStart)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.r = f
+	l.readOffset = l.offset
+	return nil
+}
+
+func (l *segmentLog) readCursor() {
+	b, err := os.ReadFile(filepath.Join(l.dir, cursorFileName))
+	if err != nil || len(l.segments) == 0 {
+		return
+	}
+	var seq uint64
+	var offset, records int64
+	_, err = fmt.Sscanf(string(b), "%d %d %d", &seq, &offset, &records)
+	if err != nil || seq != l.segments[0].seq || offset > l.segments[0].size {
+		return
+	}
+	l.offset = offset
+	l.records = records
+	l.backlog -= records
+}
+
+// writeCursor writes the read position to a temporary file
+// renamed to the cursor file, so that a crash never leaves a partial cursor.
+func (l *segmentLog) writeCursor() error {
+	path := filepath.Join(l.dir, cursorFileName)
+	err := os.WriteFile(path+".tmp",
+		[]byte(fmt.Sprintf("%d %d %d\n", l.segments[0].seq, l.offset, l.records)),
+		0o640)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(path+".tmp", path)
+	if err != nil {
+		return err
+	}
+	l.dirty = false
+	return nil
+}
+
+// readRecord reads a single record from r.
+// It returns io.EOF if r is at the end of the last complete record.
+func readRecord(r io.Reader) ([]byte, error) {
+	hdr := make([]byte, recordHeaderSize)
+	_, err := io.ReadFull(r, hdr)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, binary.BigEndian.Uint32(hdr[0:4]))
+	_, err = io.ReadFull(r, b)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(b) != binary.BigEndian.Uint32(hdr[4:8]) {
+		return nil, errCorrupted
+	}
+	return b, nil
+}
+
+// scanSegment returns the size of a segment file
+// and the number of complete records it holds.
+func scanSegment(path string) (int64, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	var records int64
+	for {
+		_, err = readRecord(f)
+		if err != nil {
+			break
+		}
+		records++
+	}
+	return fi.Size(), records, nil
+}
@@ -0,0 +1,155 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+package buffer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func readN(t *testing.T, l *segmentLog, n int) []string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b, err := l.next(ctx)
+		if err != nil {
+			t.Fatalf("failed to read record %d: %v", i, err)
+		}
+		l.commit()
+		res = append(res, string(b))
+	}
+	return res
+}
+
+func appendN(t *testing.T, l *segmentLog, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		err := l.append([]byte(fmt.Sprintf("record%03d", i)))
+		if err != nil {
+			t.Fatalf("failed to append record %d: %v", i, err)
+		}
+	}
+}
+
+func TestSegmentLogOrder(t *testing.T) {
+	// small segments to force rotations
+	l, err := openSegmentLog(t.TempDir(), 64, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	appendN(t, l, 0, 20)
+	if len(l.segments) < 2 {
+		t.Fatalf("expected several segments, got %d", len(l.segments))
+	}
+	got := readN(t, l, 20)
+	for i, r := range got {
+		if exp := fmt.Sprintf("record%03d", i); r != exp {
+			t.Fatalf("record %d: expected %q, got %q", i, exp, r)
+		}
+	}
+	if msgs, _ := l.stats(); msgs != 0 {
+		t.Fatalf("expected an empty backlog, got %d", msgs)
+	}
+	// next blocks until a record is appended
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		l.append([]byte("late"))
+	}()
+	got = readN(t, l, 1)
+	if got[0] != "late" {
+		t.Fatalf("expected %q, got %q", "late", got[0])
+	}
+}
+
+func TestSegmentLogReopen(t *testing.T) {
+	dir := t.TempDir()
+	l, err := openSegmentLog(dir, 64, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendN(t, l, 0, 10)
+	readN(t, l, 4)
+	err = l.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err = openSegmentLog(dir, 64, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if msgs, _ := l.stats(); msgs != 6 {
+		t.Fatalf("expected a backlog of 6 records, got %d", msgs)
+	}
+	appendN(t, l, 10, 12)
+	got := readN(t, l, 8)
+	for i, r := range got {
+		if exp := fmt.Sprintf("record%03d", i+4); r != exp {
+			t.Fatalf("record %d: expected %q, got %q", i, exp, r)
+		}
+	}
+}
+
+func TestSegmentLogSaveCursor(t *testing.T) {
+	dir := t.TempDir()
+	l, err := openSegmentLog(dir, 64, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendN(t, l, 0, 10)
+	readN(t, l, 3)
+	err = l.saveCursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the records read after the last saved cursor are replayed
+	// if the log is not closed, e.g: on a crash.
+	readN(t, l, 2)
+	l.r.Close()
+	l.w.Close()
+	l, err = openSegmentLog(dir, 64, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if msgs, _ := l.stats(); msgs != 7 {
+		t.Fatalf("expected a backlog of 7 records, got %d", msgs)
+	}
+	got := readN(t, l, 7)
+	if got[0] != "record003" {
+		t.Fatalf("expected %q, got %q", "record003", got[0])
+	}
+}
+
+func TestSegmentLogMaxSize(t *testing.T) {
+	l, err := openSegmentLog(t.TempDir(), 64, 128, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	var dropped int64
+	l.onDrop = func(n int64) { dropped += n }
+	appendN(t, l, 0, 30)
+	msgs, size := l.stats()
+	if size > 128 {
+		t.Fatalf("expected the buffer size to be at most 128, got %d", size)
+	}
+	if dropped == 0 || dropped+msgs != 30 {
+		t.Fatalf("unexpected dropped=%d backlog=%d", dropped, msgs)
+	}
+	// the oldest records are dropped
+	got := readN(t, l, int(msgs))
+	if exp := fmt.Sprintf("record%03d", dropped); got[0] != exp {
+		t.Fatalf("expected %q, got %q", exp, got[0])
+	}
+}
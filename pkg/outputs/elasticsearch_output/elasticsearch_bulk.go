@@ -0,0 +1,287 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package elasticsearch_output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const userAgent = "gNMIc elasticsearch"
+
+// bulkItem is a document to be indexed in index.
+type bulkItem struct {
+	index string
+	doc   []byte
+}
+
+// size returns the number of bytes the item adds to a bulk request body.
+func (i *bulkItem) size() int {
+	return len(i.index) + len(i.doc) + 32
+}
+
+type bulkClient struct {
+	client  *http.Client
+	opType  string
+	headers map[string]string
+	auth    func(*http.Request)
+
+	m         *sync.Mutex
+	addresses []string
+	next      int
+}
+
+// bulkResponse is the subset of the _bulk API response used to find the rejected items.
+type bulkResponse struct {
+	Errors bool                                 `json:"errors"`
+	Items  []map[string]*bulkResponseItemStatus `json:"items"`
+}
+
+type bulkResponseItemStatus struct {
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error,omitempty"`
+}
+
+func newBulkClient(cfg *config) (*bulkClient, error) {
+	c := &http.Client{
+		Timeout: cfg.Timeout,
+	}
+	if cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			cfg.TLS.CaFile,
+			cfg.TLS.CertFile,
+			cfg.TLS.KeyFile,
+			"",
+			cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		c.Transport = &http.Transport{
+			TLSClientConfig: tlsCfg,
+		}
+	}
+	bc := &bulkClient{
+		client:    c,
+		opType:    cfg.OpType,
+		headers:   cfg.Headers,
+		m:         new(sync.Mutex),
+		addresses: make([]string, 0, len(cfg.Addresses)),
+	}
+	for _, addr := range cfg.Addresses {
+		bc.addresses = append(bc.addresses, strings.TrimSuffix(addr, "/")+"/_bulk")
+	}
+	switch {
+	case cfg.APIKey != "":
+		bc.auth = func(r *http.Request) {
+			r.Header.Set("Authorization", "ApiKey "+cfg.APIKey)
+		}
+	case cfg.Username != "":
+		bc.auth = func(r *http.Request) {
+			r.SetBasicAuth(cfg.Username, cfg.Password)
+		}
+	}
+	return bc, nil
+}
+
+// address returns the bulk API URL to use for the next request.
+func (c *bulkClient) address() string {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.addresses[c.next]
+}
+
+// failover moves to the next configured address.
+func (c *bulkClient) failover() {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.next = (c.next + 1) % len(c.addresses)
+}
+
+// body builds the NDJSON body of a bulk request.
+func (c *bulkClient) body(items []*bulkItem) *bytes.Buffer {
+	b := new(bytes.Buffer)
+	for _, item := range items {
+		b.WriteString(`{"`)
+		b.WriteString(c.opType)
+		b.WriteString(`":{"_index":`)
+		idx, _ := json.Marshal(item.index)
+		b.Write(idx)
+		b.WriteString("}}\n")
+		b.Write(item.doc)
+		b.WriteByte('\n')
+	}
+	return b
+}
+
+// bulk sends the items in a single _bulk request.
+// If the whole request fails with a transient error, all items are returned to be retried.
+// Otherwise, the items individually rejected with a 429 status are returned to be retried,
+// and the number of items rejected for any other reason is returned as failed.
+func (c *bulkClient) bulk(ctx context.Context, items []*bulkItem) ([]*bulkItem, int, error) {
+	addr := c.address()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, addr, c.body(items))
+	if err != nil {
+		return nil, len(items), outputs.Permanent(fmt.Errorf("failed to create HTTP request: %w", err))
+	}
+	httpReq.Header.Set("Content-Type", "application/x-ndjson")
+	httpReq.Header.Set("User-Agent", userAgent)
+	if c.auth != nil {
+		c.auth(httpReq)
+	}
+	for k, v := range c.headers {
+		httpReq.Header.Set(k, v)
+	}
+	rsp, err := c.client.Do(httpReq)
+	if err != nil {
+		c.failover()
+		return items, 0, err
+	}
+	defer rsp.Body.Close()
+	body, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return items, 0, err
+	}
+	if rsp.StatusCode >= 300 {
+		err = fmt.Errorf("bulk request failed, code=%d, body=%s", rsp.StatusCode, string(body))
+		switch {
+		case rsp.StatusCode == http.StatusTooManyRequests:
+			return items, 0, err
+		case rsp.StatusCode >= 500:
+			c.failover()
+			return items, 0, err
+		}
+		return nil, len(items), outputs.Permanent(err)
+	}
+	bulkRsp := new(bulkResponse)
+	err = json.Unmarshal(body, bulkRsp)
+	if err != nil {
+		return nil, 0, outputs.Permanent(fmt.Errorf("failed to parse bulk response: %w", err))
+	}
+	if !bulkRsp.Errors {
+		return nil, 0, nil
+	}
+	if len(bulkRsp.Items) != len(items) {
+		return nil, len(items), outputs.Permanent(fmt.Errorf("unexpected bulk response: got %d items, expected %d", len(bulkRsp.Items), len(items)))
+	}
+	var retry []*bulkItem
+	var failed int
+	var firstErr json.RawMessage
+	for i, ri := range bulkRsp.Items {
+		for _, st := range ri {
+			switch {
+			case st.Status == http.StatusTooManyRequests:
+				retry = append(retry, items[i])
+			case st.Status >= 300:
+				failed++
+				if firstErr == nil {
+					firstErr = st.Error
+				}
+			}
+		}
+	}
+	if failed > 0 {
+		err = fmt.Errorf("%d documents rejected, first error: %s", failed, string(firstErr))
+	}
+	return retry, failed, err
+}
+
+func (c *bulkClient) close() {
+	c.client.CloseIdleConnections()
+}
+
+// writer batches the documents and sends them when the batch size,
+// the batch bytes limit or the flush interval is reached.
+func (e *elasticsearchOutput) writer(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]*bulkItem, 0, e.cfg.BatchSize)
+	batchBytes := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-e.itemsCh:
+			batch = append(batch, item)
+			batchBytes += item.size()
+			if len(batch) < e.cfg.BatchSize && batchBytes < e.cfg.BatchBytes {
+				continue
+			}
+			if e.cfg.Debug {
+				e.logger.Printf("batch size reached, flushing")
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+			if e.cfg.Debug {
+				e.logger.Printf("flush interval reached, flushing")
+			}
+		}
+		e.flush(ctx, batch)
+		batch = make([]*bulkItem, 0, e.cfg.BatchSize)
+		batchBytes = 0
+	}
+}
+
+// flush sends a batch of documents, retrying the transient failures
+// with an exponential backoff up to max-retries times.
+func (e *elasticsearchOutput) flush(ctx context.Context, batch []*bulkItem) {
+	policy := outputs.RetryPolicy{
+		MaxRetries:  e.cfg.MaxRetries,
+		Backoff:     e.cfg.RetryBackoff,
+		Exponential: true,
+	}
+	err := policy.Do(ctx, func() error {
+		start := time.Now()
+		retry, failed, err := e.client.bulk(ctx, batch)
+		sent := len(batch) - len(retry) - failed
+		if sent > 0 {
+			elasticsearchSendDuration.Set(float64(time.Since(start).Nanoseconds()))
+			elasticsearchNumberOfSentDocs.Add(float64(sent))
+			if e.cfg.Debug {
+				e.logger.Printf("indexed %d documents", sent)
+			}
+		}
+		if failed > 0 {
+			elasticsearchNumberOfFailedDocs.WithLabelValues("rejected").Add(float64(failed))
+		}
+		if err != nil {
+			e.logger.Printf("bulk request with %d documents failed: %v", len(batch), err)
+			if outputs.IsPermanent(err) {
+				return err
+			}
+		}
+		if len(retry) == 0 {
+			return nil
+		}
+		if e.cfg.Debug {
+			e.logger.Printf("%d documents to retry", len(retry))
+		}
+		batch = retry
+		if err == nil {
+			err = fmt.Errorf("%d documents to retry", len(retry))
+		}
+		return err
+	})
+	if err != nil && ctx.Err() == nil && !outputs.IsPermanent(err) {
+		elasticsearchNumberOfFailedDocs.WithLabelValues("max_retries").Add(float64(len(batch)))
+	}
+}
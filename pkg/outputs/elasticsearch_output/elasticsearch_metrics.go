@@ -0,0 +1,58 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package elasticsearch_output
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	namespace = "gnmic"
+	subsystem = "elasticsearch_output"
+)
+
+var elasticsearchNumberOfSentDocs = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_sent_documents_total",
+	Help:      "Number of documents successfully indexed by gnmic elasticsearch output",
+})
+
+var elasticsearchNumberOfFailedDocs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_failed_documents_total",
+	Help:      "Number of documents gnmic elasticsearch output failed to index",
+}, []string{"reason"})
+
+var elasticsearchSendDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "bulk_request_duration_ns",
+	Help:      "gnmic elasticsearch output bulk request duration in ns",
+})
+
+func initMetrics() {
+	elasticsearchNumberOfSentDocs.Add(0)
+	elasticsearchNumberOfFailedDocs.WithLabelValues("").Add(0)
+	elasticsearchSendDuration.Set(0)
+}
+
+func registerMetrics(reg *prometheus.Registry) error {
+	initMetrics()
+	var err error
+	if err = reg.Register(elasticsearchNumberOfSentDocs); err != nil {
+		return err
+	}
+	if err = reg.Register(elasticsearchNumberOfFailedDocs); err != nil {
+		return err
+	}
+	if err = reg.Register(elasticsearchSendDuration); err != nil {
+		return err
+	}
+	return nil
+}
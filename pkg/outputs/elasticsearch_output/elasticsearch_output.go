@@ -0,0 +1,407 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package elasticsearch_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType           = "elasticsearch"
+	loggingPrefix        = "[elasticsearch_output:%s] "
+	defaultIndexTemplate = `gnmic-{{ .Name }}-{{ .Time.Format "2006.01.02" }}`
+	defaultTimeout       = 10 * time.Second
+	defaultFlushInterval = 5 * time.Second
+	defaultBatchSize     = 1000
+	defaultBatchBytes    = 5 * 1024 * 1024
+	defaultBufferSize    = 1000
+	defaultMaxRetries    = 3
+	defaultRetryBackoff  = 500 * time.Millisecond
+	defaultNumWorkers    = 1
+	opTypeIndex          = "index"
+	opTypeCreate         = "create"
+)
+
+func init() {
+	outputs.Register(outputType,
+		func() outputs.Output {
+			return &elasticsearchOutput{
+				cfg:       &config{},
+				logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+				eventChan: make(chan *formatters.EventMsg),
+				msgChan:   make(chan *outputs.ProtoMsg),
+			}
+		})
+}
+
+type elasticsearchOutput struct {
+	cfg    *config
+	logger *log.Logger
+	dryRun bool
+
+	eventChan chan *formatters.EventMsg
+	msgChan   chan *outputs.ProtoMsg
+	itemsCh   chan *bulkItem
+	client    *bulkClient
+
+	evps      []formatters.EventProcessor
+	indexTpl  *template.Template
+	targetTpl *template.Template
+	cfn       context.CancelFunc
+}
+
+type config struct {
+	Name          string            `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Addresses     []string          `mapstructure:"addresses,omitempty" json:"addresses,omitempty"`
+	Index         string            `mapstructure:"index,omitempty" json:"index,omitempty"`
+	OpType        string            `mapstructure:"op-type,omitempty" json:"op-type,omitempty"`
+	Username      string            `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password      string            `mapstructure:"password,omitempty" json:"-"`
+	APIKey        string            `mapstructure:"api-key,omitempty" json:"-"`
+	Headers       map[string]string `mapstructure:"headers,omitempty" json:"headers,omitempty"`
+	TLS           *types.TLSConfig  `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Timeout       time.Duration     `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	FlushInterval time.Duration     `mapstructure:"flush-interval,omitempty" json:"flush-interval,omitempty"`
+	BatchSize     int               `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	BatchBytes    int               `mapstructure:"batch-bytes,omitempty" json:"batch-bytes,omitempty"`
+	BufferSize    int               `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	MaxRetries    int               `mapstructure:"max-retries,omitempty" json:"max-retries,omitempty"`
+	RetryBackoff  time.Duration     `mapstructure:"retry-backoff,omitempty" json:"retry-backoff,omitempty"`
+	Debug         bool              `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	//
+	AddTarget       string   `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate  string   `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors []string `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers      int      `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	EnableMetrics   bool     `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+}
+
+// document is the indexed representation of an event message,
+// it is also the input of the index name template.
+type document struct {
+	Time      time.Time              `json:"@timestamp"`
+	Name      string                 `json:"name,omitempty"`
+	Timestamp int64                  `json:"timestamp,omitempty"`
+	Tags      map[string]string      `json:"tags,omitempty"`
+	Values    map[string]interface{} `json:"values,omitempty"`
+	Deletes   []string               `json:"deletes,omitempty"`
+}
+
+func (e *elasticsearchOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, e.cfg)
+	if err != nil {
+		return err
+	}
+	if len(e.cfg.Addresses) == 0 {
+		return errors.New("missing addresses field")
+	}
+	for _, addr := range e.cfg.Addresses {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" {
+			return fmt.Errorf("address %q is missing the URL scheme", addr)
+		}
+	}
+	if e.cfg.Name == "" {
+		e.cfg.Name = name
+	}
+	e.logger.SetPrefix(fmt.Sprintf(loggingPrefix, e.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(e); err != nil {
+			return err
+		}
+	}
+
+	if e.cfg.TargetTemplate == "" {
+		e.targetTpl = outputs.DefaultTargetTemplate
+	} else if e.cfg.AddTarget != "" {
+		e.targetTpl, err = gtemplate.CreateTemplate("target-template", e.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		e.targetTpl = e.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+
+	err = e.setDefaults()
+	if err != nil {
+		return err
+	}
+	e.indexTpl, err = gtemplate.CreateTemplate("index", e.cfg.Index)
+	if err != nil {
+		return fmt.Errorf("invalid index template: %w", err)
+	}
+	e.client, err = newBulkClient(e.cfg)
+	if err != nil {
+		return err
+	}
+	if e.dryRun {
+		return nil
+	}
+	e.itemsCh = make(chan *bulkItem, e.cfg.BufferSize)
+
+	ctx, e.cfn = context.WithCancel(ctx)
+	for i := 0; i < e.cfg.NumWorkers; i++ {
+		go e.worker(ctx)
+	}
+	go e.writer(ctx)
+	e.logger.Printf("initialized elasticsearch output %s: %s", e.cfg.Name, e.String())
+	return nil
+}
+
+func (e *elasticsearchOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case e.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if e.cfg.Debug {
+			e.logger.Printf("writing expired after %s", e.cfg.Timeout)
+		}
+		return
+	}
+}
+
+func (e *elasticsearchOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range e.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case e.eventChan <- pev:
+			}
+		}
+	}
+}
+
+func (e *elasticsearchOutput) Close() error {
+	if e.cfn == nil {
+		return nil
+	}
+	e.cfn()
+	if e.client != nil {
+		e.client.close()
+	}
+	return nil
+}
+
+func (e *elasticsearchOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !e.cfg.EnableMetrics {
+		return
+	}
+	if reg == nil {
+		e.logger.Printf("ERR: output metrics enabled but main registry is not initialized, enable main metrics under `api-server`")
+		return
+	}
+	if err := registerMetrics(reg); err != nil {
+		e.logger.Printf("failed to register metric: %v", err)
+	}
+}
+
+func (e *elasticsearchOutput) String() string {
+	b, err := json.Marshal(e.cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (e *elasticsearchOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && e.logger != nil {
+		e.logger.SetOutput(logger.Writer())
+		e.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (e *elasticsearchOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	e.evps, err = formatters.MakeEventProcessors(
+		logger,
+		e.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (e *elasticsearchOutput) SetName(name string) {
+	if e.cfg.Name == "" {
+		e.cfg.Name = name
+	}
+}
+
+func (e *elasticsearchOutput) SetClusterName(_ string) {}
+
+func (e *elasticsearchOutput) SetDryRun(dryRun bool) { e.dryRun = dryRun }
+
+func (e *elasticsearchOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (e *elasticsearchOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-e.eventChan:
+			e.workerHandleEvent(ctx, ev)
+		case m := <-e.msgChan:
+			e.workerHandleProto(ctx, m)
+		}
+	}
+}
+
+func (e *elasticsearchOutput) workerHandleProto(ctx context.Context, m *outputs.ProtoMsg) {
+	pmsg := m.GetMsg()
+	switch pmsg := pmsg.(type) {
+	case *gnmi.SubscribeResponse:
+		meta := m.GetMeta()
+		measName := "default"
+		if subName, ok := meta["subscription-name"]; ok {
+			measName = subName
+		}
+		var err error
+		pmsg, err = outputs.AddSubscriptionTarget(pmsg, m.GetMeta(), e.cfg.AddTarget, e.targetTpl)
+		if err != nil {
+			e.logger.Printf("failed to add target to the response: %v", err)
+		}
+		events, err := formatters.ResponseToEventMsgs(measName, pmsg, meta, e.evps...)
+		if err != nil {
+			e.logger.Printf("failed to convert message to event: %v", err)
+			return
+		}
+		for _, ev := range events {
+			e.workerHandleEvent(ctx, ev)
+		}
+	}
+}
+
+func (e *elasticsearchOutput) workerHandleEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if e.cfg.Debug {
+		e.logger.Printf("got event to buffer: %+v", ev)
+	}
+	item, err := e.bulkItem(ev)
+	if err != nil {
+		elasticsearchNumberOfFailedDocs.WithLabelValues("marshal_error").Inc()
+		e.logger.Printf("failed to build document: %v", err)
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case e.itemsCh <- item:
+	}
+}
+
+// bulkItem builds the document from the event and
+// renders the index name template using it.
+func (e *elasticsearchOutput) bulkItem(ev *formatters.EventMsg) (*bulkItem, error) {
+	doc := &document{
+		Time:      time.Unix(0, ev.Timestamp).UTC(),
+		Name:      ev.Name,
+		Timestamp: ev.Timestamp,
+		Tags:      ev.Tags,
+		Values:    ev.Values,
+		Deletes:   ev.Deletes,
+	}
+	sb := new(strings.Builder)
+	err := e.indexTpl.Execute(sb, doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute index template: %w", err)
+	}
+	index := strings.ToLower(strings.TrimSpace(sb.String()))
+	if index == "" {
+		return nil, errors.New("index template rendered an empty index name")
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &bulkItem{index: index, doc: b}, nil
+}
+
+func (e *elasticsearchOutput) setDefaults() error {
+	switch e.cfg.OpType {
+	case "":
+		e.cfg.OpType = opTypeIndex
+	case opTypeIndex, opTypeCreate:
+	default:
+		return fmt.Errorf("unknown op-type %q, expected %q or %q", e.cfg.OpType, opTypeIndex, opTypeCreate)
+	}
+	if e.cfg.APIKey != "" && e.cfg.Username != "" {
+		return errors.New("api-key and username are mutually exclusive")
+	}
+	if e.cfg.Index == "" {
+		e.cfg.Index = defaultIndexTemplate
+	}
+	if e.cfg.Timeout <= 0 {
+		e.cfg.Timeout = defaultTimeout
+	}
+	if e.cfg.FlushInterval <= 0 {
+		e.cfg.FlushInterval = defaultFlushInterval
+	}
+	if e.cfg.BatchSize <= 0 {
+		e.cfg.BatchSize = defaultBatchSize
+	}
+	if e.cfg.BatchBytes <= 0 {
+		e.cfg.BatchBytes = defaultBatchBytes
+	}
+	if e.cfg.BufferSize <= 0 {
+		e.cfg.BufferSize = defaultBufferSize
+	}
+	e.cfg.MaxRetries = outputs.MaxRetries(e.cfg.MaxRetries, defaultMaxRetries)
+	if e.cfg.RetryBackoff <= 0 {
+		e.cfg.RetryBackoff = defaultRetryBackoff
+	}
+	if e.cfg.NumWorkers <= 0 {
+		e.cfg.NumWorkers = defaultNumWorkers
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package elasticsearch_output
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+// testServer is a fake _bulk endpoint that records the received documents
+// and rejects the first attempt of each document with a 429 status.
+type testServer struct {
+	m       sync.Mutex
+	seen    map[string]int
+	indexed map[string][]string // index name to document names
+	auth    string
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.auth = r.Header.Get("Authorization")
+	items := make([]map[string]map[string]int, 0)
+	sc := bufio.NewScanner(r.Body)
+	hasErrors := false
+	for sc.Scan() {
+		action := make(map[string]map[string]string)
+		if err := json.Unmarshal(sc.Bytes(), &action); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !sc.Scan() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		doc := new(document)
+		if err := json.Unmarshal(sc.Bytes(), doc); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		index := action["index"]["_index"]
+		s.seen[doc.Name]++
+		if s.seen[doc.Name] == 1 {
+			hasErrors = true
+			items = append(items, map[string]map[string]int{"index": {"status": http.StatusTooManyRequests}})
+			continue
+		}
+		s.indexed[index] = append(s.indexed[index], doc.Name)
+		items = append(items, map[string]map[string]int{"index": {"status": http.StatusCreated}})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": hasErrors,
+		"items":  items,
+	})
+}
+
+func TestElasticsearchOutput(t *testing.T) {
+	s := &testServer{
+		seen:    make(map[string]int),
+		indexed: make(map[string][]string),
+	}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	o := outputs.Outputs[outputType]()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := o.Init(ctx, "es", map[string]interface{}{
+		"addresses":      []string{srv.URL},
+		"index":          `gnmic-{{ index .Tags "subscription-name" }}-{{ .Time.Format "2006.01.02" }}`,
+		"api-key":        "secret",
+		"batch-size":     2,
+		"flush-interval": "100ms",
+		"retry-backoff":  "10ms",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC).UnixNano()
+	for _, name := range []string{"ev1", "ev2", "ev3"} {
+		o.WriteEvent(ctx, &formatters.EventMsg{
+			Name:      name,
+			Timestamp: ts,
+			Tags:      map[string]string{"subscription-name": "Intf"},
+			Values:    map[string]interface{}{"/interface/oper-state": "up"},
+		})
+	}
+	deadline := time.After(5 * time.Second)
+	for {
+		s.m.Lock()
+		n := len(s.indexed["gnmic-intf-2024.05.01"])
+		auth := s.auth
+		s.m.Unlock()
+		if n == 3 {
+			if auth != "ApiKey secret" {
+				t.Errorf("unexpected Authorization header: %q", auth)
+			}
+			return
+		}
+		select {
+		case <-deadline:
+			s.m.Lock()
+			defer s.m.Unlock()
+			t.Fatalf("documents not indexed: %v", s.indexed)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestBulkRequestFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		wantRetry  int
+		wantFailed int
+	}{
+		{name: "too_many_requests", status: http.StatusTooManyRequests, wantRetry: 2},
+		{name: "server_error", status: http.StatusServiceUnavailable, wantRetry: 2},
+		{name: "bad_request", status: http.StatusBadRequest, wantFailed: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			c, err := newBulkClient(&config{
+				Addresses: []string{srv.URL},
+				OpType:    opTypeIndex,
+				Timeout:   time.Second,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			items := []*bulkItem{
+				{index: "idx", doc: []byte(`{}`)},
+				{index: "idx", doc: []byte(`{}`)},
+			}
+			retry, failed, err := c.bulk(context.Background(), items)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if len(retry) != tt.wantRetry || failed != tt.wantFailed {
+				t.Errorf("got retry=%d failed=%d, expected retry=%d failed=%d", len(retry), failed, tt.wantRetry, tt.wantFailed)
+			}
+		})
+	}
+}
@@ -16,9 +16,11 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 	"text/template"
 	"time"
 
+	"github.com/openconfig/gnmi/proto/gnmi"
 	"golang.org/x/sync/semaphore"
 	"google.golang.org/protobuf/proto"
 
@@ -33,6 +35,7 @@ import (
 
 const (
 	defaultFormat           = "json"
+	defaultParquetCodec     = "snappy"
 	defaultWriteConcurrency = 1000
 	defaultSeparator        = "\n"
 	loggingPrefix           = "[file_output:%s] "
@@ -52,9 +55,14 @@ type File struct {
 	cfg    *Config
 	file   file
 	logger *log.Logger
+	dryRun bool
 	mo     *formatters.MarshalOptions
 	sem    *semaphore.Weighted
 	evps   []formatters.EventProcessor
+	// set if the format is one of the table formats
+	tables *tableWriter
+	// set if the filename is a template
+	pool *filePool
 
 	targetTpl *template.Template
 	msgTpl    *template.Template
@@ -79,6 +87,10 @@ type Config struct {
 	Debug              bool            `mapstructure:"debug,omitempty"`
 	CalculateLatency   bool            `mapstructure:"calculate-latency,omitempty"`
 	Rotation           *rotationConfig `mapstructure:"rotation,omitempty"`
+	RowGroupSize       int             `mapstructure:"row-group-size,omitempty"`
+	ParquetCompression string          `mapstructure:"parquet-compression,omitempty"`
+	IdleTimeout        time.Duration   `mapstructure:"idle-timeout,omitempty"`
+	MaxOpenFiles       int             `mapstructure:"max-open-files,omitempty"`
 }
 
 type file interface {
@@ -143,6 +155,16 @@ func (f *File) Init(ctx context.Context, name string, cfg map[string]interface{}
 	if f.cfg.FileName == "" && f.cfg.FileType == "" {
 		f.cfg.FileType = "stdout"
 	}
+	newTableEnc, isTable := tableEncoders[f.cfg.Format]
+	if isTable && f.cfg.FileType != "" {
+		return fmt.Errorf("format %q requires a filename", f.cfg.Format)
+	}
+	if isTable && strings.Contains(f.cfg.FileName, "{{") {
+		return fmt.Errorf("format %q does not support a templated filename", f.cfg.Format)
+	}
+	if f.cfg.Format == "parquet" && f.cfg.ParquetCompression == "" {
+		f.cfg.ParquetCompression = defaultParquetCodec
+	}
 
 	switch f.cfg.FileType {
 	case "stdout":
@@ -150,9 +172,34 @@ func (f *File) Init(ctx context.Context, name string, cfg map[string]interface{}
 	case "stderr":
 		f.file = os.Stderr
 	default:
+		if isTable {
+			// validate the encoder config
+			_, err = newTableEnc(io.Discard, newTableSchema(), f.cfg)
+			if err != nil {
+				return err
+			}
+			f.tables = newTableWriter(f.cfg, newTableEnc, f.logger)
+			f.file = f.tables
+			break
+		}
+		if strings.Contains(f.cfg.FileName, "{{") {
+			tpl, err := gtemplate.CreateTemplate(fmt.Sprintf("%s-filename", name), f.cfg.FileName)
+			if err != nil {
+				return fmt.Errorf("invalid filename template: %w", err)
+			}
+			if f.dryRun {
+				break
+			}
+			f.pool = newFilePool(ctx, f.cfg, tpl.Funcs(outputs.TemplateFuncs), f.logger)
+			f.file = f.pool
+			break
+		}
+		if f.dryRun {
+			break
+		}
 	CRFILE:
 		if f.cfg.Rotation != nil {
-			f.file = newRotatingFile(f.cfg)
+			f.file = newRotatingFile(f.cfg.FileName, f.cfg.Rotation)
 		} else {
 			f.file, err = os.OpenFile(f.cfg.FileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 			if err != nil {
@@ -209,6 +256,9 @@ func (f *File) Init(ctx context.Context, name string, cfg map[string]interface{}
 		f.msgTpl = f.msgTpl.Funcs(outputs.TemplateFuncs)
 	}
 
+	if f.dryRun {
+		return nil
+	}
 	f.logger.Printf("initialized file output: %s", f.String())
 	go func() {
 		<-ctx.Done()
@@ -237,6 +287,10 @@ func (f *File) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta)
 	if err != nil {
 		f.logger.Printf("failed to add target to the response: %v", err)
 	}
+	if f.tables != nil {
+		f.writeTable(rsp, meta)
+		return
+	}
 	bb, err := outputs.Marshal(rsp, meta, f.mo, f.cfg.SplitEvents, f.evps...)
 	if err != nil {
 		if f.cfg.Debug {
@@ -248,6 +302,15 @@ func (f *File) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta)
 	if len(bb) == 0 {
 		return
 	}
+	w, release, err := f.writer(meta)
+	if err != nil {
+		if f.cfg.Debug {
+			f.logger.Printf("failed to get file: %v", err)
+		}
+		numberOfFailWriteMsgs.WithLabelValues(f.file.Name(), "file_error").Inc()
+		return
+	}
+	defer release()
 	for _, b := range bb {
 		if f.msgTpl != nil {
 			b, err = outputs.ExecTemplate(b, f.msgTpl)
@@ -260,10 +323,10 @@ func (f *File) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta)
 			}
 		}
 
-		n, err := f.file.Write(append(b, []byte(f.cfg.Separator)...))
+		n, err := w.Write(append(b, []byte(f.cfg.Separator)...))
 		if err != nil {
 			if f.cfg.Debug {
-				f.logger.Printf("failed to write to file '%s': %v", f.file.Name(), err)
+				f.logger.Printf("failed to write to file '%s': %v", w.Name(), err)
 			}
 			numberOfFailWriteMsgs.WithLabelValues(f.file.Name(), "write_error").Inc()
 			return
@@ -283,6 +346,42 @@ func (f *File) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
 	for _, proc := range f.evps {
 		evs = proc.Apply(evs...)
 	}
+	if f.tables != nil {
+		f.writeEvents(evs)
+		return
+	}
+	if f.pool == nil {
+		f.writeEventMsgs(f.file, evs)
+		return
+	}
+	// group the events by file
+	names := make([]string, 0, 1)
+	files := make(map[string][]*formatters.EventMsg)
+	for _, ev := range evs {
+		name, err := f.pool.fileName(ev.Tags)
+		if err != nil {
+			f.logger.Printf("failed to get file: %v", err)
+			numberOfFailWriteMsgs.WithLabelValues(f.file.Name(), "file_error").Inc()
+			continue
+		}
+		if _, ok := files[name]; !ok {
+			names = append(names, name)
+		}
+		files[name] = append(files[name], ev)
+	}
+	for _, name := range names {
+		w, err := f.pool.get(name)
+		if err != nil {
+			f.logger.Printf("failed to get file: %v", err)
+			numberOfFailWriteMsgs.WithLabelValues(f.file.Name(), "file_error").Inc()
+			continue
+		}
+		f.writeEventMsgs(w, files[name])
+		f.pool.release(name)
+	}
+}
+
+func (f *File) writeEventMsgs(w file, evs []*formatters.EventMsg) {
 	toWrite := []byte{}
 	if f.cfg.SplitEvents {
 		for _, pev := range evs {
@@ -318,7 +417,7 @@ func (f *File) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
 		toWrite = append(toWrite, []byte(f.cfg.Separator)...)
 	}
 
-	n, err := f.file.Write(toWrite)
+	n, err := w.Write(toWrite)
 	if err != nil {
 		fmt.Printf("failed to WriteEvent: %v", err)
 		numberOfFailWriteMsgs.WithLabelValues(f.file.Name(), "write_error").Inc()
@@ -328,6 +427,58 @@ func (f *File) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
 	numberOfWrittenMsgs.WithLabelValues(f.file.Name()).Inc()
 }
 
+// writer returns the file to write a message with the given metadata to,
+// release must be called once the write is done.
+func (f *File) writer(meta outputs.Meta) (file, func(), error) {
+	if f.pool == nil {
+		return f.file, func() {}, nil
+	}
+	name, err := f.pool.fileName(meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	w, err := f.pool.get(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return w, func() { f.pool.release(name) }, nil
+}
+
+func (f *File) writeTable(rsp proto.Message, meta outputs.Meta) {
+	switch rsp := f.mo.OverrideTimestamp(rsp).(type) {
+	case *gnmi.SubscribeResponse:
+		subName := "default"
+		if sn, ok := meta["subscription-name"]; ok {
+			subName = sn
+		}
+		evs, err := formatters.ResponseToEventMsgs(subName, rsp, meta, f.evps...)
+		if err != nil {
+			if f.cfg.Debug {
+				f.logger.Printf("failed to convert message to events: %v", err)
+			}
+			numberOfFailWriteMsgs.WithLabelValues(f.file.Name(), "marshal_error").Inc()
+			return
+		}
+		f.writeEvents(evs)
+	}
+}
+
+func (f *File) writeEvents(evs []*formatters.EventMsg) {
+	if len(evs) == 0 {
+		return
+	}
+	n, err := f.tables.writeEvents(evs)
+	numberOfWrittenBytes.WithLabelValues(f.file.Name()).Add(float64(n))
+	if err != nil {
+		if f.cfg.Debug {
+			f.logger.Printf("failed to write events: %v", err)
+		}
+		numberOfFailWriteMsgs.WithLabelValues(f.file.Name(), "write_error").Inc()
+		return
+	}
+	numberOfWrittenMsgs.WithLabelValues(f.file.Name()).Inc()
+}
+
 // Close //
 func (f *File) Close() error {
 	f.logger.Printf("closing file '%s' output", f.file.Name())
@@ -345,5 +496,6 @@ func (f *File) RegisterMetrics(reg *prometheus.Registry) {
 }
 
 func (f *File) SetName(name string)                             {}
+func (f *File) SetDryRun(dryRun bool)                           { f.dryRun = dryRun }
 func (f *File) SetClusterName(name string)                      {}
 func (f *File) SetTargetsConfig(map[string]*types.TargetConfig) {}
@@ -0,0 +1,212 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+)
+
+const (
+	defaultIdleTimeout = 5 * time.Minute
+)
+
+// fileNameData is the input of the filename template.
+type fileNameData struct {
+	// Target is the subscription target if set,
+	// otherwise the message source without the port number.
+	Target       string
+	Source       string
+	Subscription string
+	// Time is the current time.
+	Time time.Time
+}
+
+func newFileNameData(tags map[string]string, now time.Time) *fileNameData {
+	target := tags["subscription-target"]
+	if target == "" {
+		target = utils.GetHost(tags["source"])
+	}
+	return &fileNameData{
+		Target:       sanitizeFileName(target),
+		Source:       sanitizeFileName(tags["source"]),
+		Subscription: sanitizeFileName(tags["subscription-name"]),
+		Time:         now,
+	}
+}
+
+type pooledFile struct {
+	f        file
+	inUse    int
+	lastUsed time.Time
+}
+
+// filePool manages the files opened using a templated filename,
+// one per rendered filename.
+// The files not written to for idle-timeout are closed,
+// they are reopened in append mode on the next write.
+type filePool struct {
+	cfg    *Config
+	tpl    *template.Template
+	logger *log.Logger
+
+	m     *sync.Mutex
+	files map[string]*pooledFile
+}
+
+func newFilePool(ctx context.Context, cfg *Config, tpl *template.Template, logger *log.Logger) *filePool {
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = defaultIdleTimeout
+	}
+	p := &filePool{
+		cfg:    cfg,
+		tpl:    tpl,
+		logger: logger,
+		m:      new(sync.Mutex),
+		files:  make(map[string]*pooledFile),
+	}
+	go p.closeIdle(ctx)
+	return p
+}
+
+// fileName renders the filename template.
+func (p *filePool) fileName(tags map[string]string) (string, error) {
+	sb := new(strings.Builder)
+	err := p.tpl.Execute(sb, newFileNameData(tags, time.Now()))
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(sb.String())
+	if name == "" {
+		return "", errors.New("filename template rendered an empty name")
+	}
+	return filepath.Clean(name), nil
+}
+
+// get returns the file with the given name, opening it if needed.
+// The file is not closed until it is released.
+func (p *filePool) get(name string) (file, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	pf, ok := p.files[name]
+	if !ok {
+		if p.cfg.MaxOpenFiles > 0 && len(p.files) >= p.cfg.MaxOpenFiles {
+			p.closeLRU()
+		}
+		f, err := p.open(name)
+		if err != nil {
+			return nil, err
+		}
+		pf = &pooledFile{f: f}
+		p.files[name] = pf
+	}
+	pf.inUse++
+	pf.lastUsed = time.Now()
+	return pf.f, nil
+}
+
+func (p *filePool) release(name string) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if pf, ok := p.files[name]; ok {
+		pf.inUse--
+	}
+}
+
+func (p *filePool) open(name string) (file, error) {
+	err := os.MkdirAll(filepath.Dir(name), 0755)
+	if err != nil {
+		return nil, err
+	}
+	if p.cfg.Rotation != nil {
+		return newRotatingFile(name, p.cfg.Rotation), nil
+	}
+	return os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+}
+
+// closeLRU closes the least recently used file that is not being written to.
+func (p *filePool) closeLRU() {
+	var lru string
+	var lastUsed time.Time
+	for name, pf := range p.files {
+		if pf.inUse > 0 {
+			continue
+		}
+		if lru == "" || pf.lastUsed.Before(lastUsed) {
+			lru = name
+			lastUsed = pf.lastUsed
+		}
+	}
+	if lru != "" {
+		p.closeFile(lru)
+	}
+}
+
+func (p *filePool) closeFile(name string) {
+	if p.cfg.Debug {
+		p.logger.Printf("closing file %s", name)
+	}
+	err := p.files[name].f.Close()
+	if err != nil {
+		p.logger.Printf("failed to close file %s: %v", name, err)
+	}
+	delete(p.files, name)
+}
+
+func (p *filePool) closeIdle(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.IdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			p.m.Lock()
+			for name, pf := range p.files {
+				if pf.inUse == 0 && now.Sub(pf.lastUsed) >= p.cfg.IdleTimeout {
+					p.closeFile(name)
+				}
+			}
+			p.m.Unlock()
+		}
+	}
+}
+
+// Close closes all the open files.
+func (p *filePool) Close() error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	var errs []error
+	for name, pf := range p.files {
+		if err := pf.f.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		delete(p.files, name)
+	}
+	return errors.Join(errs...)
+}
+
+// Name returns the filename template.
+func (p *filePool) Name() string {
+	return p.cfg.FileName
+}
+
+func (p *filePool) Write([]byte) (int, error) {
+	return 0, errors.New("templated filename requires a rendered file")
+}
@@ -0,0 +1,172 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+func newTestFileOutput(t *testing.T, cfg map[string]interface{}) *File {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	o := outputs.Outputs["file"]().(*File)
+	err := o.Init(ctx, "test", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestTemplatedFileName(t *testing.T) {
+	dir := t.TempDir()
+	o := newTestFileOutput(t, map[string]interface{}{
+		"filename":     filepath.Join(dir, "{{ .Target }}", "{{ .Subscription }}.json"),
+		"format":       "event",
+		"split-events": true,
+	})
+	for _, src := range []string{"r1:57400", "r2:57400", "r1:57400"} {
+		o.WriteEvent(context.Background(), &formatters.EventMsg{
+			Name: "sub1",
+			Tags: map[string]string{"source": src, "subscription-name": "sub1"},
+		})
+	}
+	o.WriteEvent(context.Background(), &formatters.EventMsg{
+		Name: "sub2",
+		Tags: map[string]string{"source": "r3", "subscription-target": "router/3", "subscription-name": "sub2"},
+	})
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{
+		"r1/sub1.json":       2,
+		"r2/sub1.json":       1,
+		"router_3/sub2.json": 1,
+	}
+	for fn, n := range want {
+		b, err := os.ReadFile(filepath.Join(dir, fn))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Count(string(b), "\n"); got != n {
+			t.Errorf("%s: got %d events, want %d", fn, got, n)
+		}
+	}
+}
+
+func TestFilePoolIdleClose(t *testing.T) {
+	dir := t.TempDir()
+	o := newTestFileOutput(t, map[string]interface{}{
+		"filename":       filepath.Join(dir, "{{ .Target }}.json"),
+		"format":         "event",
+		"idle-timeout":   "20ms",
+		"max-open-files": 1,
+	})
+	write := func(src string) {
+		o.WriteEvent(context.Background(), &formatters.EventMsg{
+			Name: "sub1",
+			Tags: map[string]string{"source": src},
+		})
+	}
+	numOpen := func() int {
+		o.pool.m.Lock()
+		defer o.pool.m.Unlock()
+		return len(o.pool.files)
+	}
+	write("r1")
+	write("r2")
+	if n := numOpen(); n != 1 {
+		t.Fatalf("expected 1 open file, got %d", n)
+	}
+	deadline := time.After(2 * time.Second)
+	for numOpen() != 0 {
+		select {
+		case <-deadline:
+			t.Fatal("timeout waiting for the idle file to be closed")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	// reopened in append mode
+	write("r1")
+	o.Close()
+	b, err := os.ReadFile(filepath.Join(dir, "r1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(b), "\n"); got != 2 {
+		t.Errorf("got %d lines, want 2", got)
+	}
+}
+
+func TestRotationPeriodStart(t *testing.T) {
+	loc := time.FixedZone("test", 2*3600)
+	now := time.Date(2024, 5, 2, 1, 30, 15, 0, loc)
+	tests := []struct {
+		interval time.Duration
+		want     time.Time
+	}{
+		{interval: time.Hour, want: time.Date(2024, 5, 2, 1, 0, 0, 0, loc)},
+		{interval: 24 * time.Hour, want: time.Date(2024, 5, 2, 0, 0, 0, 0, loc)},
+		{interval: 15 * time.Minute, want: time.Date(2024, 5, 2, 1, 30, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		rc := &rotationConfig{Interval: tt.interval}
+		if got := rc.periodStart(now); !got.Equal(tt.want) {
+			t.Errorf("interval %s: got %s, want %s", tt.interval, got, tt.want)
+		}
+	}
+	var rc *rotationConfig
+	if got := rc.periodStart(now); !got.IsZero() {
+		t.Errorf("expected a zero time without rotation, got %s", got)
+	}
+}
+
+func TestRotatingFileInterval(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "out.json")
+	r := newRotatingFile(fn, &rotationConfig{Interval: time.Hour})
+	defer r.Close()
+	now := time.Now()
+	if err := r.rotateIfDue(now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.l.Write([]byte("1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.rotateIfDue(now); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.rotateIfDue(now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.l.Write([]byte("2\n")); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(fn), "out*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected the current and a rotated file, got %v", files)
+	}
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "2\n" {
+		t.Errorf("unexpected current file content %q", b)
+	}
+}
@@ -1,6 +1,10 @@
 package file
 
 import (
+	"os"
+	"sync"
+	"time"
+
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
@@ -10,6 +14,9 @@ type rotationConfig struct {
 	MaxBackups int  `mapstructure:"max-backups,omitempty"`
 	MaxAge     int  `mapstructure:"max-age,omitempty"`
 	Compress   bool `mapstructure:"compress,omitempty"`
+	// Interval rotates the files on wall clock boundaries,
+	// e.g: every hour or every day at midnight local time.
+	Interval time.Duration `mapstructure:"interval,omitempty"`
 }
 
 func (r *rotationConfig) SetDefaults() {
@@ -25,23 +32,39 @@ func (r *rotationConfig) SetDefaults() {
 	}
 }
 
+// periodStart returns the start of the rotation interval t is in,
+// aligned on local time boundaries.
+func (r *rotationConfig) periodStart(t time.Time) time.Time {
+	if r == nil || r.Interval <= 0 {
+		return time.Time{}
+	}
+	_, offset := t.Zone()
+	tz := time.Duration(offset) * time.Second
+	return t.Add(tz).Truncate(r.Interval).Add(-tz)
+}
+
 type rotatingFile struct {
-	l *lumberjack.Logger
+	l  *lumberjack.Logger
+	rc *rotationConfig
+
+	m *sync.Mutex
+	// start of the current rotation interval
+	period time.Time
 }
 
 // newRotatingFile initialize the lumberjack instance
-func newRotatingFile(cfg *Config) *rotatingFile {
-	cfg.Rotation.SetDefaults()
+func newRotatingFile(filename string, rc *rotationConfig) *rotatingFile {
+	rc.SetDefaults()
 
 	lj := lumberjack.Logger{
-		Filename:   cfg.FileName,
-		MaxSize:    cfg.Rotation.MaxSize,
-		MaxBackups: cfg.Rotation.MaxBackups,
-		MaxAge:     cfg.Rotation.MaxAge,
-		Compress:   cfg.Rotation.Compress,
+		Filename:   filename,
+		MaxSize:    rc.MaxSize,
+		MaxBackups: rc.MaxBackups,
+		MaxAge:     rc.MaxAge,
+		Compress:   rc.Compress,
 	}
 
-	return &rotatingFile{l: &lj}
+	return &rotatingFile{l: &lj, rc: rc, m: new(sync.Mutex)}
 }
 
 // Close closes the file
@@ -56,5 +79,33 @@ func (r *rotatingFile) Name() string {
 
 // Write implements io.Writer
 func (r *rotatingFile) Write(b []byte) (int, error) {
+	if r.rc.Interval > 0 {
+		err := r.rotateIfDue(time.Now())
+		if err != nil {
+			return 0, err
+		}
+	}
 	return r.l.Write(b)
 }
+
+// rotateIfDue rotates the file if a new rotation interval started
+// since the last write.
+func (r *rotatingFile) rotateIfDue(now time.Time) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	start := r.rc.periodStart(now)
+	if r.period.IsZero() {
+		r.period = start
+		// a file left from a previous interval is rotated
+		fi, err := os.Stat(r.l.Filename)
+		if err == nil && fi.Size() > 0 && fi.ModTime().Before(start) {
+			return r.l.Rotate()
+		}
+		return nil
+	}
+	if !start.After(r.period) {
+		return nil
+	}
+	r.period = start
+	return r.l.Rotate()
+}
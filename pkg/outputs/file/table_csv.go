@@ -0,0 +1,101 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+func init() {
+	tableEncoders["csv"] = newCSVEncoder
+}
+
+// csvEncoder writes a header with the column names followed by a record per row.
+// The file is gzip compressed if rotation compress is enabled.
+type csvEncoder struct {
+	w   *csv.Writer
+	gzw *gzip.Writer
+}
+
+func newCSVEncoder(w io.Writer, s *tableSchema, cfg *Config) (tableEncoder, error) {
+	e := new(csvEncoder)
+	if cfg.Rotation != nil && cfg.Rotation.Compress {
+		e.gzw = gzip.NewWriter(w)
+		w = e.gzw
+	}
+	e.w = csv.NewWriter(w)
+	header := make([]string, 0, len(s.columns))
+	for _, c := range s.columns {
+		header = append(header, c.name)
+	}
+	err := e.w.Write(header)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *csvEncoder) writeRows(rows [][]interface{}) error {
+	for _, r := range rows {
+		rec := make([]string, 0, len(r))
+		for _, v := range r {
+			rec = append(rec, csvField(v))
+		}
+		err := e.w.Write(rec)
+		if err != nil {
+			return err
+		}
+	}
+	// records are not kept in memory, unlike parquet row groups.
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		return err
+	}
+	if e.gzw != nil {
+		return e.gzw.Flush()
+	}
+	return nil
+}
+
+func (e *csvEncoder) close() error {
+	err := e.flush()
+	if err != nil {
+		return err
+	}
+	if e.gzw != nil {
+		return e.gzw.Close()
+	}
+	return nil
+}
+
+func csvField(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return ""
+}
@@ -0,0 +1,566 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	defaultRowGroupSize = 10000
+	tableFileTimeFormat = "2006-01-02T15-04-05.000000000"
+)
+
+// tableEncoders are the formats writing events as rows of a table,
+// keyed by format name.
+var tableEncoders = map[string]tableEncoderFn{}
+
+type tableEncoderFn func(w io.Writer, s *tableSchema, cfg *Config) (tableEncoder, error)
+
+type tableEncoder interface {
+	// writeRows encodes rows aligned with the schema columns.
+	writeRows(rows [][]interface{}) error
+	// flush writes the buffered rows to the underlying writer,
+	// as a row group for the parquet format.
+	flush() error
+	// close flushes the buffered rows and completes the file.
+	close() error
+}
+
+type columnKind int
+
+const (
+	kindBool columnKind = iota
+	kindInt
+	kindUint
+	kindDouble
+	kindString
+)
+
+type column struct {
+	name string
+	kind columnKind
+}
+
+// tableSchema is the ordered list of columns of a table:
+// the timestamp and name columns followed by the tags and values columns
+// in the order they were discovered.
+type tableSchema struct {
+	columns []*column
+	// tag name to column index
+	tags map[string]int
+	// value name to column index
+	values map[string]int
+	// column names in use
+	names map[string]struct{}
+}
+
+func newTableSchema() *tableSchema {
+	return &tableSchema{
+		columns: []*column{
+			{name: "timestamp", kind: kindInt},
+			{name: "name", kind: kindString},
+		},
+		tags:   make(map[string]int),
+		values: make(map[string]int),
+		names:  map[string]struct{}{"timestamp": {}, "name": {}},
+	}
+}
+
+func (s *tableSchema) clone() *tableSchema {
+	ns := &tableSchema{
+		columns: make([]*column, 0, len(s.columns)),
+		tags:    make(map[string]int, len(s.tags)),
+		values:  make(map[string]int, len(s.values)),
+		names:   make(map[string]struct{}, len(s.names)),
+	}
+	for _, c := range s.columns {
+		nc := *c
+		ns.columns = append(ns.columns, &nc)
+	}
+	for k, v := range s.tags {
+		ns.tags[k] = v
+	}
+	for k, v := range s.values {
+		ns.values[k] = v
+	}
+	for k := range s.names {
+		ns.names[k] = struct{}{}
+	}
+	return ns
+}
+
+// merge returns the schema extended with the tags and values of evs.
+// A column whose type does not fit a new value is promoted to
+// a double (numbers) or to a string.
+// The returned bool is true if the schema changed, in which case
+// the returned schema is a copy.
+func (s *tableSchema) merge(evs []*formatters.EventMsg) (*tableSchema, bool) {
+	ns := s
+	changed := false
+	mutable := func() {
+		if !changed {
+			ns = s.clone()
+			changed = true
+		}
+	}
+	for _, ev := range evs {
+		for _, k := range sortedKeys(ev.Tags) {
+			if _, ok := ns.tags[k]; ok {
+				continue
+			}
+			mutable()
+			ns.addColumn(ns.tags, k, kindString, "_tag")
+		}
+		for _, k := range sortedKeys(ev.Values) {
+			kind, ok := valueKind(ev.Values[k])
+			if !ok {
+				continue
+			}
+			i, ok := ns.values[k]
+			if !ok {
+				mutable()
+				ns.addColumn(ns.values, k, kind, "_value")
+				continue
+			}
+			pk := promoteKind(ns.columns[i].kind, kind)
+			if pk == ns.columns[i].kind {
+				continue
+			}
+			mutable()
+			ns.columns[i].kind = pk
+		}
+	}
+	return ns, changed
+}
+
+// addColumn adds a column named key, the suffix is appended
+// to the column name if it is already used.
+func (s *tableSchema) addColumn(idx map[string]int, key string, kind columnKind, suffix string) {
+	name := key
+	for {
+		if _, ok := s.names[name]; !ok {
+			break
+		}
+		name += suffix
+	}
+	s.names[name] = struct{}{}
+	idx[key] = len(s.columns)
+	s.columns = append(s.columns, &column{name: name, kind: kind})
+}
+
+// row returns the event as a row aligned with the schema columns,
+// the missing tags and values are nil.
+func (s *tableSchema) row(ev *formatters.EventMsg) []interface{} {
+	r := make([]interface{}, len(s.columns))
+	r[0] = ev.Timestamp
+	r[1] = ev.Name
+	for k, v := range ev.Tags {
+		if i, ok := s.tags[k]; ok {
+			r[i] = v
+		}
+	}
+	for k, v := range ev.Values {
+		if i, ok := s.values[k]; ok {
+			r[i] = convertValue(v, s.columns[i].kind)
+		}
+	}
+	return r
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// valueKind returns the column type of an event value,
+// the bool is false if the value is nil.
+func valueKind(v interface{}) (columnKind, bool) {
+	switch v.(type) {
+	case nil:
+		return 0, false
+	case bool:
+		return kindBool, true
+	case int, int8, int16, int32, int64:
+		return kindInt, true
+	case uint, uint8, uint16, uint32, uint64:
+		return kindUint, true
+	case float32, float64:
+		return kindDouble, true
+	default:
+		return kindString, true
+	}
+}
+
+func promoteKind(a, b columnKind) columnKind {
+	switch {
+	case a == b:
+		return a
+	case a != kindBool && a != kindString && b != kindBool && b != kindString:
+		return kindDouble
+	default:
+		return kindString
+	}
+}
+
+// convertValue converts an event value to the Go type of a column type:
+// bool, int64, uint64, float64 or string.
+func convertValue(v interface{}, kind columnKind) interface{} {
+	switch kind {
+	case kindBool:
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	case kindInt:
+		switch v := v.(type) {
+		case int:
+			return int64(v)
+		case int8:
+			return int64(v)
+		case int16:
+			return int64(v)
+		case int32:
+			return int64(v)
+		case int64:
+			return v
+		}
+	case kindUint:
+		switch v := v.(type) {
+		case uint:
+			return uint64(v)
+		case uint8:
+			return uint64(v)
+		case uint16:
+			return uint64(v)
+		case uint32:
+			return uint64(v)
+		case uint64:
+			return v
+		}
+	case kindDouble:
+		switch v := v.(type) {
+		case float64:
+			return v
+		case float32:
+			return float64(v)
+		}
+		if i, ok := convertValue(v, kindInt).(int64); ok {
+			return float64(i)
+		}
+		if u, ok := convertValue(v, kindUint).(uint64); ok {
+			return float64(u)
+		}
+	case kindString:
+		switch v := v.(type) {
+		case string:
+			return v
+		case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return fmt.Sprint(v)
+		case float32:
+			return formatFloat(float64(v))
+		case float64:
+			return formatFloat(v)
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+	return nil
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return fmt.Sprint(f)
+	}
+	b, _ := json.Marshal(f)
+	return string(b)
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w     io.Writer
+	n     int64
+	total *int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	*c.total += int64(n)
+	return n, err
+}
+
+type tableFile struct {
+	f      *os.File
+	cw     *countingWriter
+	enc    tableEncoder
+	schema *tableSchema
+	// rows written since the last flush
+	numRows int
+	// start of the rotation interval the file was created in
+	period time.Time
+}
+
+// tableWriter writes events as rows of a table, to one file per event name.
+// A file is completed and a new one is started when the table schema changes,
+// when the file reaches the rotation max-size or when a rotation interval starts,
+// so that each file is self-contained.
+type tableWriter struct {
+	cfg    *Config
+	newEnc tableEncoderFn
+	logger *log.Logger
+
+	m *sync.Mutex
+	// schema of each table, kept across files
+	schemas map[string]*tableSchema
+	files   map[string]*tableFile
+	// bytes written during the current write call
+	written int64
+}
+
+func newTableWriter(cfg *Config, newEnc tableEncoderFn, logger *log.Logger) *tableWriter {
+	if cfg.Rotation != nil {
+		cfg.Rotation.SetDefaults()
+	}
+	if cfg.RowGroupSize <= 0 {
+		cfg.RowGroupSize = defaultRowGroupSize
+	}
+	return &tableWriter{
+		cfg:     cfg,
+		newEnc:  newEnc,
+		logger:  logger,
+		m:       new(sync.Mutex),
+		schemas: make(map[string]*tableSchema),
+		files:   make(map[string]*tableFile),
+	}
+}
+
+// writeEvents writes the events to their tables,
+// it returns the number of bytes written to the files.
+func (t *tableWriter) writeEvents(evs []*formatters.EventMsg) (int64, error) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.written = 0
+	tables := make(map[string][]*formatters.EventMsg)
+	for _, ev := range evs {
+		name := ev.Name
+		if name == "" {
+			name = "default"
+		}
+		tables[name] = append(tables[name], ev)
+	}
+	var errs []error
+	for _, name := range sortedKeys(tables) {
+		err := t.writeTable(name, tables[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("table %q: %w", name, err))
+		}
+	}
+	return t.written, errors.Join(errs...)
+}
+
+func (t *tableWriter) writeTable(name string, evs []*formatters.EventMsg) error {
+	s, ok := t.schemas[name]
+	if !ok {
+		s = newTableSchema()
+	}
+	s, changed := s.merge(evs)
+	t.schemas[name] = s
+	tf, ok := t.files[name]
+	if ok && (changed || !tf.period.Equal(t.cfg.Rotation.periodStart(time.Now()))) {
+		if t.cfg.Debug {
+			t.logger.Printf("table %q schema changed or rotation interval started, starting a new file", name)
+		}
+		err := t.closeFile(name, tf)
+		if err != nil {
+			return err
+		}
+		ok = false
+	}
+	if !ok {
+		var err error
+		tf, err = t.openFile(name, s)
+		if err != nil {
+			return err
+		}
+		t.files[name] = tf
+	}
+	rows := make([][]interface{}, 0, len(evs))
+	for _, ev := range evs {
+		rows = append(rows, s.row(ev))
+	}
+	err := tf.enc.writeRows(rows)
+	if err != nil {
+		return err
+	}
+	tf.numRows += len(rows)
+	if tf.numRows >= t.cfg.RowGroupSize {
+		err = tf.enc.flush()
+		if err != nil {
+			return err
+		}
+		tf.numRows = 0
+	}
+	if t.cfg.Rotation != nil && tf.cw.n >= int64(t.cfg.Rotation.MaxSize)*1024*1024 {
+		return t.closeFile(name, tf)
+	}
+	return nil
+}
+
+func (t *tableWriter) openFile(name string, s *tableSchema) (*tableFile, error) {
+	now := time.Now()
+	fileName := t.filePath(name, now)
+	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0666)
+	if err != nil {
+		return nil, err
+	}
+	tf := &tableFile{
+		f:      f,
+		cw:     &countingWriter{w: f, total: &t.written},
+		schema: s,
+		period: t.cfg.Rotation.periodStart(now),
+	}
+	tf.enc, err = t.newEnc(tf.cw, s, t.cfg)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if t.cfg.Debug {
+		t.logger.Printf("table %q: created file %s", name, fileName)
+	}
+	return tf, nil
+}
+
+// closeFile completes the table file and removes the old files
+// according to the rotation config.
+func (t *tableWriter) closeFile(name string, tf *tableFile) error {
+	delete(t.files, name)
+	err := tf.enc.close()
+	if cerr := tf.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	t.removeOldFiles(name)
+	return nil
+}
+
+// filePath returns the path of a new file of the named table:
+// $dir/$stem-$table-$time$ext, where $dir, $stem and $ext are derived from filename.
+func (t *tableWriter) filePath(name string, now time.Time) string {
+	dir, stem, ext := t.fileNameParts()
+	return filepath.Join(dir, fmt.Sprintf("%s-%s-%s%s", stem, sanitizeFileName(name), now.Format(tableFileTimeFormat), ext))
+}
+
+func (t *tableWriter) fileNameParts() (string, string, string) {
+	dir, base := filepath.Split(t.cfg.FileName)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	if ext == "" {
+		ext = "." + t.cfg.Format
+	}
+	if t.cfg.Format == "csv" && t.cfg.Rotation != nil && t.cfg.Rotation.Compress && ext != ".gz" {
+		ext += ".gz"
+	}
+	return dir, stem, ext
+}
+
+// removeOldFiles removes the completed files of the named table
+// beyond rotation max-backups or older than rotation max-age.
+func (t *tableWriter) removeOldFiles(name string) {
+	if t.cfg.Rotation == nil {
+		return
+	}
+	dir, stem, ext := t.fileNameParts()
+	prefix := fmt.Sprintf("%s-%s-", stem, sanitizeFileName(name))
+	matches, err := filepath.Glob(filepath.Join(dir, prefix+"*"+ext))
+	if err != nil {
+		t.logger.Printf("table %q: failed to list files: %v", name, err)
+		return
+	}
+	files := make([]string, 0, len(matches))
+	for _, m := range matches {
+		ts := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), prefix), ext)
+		// ignore the files of other tables sharing the same prefix
+		if _, err := time.Parse(tableFileTimeFormat, ts); err == nil {
+			files = append(files, m)
+		}
+	}
+	// oldest first
+	sort.Strings(files)
+	maxAge := time.Duration(t.cfg.Rotation.MaxAge) * 24 * time.Hour
+	for i, fn := range files {
+		remove := t.cfg.Rotation.MaxBackups > 0 && i < len(files)-t.cfg.Rotation.MaxBackups
+		if !remove && t.cfg.Rotation.MaxAge > 0 {
+			fi, err := os.Stat(fn)
+			remove = err == nil && time.Since(fi.ModTime()) > maxAge
+		}
+		if !remove {
+			continue
+		}
+		if t.cfg.Debug {
+			t.logger.Printf("table %q: removing old file %s", name, fn)
+		}
+		if err := os.Remove(fn); err != nil {
+			t.logger.Printf("table %q: failed to remove old file: %v", name, err)
+		}
+	}
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '[', ']', '"', '<', '>', '|', ' ':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// Close completes all the open files.
+func (t *tableWriter) Close() error {
+	t.m.Lock()
+	defer t.m.Unlock()
+	var errs []error
+	for name, tf := range t.files {
+		if err := t.closeFile(name, tf); err != nil {
+			errs = append(errs, fmt.Errorf("table %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Name returns the configured file name.
+func (t *tableWriter) Name() string {
+	return t.cfg.FileName
+}
+
+func (t *tableWriter) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("format %q only supports events", t.cfg.Format)
+}
@@ -0,0 +1,201 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"encoding/csv"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+func TestTableSchemaMerge(t *testing.T) {
+	s := newTableSchema()
+	s1, changed := s.merge([]*formatters.EventMsg{
+		{
+			Tags:   map[string]string{"source": "r1", "name": "eth1"},
+			Values: map[string]interface{}{"in": int64(1), "up": true, "source": "x"},
+		},
+	})
+	if !changed {
+		t.Fatal("expected the schema to change")
+	}
+	if len(s.columns) != 2 {
+		t.Fatalf("the original schema was modified: %d columns", len(s.columns))
+	}
+	want := []column{
+		{"timestamp", kindInt},
+		{"name", kindString},
+		{"name_tag", kindString},
+		{"source", kindString},
+		{"in", kindInt},
+		{"source_value", kindString},
+		{"up", kindBool},
+	}
+	checkColumns(t, s1, want)
+
+	// same tags and values
+	s2, changed := s1.merge([]*formatters.EventMsg{
+		{
+			Tags:   map[string]string{"source": "r2"},
+			Values: map[string]interface{}{"in": int64(2)},
+		},
+	})
+	if changed || s2 != s1 {
+		t.Fatal("expected the schema to be unchanged")
+	}
+	// promotions
+	s3, changed := s1.merge([]*formatters.EventMsg{
+		{
+			Values: map[string]interface{}{"in": 2.5, "up": "yes"},
+		},
+	})
+	if !changed {
+		t.Fatal("expected the schema to change")
+	}
+	want[4].kind = kindDouble
+	want[6].kind = kindString
+	checkColumns(t, s3, want)
+
+	r := s3.row(&formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 42,
+		Tags:      map[string]string{"source": "r1"},
+		Values:    map[string]interface{}{"in": int64(3), "up": true},
+	})
+	wantRow := []interface{}{int64(42), "sub1", nil, "r1", float64(3), nil, "true"}
+	if !reflect.DeepEqual(r, wantRow) {
+		t.Errorf("got row %v, want %v", r, wantRow)
+	}
+}
+
+func checkColumns(t *testing.T, s *tableSchema, want []column) {
+	t.Helper()
+	if len(s.columns) != len(want) {
+		t.Fatalf("got %d columns, want %d", len(s.columns), len(want))
+	}
+	for i, c := range s.columns {
+		if *c != want[i] {
+			t.Errorf("column %d: got %+v, want %+v", i, *c, want[i])
+		}
+	}
+}
+
+func newTestTableWriter(t *testing.T, cfg *Config) *tableWriter {
+	t.Helper()
+	cfg.FileName = filepath.Join(t.TempDir(), "out.csv")
+	cfg.Format = "csv"
+	tw := newTableWriter(cfg, tableEncoders["csv"], log.New(io.Discard, "", 0))
+	t.Cleanup(func() { tw.Close() })
+	return tw
+}
+
+func readCSVFiles(t *testing.T, pattern string) [][][]string {
+	t.Helper()
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	res := make([][][]string, 0, len(files))
+	for _, fn := range files {
+		f, err := os.Open(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recs, err := csv.NewReader(f).ReadAll()
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", fn, err)
+		}
+		res = append(res, recs)
+	}
+	return res
+}
+
+func TestTableWriterCSV(t *testing.T) {
+	tw := newTestTableWriter(t, &Config{})
+	_, err := tw.writeEvents([]*formatters.EventMsg{
+		{Name: "sub1", Timestamp: 1, Tags: map[string]string{"source": "r1"}, Values: map[string]interface{}{"v": int64(1)}},
+		{Name: "sub2", Timestamp: 1, Tags: map[string]string{"source": "r1"}, Values: map[string]interface{}{"s": "a,b"}},
+		{Name: "sub1", Timestamp: 2, Tags: map[string]string{"source": "r2"}, Values: map[string]interface{}{"v": int64(2)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// new column, starts a new sub1 file
+	_, err = tw.writeEvents([]*formatters.EventMsg{
+		{Name: "sub1", Timestamp: 3, Tags: map[string]string{"source": "r1"}, Values: map[string]interface{}{"v": int64(3), "w": 1.5}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Dir(tw.cfg.FileName)
+	sub1 := readCSVFiles(t, filepath.Join(dir, "out-sub1-*.csv"))
+	want1 := [][][]string{
+		{
+			{"timestamp", "name", "source", "v"},
+			{"1", "sub1", "r1", "1"},
+			{"2", "sub1", "r2", "2"},
+		},
+		{
+			{"timestamp", "name", "source", "v", "w"},
+			{"3", "sub1", "r1", "3", "1.5"},
+		},
+	}
+	if !reflect.DeepEqual(sub1, want1) {
+		t.Errorf("sub1: got %v, want %v", sub1, want1)
+	}
+	sub2 := readCSVFiles(t, filepath.Join(dir, "out-sub2-*.csv"))
+	want2 := [][][]string{
+		{
+			{"timestamp", "name", "source", "s"},
+			{"1", "sub2", "r1", "a,b"},
+		},
+	}
+	if !reflect.DeepEqual(sub2, want2) {
+		t.Errorf("sub2: got %v, want %v", sub2, want2)
+	}
+}
+
+func TestTableWriterRotation(t *testing.T) {
+	tw := newTestTableWriter(t, &Config{
+		Rotation: &rotationConfig{MaxSize: 1, MaxBackups: 2},
+	})
+	big := strings.Repeat("x", 300*1024)
+	for i := 0; i < 16; i++ {
+		_, err := tw.writeEvents([]*formatters.EventMsg{
+			{Name: "sub1", Values: map[string]interface{}{"v": big}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 4 rows per file, 4 completed files, 2 of them removed
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(tw.cfg.FileName), "out-sub1-*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("expected 2 files, got %d: %v", len(files), files)
+	}
+	if len(tw.files) != 0 {
+		t.Errorf("expected no open file, got %d", len(tw.files))
+	}
+}
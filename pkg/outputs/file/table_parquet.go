@@ -0,0 +1,130 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package file
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+)
+
+func init() {
+	tableEncoders["parquet"] = newParquetEncoder
+}
+
+// parquetCodecs are the supported values of parquet-compression.
+var parquetCodecs = map[string]compress.Codec{
+	"none":   &parquet.Uncompressed,
+	"snappy": &parquet.Snappy,
+	"gzip":   &parquet.Gzip,
+	"zstd":   &parquet.Zstd,
+}
+
+// parquetEncoder writes the rows in row groups,
+// the file footer is written when the encoder is closed.
+type parquetEncoder struct {
+	w *parquet.Writer
+	// index of each table column in the parquet schema,
+	// where the columns are sorted by name.
+	leafIndex []int
+	optional  []bool
+}
+
+func newParquetEncoder(w io.Writer, s *tableSchema, cfg *Config) (tableEncoder, error) {
+	codec, ok := parquetCodecs[cfg.ParquetCompression]
+	if !ok {
+		return nil, fmt.Errorf("unsupported parquet compression %q", cfg.ParquetCompression)
+	}
+	group := make(parquet.Group, len(s.columns))
+	e := &parquetEncoder{
+		leafIndex: make([]int, len(s.columns)),
+		optional:  make([]bool, len(s.columns)),
+	}
+	for i, c := range s.columns {
+		switch i {
+		case 0: // timestamp
+			group[c.name] = parquet.Timestamp(parquet.Nanosecond)
+		case 1: // name
+			group[c.name] = parquet.String()
+		default:
+			group[c.name] = parquet.Optional(parquetNode(c.kind))
+			e.optional[i] = true
+		}
+	}
+	schema := parquet.NewSchema("gnmic", group)
+	leaves := make(map[string]int, len(s.columns))
+	for i, path := range schema.Columns() {
+		leaves[path[0]] = i
+	}
+	for i, c := range s.columns {
+		e.leafIndex[i] = leaves[c.name]
+	}
+	e.w = parquet.NewWriter(w, schema, parquet.Compression(codec))
+	return e, nil
+}
+
+func parquetNode(kind columnKind) parquet.Node {
+	switch kind {
+	case kindBool:
+		return parquet.Leaf(parquet.BooleanType)
+	case kindInt:
+		return parquet.Int(64)
+	case kindUint:
+		return parquet.Uint(64)
+	case kindDouble:
+		return parquet.Leaf(parquet.DoubleType)
+	default:
+		return parquet.String()
+	}
+}
+
+func (e *parquetEncoder) writeRows(rows [][]interface{}) error {
+	prows := make([]parquet.Row, 0, len(rows))
+	for _, r := range rows {
+		prow := make(parquet.Row, len(r))
+		for i, v := range r {
+			ci := e.leafIndex[i]
+			pv := parquetValue(v)
+			defLevel := 0
+			if e.optional[i] && !pv.IsNull() {
+				defLevel = 1
+			}
+			prow[ci] = pv.Level(0, defLevel, ci)
+		}
+		prows = append(prows, prow)
+	}
+	_, err := e.w.WriteRows(prows)
+	return err
+}
+
+func parquetValue(v interface{}) parquet.Value {
+	switch v := v.(type) {
+	case bool:
+		return parquet.BooleanValue(v)
+	case int64:
+		return parquet.Int64Value(v)
+	case uint64:
+		return parquet.Int64Value(int64(v))
+	case float64:
+		return parquet.DoubleValue(v)
+	case string:
+		return parquet.ByteArrayValue([]byte(v))
+	}
+	return parquet.NullValue()
+}
+
+func (e *parquetEncoder) flush() error {
+	return e.w.Flush()
+}
+
+func (e *parquetEncoder) close() error {
+	return e.w.Close()
+}
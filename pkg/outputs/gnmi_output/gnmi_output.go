@@ -54,6 +54,7 @@ func init() {
 type gNMIOutput struct {
 	cfg       *config
 	logger    *log.Logger
+	dryRun    bool
 	targetTpl *template.Template
 	//
 	srv     *server
@@ -98,6 +99,9 @@ func (g *gNMIOutput) Init(ctx context.Context, name string, cfg map[string]inter
 			return err
 		}
 	}
+	if g.dryRun {
+		return nil
+	}
 	err = g.startGRPCServer()
 	if err != nil {
 		return err
@@ -184,6 +188,8 @@ func (g *gNMIOutput) SetName(string) {}
 
 func (g *gNMIOutput) SetClusterName(string) {}
 
+func (g *gNMIOutput) SetDryRun(dryRun bool) { g.dryRun = dryRun }
+
 func (g *gNMIOutput) SetTargetsConfig(tcs map[string]*types.TargetConfig) {
 	if g.srv == nil {
 		return
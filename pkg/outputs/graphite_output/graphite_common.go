@@ -0,0 +1,223 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphite_output
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	// DefaultNameTemplate builds metric names like
+	// <target>.<event name>.<key tags values>.<value path>
+	DefaultNameTemplate = "{{ .Target }}.{{ .Name }}.{{ .Keys }}.{{ .Value }}"
+	invalidCharsRegex   = `[^a-zA-Z0-9_\-]+`
+)
+
+var (
+	InvalidCharsRegex = regexp.MustCompile(invalidCharsRegex)
+	// metaTags are added by gNMIc to every event,
+	// they are not part of the .Keys path.
+	metaTags = map[string]struct{}{
+		"source":              {},
+		"subscription-name":   {},
+		"subscription-target": {},
+	}
+)
+
+// Metric is a numeric event value with a dotted name.
+type Metric struct {
+	Name  string
+	Tags  map[string]string
+	Value float64
+	Time  time.Time
+}
+
+// NameData is the input of the metric name template.
+// The dots in the tags values are replaced with an underscore
+// so that they do not add path components to the metric name.
+type NameData struct {
+	// Target is the subscription target if set,
+	// otherwise the event source without the port number.
+	Target       string
+	Source       string
+	Subscription string
+	// Name is the event name.
+	Name string
+	// Keys are the event tags values, excluding source, subscription-name
+	// and subscription-target, sorted by tag name and joined with dots.
+	Keys string
+	// Value is the value name with its slashes replaced with dots.
+	Value string
+	Tags  map[string]string
+}
+
+type MetricBuilder struct {
+	Prefix             string
+	NameTemplate       *template.Template
+	OverrideTimestamps bool
+}
+
+// NewMetricBuilder parses the metric name template,
+// an empty template is replaced with DefaultNameTemplate.
+func NewMetricBuilder(prefix, nameTemplate string, overrideTimestamps bool) (*MetricBuilder, error) {
+	if nameTemplate == "" {
+		nameTemplate = DefaultNameTemplate
+	}
+	tpl, err := gtemplate.CreateTemplate("name-template", nameTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &MetricBuilder{
+		Prefix:             prefix,
+		NameTemplate:       tpl.Funcs(outputs.TemplateFuncs),
+		OverrideTimestamps: overrideTimestamps,
+	}, nil
+}
+
+// MetricsFromEvent returns a metric per numeric event value,
+// the values that cannot be converted to a float are skipped.
+func (m *MetricBuilder) MetricsFromEvent(ev *formatters.EventMsg, now time.Time) ([]*Metric, error) {
+	ts := now
+	if !m.OverrideTimestamps && ev.Timestamp > 0 {
+		ts = time.Unix(0, ev.Timestamp)
+	}
+	data := &NameData{
+		Target:       targetName(ev.Tags),
+		Source:       ev.Tags["source"],
+		Subscription: ev.Tags["subscription-name"],
+		Name:         ev.Name,
+		Tags:         make(map[string]string, len(ev.Tags)),
+	}
+	keys := make([]string, 0, len(ev.Tags))
+	for k, v := range ev.Tags {
+		data.Tags[k] = strings.ReplaceAll(v, ".", "_")
+		if _, ok := metaTags[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		keys[i] = data.Tags[k]
+	}
+	data.Keys = strings.Join(keys, ".")
+	data.Target = strings.ReplaceAll(data.Target, ".", "_")
+
+	vNames := make([]string, 0, len(ev.Values))
+	for k := range ev.Values {
+		vNames = append(vNames, k)
+	}
+	sort.Strings(vNames)
+
+	metrics := make([]*Metric, 0, len(ev.Values))
+	for _, vName := range vNames {
+		v, err := toFloat(ev.Values[vName])
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		data.Value = strings.ReplaceAll(strings.Trim(vName, "/"), "/", ".")
+		name, err := m.MetricName(data)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, &Metric{
+			Name:  name,
+			Tags:  ev.Tags,
+			Value: v,
+			Time:  ts,
+		})
+	}
+	return metrics, nil
+}
+
+// MetricName renders the name template and prepends the prefix.
+// The empty path components are removed and the characters
+// other than letters, digits, underscores and dashes are replaced with an underscore.
+func (m *MetricBuilder) MetricName(data *NameData) (string, error) {
+	sb := new(strings.Builder)
+	if m.Prefix != "" {
+		sb.WriteString(m.Prefix)
+		sb.WriteString(".")
+	}
+	err := m.NameTemplate.Execute(sb, data)
+	if err != nil {
+		return "", err
+	}
+	comps := strings.Split(sb.String(), ".")
+	name := make([]string, 0, len(comps))
+	for _, c := range comps {
+		c = InvalidCharsRegex.ReplaceAllString(strings.TrimSpace(c), "_")
+		if c == "" {
+			continue
+		}
+		name = append(name, c)
+	}
+	if len(name) == 0 {
+		return "", errors.New("name template rendered an empty metric name")
+	}
+	return strings.Join(name, "."), nil
+}
+
+// targetName returns the subscription target if set,
+// otherwise the source without the port number.
+func targetName(tags map[string]string) string {
+	if t := tags["subscription-target"]; t != "" {
+		return t
+	}
+	return utils.GetHost(tags["source"])
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch i := v.(type) {
+	case float64:
+		return i, nil
+	case float32:
+		return float64(i), nil
+	case int64:
+		return float64(i), nil
+	case int32:
+		return float64(i), nil
+	case int16:
+		return float64(i), nil
+	case int8:
+		return float64(i), nil
+	case uint64:
+		return float64(i), nil
+	case uint32:
+		return float64(i), nil
+	case uint16:
+		return float64(i), nil
+	case uint8:
+		return float64(i), nil
+	case int:
+		return float64(i), nil
+	case uint:
+		return float64(i), nil
+	case bool:
+		if i {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseFloat(i, 64)
+	default:
+		return math.NaN(), errors.New("toFloat: unknown value is of incompatible type")
+	}
+}
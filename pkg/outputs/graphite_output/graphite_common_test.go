@@ -0,0 +1,140 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphite_output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+var metricsFromEventSet = map[string]struct {
+	prefix     string
+	tpl        string
+	overrideTS bool
+	ev         *formatters.EventMsg
+	want       []*Metric
+}{
+	"default_template": {
+		ev: &formatters.EventMsg{
+			Name:      "sub1",
+			Timestamp: 1700000000000000000,
+			Tags: map[string]string{
+				"source":            "10.0.0.1:57400",
+				"subscription-name": "sub1",
+				"interface_name":    "ethernet-1/1",
+			},
+			Values: map[string]interface{}{
+				"/interfaces/interface/state/counters/in-octets": uint64(42),
+			},
+		},
+		want: []*Metric{
+			{
+				Name:  "10_0_0_1.sub1.ethernet-1_1.interfaces.interface.state.counters.in-octets",
+				Value: 42,
+				Time:  time.Unix(0, 1700000000000000000),
+			},
+		},
+	},
+	"prefix_custom_template": {
+		prefix: "network",
+		tpl:    `{{ .Target }}.{{ index .Tags "interface_name" }}.{{ .Value }}`,
+		ev: &formatters.EventMsg{
+			Name:      "sub1",
+			Timestamp: 1700000000000000000,
+			Tags: map[string]string{
+				"source":              "10.0.0.1:57400",
+				"subscription-target": "router1.dc1",
+				"interface_name":      "ethernet-1/1.10",
+			},
+			Values: map[string]interface{}{
+				"/interfaces/interface/state/oper-status": "UP",
+				"/interfaces/interface/state/mtu":         "1500",
+				"/interfaces/interface/state/enabled":     true,
+			},
+		},
+		want: []*Metric{
+			{
+				Name:  "network.router1_dc1.ethernet-1_1_10.interfaces.interface.state.enabled",
+				Value: 1,
+				Time:  time.Unix(0, 1700000000000000000),
+			},
+			{
+				Name:  "network.router1_dc1.ethernet-1_1_10.interfaces.interface.state.mtu",
+				Value: 1500,
+				Time:  time.Unix(0, 1700000000000000000),
+			},
+		},
+	},
+	"keys_sorted_by_tag_name": {
+		tpl:        "{{ .Name }}.{{ .Keys }}.{{ .Value }}",
+		overrideTS: true,
+		ev: &formatters.EventMsg{
+			Name:      "bgp",
+			Timestamp: 1700000000000000000,
+			Tags: map[string]string{
+				"source":        "router1",
+				"peer_address":  "192.168.1.1",
+				"instance_name": "default",
+			},
+			Values: map[string]interface{}{
+				"received-routes": int64(-3),
+			},
+		},
+		want: []*Metric{
+			{
+				Name:  "bgp.default.192_168_1_1.received-routes",
+				Value: -3,
+				Time:  time.Unix(0, 1800000000000000000),
+			},
+		},
+	},
+}
+
+func TestMetricsFromEvent(t *testing.T) {
+	now := time.Unix(0, 1800000000000000000)
+	for name, tc := range metricsFromEventSet {
+		t.Run(name, func(t *testing.T) {
+			mb, err := NewMetricBuilder(tc.prefix, tc.tpl, tc.overrideTS)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := mb.MetricsFromEvent(tc.ev, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d metrics, expected %d: %+v", len(got), len(tc.want), got)
+			}
+			for i := range got {
+				if got[i].Name != tc.want[i].Name {
+					t.Errorf("metric %d: got name %q, expected %q", i, got[i].Name, tc.want[i].Name)
+				}
+				if got[i].Value != tc.want[i].Value {
+					t.Errorf("metric %d: got value %v, expected %v", i, got[i].Value, tc.want[i].Value)
+				}
+				if !got[i].Time.Equal(tc.want[i].Time) {
+					t.Errorf("metric %d: got time %v, expected %v", i, got[i].Time, tc.want[i].Time)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricNameEmpty(t *testing.T) {
+	mb, err := NewMetricBuilder("", "{{ .Keys }}", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = mb.MetricName(&NameData{})
+	if err == nil {
+		t.Fatal("expected an error for an empty metric name")
+	}
+}
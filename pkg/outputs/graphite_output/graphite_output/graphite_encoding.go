@@ -0,0 +1,70 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphite_output
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strconv"
+
+	graphite "github.com/openconfig/gnmic/pkg/outputs/graphite_output"
+)
+
+// pickle protocol 2 opcodes
+const (
+	pickleProto      = 0x80
+	pickleEmptyList  = ']'
+	pickleMark       = '('
+	pickleAppends    = 'e'
+	pickleBinUnicode = 'X'
+	pickleBinFloat   = 'G'
+	pickleTuple2     = 0x86
+	pickleStop       = '.'
+)
+
+// encodePlaintext encodes the metrics using the carbon plaintext protocol:
+// one "<name> <value> <unix timestamp>" line per metric.
+func encodePlaintext(metrics []*graphite.Metric) []byte {
+	b := make([]byte, 0, len(metrics)*64)
+	for _, m := range metrics {
+		b = append(b, m.Name...)
+		b = append(b, ' ')
+		b = strconv.AppendFloat(b, m.Value, 'f', -1, 64)
+		b = append(b, ' ')
+		b = strconv.AppendInt(b, m.Time.Unix(), 10)
+		b = append(b, '\n')
+	}
+	return b
+}
+
+// encodePickle encodes the metrics using the carbon pickle protocol:
+// a 4 bytes big endian length header followed by a pickled
+// list of (name, (timestamp, value)) tuples.
+func encodePickle(metrics []*graphite.Metric) []byte {
+	buf := new(bytes.Buffer)
+	// reserve the length header
+	buf.Write([]byte{0, 0, 0, 0})
+	buf.Write([]byte{pickleProto, 2, pickleEmptyList, pickleMark})
+	for _, m := range metrics {
+		buf.WriteByte(pickleBinUnicode)
+		binary.Write(buf, binary.LittleEndian, uint32(len(m.Name)))
+		buf.WriteString(m.Name)
+		buf.WriteByte(pickleBinFloat)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(float64(m.Time.Unix())))
+		buf.WriteByte(pickleBinFloat)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(m.Value))
+		buf.WriteByte(pickleTuple2)
+		buf.WriteByte(pickleTuple2)
+	}
+	buf.Write([]byte{pickleAppends, pickleStop})
+	b := buf.Bytes()
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	return b
+}
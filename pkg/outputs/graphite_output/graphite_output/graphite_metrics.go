@@ -0,0 +1,69 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphite_output
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	namespace = "gnmic"
+	subsystem = "graphite_output"
+)
+
+var graphiteNumberOfSentMetrics = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_sent_metrics_total",
+	Help:      "Number of metrics successfully sent by gnmic graphite output",
+}, []string{"name"})
+
+var graphiteNumberOfSentBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_bytes_written_total",
+	Help:      "Number of bytes written by gnmic graphite output",
+}, []string{"name"})
+
+var graphiteNumberOfFailedMetrics = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_failed_metrics_total",
+	Help:      "Number of metrics gnmic graphite output failed to send",
+}, []string{"name", "reason"})
+
+var graphiteSendDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "send_duration_ns",
+	Help:      "gnmic graphite output batch send duration in ns",
+}, []string{"name"})
+
+func initMetrics() {
+	graphiteNumberOfSentMetrics.WithLabelValues("").Add(0)
+	graphiteNumberOfSentBytes.WithLabelValues("").Add(0)
+	graphiteNumberOfFailedMetrics.WithLabelValues("", "").Add(0)
+	graphiteSendDuration.WithLabelValues("").Set(0)
+}
+
+func registerMetrics(reg *prometheus.Registry) error {
+	initMetrics()
+	var err error
+	if err = reg.Register(graphiteNumberOfSentMetrics); err != nil {
+		return err
+	}
+	if err = reg.Register(graphiteNumberOfSentBytes); err != nil {
+		return err
+	}
+	if err = reg.Register(graphiteNumberOfFailedMetrics); err != nil {
+		return err
+	}
+	if err = reg.Register(graphiteSendDuration); err != nil {
+		return err
+	}
+	return nil
+}
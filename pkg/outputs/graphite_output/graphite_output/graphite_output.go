@@ -0,0 +1,448 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphite_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+	graphite "github.com/openconfig/gnmic/pkg/outputs/graphite_output"
+)
+
+const (
+	outputType           = "graphite"
+	loggingPrefix        = "[graphite_output:%s] "
+	defaultAddress       = "localhost:2003"
+	defaultProtocol      = "plaintext"
+	defaultBatchSize     = 500
+	defaultBufferSize    = 1000
+	defaultFlushInterval = 5 * time.Second
+	defaultWriteTimeout  = 10 * time.Second
+	defaultRetryInterval = 2 * time.Second
+	defaultMaxRetries    = 3
+	defaultNumWorkers    = 1
+)
+
+func init() {
+	outputs.Register(outputType, func() outputs.Output {
+		return &graphiteOutput{
+			cfg:       &config{},
+			logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+			eventChan: make(chan *formatters.EventMsg),
+			msgChan:   make(chan *outputs.ProtoMsg),
+		}
+	})
+}
+
+type graphiteOutput struct {
+	cfg    *config
+	logger *log.Logger
+	dryRun bool
+
+	eventChan chan *formatters.EventMsg
+	msgChan   chan *outputs.ProtoMsg
+	metricsCh chan *graphite.Metric
+	mb        *graphite.MetricBuilder
+	encode    func([]*graphite.Metric) []byte
+	// conn is only used by the writer goroutine.
+	conn net.Conn
+
+	evps      []formatters.EventProcessor
+	targetTpl *template.Template
+	cfn       context.CancelFunc
+}
+
+type config struct {
+	Name               string        `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Address            string        `mapstructure:"address,omitempty" json:"address,omitempty"`
+	Protocol           string        `mapstructure:"protocol,omitempty" json:"protocol,omitempty"`
+	Prefix             string        `mapstructure:"prefix,omitempty" json:"prefix,omitempty"`
+	NameTemplate       string        `mapstructure:"name-template,omitempty" json:"name-template,omitempty"`
+	OverrideTimestamps bool          `mapstructure:"override-timestamps,omitempty" json:"override-timestamps,omitempty"`
+	BatchSize          int           `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	BufferSize         int           `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	FlushInterval      time.Duration `mapstructure:"flush-interval,omitempty" json:"flush-interval,omitempty"`
+	KeepAlive          time.Duration `mapstructure:"keep-alive,omitempty" json:"keep-alive,omitempty"`
+	WriteTimeout       time.Duration `mapstructure:"write-timeout,omitempty" json:"write-timeout,omitempty"`
+	RetryInterval      time.Duration `mapstructure:"retry-interval,omitempty" json:"retry-interval,omitempty"`
+	MaxRetries         int           `mapstructure:"max-retries,omitempty" json:"max-retries,omitempty"`
+	AddTarget          string        `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate     string        `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors    []string      `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers         int           `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	Debug              bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	EnableMetrics      bool          `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+}
+
+func (g *graphiteOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, g.cfg)
+	if err != nil {
+		return err
+	}
+	if g.cfg.Name == "" {
+		g.cfg.Name = name
+	}
+	g.logger.SetPrefix(fmt.Sprintf(loggingPrefix, g.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(g); err != nil {
+			return err
+		}
+	}
+	err = g.setDefaults()
+	if err != nil {
+		return err
+	}
+	switch g.cfg.Protocol {
+	case "plaintext":
+		g.encode = encodePlaintext
+	case "pickle":
+		g.encode = encodePickle
+	}
+
+	if g.cfg.TargetTemplate == "" {
+		g.targetTpl = outputs.DefaultTargetTemplate
+	} else if g.cfg.AddTarget != "" {
+		g.targetTpl, err = gtemplate.CreateTemplate("target-template", g.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		g.targetTpl = g.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+	g.mb, err = graphite.NewMetricBuilder(g.cfg.Prefix, g.cfg.NameTemplate, g.cfg.OverrideTimestamps)
+	if err != nil {
+		return fmt.Errorf("invalid name template: %w", err)
+	}
+
+	if g.dryRun {
+		return nil
+	}
+	g.metricsCh = make(chan *graphite.Metric, g.cfg.BufferSize)
+	ctx, g.cfn = context.WithCancel(ctx)
+	for i := 0; i < g.cfg.NumWorkers; i++ {
+		go g.worker(ctx)
+	}
+	go g.writer(ctx)
+	g.logger.Printf("initialized graphite output %s: %s", g.cfg.Name, g.String())
+	return nil
+}
+
+func (g *graphiteOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, g.cfg.WriteTimeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case g.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if g.cfg.Debug {
+			g.logger.Printf("writing expired after %s", g.cfg.WriteTimeout)
+		}
+		graphiteNumberOfFailedMetrics.WithLabelValues(g.cfg.Name, "timeout").Inc()
+		return
+	}
+}
+
+func (g *graphiteOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range g.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case g.eventChan <- pev:
+			}
+		}
+	}
+}
+
+func (g *graphiteOutput) Close() error {
+	if g.cfn != nil {
+		g.cfn()
+	}
+	return nil
+}
+
+func (g *graphiteOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !g.cfg.EnableMetrics {
+		return
+	}
+	if reg == nil {
+		g.logger.Printf("ERR: output metrics enabled but main registry is not initialized, enable main metrics under `api-server`")
+		return
+	}
+	if err := registerMetrics(reg); err != nil {
+		g.logger.Printf("failed to register metric: %v", err)
+	}
+}
+
+func (g *graphiteOutput) String() string {
+	b, err := json.Marshal(g.cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (g *graphiteOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && g.logger != nil {
+		g.logger.SetOutput(logger.Writer())
+		g.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (g *graphiteOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	g.evps, err = formatters.MakeEventProcessors(
+		logger,
+		g.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (g *graphiteOutput) SetName(name string) {
+	if g.cfg.Name == "" {
+		g.cfg.Name = name
+	}
+}
+
+func (g *graphiteOutput) SetClusterName(_ string) {}
+
+func (g *graphiteOutput) SetDryRun(dryRun bool) { g.dryRun = dryRun }
+
+func (g *graphiteOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (g *graphiteOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-g.eventChan:
+			g.handleEvent(ctx, ev)
+		case pm := <-g.msgChan:
+			meta := pm.GetMeta()
+			rsp, err := outputs.AddSubscriptionTarget(pm.GetMsg(), meta, g.cfg.AddTarget, g.targetTpl)
+			if err != nil {
+				g.logger.Printf("failed to add target to the response: %v", err)
+			}
+			if rsp == nil {
+				continue
+			}
+			subName := "default"
+			if sn, ok := meta["subscription-name"]; ok {
+				subName = sn
+			}
+			events, err := formatters.ResponseToEventMsgs(subName, rsp, meta, g.evps...)
+			if err != nil {
+				g.logger.Printf("failed to convert message to event: %v", err)
+				continue
+			}
+			for _, ev := range events {
+				g.handleEvent(ctx, ev)
+			}
+		}
+	}
+}
+
+func (g *graphiteOutput) handleEvent(ctx context.Context, ev *formatters.EventMsg) {
+	metrics, err := g.mb.MetricsFromEvent(ev, time.Now())
+	if err != nil {
+		g.logger.Printf("failed to build metric name: %v", err)
+		graphiteNumberOfFailedMetrics.WithLabelValues(g.cfg.Name, "template_error").Inc()
+		return
+	}
+	for _, m := range metrics {
+		select {
+		case <-ctx.Done():
+			return
+		case g.metricsCh <- m:
+		}
+	}
+}
+
+// writer batches the metrics and sends them when the batch size
+// is reached or every flush interval.
+func (g *graphiteOutput) writer(ctx context.Context) {
+	ticker := time.NewTicker(g.cfg.FlushInterval)
+	defer ticker.Stop()
+	defer func() {
+		if g.conn != nil {
+			g.conn.Close()
+		}
+	}()
+	batch := make([]*graphite.Metric, 0, g.cfg.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-g.metricsCh:
+			batch = append(batch, m)
+			if len(batch) < g.cfg.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		g.flush(ctx, batch)
+		batch = make([]*graphite.Metric, 0, g.cfg.BatchSize)
+	}
+}
+
+// flush sends a batch of metrics, reconnecting and retrying
+// up to max-retries times if the write fails.
+func (g *graphiteOutput) flush(ctx context.Context, batch []*graphite.Metric) {
+	b := g.encode(batch)
+	policy := outputs.RetryPolicy{
+		MaxRetries: g.cfg.MaxRetries,
+		Backoff:    g.cfg.RetryInterval,
+	}
+	err := policy.Do(ctx, func() error {
+		start := time.Now()
+		err := g.send(b)
+		if err != nil {
+			g.logger.Printf("failed to send %d metrics: %v", len(batch), err)
+			return err
+		}
+		graphiteSendDuration.WithLabelValues(g.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+		graphiteNumberOfSentMetrics.WithLabelValues(g.cfg.Name).Add(float64(len(batch)))
+		graphiteNumberOfSentBytes.WithLabelValues(g.cfg.Name).Add(float64(len(b)))
+		if g.cfg.Debug {
+			g.logger.Printf("sent %d metrics", len(batch))
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		graphiteNumberOfFailedMetrics.WithLabelValues(g.cfg.Name, "max_retries").Add(float64(len(batch)))
+	}
+}
+
+// send writes b to the carbon receiver, the connection is (re)established
+// if needed and closed if the write fails.
+func (g *graphiteOutput) send(b []byte) error {
+	if g.conn != nil && peerClosed(g.conn) {
+		if g.cfg.Debug {
+			g.logger.Printf("connection closed by %s, reconnecting", g.cfg.Address)
+		}
+		g.conn.Close()
+		g.conn = nil
+	}
+	if g.conn == nil {
+		d := &net.Dialer{
+			Timeout:   g.cfg.WriteTimeout,
+			KeepAlive: g.cfg.KeepAlive,
+		}
+		conn, err := d.Dial("tcp", g.cfg.Address)
+		if err != nil {
+			return err
+		}
+		g.conn = conn
+	}
+	err := g.conn.SetWriteDeadline(time.Now().Add(g.cfg.WriteTimeout))
+	if err == nil {
+		_, err = g.conn.Write(b)
+	}
+	if err != nil {
+		g.conn.Close()
+		g.conn = nil
+		return err
+	}
+	return nil
+}
+
+// peerClosed checks if the carbon receiver closed the connection.
+// The receiver never writes to the connection so a read either times out
+// or returns an error, without it the first write after the connection
+// is closed by the peer succeeds and its metrics are lost.
+func peerClosed(conn net.Conn) bool {
+	err := conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	if err != nil {
+		return true
+	}
+	defer conn.SetReadDeadline(time.Time{})
+	_, err = conn.Read(make([]byte, 1))
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return false
+	}
+	return err != nil
+}
+
+func (g *graphiteOutput) setDefaults() error {
+	if g.cfg.Address == "" {
+		g.cfg.Address = defaultAddress
+	}
+	if _, _, err := net.SplitHostPort(g.cfg.Address); err != nil {
+		return fmt.Errorf("wrong address format: %v", err)
+	}
+	if g.cfg.Protocol == "" {
+		g.cfg.Protocol = defaultProtocol
+	}
+	if g.cfg.Protocol != "plaintext" && g.cfg.Protocol != "pickle" {
+		return errors.New("unsupported protocol, expected 'plaintext' or 'pickle'")
+	}
+	if g.cfg.BatchSize <= 0 {
+		g.cfg.BatchSize = defaultBatchSize
+	}
+	if g.cfg.BufferSize <= 0 {
+		g.cfg.BufferSize = defaultBufferSize
+	}
+	if g.cfg.FlushInterval <= 0 {
+		g.cfg.FlushInterval = defaultFlushInterval
+	}
+	if g.cfg.WriteTimeout <= 0 {
+		g.cfg.WriteTimeout = defaultWriteTimeout
+	}
+	if g.cfg.RetryInterval <= 0 {
+		g.cfg.RetryInterval = defaultRetryInterval
+	}
+	g.cfg.MaxRetries = outputs.MaxRetries(g.cfg.MaxRetries, defaultMaxRetries)
+	if g.cfg.NumWorkers <= 0 {
+		g.cfg.NumWorkers = defaultNumWorkers
+	}
+	return nil
+}
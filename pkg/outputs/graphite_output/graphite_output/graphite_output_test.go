@@ -0,0 +1,115 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphite_output
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+	graphite "github.com/openconfig/gnmic/pkg/outputs/graphite_output"
+)
+
+var testMetrics = []*graphite.Metric{
+	{Name: "a.b", Value: 1.5, Time: time.Unix(1700000000, 0)},
+	{Name: "c", Value: -2, Time: time.Unix(1700000000, 0)},
+}
+
+func TestEncodePlaintext(t *testing.T) {
+	got := string(encodePlaintext(testMetrics))
+	want := "a.b 1.5 1700000000\nc -2 1700000000\n"
+	if got != want {
+		t.Errorf("got %q, expected %q", got, want)
+	}
+}
+
+func TestEncodePickle(t *testing.T) {
+	got := encodePickle(testMetrics)
+	want := []byte{
+		0, 0, 0, 60, // length header
+		0x80, 2, ']', '(',
+		'X', 3, 0, 0, 0, 'a', '.', 'b',
+		'G', 0x41, 0xd9, 0x54, 0xfc, 0x40, 0, 0, 0,
+		'G', 0x3f, 0xf8, 0, 0, 0, 0, 0, 0,
+		0x86, 0x86,
+		'X', 1, 0, 0, 0, 'c',
+		'G', 0x41, 0xd9, 0x54, 0xfc, 0x40, 0, 0, 0,
+		'G', 0xc0, 0, 0, 0, 0, 0, 0, 0,
+		0x86, 0x86,
+		'e', '.',
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %x, expected %x", got, want)
+	}
+}
+
+func TestWriteEventReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			// read a single line and drop the connection
+			// to force the output to reconnect.
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			if err == nil {
+				lines <- line
+			}
+			conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	o := &graphiteOutput{
+		cfg:       &config{},
+		logger:    log.New(io.Discard, "", 0),
+		eventChan: make(chan *formatters.EventMsg),
+		msgChan:   make(chan *outputs.ProtoMsg),
+	}
+	err = o.Init(ctx, "test", map[string]interface{}{
+		"address":        l.Addr().String(),
+		"batch-size":     1,
+		"retry-interval": "10ms",
+		"name-template":  "{{ .Name }}.{{ .Value }}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	for i, want := range []string{"sub1.counter 1 1700000000\n", "sub1.counter 2 1700000001\n"} {
+		o.WriteEvent(ctx, &formatters.EventMsg{
+			Name:      "sub1",
+			Timestamp: int64(1700000000+i) * int64(time.Second),
+			Values:    map[string]interface{}{"counter": i + 1},
+		})
+		select {
+		case got := <-lines:
+			if got != want {
+				t.Errorf("got %q, expected %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for metric %d", i)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package statsd_output
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	namespace = "gnmic"
+	subsystem = "statsd_output"
+)
+
+var statsdNumberOfSentMetrics = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_sent_metrics_total",
+	Help:      "Number of metrics successfully sent by gnmic statsd output",
+}, []string{"name"})
+
+var statsdNumberOfSentBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_bytes_written_total",
+	Help:      "Number of bytes written by gnmic statsd output",
+}, []string{"name"})
+
+var statsdNumberOfFailedMetrics = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_failed_metrics_total",
+	Help:      "Number of metrics gnmic statsd output failed to send",
+}, []string{"name", "reason"})
+
+func initMetrics() {
+	statsdNumberOfSentMetrics.WithLabelValues("").Add(0)
+	statsdNumberOfSentBytes.WithLabelValues("").Add(0)
+	statsdNumberOfFailedMetrics.WithLabelValues("", "").Add(0)
+}
+
+func registerMetrics(reg *prometheus.Registry) error {
+	initMetrics()
+	var err error
+	if err = reg.Register(statsdNumberOfSentMetrics); err != nil {
+		return err
+	}
+	if err = reg.Register(statsdNumberOfSentBytes); err != nil {
+		return err
+	}
+	if err = reg.Register(statsdNumberOfFailedMetrics); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,429 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package statsd_output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+	graphite "github.com/openconfig/gnmic/pkg/outputs/graphite_output"
+)
+
+const (
+	outputType           = "statsd"
+	loggingPrefix        = "[statsd_output:%s] "
+	defaultAddress       = "localhost:8125"
+	dogStatsDTemplate    = "{{ .Name }}.{{ .Value }}"
+	defaultMaxPacketSize = 1432
+	defaultBufferSize    = 1000
+	defaultFlushInterval = time.Second
+	defaultWriteTimeout  = 5 * time.Second
+	defaultNumWorkers    = 1
+)
+
+var (
+	dogStatsDTagReplacer = strings.NewReplacer(",", "_", "|", "_", "#", "_", " ", "_", "\n", "_")
+	dogStatsDKeyReplacer = strings.NewReplacer(":", "_")
+)
+
+func init() {
+	outputs.Register(outputType, func() outputs.Output {
+		return &statsdOutput{
+			cfg:       &config{},
+			logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+			eventChan: make(chan *formatters.EventMsg),
+			msgChan:   make(chan *outputs.ProtoMsg),
+		}
+	})
+}
+
+type statsdOutput struct {
+	cfg    *config
+	logger *log.Logger
+	dryRun bool
+
+	eventChan chan *formatters.EventMsg
+	msgChan   chan *outputs.ProtoMsg
+	linesCh   chan []byte
+	mb        *graphite.MetricBuilder
+	// conn is only used by the writer goroutine.
+	conn net.Conn
+
+	evps      []formatters.EventProcessor
+	targetTpl *template.Template
+	cfn       context.CancelFunc
+}
+
+type config struct {
+	Name            string        `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Address         string        `mapstructure:"address,omitempty" json:"address,omitempty"`
+	Prefix          string        `mapstructure:"prefix,omitempty" json:"prefix,omitempty"`
+	NameTemplate    string        `mapstructure:"name-template,omitempty" json:"name-template,omitempty"`
+	DogStatsD       bool          `mapstructure:"dogstatsd,omitempty" json:"dogstatsd,omitempty"`
+	MaxPacketSize   int           `mapstructure:"max-packet-size,omitempty" json:"max-packet-size,omitempty"`
+	BufferSize      int           `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	FlushInterval   time.Duration `mapstructure:"flush-interval,omitempty" json:"flush-interval,omitempty"`
+	WriteTimeout    time.Duration `mapstructure:"write-timeout,omitempty" json:"write-timeout,omitempty"`
+	AddTarget       string        `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate  string        `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors []string      `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers      int           `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	Debug           bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	EnableMetrics   bool          `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+}
+
+func (s *statsdOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, s.cfg)
+	if err != nil {
+		return err
+	}
+	if s.cfg.Name == "" {
+		s.cfg.Name = name
+	}
+	s.logger.SetPrefix(fmt.Sprintf(loggingPrefix, s.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return err
+		}
+	}
+	err = s.setDefaults()
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.TargetTemplate == "" {
+		s.targetTpl = outputs.DefaultTargetTemplate
+	} else if s.cfg.AddTarget != "" {
+		s.targetTpl, err = gtemplate.CreateTemplate("target-template", s.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		s.targetTpl = s.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+	// statsd does not carry timestamps, the metrics are timestamped on reception.
+	s.mb, err = graphite.NewMetricBuilder(s.cfg.Prefix, s.cfg.NameTemplate, true)
+	if err != nil {
+		return fmt.Errorf("invalid name template: %w", err)
+	}
+
+	if s.dryRun {
+		return nil
+	}
+	s.linesCh = make(chan []byte, s.cfg.BufferSize)
+	ctx, s.cfn = context.WithCancel(ctx)
+	for i := 0; i < s.cfg.NumWorkers; i++ {
+		go s.worker(ctx)
+	}
+	go s.writer(ctx)
+	s.logger.Printf("initialized statsd output %s: %s", s.cfg.Name, s.String())
+	return nil
+}
+
+func (s *statsdOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, s.cfg.WriteTimeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case s.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if s.cfg.Debug {
+			s.logger.Printf("writing expired after %s", s.cfg.WriteTimeout)
+		}
+		statsdNumberOfFailedMetrics.WithLabelValues(s.cfg.Name, "timeout").Inc()
+		return
+	}
+}
+
+func (s *statsdOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range s.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case s.eventChan <- pev:
+			}
+		}
+	}
+}
+
+func (s *statsdOutput) Close() error {
+	if s.cfn != nil {
+		s.cfn()
+	}
+	return nil
+}
+
+func (s *statsdOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !s.cfg.EnableMetrics {
+		return
+	}
+	if reg == nil {
+		s.logger.Printf("ERR: output metrics enabled but main registry is not initialized, enable main metrics under `api-server`")
+		return
+	}
+	if err := registerMetrics(reg); err != nil {
+		s.logger.Printf("failed to register metric: %v", err)
+	}
+}
+
+func (s *statsdOutput) String() string {
+	b, err := json.Marshal(s.cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (s *statsdOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && s.logger != nil {
+		s.logger.SetOutput(logger.Writer())
+		s.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (s *statsdOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	s.evps, err = formatters.MakeEventProcessors(
+		logger,
+		s.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *statsdOutput) SetName(name string) {
+	if s.cfg.Name == "" {
+		s.cfg.Name = name
+	}
+}
+
+func (s *statsdOutput) SetClusterName(_ string) {}
+
+func (s *statsdOutput) SetDryRun(dryRun bool) { s.dryRun = dryRun }
+
+func (s *statsdOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (s *statsdOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-s.eventChan:
+			s.handleEvent(ctx, ev)
+		case pm := <-s.msgChan:
+			meta := pm.GetMeta()
+			rsp, err := outputs.AddSubscriptionTarget(pm.GetMsg(), meta, s.cfg.AddTarget, s.targetTpl)
+			if err != nil {
+				s.logger.Printf("failed to add target to the response: %v", err)
+			}
+			if rsp == nil {
+				continue
+			}
+			subName := "default"
+			if sn, ok := meta["subscription-name"]; ok {
+				subName = sn
+			}
+			events, err := formatters.ResponseToEventMsgs(subName, rsp, meta, s.evps...)
+			if err != nil {
+				s.logger.Printf("failed to convert message to event: %v", err)
+				continue
+			}
+			for _, ev := range events {
+				s.handleEvent(ctx, ev)
+			}
+		}
+	}
+}
+
+func (s *statsdOutput) handleEvent(ctx context.Context, ev *formatters.EventMsg) {
+	metrics, err := s.mb.MetricsFromEvent(ev, time.Now())
+	if err != nil {
+		s.logger.Printf("failed to build metric name: %v", err)
+		statsdNumberOfFailedMetrics.WithLabelValues(s.cfg.Name, "template_error").Inc()
+		return
+	}
+	for _, m := range metrics {
+		select {
+		case <-ctx.Done():
+			return
+		case s.linesCh <- s.line(m):
+		}
+	}
+}
+
+// line encodes a metric as a statsd gauge, followed by
+// the metric tags if the DogStatsD extension is enabled.
+func (s *statsdOutput) line(m *graphite.Metric) []byte {
+	b := make([]byte, 0, 128)
+	// a signed gauge value is a delta in the original statsd protocol,
+	// a negative value is set by resetting the gauge to zero first.
+	if m.Value < 0 && !s.cfg.DogStatsD {
+		b = append(b, m.Name...)
+		b = append(b, ":0|g\n"...)
+	}
+	b = append(b, m.Name...)
+	b = append(b, ':')
+	b = strconv.AppendFloat(b, m.Value, 'f', -1, 64)
+	b = append(b, "|g"...)
+	if !s.cfg.DogStatsD || len(m.Tags) == 0 {
+		return b
+	}
+	keys := make([]string, 0, len(m.Tags))
+	for k := range m.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b = append(b, "|#"...)
+	for i, k := range keys {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, dogStatsDTagReplacer.Replace(dogStatsDKeyReplacer.Replace(k))...)
+		b = append(b, ':')
+		b = append(b, dogStatsDTagReplacer.Replace(m.Tags[k])...)
+	}
+	return b
+}
+
+// writer packs the metric lines in datagrams of up to max-packet-size bytes,
+// a datagram is sent when it is full or every flush interval.
+func (s *statsdOutput) writer(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+	defer func() {
+		if s.conn != nil {
+			s.conn.Close()
+		}
+	}()
+	packet := make([]byte, 0, s.cfg.MaxPacketSize)
+	numMetrics := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case l := <-s.linesCh:
+			if len(packet) > 0 && len(packet)+1+len(l) > s.cfg.MaxPacketSize {
+				s.send(packet, numMetrics)
+				packet = packet[:0]
+				numMetrics = 0
+			}
+			if len(packet) > 0 {
+				packet = append(packet, '\n')
+			}
+			packet = append(packet, l...)
+			numMetrics++
+		case <-ticker.C:
+			if len(packet) == 0 {
+				continue
+			}
+			s.send(packet, numMetrics)
+			packet = packet[:0]
+			numMetrics = 0
+		}
+	}
+}
+
+// send writes a datagram, a failed datagram is dropped
+// and the socket is re-created on the next send.
+func (s *statsdOutput) send(b []byte, numMetrics int) {
+	if s.conn == nil {
+		conn, err := net.Dial("udp", s.cfg.Address)
+		if err != nil {
+			s.logger.Printf("failed to dial %s: %v", s.cfg.Address, err)
+			statsdNumberOfFailedMetrics.WithLabelValues(s.cfg.Name, "dial_error").Add(float64(numMetrics))
+			return
+		}
+		s.conn = conn
+	}
+	err := s.conn.SetWriteDeadline(time.Now().Add(s.cfg.WriteTimeout))
+	if err == nil {
+		_, err = s.conn.Write(b)
+	}
+	if err != nil {
+		if s.cfg.Debug {
+			s.logger.Printf("failed to send %d metrics: %v", numMetrics, err)
+		}
+		statsdNumberOfFailedMetrics.WithLabelValues(s.cfg.Name, "write_error").Add(float64(numMetrics))
+		s.conn.Close()
+		s.conn = nil
+		return
+	}
+	statsdNumberOfSentMetrics.WithLabelValues(s.cfg.Name).Add(float64(numMetrics))
+	statsdNumberOfSentBytes.WithLabelValues(s.cfg.Name).Add(float64(len(b)))
+}
+
+func (s *statsdOutput) setDefaults() error {
+	if s.cfg.Address == "" {
+		s.cfg.Address = defaultAddress
+	}
+	if _, _, err := net.SplitHostPort(s.cfg.Address); err != nil {
+		return fmt.Errorf("wrong address format: %v", err)
+	}
+	if s.cfg.NameTemplate == "" && s.cfg.DogStatsD {
+		// the tags are sent separately
+		s.cfg.NameTemplate = dogStatsDTemplate
+	}
+	if s.cfg.MaxPacketSize <= 0 {
+		s.cfg.MaxPacketSize = defaultMaxPacketSize
+	}
+	if s.cfg.BufferSize <= 0 {
+		s.cfg.BufferSize = defaultBufferSize
+	}
+	if s.cfg.FlushInterval <= 0 {
+		s.cfg.FlushInterval = defaultFlushInterval
+	}
+	if s.cfg.WriteTimeout <= 0 {
+		s.cfg.WriteTimeout = defaultWriteTimeout
+	}
+	if s.cfg.NumWorkers <= 0 {
+		s.cfg.NumWorkers = defaultNumWorkers
+	}
+	return nil
+}
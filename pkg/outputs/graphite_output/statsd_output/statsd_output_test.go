@@ -0,0 +1,121 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package statsd_output
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+	graphite "github.com/openconfig/gnmic/pkg/outputs/graphite_output"
+)
+
+var lineSet = map[string]struct {
+	dogStatsD bool
+	m         *graphite.Metric
+	want      string
+}{
+	"gauge": {
+		m:    &graphite.Metric{Name: "r1.sub1.in-octets", Value: 42},
+		want: "r1.sub1.in-octets:42|g",
+	},
+	"negative_gauge": {
+		m:    &graphite.Metric{Name: "r1.sub1.temp", Value: -1.5},
+		want: "r1.sub1.temp:0|g\nr1.sub1.temp:-1.5|g",
+	},
+	"dogstatsd_tags": {
+		dogStatsD: true,
+		m: &graphite.Metric{
+			Name:  "sub1.temp",
+			Value: -1.5,
+			Tags: map[string]string{
+				"source":         "10.0.0.1:57400",
+				"interface_name": "ethernet-1/1,2",
+			},
+		},
+		want: "sub1.temp:-1.5|g|#interface_name:ethernet-1/1_2,source:10.0.0.1:57400",
+	},
+	"dogstatsd_no_tags": {
+		dogStatsD: true,
+		m:         &graphite.Metric{Name: "sub1.temp", Value: 1},
+		want:      "sub1.temp:1|g",
+	},
+}
+
+func TestLine(t *testing.T) {
+	for name, tc := range lineSet {
+		t.Run(name, func(t *testing.T) {
+			s := &statsdOutput{cfg: &config{DogStatsD: tc.dogStatsD}}
+			got := string(s.line(tc.m))
+			if got != tc.want {
+				t.Errorf("got %q, expected %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMaxPacketSize(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &statsdOutput{
+		cfg:       &config{},
+		logger:    log.New(io.Discard, "", 0),
+		eventChan: make(chan *formatters.EventMsg),
+		msgChan:   make(chan *outputs.ProtoMsg),
+	}
+	err = s.Init(ctx, "test", map[string]interface{}{
+		"address":         pc.LocalAddr().String(),
+		"max-packet-size": 40,
+		"flush-interval":  "50ms",
+		"name-template":   "{{ .Value }}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// each line is 15 bytes long, two lines fit in a 40 bytes datagram.
+	s.WriteEvent(ctx, &formatters.EventMsg{
+		Name: "sub1",
+		Values: map[string]interface{}{
+			"counter-1": 100,
+			"counter-2": 200,
+			"counter-3": 300,
+		},
+	})
+	want := []string{
+		"counter-1:100|g\ncounter-2:200|g",
+		"counter-3:300|g",
+	}
+	buf := make([]byte, 1500)
+	for i := range want {
+		pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("datagram %d: %v", i, err)
+		}
+		got := string(buf[:n])
+		if len(got) > 40 {
+			t.Errorf("datagram %d exceeds the max packet size: %d", i, len(got))
+		}
+		if got != want[i] {
+			t.Errorf("datagram %d: got %q, expected %q", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http_output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const userAgent = "gNMIc http"
+
+func newHTTPClient(cfg *config) (*http.Client, error) {
+	c := &http.Client{
+		Timeout: cfg.Timeout,
+	}
+	if cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			cfg.TLS.CaFile,
+			cfg.TLS.CertFile,
+			cfg.TLS.KeyFile,
+			"",
+			cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		c.Transport = &http.Transport{
+			TLSClientConfig: tlsCfg,
+		}
+	}
+	return c, nil
+}
+
+// writer batches the items and sends them when the batch size
+// is reached or every flush interval.
+func (h *httpOutput) writer(ctx context.Context) {
+	ticker := time.NewTicker(h.cfg.FlushInterval)
+	defer ticker.Stop()
+	batch := make([][]byte, 0, h.cfg.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case b := <-h.itemsCh:
+			batch = append(batch, b)
+			if len(batch) < h.cfg.BatchSize {
+				continue
+			}
+			if h.cfg.Debug {
+				h.logger.Printf("batch size reached, sending")
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+			if h.cfg.Debug {
+				h.logger.Printf("flush interval reached, sending")
+			}
+		}
+		h.flush(ctx, batch)
+		batch = make([][]byte, 0, h.cfg.BatchSize)
+	}
+}
+
+// flush renders the request body from a batch and sends it,
+// retrying with an exponential backoff up to max-retries times unless the error is permanent.
+// The body of a request that could not be sent is written to the dead letter file, if configured.
+func (h *httpOutput) flush(ctx context.Context, batch [][]byte) {
+	body, err := h.body(batch)
+	if err != nil {
+		h.logger.Printf("failed to render request body: %v", err)
+		httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "template_error").Add(float64(len(batch)))
+		return
+	}
+	policy := outputs.RetryPolicy{
+		MaxRetries:  h.cfg.MaxRetries,
+		Backoff:     h.cfg.RetryBackoff,
+		Exponential: true,
+	}
+	err = policy.Do(ctx, func() error {
+		start := time.Now()
+		err := h.send(ctx, body)
+		if err != nil {
+			h.logger.Printf("failed to send %d messages: %v", len(batch), err)
+			return err
+		}
+		httpRequestDuration.WithLabelValues(h.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+		httpNumberOfSentMsgs.WithLabelValues(h.cfg.Name).Add(float64(len(batch)))
+		if h.cfg.Debug {
+			h.logger.Printf("sent %d messages", len(batch))
+		}
+		return nil
+	})
+	switch {
+	case err == nil, ctx.Err() != nil:
+		return
+	case outputs.IsPermanent(err):
+		httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "rejected").Add(float64(len(batch)))
+	default:
+		httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "max_retries").Add(float64(len(batch)))
+	}
+	if h.deadLetter == nil {
+		return
+	}
+	err = h.deadLetter.write(body)
+	if err != nil {
+		h.logger.Printf("failed to write to dead letter file: %v", err)
+		return
+	}
+	httpNumberOfDeadLetterMsgs.WithLabelValues(h.cfg.Name).Add(float64(len(batch)))
+}
+
+// sendBatch sends a batch in a single request, without retrying it.
+// The body rendering errors are permanent.
+func (h *httpOutput) sendBatch(ctx context.Context, batch [][]byte) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	body, err := h.body(batch)
+	if err != nil {
+		httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "template_error").Add(float64(len(batch)))
+		return outputs.Permanent(fmt.Errorf("failed to render request body: %w", err))
+	}
+	start := time.Now()
+	err = h.send(ctx, body)
+	if err != nil {
+		return err
+	}
+	httpRequestDuration.WithLabelValues(h.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+	httpNumberOfSentMsgs.WithLabelValues(h.cfg.Name).Add(float64(len(batch)))
+	return nil
+}
+
+// body returns the request body of a batch.
+// Without a body template, the body is a JSON array of the batch items.
+// Otherwise it is the result of the template executed with the decoded array as input.
+func (h *httpOutput) body(batch [][]byte) ([]byte, error) {
+	b := make([]byte, 0, len(batch)*256)
+	b = append(b, '[')
+	for i, item := range batch {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, item...)
+	}
+	b = append(b, ']')
+	if h.bodyTpl == nil {
+		return b, nil
+	}
+	var input []interface{}
+	err := json.Unmarshal(b, &input)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	err = h.bodyTpl.Execute(buf, input)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// send sends a single request.
+// The connection errors, as well as the 408, 429 and 5xx status codes are retried.
+func (h *httpOutput) send(ctx context.Context, body []byte) error {
+	var r io.Reader = bytes.NewReader(body)
+	if h.cfg.Gzip {
+		buf := new(bytes.Buffer)
+		gw := gzip.NewWriter(buf)
+		_, err := gw.Write(body)
+		if err != nil {
+			return outputs.Permanent(err)
+		}
+		err = gw.Close()
+		if err != nil {
+			return outputs.Permanent(err)
+		}
+		r = buf
+	}
+	req, err := http.NewRequestWithContext(ctx, h.cfg.Method, h.cfg.URL, r)
+	if err != nil {
+		return outputs.Permanent(fmt.Errorf("failed to create HTTP request: %w", err))
+	}
+	req.Header.Set("Content-Type", h.cfg.ContentType)
+	req.Header.Set("User-Agent", userAgent)
+	if h.cfg.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if h.cfg.Authentication != nil {
+		req.SetBasicAuth(h.cfg.Authentication.Username, h.cfg.Authentication.Password)
+	}
+	if h.cfg.Authorization != nil && h.cfg.Authorization.Type != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("%s %s", h.cfg.Authorization.Type, h.cfg.Authorization.Credentials))
+	}
+	for k, v := range h.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	rsp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode < 300 {
+		io.Copy(io.Discard, rsp.Body)
+		return nil
+	}
+	rb, _ := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+	err = fmt.Errorf("request failed, code=%d, body=%s", rsp.StatusCode, string(rb))
+	switch {
+	case rsp.StatusCode == http.StatusTooManyRequests,
+		rsp.StatusCode == http.StatusRequestTimeout,
+		rsp.StatusCode >= 500:
+		return err
+	}
+	return outputs.Permanent(err)
+}
+
+// deadLetterFile stores the bodies of the requests that could not be sent,
+// one per line.
+type deadLetterFile struct {
+	m sync.Mutex
+	f *os.File
+}
+
+func openDeadLetterFile(name string) (*deadLetterFile, error) {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	return &deadLetterFile{f: f}, nil
+}
+
+func (d *deadLetterFile) write(body []byte) error {
+	d.m.Lock()
+	defer d.m.Unlock()
+	b := make([]byte, 0, len(body)+1)
+	b = append(b, bytes.TrimRight(body, "\n")...)
+	b = append(b, '\n')
+	_, err := d.f.Write(b)
+	return err
+}
+
+func (d *deadLetterFile) close() error {
+	d.m.Lock()
+	defer d.m.Unlock()
+	return d.f.Close()
+}
@@ -0,0 +1,69 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http_output
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	namespace = "gnmic"
+	subsystem = "http_output"
+)
+
+var httpNumberOfSentMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_sent_msgs_total",
+	Help:      "Number of events or messages successfully sent by gnmic http output",
+}, []string{"name"})
+
+var httpNumberOfFailedMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_failed_msgs_total",
+	Help:      "Number of events or messages gnmic http output failed to send",
+}, []string{"name", "reason"})
+
+var httpNumberOfDeadLetterMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_dead_letter_msgs_total",
+	Help:      "Number of events or messages written to the dead letter file by gnmic http output",
+}, []string{"name"})
+
+var httpRequestDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "request_duration_ns",
+	Help:      "gnmic http output request duration in ns",
+}, []string{"name"})
+
+func initMetrics() {
+	httpNumberOfSentMsgs.WithLabelValues("").Add(0)
+	httpNumberOfFailedMsgs.WithLabelValues("", "").Add(0)
+	httpNumberOfDeadLetterMsgs.WithLabelValues("").Add(0)
+	httpRequestDuration.WithLabelValues("").Set(0)
+}
+
+func registerMetrics(reg *prometheus.Registry) error {
+	initMetrics()
+	var err error
+	if err = reg.Register(httpNumberOfSentMsgs); err != nil {
+		return err
+	}
+	if err = reg.Register(httpNumberOfFailedMsgs); err != nil {
+		return err
+	}
+	if err = reg.Register(httpNumberOfDeadLetterMsgs); err != nil {
+		return err
+	}
+	if err = reg.Register(httpRequestDuration); err != nil {
+		return err
+	}
+	return nil
+}
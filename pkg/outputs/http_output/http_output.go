@@ -0,0 +1,448 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType           = "http"
+	loggingPrefix        = "[http_output:%s] "
+	defaultMethod        = http.MethodPost
+	defaultContentType   = "application/json"
+	defaultFormat        = "event"
+	defaultTimeout       = 10 * time.Second
+	defaultFlushInterval = 5 * time.Second
+	defaultBatchSize     = 100
+	defaultBufferSize    = 1000
+	defaultMaxRetries    = 3
+	defaultRetryBackoff  = 500 * time.Millisecond
+	defaultNumWorkers    = 1
+)
+
+func init() {
+	outputs.Register(outputType,
+		func() outputs.Output {
+			return &httpOutput{
+				cfg:       &config{},
+				logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+				eventChan: make(chan *formatters.EventMsg),
+				msgChan:   make(chan *outputs.ProtoMsg),
+			}
+		})
+}
+
+type httpOutput struct {
+	cfg    *config
+	logger *log.Logger
+	dryRun bool
+
+	eventChan chan *formatters.EventMsg
+	msgChan   chan *outputs.ProtoMsg
+	// JSON encoded events or messages waiting to be batched
+	itemsCh    chan []byte
+	client     *http.Client
+	deadLetter *deadLetterFile
+	mo         *formatters.MarshalOptions
+
+	evps      []formatters.EventProcessor
+	bodyTpl   *template.Template
+	targetTpl *template.Template
+	cfn       context.CancelFunc
+}
+
+type config struct {
+	Name               string               `mapstructure:"name,omitempty" json:"name,omitempty"`
+	URL                string               `mapstructure:"url,omitempty" json:"url,omitempty"`
+	Method             string               `mapstructure:"method,omitempty" json:"method,omitempty"`
+	Headers            map[string]string    `mapstructure:"headers,omitempty" json:"headers,omitempty"`
+	Authentication     *types.BasicAuth     `mapstructure:"authentication,omitempty" json:"authentication,omitempty"`
+	Authorization      *types.Authorization `mapstructure:"authorization,omitempty" json:"authorization,omitempty"`
+	TLS                *types.TLSConfig     `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Timeout            time.Duration        `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	Format             string               `mapstructure:"format,omitempty" json:"format,omitempty"`
+	BodyTemplate       string               `mapstructure:"body-template,omitempty" json:"body-template,omitempty"`
+	ContentType        string               `mapstructure:"content-type,omitempty" json:"content-type,omitempty"`
+	Gzip               bool                 `mapstructure:"gzip,omitempty" json:"gzip,omitempty"`
+	FlushInterval      time.Duration        `mapstructure:"flush-interval,omitempty" json:"flush-interval,omitempty"`
+	BatchSize          int                  `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	BufferSize         int                  `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	MaxRetries         int                  `mapstructure:"max-retries,omitempty" json:"max-retries,omitempty"`
+	RetryBackoff       time.Duration        `mapstructure:"retry-backoff,omitempty" json:"retry-backoff,omitempty"`
+	DeadLetterFile     string               `mapstructure:"dead-letter-file,omitempty" json:"dead-letter-file,omitempty"`
+	OverrideTimestamps bool                 `mapstructure:"override-timestamps,omitempty" json:"override-timestamps,omitempty"`
+	AddTarget          string               `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate     string               `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors    []string             `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers         int                  `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	Debug              bool                 `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	EnableMetrics      bool                 `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+}
+
+func (h *httpOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, h.cfg)
+	if err != nil {
+		return err
+	}
+	if h.cfg.URL == "" {
+		return errors.New("missing url field")
+	}
+	u, err := url.Parse(h.cfg.URL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url %q: unsupported scheme %q", h.cfg.URL, u.Scheme)
+	}
+	if h.cfg.Name == "" {
+		h.cfg.Name = name
+	}
+	h.logger.SetPrefix(fmt.Sprintf(loggingPrefix, h.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(h); err != nil {
+			return err
+		}
+	}
+	err = h.setDefaults()
+	if err != nil {
+		return err
+	}
+	h.mo = &formatters.MarshalOptions{
+		Format:     h.cfg.Format,
+		OverrideTS: h.cfg.OverrideTimestamps,
+	}
+
+	if h.cfg.TargetTemplate == "" {
+		h.targetTpl = outputs.DefaultTargetTemplate
+	} else if h.cfg.AddTarget != "" {
+		h.targetTpl, err = gtemplate.CreateTemplate("target-template", h.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		h.targetTpl = h.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+	if h.cfg.BodyTemplate != "" {
+		h.bodyTpl, err = gtemplate.CreateTemplate("body-template", h.cfg.BodyTemplate)
+		if err != nil {
+			return err
+		}
+		h.bodyTpl = h.bodyTpl.Funcs(outputs.TemplateFuncs)
+	}
+
+	h.client, err = newHTTPClient(h.cfg)
+	if err != nil {
+		return err
+	}
+	if h.dryRun {
+		return nil
+	}
+	if h.cfg.DeadLetterFile != "" {
+		h.deadLetter, err = openDeadLetterFile(h.cfg.DeadLetterFile)
+		if err != nil {
+			return err
+		}
+	}
+	h.itemsCh = make(chan []byte, h.cfg.BufferSize)
+
+	ctx, h.cfn = context.WithCancel(ctx)
+	for i := 0; i < h.cfg.NumWorkers; i++ {
+		go h.worker(ctx)
+	}
+	go h.writer(ctx)
+	h.logger.Printf("initialized http output %s: %s", h.cfg.Name, h.String())
+	return nil
+}
+
+func (h *httpOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, h.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case h.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if h.cfg.Debug {
+			h.logger.Printf("writing expired after %s", h.cfg.Timeout)
+		}
+		httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "timeout").Inc()
+		return
+	}
+}
+
+func (h *httpOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range h.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case h.eventChan <- pev:
+			}
+		}
+	}
+}
+
+// WriteSync sends the message in a single request, without batching nor retrying it,
+// and returns once it is delivered or failed.
+func (h *httpOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	return h.sendBatch(ctx, h.protoItems(rsp, meta))
+}
+
+// WriteEventSync sends the event in a single request, without batching nor retrying it,
+// and returns once it is delivered or failed.
+func (h *httpOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	if ev == nil {
+		return nil
+	}
+	evs := []*formatters.EventMsg{ev}
+	for _, proc := range h.evps {
+		evs = proc.Apply(evs...)
+	}
+	batch := make([][]byte, 0, len(evs))
+	for _, pev := range evs {
+		if b := h.eventItem(pev); b != nil {
+			batch = append(batch, b)
+		}
+	}
+	return h.sendBatch(ctx, batch)
+}
+
+func (h *httpOutput) Close() error {
+	if h.cfn == nil {
+		return nil
+	}
+	h.cfn()
+	if h.client != nil {
+		h.client.CloseIdleConnections()
+	}
+	if h.deadLetter != nil {
+		return h.deadLetter.close()
+	}
+	return nil
+}
+
+func (h *httpOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !h.cfg.EnableMetrics {
+		return
+	}
+	if reg == nil {
+		h.logger.Printf("ERR: output metrics enabled but main registry is not initialized, enable main metrics under `api-server`")
+		return
+	}
+	if err := registerMetrics(reg); err != nil {
+		h.logger.Printf("failed to register metric: %v", err)
+	}
+}
+
+func (h *httpOutput) String() string {
+	b, err := json.Marshal(h.cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (h *httpOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && h.logger != nil {
+		h.logger.SetOutput(logger.Writer())
+		h.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (h *httpOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	h.evps, err = formatters.MakeEventProcessors(
+		logger,
+		h.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (h *httpOutput) SetName(name string) {
+	if h.cfg.Name == "" {
+		h.cfg.Name = name
+	}
+}
+
+func (h *httpOutput) SetClusterName(_ string) {}
+
+func (h *httpOutput) SetDryRun(dryRun bool) { h.dryRun = dryRun }
+
+func (h *httpOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (h *httpOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-h.eventChan:
+			h.workerHandleEvent(ctx, ev)
+		case m := <-h.msgChan:
+			h.workerHandleProto(ctx, m)
+		}
+	}
+}
+
+func (h *httpOutput) workerHandleProto(ctx context.Context, m *outputs.ProtoMsg) {
+	for _, b := range h.protoItems(m.GetMsg(), m.GetMeta()) {
+		h.enqueue(ctx, b)
+	}
+}
+
+func (h *httpOutput) workerHandleEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if b := h.eventItem(ev); b != nil {
+		h.enqueue(ctx, b)
+	}
+}
+
+// protoItems returns the JSON encoded items of a message,
+// a single item or one per event depending on the format.
+func (h *httpOutput) protoItems(pmsg proto.Message, meta outputs.Meta) [][]byte {
+	switch pmsg := pmsg.(type) {
+	case *gnmi.SubscribeResponse:
+		var err error
+		pmsg, err = outputs.AddSubscriptionTarget(pmsg, meta, h.cfg.AddTarget, h.targetTpl)
+		if err != nil {
+			h.logger.Printf("failed to add target to the response: %v", err)
+		}
+		if h.cfg.Format != "event" {
+			bb, err := outputs.Marshal(pmsg, meta, h.mo, false, h.evps...)
+			if err != nil {
+				h.logger.Printf("failed marshaling proto msg: %v", err)
+				httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "marshal_error").Inc()
+				return nil
+			}
+			return bb
+		}
+		measName := "default"
+		if subName, ok := meta["subscription-name"]; ok {
+			measName = subName
+		}
+		events, err := formatters.ResponseToEventMsgs(measName, pmsg, meta, h.evps...)
+		if err != nil {
+			h.logger.Printf("failed to convert message to event: %v", err)
+			return nil
+		}
+		items := make([][]byte, 0, len(events))
+		for _, ev := range events {
+			if b := h.eventItem(ev); b != nil {
+				items = append(items, b)
+			}
+		}
+		return items
+	}
+	return nil
+}
+
+// eventItem returns the JSON encoded event, or nil if it cannot be marshaled.
+func (h *httpOutput) eventItem(ev *formatters.EventMsg) []byte {
+	if h.cfg.OverrideTimestamps {
+		ev.Timestamp = time.Now().UnixNano()
+	}
+	b, err := json.Marshal(ev)
+	if err != nil {
+		h.logger.Printf("failed marshaling event: %v", err)
+		httpNumberOfFailedMsgs.WithLabelValues(h.cfg.Name, "marshal_error").Inc()
+		return nil
+	}
+	return b
+}
+
+func (h *httpOutput) enqueue(ctx context.Context, b []byte) {
+	select {
+	case <-ctx.Done():
+	case h.itemsCh <- b:
+	}
+}
+
+func (h *httpOutput) setDefaults() error {
+	h.cfg.Format = strings.ToLower(h.cfg.Format)
+	if h.cfg.Format == "" {
+		h.cfg.Format = defaultFormat
+	}
+	switch h.cfg.Format {
+	case "event", "json", "protojson":
+	default:
+		return fmt.Errorf("unsupported output format '%s' for output type HTTP", h.cfg.Format)
+	}
+	h.cfg.Method = strings.ToUpper(h.cfg.Method)
+	if h.cfg.Method == "" {
+		h.cfg.Method = defaultMethod
+	}
+	if h.cfg.ContentType == "" {
+		h.cfg.ContentType = defaultContentType
+	}
+	if h.cfg.Timeout <= 0 {
+		h.cfg.Timeout = defaultTimeout
+	}
+	if h.cfg.FlushInterval <= 0 {
+		h.cfg.FlushInterval = defaultFlushInterval
+	}
+	if h.cfg.BatchSize <= 0 {
+		h.cfg.BatchSize = defaultBatchSize
+	}
+	if h.cfg.BufferSize <= 0 {
+		h.cfg.BufferSize = defaultBufferSize
+	}
+	h.cfg.MaxRetries = outputs.MaxRetries(h.cfg.MaxRetries, defaultMaxRetries)
+	if h.cfg.RetryBackoff <= 0 {
+		h.cfg.RetryBackoff = defaultRetryBackoff
+	}
+	if h.cfg.NumWorkers <= 0 {
+		h.cfg.NumWorkers = defaultNumWorkers
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http_output
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+type testServer struct {
+	*httptest.Server
+	m        sync.Mutex
+	requests []*request
+	codes    []int
+}
+
+// newTestServer starts an HTTP server recording the requests it receives,
+// it replies with the codes in order, then with 200.
+func newTestServer(t *testing.T, codes ...int) *testServer {
+	ts := &testServer{codes: codes}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rd io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("failed to create gzip reader: %v", err)
+				return
+			}
+			rd = gr
+		}
+		b, err := io.ReadAll(rd)
+		if err != nil {
+			t.Errorf("failed to read body: %v", err)
+		}
+		ts.m.Lock()
+		ts.requests = append(ts.requests, &request{header: r.Header, body: b})
+		code := http.StatusOK
+		if len(ts.codes) > 0 {
+			code = ts.codes[0]
+			ts.codes = ts.codes[1:]
+		}
+		ts.m.Unlock()
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *testServer) waitRequests(t *testing.T, n int) []*request {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		ts.m.Lock()
+		if len(ts.requests) >= n {
+			rs := ts.requests
+			ts.m.Unlock()
+			return rs
+		}
+		ts.m.Unlock()
+		select {
+		case <-deadline:
+			t.Fatalf("timeout waiting for %d requests", n)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func newTestOutput(t *testing.T, cfg map[string]interface{}) *httpOutput {
+	t.Helper()
+	o := outputs.Outputs[outputType]().(*httpOutput)
+	err := o.Init(context.Background(), "test", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { o.Close() })
+	return o
+}
+
+func testEvent(i int) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: int64(i),
+		Tags:      map[string]string{"source": "router1"},
+		Values:    map[string]interface{}{"counter": i},
+	}
+}
+
+func TestBatch(t *testing.T) {
+	ts := newTestServer(t)
+	o := newTestOutput(t, map[string]interface{}{
+		"url":            ts.URL,
+		"batch-size":     2,
+		"flush-interval": "1h",
+		"headers":        map[string]string{"X-Test": "gnmic"},
+		"authentication": map[string]interface{}{"username": "user", "password": "pass"},
+	})
+	for i := 0; i < 4; i++ {
+		o.WriteEvent(context.Background(), testEvent(i))
+	}
+	rs := ts.waitRequests(t, 2)
+	for _, r := range rs {
+		evs := make([]*formatters.EventMsg, 0)
+		err := json.Unmarshal(r.body, &evs)
+		if err != nil {
+			t.Fatalf("failed to unmarshal body %s: %v", r.body, err)
+		}
+		if len(evs) != 2 {
+			t.Errorf("expected 2 events per request, got %d", len(evs))
+		}
+		if r.header.Get("X-Test") != "gnmic" {
+			t.Errorf("missing custom header: %v", r.header)
+		}
+		if r.header.Get("Content-Type") != defaultContentType {
+			t.Errorf("unexpected content type: %s", r.header.Get("Content-Type"))
+		}
+		if !strings.HasPrefix(r.header.Get("Authorization"), "Basic ") {
+			t.Errorf("unexpected Authorization header: %q", r.header.Get("Authorization"))
+		}
+	}
+}
+
+func TestBodyTemplateGzip(t *testing.T) {
+	ts := newTestServer(t)
+	o := newTestOutput(t, map[string]interface{}{
+		"url":            ts.URL,
+		"gzip":           true,
+		"content-type":   "text/plain",
+		"flush-interval": "10ms",
+		"body-template":  `{{ range . }}{{ .name }} {{ index .values "counter" }};{{ end }}`,
+		"authorization":  map[string]interface{}{"type": "Bearer", "credentials": "token"},
+	})
+	o.WriteEvent(context.Background(), testEvent(1))
+	rs := ts.waitRequests(t, 1)
+	if got := string(rs[0].body); got != "sub1 1;" {
+		t.Errorf("unexpected body %q", got)
+	}
+	if rs[0].header.Get("Content-Encoding") != "gzip" {
+		t.Errorf("missing Content-Encoding header")
+	}
+	if rs[0].header.Get("Authorization") != "Bearer token" {
+		t.Errorf("unexpected Authorization header: %q", rs[0].header.Get("Authorization"))
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	tests := []struct {
+		name         string
+		codes        []int
+		wantRequests int
+	}{
+		{
+			name:         "retried",
+			codes:        []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+			wantRequests: 3,
+		},
+		{
+			name:         "rejected",
+			codes:        []int{http.StatusBadRequest},
+			wantRequests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, tt.codes...)
+			dl := filepath.Join(t.TempDir(), "dead-letter.json")
+			o := newTestOutput(t, map[string]interface{}{
+				"url":              ts.URL,
+				"batch-size":       1,
+				"max-retries":      2,
+				"retry-backoff":    "1ms",
+				"dead-letter-file": dl,
+			})
+			o.WriteEvent(context.Background(), testEvent(1))
+			var b []byte
+			deadline := time.After(5 * time.Second)
+			for len(b) == 0 {
+				select {
+				case <-deadline:
+					t.Fatal("timeout waiting for the dead letter file")
+				case <-time.After(10 * time.Millisecond):
+				}
+				var err error
+				b, err = os.ReadFile(dl)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			ts.m.Lock()
+			numRequests := len(ts.requests)
+			ts.m.Unlock()
+			if numRequests != tt.wantRequests {
+				t.Errorf("expected %d requests, got %d", tt.wantRequests, numRequests)
+			}
+			evs := make([]*formatters.EventMsg, 0)
+			err := json.Unmarshal(b, &evs)
+			if err != nil || len(evs) != 1 {
+				t.Errorf("unexpected dead letter file content %q: %v", b, err)
+			}
+		})
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	dl := filepath.Join(t.TempDir(), "dead-letter.json")
+	cfg := map[string]interface{}{
+		"url":              "http://localhost:1",
+		"dead-letter-file": dl,
+	}
+	o := outputs.Outputs[outputType]().(*httpOutput)
+	err := o.Init(context.Background(), "test", cfg, outputs.WithDryRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.itemsCh != nil {
+		t.Errorf("dry run started the workers")
+	}
+	if _, err := os.Stat(dl); !os.IsNotExist(err) {
+		t.Errorf("dry run created the dead letter file: %v", err)
+	}
+	cfg["body-template"] = "{{ .x "
+	o = outputs.Outputs[outputType]().(*httpOutput)
+	err = o.Init(context.Background(), "test", cfg, outputs.WithDryRun())
+	if err == nil {
+		t.Errorf("expected an invalid body template error")
+	}
+}
+
+func TestWriteEventSync(t *testing.T) {
+	ts := newTestServer(t, http.StatusServiceUnavailable, http.StatusBadRequest)
+	o := newTestOutput(t, map[string]interface{}{
+		"url": ts.URL,
+	})
+	ctx := context.Background()
+	err := o.WriteEventSync(ctx, testEvent(1))
+	if err == nil || outputs.IsPermanent(err) {
+		t.Errorf("expected a temporary error, got %v", err)
+	}
+	err = o.WriteEventSync(ctx, testEvent(1))
+	if !outputs.IsPermanent(err) {
+		t.Errorf("expected a permanent error, got %v", err)
+	}
+	err = o.WriteEventSync(ctx, testEvent(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := ts.waitRequests(t, 3)
+	evs := make([]*formatters.EventMsg, 0)
+	err = json.Unmarshal(rs[2].body, &evs)
+	if err != nil || len(evs) != 1 || evs[0].Timestamp != 1 {
+		t.Errorf("unexpected body %q: %v", rs[2].body, err)
+	}
+}
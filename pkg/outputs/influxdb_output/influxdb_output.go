@@ -12,10 +12,12 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"math"
+	"net/http"
 	"strings"
 	"text/template"
 	"time"
@@ -23,6 +25,8 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	ihttp "github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
 	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -62,6 +66,7 @@ type influxDBOutput struct {
 	Cfg       *Config
 	client    influxdb2.Client
 	logger    *log.Logger
+	dryRun    bool
 	cancelFn  context.CancelFunc
 	eventChan chan *formatters.EventMsg
 	reset     chan struct{}
@@ -147,13 +152,6 @@ func (i *influxDBOutput) Init(ctx context.Context, name string, cfg map[string]i
 	}
 	i.setDefaults()
 
-	if i.Cfg.CacheConfig != nil {
-		err = i.initCache(ctx, name)
-		if err != nil {
-			return err
-		}
-	}
-
 	if i.Cfg.TargetTemplate == "" {
 		i.targetTpl = outputs.DefaultTargetTemplate
 	} else if i.Cfg.AddTarget != "" {
@@ -164,6 +162,16 @@ func (i *influxDBOutput) Init(ctx context.Context, name string, cfg map[string]i
 		i.targetTpl = i.targetTpl.Funcs(outputs.TemplateFuncs)
 	}
 
+	if i.dryRun {
+		return nil
+	}
+	if i.Cfg.CacheConfig != nil {
+		err = i.initCache(ctx, name)
+		if err != nil {
+			return err
+		}
+	}
+
 	ctx, i.cancelFn = context.WithCancel(ctx)
 	influxOpts, err := i.clientOpts()
 	if err != nil {
@@ -267,6 +275,75 @@ func (i *influxDBOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg
 	}
 }
 
+// WriteSync writes the message points in a single blocking request,
+// bypassing the cache, and returns once they are written or failed.
+func (i *influxDBOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	var err error
+	rsp, err = outputs.AddSubscriptionTarget(rsp, meta, i.Cfg.AddTarget, i.targetTpl)
+	if err != nil {
+		i.logger.Printf("failed to add target to the response: %v", err)
+	}
+	sr, ok := rsp.(*gnmi.SubscribeResponse)
+	if !ok {
+		return nil
+	}
+	measName := "default"
+	if subName, ok := meta["subscription-name"]; ok {
+		measName = subName
+	}
+	events, err := formatters.ResponseToEventMsgs(measName, sr, meta, i.evps...)
+	if err != nil {
+		return outputs.Permanent(fmt.Errorf("failed to convert message to event: %w", err))
+	}
+	return i.writePoints(ctx, events)
+}
+
+// WriteEventSync writes the event points in a single blocking request
+// and returns once they are written or failed.
+func (i *influxDBOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	if ev == nil {
+		return nil
+	}
+	evs := []*formatters.EventMsg{ev}
+	for _, proc := range i.evps {
+		evs = proc.Apply(evs...)
+	}
+	return i.writePoints(ctx, evs)
+}
+
+func (i *influxDBOutput) writePoints(ctx context.Context, evs []*formatters.EventMsg) error {
+	points := make([]*write.Point, 0, len(evs))
+	for _, ev := range evs {
+		points = append(points, i.eventPoints(ev)...)
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	if i.client == nil {
+		return errors.New("influxdb client not initialized")
+	}
+	err := i.client.WriteAPIBlocking(i.Cfg.Org, i.Cfg.Bucket).WritePoint(ctx, points...)
+	if err == nil {
+		return nil
+	}
+	// requests rejected by the server are not retried,
+	// unless it is overloaded or failed.
+	var herr *ihttp.Error
+	if errors.As(err, &herr) && herr.StatusCode >= 400 {
+		switch {
+		case herr.StatusCode == http.StatusTooManyRequests,
+			herr.StatusCode == http.StatusRequestTimeout,
+			herr.StatusCode >= 500:
+			return err
+		}
+		return outputs.Permanent(err)
+	}
+	return err
+}
+
 func (i *influxDBOutput) Close() error {
 	i.logger.Printf("closing client...")
 	if i.Cfg.CacheConfig != nil {
@@ -346,40 +423,8 @@ START:
 			i.logger.Printf("worker-%d terminating...", idx)
 			return
 		case ev := <-i.eventChan:
-			if len(ev.Values) == 0 || (len(ev.Deletes) == 0 && i.Cfg.DeleteTag != "") {
-				continue
-			}
-			for n, v := range ev.Values {
-				switch v := v.(type) {
-				//lint:ignore SA1019 still need DecimalVal for backward compatibility
-				case *gnmi.Decimal64:
-					ev.Values[n] = float64(v.Digits) / math.Pow10(int(v.Precision))
-				}
-			}
-			if ev.Timestamp == 0 || i.Cfg.OverrideTimestamps {
-				ev.Timestamp = time.Now().UnixNano()
-			}
-			if subscriptionName, ok := ev.Tags["subscription-name"]; ok {
-				ev.Name = subscriptionName
-				delete(ev.Tags, "subscription-name")
-			}
-
-			if len(ev.Values) > 0 {
-				i.convertUints(ev)
-				writer.WritePoint(influxdb2.NewPoint(ev.Name, ev.Tags, ev.Values, time.Unix(0, ev.Timestamp)))
-			}
-			
-			if len(ev.Deletes) > 0 && i.Cfg.DeleteTag != "" {
-				tags := make(map[string]string, len(ev.Tags))
-				for k, v := range ev.Tags {
-					tags[k] = v
-				}
-				tags[i.Cfg.DeleteTag] = deleteTagValue
-				values := make(map[string]any, len(ev.Deletes))
-				for _, del := range ev.Deletes {
-					values[del] = 0
-				}
-				writer.WritePoint(influxdb2.NewPoint(ev.Name, tags, values, time.Unix(0, ev.Timestamp)))
+			for _, p := range i.eventPoints(ev) {
+				writer.WritePoint(p)
 			}
 		case <-i.reset:
 			firstStart = false
@@ -391,7 +436,49 @@ START:
 	}
 }
 
+// eventPoints converts an event to the points written to influxdb.
+func (i *influxDBOutput) eventPoints(ev *formatters.EventMsg) []*write.Point {
+	if len(ev.Values) == 0 || (len(ev.Deletes) == 0 && i.Cfg.DeleteTag != "") {
+		return nil
+	}
+	points := make([]*write.Point, 0, 2)
+	for n, v := range ev.Values {
+		switch v := v.(type) {
+		//lint:ignore SA1019 still need DecimalVal for backward compatibility
+		case *gnmi.Decimal64:
+			ev.Values[n] = float64(v.Digits) / math.Pow10(int(v.Precision))
+		}
+	}
+	if ev.Timestamp == 0 || i.Cfg.OverrideTimestamps {
+		ev.Timestamp = time.Now().UnixNano()
+	}
+	if subscriptionName, ok := ev.Tags["subscription-name"]; ok {
+		ev.Name = subscriptionName
+		delete(ev.Tags, "subscription-name")
+	}
+
+	if len(ev.Values) > 0 {
+		i.convertUints(ev)
+		points = append(points, influxdb2.NewPoint(ev.Name, ev.Tags, ev.Values, time.Unix(0, ev.Timestamp)))
+	}
+
+	if len(ev.Deletes) > 0 && i.Cfg.DeleteTag != "" {
+		tags := make(map[string]string, len(ev.Tags))
+		for k, v := range ev.Tags {
+			tags[k] = v
+		}
+		tags[i.Cfg.DeleteTag] = deleteTagValue
+		values := make(map[string]any, len(ev.Deletes))
+		for _, del := range ev.Deletes {
+			values[del] = 0
+		}
+		points = append(points, influxdb2.NewPoint(ev.Name, tags, values, time.Unix(0, ev.Timestamp)))
+	}
+	return points
+}
+
 func (i *influxDBOutput) SetName(name string)                             {}
+func (i *influxDBOutput) SetDryRun(dryRun bool)                           { i.dryRun = dryRun }
 func (i *influxDBOutput) SetClusterName(name string)                      {}
 func (i *influxDBOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
 
@@ -0,0 +1,73 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package influxdb_output
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+func TestWriteSync(t *testing.T) {
+	rsp := &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{
+		Update: &gnmi.Notification{
+			Timestamp: 42,
+			Update: []*gnmi.Update{{
+				Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interface", Key: map[string]string{"name": "ethernet-1/1"}}, {Name: "mtu"}}},
+				Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 1500}},
+			}},
+		},
+	}}
+	tests := []struct {
+		name      string
+		code      int
+		wantErr   bool
+		permanent bool
+	}{
+		{name: "written", code: http.StatusNoContent},
+		{name: "bad_request", code: http.StatusBadRequest, wantErr: true, permanent: true},
+		{name: "unavailable", code: http.StatusServiceUnavailable, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				body = string(b)
+				w.WriteHeader(tt.code)
+			}))
+			defer srv.Close()
+			i := &influxDBOutput{
+				Cfg:    &Config{Org: "org", Bucket: "bucket"},
+				logger: log.New(io.Discard, "", 0),
+				client: influxdb2.NewClientWithOptions(srv.URL, "token", influxdb2.DefaultOptions().SetMaxRetries(0)),
+			}
+			defer i.client.Close()
+			err := i.WriteSync(context.Background(), rsp, outputs.Meta{"subscription-name": "sub1"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if outputs.IsPermanent(err) != tt.permanent {
+				t.Fatalf("expected permanent=%v, got %v", tt.permanent, err)
+			}
+			if !strings.HasPrefix(body, "sub1,") || !strings.Contains(body, "mtu=1500") {
+				t.Fatalf("unexpected line protocol: %q", body)
+			}
+		})
+	}
+}
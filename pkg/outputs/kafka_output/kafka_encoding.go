@@ -0,0 +1,270 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka_output
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	schemaTypeAvro     = "AVRO"
+	schemaTypeProtobuf = "PROTOBUF"
+	// wireMagicByte is the first byte of a message
+	// in the schema registry wire format.
+	wireMagicByte = 0x0
+)
+
+// avroEventSchema is the Avro schema of an event,
+// the values that are not a boolean, a number or a string are JSON encoded.
+const avroEventSchema = `{"type":"record","name":"Event","namespace":"gnmic","fields":[` +
+	`{"name":"name","type":"string"},` +
+	`{"name":"timestamp","type":"long"},` +
+	`{"name":"tags","type":{"type":"map","values":"string"}},` +
+	`{"name":"values","type":{"type":"map","values":["null","boolean","long","double","string"]}}]}`
+
+// protobufEventSchema is the Protobuf schema of an event,
+// the values that are not a boolean, a number or a string are JSON encoded.
+const protobufEventSchema = `syntax = "proto3";
+package gnmic;
+
+message Event {
+  string name = 1;
+  int64 timestamp = 2;
+  map<string, string> tags = 3;
+  map<string, Value> values = 4;
+}
+
+message Value {
+  oneof value {
+    string string_val = 1;
+    int64 int_val = 2;
+    uint64 uint_val = 3;
+    double double_val = 4;
+    bool bool_val = 5;
+  }
+}
+`
+
+// avro union branches of the event values
+const (
+	avroNull = iota
+	avroBoolean
+	avroLong
+	avroDouble
+	avroString
+)
+
+// wireFormat prefixes the payload with the magic byte and the schema ID.
+// The Protobuf payloads are also prefixed with the message indexes,
+// a single zero byte referring to the first message of the schema.
+func wireFormat(schemaID int, schemaType string, payload []byte) []byte {
+	b := make([]byte, 5, 6+len(payload))
+	b[0] = wireMagicByte
+	binary.BigEndian.PutUint32(b[1:], uint32(schemaID))
+	if schemaType == schemaTypeProtobuf {
+		b = append(b, 0)
+	}
+	return append(b, payload...)
+}
+
+// encodeAvroEvent encodes an event using the Avro binary encoding of avroEventSchema.
+func encodeAvroEvent(ev *formatters.EventMsg) []byte {
+	b := make([]byte, 0, 256)
+	b = appendAvroString(b, ev.Name)
+	b = appendAvroLong(b, ev.Timestamp)
+	// maps are encoded as a single block followed by an empty block
+	if len(ev.Tags) > 0 {
+		b = appendAvroLong(b, int64(len(ev.Tags)))
+		for _, k := range sortedKeys(ev.Tags) {
+			b = appendAvroString(b, k)
+			b = appendAvroString(b, ev.Tags[k])
+		}
+	}
+	b = appendAvroLong(b, 0)
+	if len(ev.Values) > 0 {
+		b = appendAvroLong(b, int64(len(ev.Values)))
+		for _, k := range sortedKeys(ev.Values) {
+			b = appendAvroString(b, k)
+			b = appendAvroValue(b, ev.Values[k])
+		}
+	}
+	return appendAvroLong(b, 0)
+}
+
+func appendAvroLong(b []byte, v int64) []byte {
+	// zig-zag encoding
+	return binary.AppendUvarint(b, uint64((v<<1)^(v>>63)))
+}
+
+func appendAvroString(b []byte, s string) []byte {
+	b = appendAvroLong(b, int64(len(s)))
+	return append(b, s...)
+}
+
+func appendAvroDouble(b []byte, f float64) []byte {
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(f))
+}
+
+func appendAvroValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return appendAvroLong(b, avroNull)
+	case bool:
+		b = appendAvroLong(b, avroBoolean)
+		if v {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case string:
+		b = appendAvroLong(b, avroString)
+		return appendAvroString(b, v)
+	case float64:
+		b = appendAvroLong(b, avroDouble)
+		return appendAvroDouble(b, v)
+	case float32:
+		b = appendAvroLong(b, avroDouble)
+		return appendAvroDouble(b, float64(v))
+	}
+	if i, ok := toInt64(v); ok {
+		b = appendAvroLong(b, avroLong)
+		return appendAvroLong(b, i)
+	}
+	if u, ok := toUint64(v); ok {
+		// does not fit in a long
+		b = appendAvroLong(b, avroDouble)
+		return appendAvroDouble(b, float64(u))
+	}
+	b = appendAvroLong(b, avroString)
+	return appendAvroString(b, jsonString(v))
+}
+
+// encodeProtobufEvent encodes an event as the Event message of protobufEventSchema.
+func encodeProtobufEvent(ev *formatters.EventMsg) []byte {
+	b := make([]byte, 0, 256)
+	if ev.Name != "" {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, ev.Name)
+	}
+	if ev.Timestamp != 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(ev.Timestamp))
+	}
+	for _, k := range sortedKeys(ev.Tags) {
+		entry := protowire.AppendTag(nil, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, k)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendString(entry, ev.Tags[k])
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	for _, k := range sortedKeys(ev.Values) {
+		entry := protowire.AppendTag(nil, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, k)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, protobufValue(ev.Values[k]))
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	return b
+}
+
+// protobufValue encodes v as the Value message of protobufEventSchema.
+func protobufValue(v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case bool:
+		b := protowire.AppendTag(nil, 5, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeBool(v))
+	case string:
+		b := protowire.AppendTag(nil, 1, protowire.BytesType)
+		return protowire.AppendString(b, v)
+	case float64:
+		b := protowire.AppendTag(nil, 4, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(v))
+	case float32:
+		b := protowire.AppendTag(nil, 4, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(float64(v)))
+	}
+	if i, ok := toInt64(v); ok {
+		b := protowire.AppendTag(nil, 2, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(i))
+	}
+	if u, ok := toUint64(v); ok {
+		b := protowire.AppendTag(nil, 3, protowire.VarintType)
+		return protowire.AppendVarint(b, u)
+	}
+	b := protowire.AppendTag(nil, 1, protowire.BytesType)
+	return protowire.AppendString(b, jsonString(v))
+}
+
+// toInt64 converts the integer types that fit in an int64.
+func toInt64(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint:
+		if uint64(v) <= math.MaxInt64 {
+			return int64(v), true
+		}
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
+
+func toUint64(v interface{}) (uint64, bool) {
+	switch v := v.(type) {
+	case uint:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	}
+	return 0, false
+}
+
+func jsonString(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,178 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka_output
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+func TestWireFormat(t *testing.T) {
+	got := wireFormat(258, schemaTypeAvro, []byte{0xaa})
+	want := []byte{0, 0, 0, 1, 2, 0xaa}
+	if !bytes.Equal(got, want) {
+		t.Errorf("avro: got %x, expected %x", got, want)
+	}
+	got = wireFormat(258, schemaTypeProtobuf, []byte{0xaa})
+	want = []byte{0, 0, 0, 1, 2, 0, 0xaa}
+	if !bytes.Equal(got, want) {
+		t.Errorf("protobuf: got %x, expected %x", got, want)
+	}
+}
+
+func TestEncodeAvroEvent(t *testing.T) {
+	ev := &formatters.EventMsg{
+		Name:      "s",
+		Timestamp: 1,
+		Tags:      map[string]string{"a": "b"},
+		Values:    map[string]interface{}{"v": int64(-1)},
+	}
+	want := []byte{
+		0x02, 's', // name
+		0x02,                          // timestamp
+		0x02, 0x02, 'a', 0x02, 'b', 0, // tags
+		0x02, 0x02, 'v', 0x04, 0x01, 0, // values
+	}
+	got := encodeAvroEvent(ev)
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %x, expected %x", got, want)
+	}
+	// empty maps
+	got = encodeAvroEvent(&formatters.EventMsg{})
+	want = []byte{0, 0, 0, 0}
+	if !bytes.Equal(got, want) {
+		t.Errorf("empty event: got %x, expected %x", got, want)
+	}
+}
+
+var avroValueSet = map[string]struct {
+	v    interface{}
+	want []byte
+}{
+	"null":       {v: nil, want: []byte{0x00}},
+	"bool":       {v: true, want: []byte{0x02, 0x01}},
+	"long":       {v: uint32(64), want: []byte{0x04, 0x80, 0x01}},
+	"double":     {v: 1.5, want: []byte{0x06, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f}},
+	"big_uint64": {v: uint64(math.MaxUint64), want: []byte{0x06, 0, 0, 0, 0, 0, 0, 0xf0, 0x43}},
+	"string":     {v: "x", want: []byte{0x08, 0x02, 'x'}},
+	"leaf_list":  {v: []interface{}{1, 2}, want: []byte{0x08, 0x0a, '[', '1', ',', '2', ']'}},
+}
+
+func TestAppendAvroValue(t *testing.T) {
+	for name, tc := range avroValueSet {
+		t.Run(name, func(t *testing.T) {
+			got := appendAvroValue(nil, tc.v)
+			if !bytes.Equal(got, tc.want) {
+				t.Errorf("got %x, expected %x", got, tc.want)
+			}
+		})
+	}
+}
+
+// protoFields decodes the fields of a protobuf message,
+// the values of the length delimited fields are returned as bytes.
+func protoFields(t *testing.T, b []byte) map[protowire.Number][]interface{} {
+	fields := make(map[protowire.Number][]interface{})
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("invalid tag: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		var v interface{}
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			var u uint64
+			u, n = protowire.ConsumeFixed64(b)
+			v = math.Float64frombits(u)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			t.Fatalf("unexpected wire type %v", typ)
+		}
+		if n < 0 {
+			t.Fatalf("invalid field %d: %v", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+		fields[num] = append(fields[num], v)
+	}
+	return fields
+}
+
+func TestEncodeProtobufEvent(t *testing.T) {
+	ev := &formatters.EventMsg{
+		Name:      "port-stats",
+		Timestamp: 1700000000000000000,
+		Tags:      map[string]string{"source": "r1", "interface_name": "ethernet-1/1"},
+		Values: map[string]interface{}{
+			"in-octets":   uint64(math.MaxUint64),
+			"oper-status": "UP",
+			"temperature": -3.5,
+			"counter":     int64(-7),
+			"enabled":     true,
+		},
+	}
+	fields := protoFields(t, encodeProtobufEvent(ev))
+	if string(fields[1][0].([]byte)) != "port-stats" {
+		t.Errorf("unexpected name: %s", fields[1][0])
+	}
+	if int64(fields[2][0].(uint64)) != ev.Timestamp {
+		t.Errorf("unexpected timestamp: %d", fields[2][0])
+	}
+	tags := make(map[string]string)
+	for _, e := range fields[3] {
+		entry := protoFields(t, e.([]byte))
+		tags[string(entry[1][0].([]byte))] = string(entry[2][0].([]byte))
+	}
+	if len(tags) != 2 || tags["source"] != "r1" || tags["interface_name"] != "ethernet-1/1" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+	// value name to the expected Value oneof field number and value
+	want := map[string]struct {
+		num protowire.Number
+		v   interface{}
+	}{
+		"in-octets":   {3, uint64(math.MaxUint64)},
+		"oper-status": {1, "UP"},
+		"temperature": {4, -3.5},
+		"counter":     {2, uint64(math.MaxUint64 - 6)}, // two's complement of -7
+		"enabled":     {5, uint64(1)},
+	}
+	if len(fields[4]) != len(want) {
+		t.Fatalf("got %d values, expected %d", len(fields[4]), len(want))
+	}
+	for _, e := range fields[4] {
+		entry := protoFields(t, e.([]byte))
+		name := string(entry[1][0].([]byte))
+		value := protoFields(t, entry[2][0].([]byte))
+		w, ok := want[name]
+		if !ok {
+			t.Errorf("unexpected value %q", name)
+			continue
+		}
+		if len(value) != 1 || len(value[w.num]) != 1 {
+			t.Errorf("value %q: expected a single field %d, got %v", name, w.num, value)
+			continue
+		}
+		got := value[w.num][0]
+		if b, ok := got.([]byte); ok {
+			got = string(b)
+		}
+		if got != w.v {
+			t.Errorf("value %q: got %v, expected %v", name, got, w.v)
+		}
+	}
+}
@@ -63,6 +63,7 @@ func init() {
 type kafkaOutput struct {
 	cfg      *config
 	logger   sarama.StdLogger
+	dryRun   bool
 	mo       *formatters.MarshalOptions
 	cancelFn context.CancelFunc
 	msgChan  chan *outputs.ProtoMsg
@@ -71,36 +72,72 @@ type kafkaOutput struct {
 
 	targetTpl *template.Template
 	msgTpl    *template.Template
+	keyTpl    *template.Template
+	registry  *schemaRegistry
+
+	// producer used by WriteSync, created on first use
+	syncMu       sync.Mutex
+	syncProducer sarama.SyncProducer
+	syncConfig   *sarama.Config
+	// messages of the last failed WriteSync not acknowledged yet,
+	// a retry with the same message sends only those.
+	syncPending    []*sarama.ProducerMessage
+	syncPendingMsg proto.Message
 }
 
 // config //
 type config struct {
-	Address            string           `mapstructure:"address,omitempty"`
-	Topic              string           `mapstructure:"topic,omitempty"`
-	TopicPrefix        string           `mapstructure:"topic-prefix,omitempty"`
-	Name               string           `mapstructure:"name,omitempty"`
-	SASL               *types.SASL      `mapstructure:"sasl,omitempty"`
-	TLS                *types.TLSConfig `mapstructure:"tls,omitempty"`
-	MaxRetry           int              `mapstructure:"max-retry,omitempty"`
-	Timeout            time.Duration    `mapstructure:"timeout,omitempty"`
-	RecoveryWaitTime   time.Duration    `mapstructure:"recovery-wait-time,omitempty"`
-	FlushFrequency     time.Duration    `mapstructure:"flush-frequency,omitempty"`
-	SyncProducer       bool             `mapstructure:"sync-producer,omitempty"`
-	RequiredAcks       string           `mapstructure:"required-acks,omitempty"`
-	Format             string           `mapstructure:"format,omitempty"`
-	InsertKey          bool             `mapstructure:"insert-key,omitempty"`
-	AddTarget          string           `mapstructure:"add-target,omitempty"`
-	TargetTemplate     string           `mapstructure:"target-template,omitempty"`
-	MsgTemplate        string           `mapstructure:"msg-template,omitempty"`
-	SplitEvents        bool             `mapstructure:"split-events,omitempty"`
-	NumWorkers         int              `mapstructure:"num-workers,omitempty"`
-	CompressionCodec   string           `mapstructure:"compression-codec,omitempty"`
-	KafkaVersion       string           `mapstructure:"kafka-version,omitempty"`
-	Debug              bool             `mapstructure:"debug,omitempty"`
-	BufferSize         int              `mapstructure:"buffer-size,omitempty"`
-	OverrideTimestamps bool             `mapstructure:"override-timestamps,omitempty"`
-	EnableMetrics      bool             `mapstructure:"enable-metrics,omitempty"`
-	EventProcessors    []string         `mapstructure:"event-processors,omitempty"`
+	Address            string                `mapstructure:"address,omitempty"`
+	Topic              string                `mapstructure:"topic,omitempty"`
+	TopicPrefix        string                `mapstructure:"topic-prefix,omitempty"`
+	Name               string                `mapstructure:"name,omitempty"`
+	SASL               *types.SASL           `mapstructure:"sasl,omitempty"`
+	TLS                *types.TLSConfig      `mapstructure:"tls,omitempty"`
+	MaxRetry           int                   `mapstructure:"max-retry,omitempty"`
+	Timeout            time.Duration         `mapstructure:"timeout,omitempty"`
+	RecoveryWaitTime   time.Duration         `mapstructure:"recovery-wait-time,omitempty"`
+	FlushFrequency     time.Duration         `mapstructure:"flush-frequency,omitempty"`
+	SyncProducer       bool                  `mapstructure:"sync-producer,omitempty"`
+	RequiredAcks       string                `mapstructure:"required-acks,omitempty"`
+	Format             string                `mapstructure:"format,omitempty"`
+	InsertKey          bool                  `mapstructure:"insert-key,omitempty"`
+	KeyTemplate        string                `mapstructure:"key-template,omitempty"`
+	Headers            *headersConfig        `mapstructure:"headers,omitempty"`
+	SchemaRegistry     *schemaRegistryConfig `mapstructure:"schema-registry,omitempty"`
+	AddTarget          string                `mapstructure:"add-target,omitempty"`
+	TargetTemplate     string                `mapstructure:"target-template,omitempty"`
+	MsgTemplate        string                `mapstructure:"msg-template,omitempty"`
+	SplitEvents        bool                  `mapstructure:"split-events,omitempty"`
+	NumWorkers         int                   `mapstructure:"num-workers,omitempty"`
+	CompressionCodec   string                `mapstructure:"compression-codec,omitempty"`
+	KafkaVersion       string                `mapstructure:"kafka-version,omitempty"`
+	Debug              bool                  `mapstructure:"debug,omitempty"`
+	BufferSize         int                   `mapstructure:"buffer-size,omitempty"`
+	OverrideTimestamps bool                  `mapstructure:"override-timestamps,omitempty"`
+	EnableMetrics      bool                  `mapstructure:"enable-metrics,omitempty"`
+	EventProcessors    []string              `mapstructure:"event-processors,omitempty"`
+}
+
+// headersConfig lists the message metadata and event tags
+// added as headers to the kafka messages.
+type headersConfig struct {
+	Meta []string `mapstructure:"meta,omitempty"`
+	Tags []string `mapstructure:"tags,omitempty"`
+}
+
+// keyData is the input of the key template.
+type keyData struct {
+	// Target is the subscription target if set,
+	// otherwise the message source without the port number.
+	Target       string
+	Source       string
+	Subscription string
+	// Name is the event name with the event format and split-events,
+	// the subscription name otherwise.
+	Name string
+	// Tags are the event tags with the event format and split-events,
+	// the message metadata otherwise.
+	Tags map[string]string
 }
 
 func (k *kafkaOutput) String() string {
@@ -178,10 +215,33 @@ func (k *kafkaOutput) Init(ctx context.Context, name string, cfg map[string]inte
 		k.msgTpl = k.msgTpl.Funcs(outputs.TemplateFuncs)
 	}
 
+	if k.cfg.KeyTemplate != "" {
+		k.keyTpl, err = gtemplate.CreateTemplate("key-template", k.cfg.KeyTemplate)
+		if err != nil {
+			return err
+		}
+		k.keyTpl = k.keyTpl.Funcs(outputs.TemplateFuncs)
+	}
+	if k.cfg.Headers != nil && len(k.cfg.Headers.Tags) > 0 && !k.splitEvents() {
+		k.logger.Printf("headers from event tags are only added with format `event` and `split-events: true`, `avro` or `protobuf-event`")
+	}
+	if k.cfg.SchemaRegistry != nil {
+		k.registry, err = newSchemaRegistry(k.cfg.SchemaRegistry)
+		if err != nil {
+			return err
+		}
+	}
+
 	config, err := k.createConfig()
 	if err != nil {
 		return err
 	}
+	if k.dryRun {
+		return nil
+	}
+	syncConfig := *config
+	syncConfig.ClientID = config.ClientID + "-sync"
+	k.syncConfig = &syncConfig
 	ctx, k.cancelFn = context.WithCancel(ctx)
 	k.wg.Add(k.cfg.NumWorkers)
 	for i := 0; i < k.cfg.NumWorkers; i++ {
@@ -200,9 +260,24 @@ func (k *kafkaOutput) setDefaults() error {
 	if k.cfg.Format == "" {
 		k.cfg.Format = defaultFormat
 	}
-	if !(k.cfg.Format == "event" || k.cfg.Format == "protojson" || k.cfg.Format == "prototext" || k.cfg.Format == "proto" || k.cfg.Format == "json") {
+	if !(k.cfg.Format == "event" || k.cfg.Format == "protojson" || k.cfg.Format == "prototext" || k.cfg.Format == "proto" || k.cfg.Format == "json" ||
+		k.cfg.Format == "avro" || k.cfg.Format == "protobuf-event") {
 		return fmt.Errorf("unsupported output format '%s' for output type kafka", k.cfg.Format)
 	}
+	if k.cfg.Format == "avro" || k.cfg.Format == "protobuf-event" {
+		if k.cfg.SchemaRegistry == nil {
+			return fmt.Errorf("format %q requires a schema-registry", k.cfg.Format)
+		}
+		if k.cfg.MsgTemplate != "" {
+			return fmt.Errorf("msg-template is not supported with format %q", k.cfg.Format)
+		}
+	}
+	if k.cfg.SchemaRegistry != nil {
+		err := k.cfg.SchemaRegistry.setDefaults()
+		if err != nil {
+			return err
+		}
+	}
 	if k.cfg.Address == "" {
 		k.cfg.Address = defaultAddress
 	}
@@ -275,10 +350,75 @@ func (k *kafkaOutput) Write(ctx context.Context, rsp proto.Message, meta outputs
 
 func (k *kafkaOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
 
+// WriteSync sends the message using a sync producer
+// and returns once it is acknowledged or failed.
+func (k *kafkaOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	k.syncMu.Lock()
+	defer k.syncMu.Unlock()
+	if k.syncConfig == nil {
+		return errors.New("kafka output not initialized")
+	}
+	if k.syncProducer == nil {
+		var err error
+		k.syncProducer, err = sarama.NewSyncProducer(strings.Split(k.cfg.Address, ","), k.syncConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create kafka producer: %w", err)
+		}
+	}
+	clientID := k.syncConfig.ClientID
+	msgs := k.syncPending
+	if rsp != k.syncPendingMsg {
+		msgs = k.producerMessages(outputs.NewProtoMsg(rsp, meta), clientID, "sync")
+	}
+	k.syncPending, k.syncPendingMsg = nil, nil
+	for i, msg := range msgs {
+		var start time.Time
+		if k.cfg.EnableMetrics {
+			start = time.Now()
+		}
+		_, _, err := k.syncProducer.SendMessage(msg)
+		if err != nil {
+			if k.cfg.EnableMetrics {
+				kafkaNumberOfFailSendMsgs.WithLabelValues(clientID, "send_error").Inc()
+			}
+			// the messages already acknowledged are not sent again
+			// when the write is retried.
+			k.syncPending, k.syncPendingMsg = msgs[i:], rsp
+			// the producer is created again on the next write
+			k.syncProducer.Close()
+			k.syncProducer = nil
+			return err
+		}
+		if k.cfg.EnableMetrics {
+			kafkaSendDuration.WithLabelValues(clientID).Set(float64(time.Since(start).Nanoseconds()))
+			kafkaNumberOfSentMsgs.WithLabelValues(clientID).Inc()
+			kafkaNumberOfSentBytes.WithLabelValues(clientID).Add(float64(msg.Value.Length()))
+		}
+	}
+	return nil
+}
+
+// WriteEventSync rejects the event, the kafka output does not write events.
+func (k *kafkaOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	return outputs.Permanent(errors.New("kafka output does not support writing events"))
+}
+
 // Close //
 func (k *kafkaOutput) Close() error {
 	k.cancelFn()
 	k.wg.Wait()
+	k.syncMu.Lock()
+	defer k.syncMu.Unlock()
+	if k.syncProducer != nil {
+		k.syncProducer.Close()
+		k.syncProducer = nil
+	}
 	return nil
 }
 
@@ -357,48 +497,9 @@ CRPROD:
 			k.logger.Printf("%s shutting down", workerLogPrefix)
 			return
 		case m := <-k.msgChan:
-			pmsg := m.GetMsg()
-			pmsg, err = outputs.AddSubscriptionTarget(pmsg, m.GetMeta(), k.cfg.AddTarget, k.targetTpl)
-			if err != nil {
-				k.logger.Printf("failed to add target to the response: %v", err)
-			}
-			bb, err := outputs.Marshal(pmsg, m.GetMeta(), k.mo, k.cfg.SplitEvents, k.evps...)
-			if err != nil {
-				if k.cfg.Debug {
-					k.logger.Printf("%s failed marshaling proto msg: %v", workerLogPrefix, err)
-				}
+			for _, msg := range k.producerMessages(m, config.ClientID, workerLogPrefix) {
 				if k.cfg.EnableMetrics {
-					kafkaNumberOfFailSendMsgs.WithLabelValues(config.ClientID, "marshal_error").Inc()
-				}
-				continue
-			}
-			if len(bb) == 0 {
-				continue
-			}
-			for _, b := range bb {
-				if k.msgTpl != nil {
-					b, err = outputs.ExecTemplate(b, k.msgTpl)
-					if err != nil {
-						if k.cfg.Debug {
-							log.Printf("failed to execute template: %v", err)
-						}
-						kafkaNumberOfFailSendMsgs.WithLabelValues(config.ClientID, "template_error").Inc()
-						continue
-					}
-				}
-
-				topic := k.selectTopic(m.GetMeta())
-				msg := &sarama.ProducerMessage{
-					Topic: topic,
-					Value: sarama.ByteEncoder(b),
-				}
-				if k.cfg.InsertKey {
-					msg.Key = sarama.ByteEncoder(k.partitionKey(m.GetMeta()))
-				}
-				var start time.Time
-				if k.cfg.EnableMetrics {
-					start = time.Now()
-					msg.Metadata = start
+					msg.Metadata = time.Now()
 				}
 				producer.Input() <- msg
 			}
@@ -427,44 +528,7 @@ CRPROD:
 			k.logger.Printf("%s shutting down", workerLogPrefix)
 			return
 		case m := <-k.msgChan:
-			pmsg := m.GetMsg()
-			pmsg, err = outputs.AddSubscriptionTarget(pmsg, m.GetMeta(), k.cfg.AddTarget, k.targetTpl)
-			if err != nil {
-				k.logger.Printf("failed to add target to the response: %v", err)
-			}
-			bb, err := outputs.Marshal(pmsg, m.GetMeta(), k.mo, k.cfg.SplitEvents, k.evps...)
-			if err != nil {
-				if k.cfg.Debug {
-					k.logger.Printf("%s failed marshaling proto msg: %v", workerLogPrefix, err)
-				}
-				if k.cfg.EnableMetrics {
-					kafkaNumberOfFailSendMsgs.WithLabelValues(config.ClientID, "marshal_error").Inc()
-				}
-				continue
-			}
-			if len(bb) == 0 {
-				continue
-			}
-			for _, b := range bb {
-				if k.msgTpl != nil {
-					b, err = outputs.ExecTemplate(b, k.msgTpl)
-					if err != nil {
-						if k.cfg.Debug {
-							log.Printf("failed to execute template: %v", err)
-						}
-						kafkaNumberOfFailSendMsgs.WithLabelValues(config.ClientID, "template_error").Inc()
-						continue
-					}
-				}
-
-				topic := k.selectTopic(m.GetMeta())
-				msg := &sarama.ProducerMessage{
-					Topic: topic,
-					Value: sarama.ByteEncoder(b),
-				}
-				if k.cfg.InsertKey {
-					msg.Key = sarama.ByteEncoder(k.partitionKey(m.GetMeta()))
-				}
+			for _, msg := range k.producerMessages(m, config.ClientID, workerLogPrefix) {
 				var start time.Time
 				if k.cfg.EnableMetrics {
 					start = time.Now()
@@ -472,7 +536,7 @@ CRPROD:
 				_, _, err = producer.SendMessage(msg)
 				if err != nil {
 					if k.cfg.Debug {
-						k.logger.Printf("%s failed to send a kafka msg to topic '%s': %v", workerLogPrefix, topic, err)
+						k.logger.Printf("%s failed to send a kafka msg to topic '%s': %v", workerLogPrefix, msg.Topic, err)
 					}
 					if k.cfg.EnableMetrics {
 						kafkaNumberOfFailSendMsgs.WithLabelValues(config.ClientID, "send_error").Inc()
@@ -484,7 +548,7 @@ CRPROD:
 				if k.cfg.EnableMetrics {
 					kafkaSendDuration.WithLabelValues(config.ClientID).Set(float64(time.Since(start).Nanoseconds()))
 					kafkaNumberOfSentMsgs.WithLabelValues(config.ClientID).Inc()
-					kafkaNumberOfSentBytes.WithLabelValues(config.ClientID).Add(float64(len(b)))
+					kafkaNumberOfSentBytes.WithLabelValues(config.ClientID).Add(float64(msg.Value.Length()))
 				}
 			}
 		}
@@ -504,6 +568,8 @@ func (k *kafkaOutput) SetName(name string) {
 
 func (k *kafkaOutput) SetClusterName(name string) {}
 
+func (k *kafkaOutput) SetDryRun(dryRun bool) { k.dryRun = dryRun }
+
 func (k *kafkaOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
 
 func (k *kafkaOutput) createConfig() (*sarama.Config, error) {
@@ -565,6 +631,11 @@ func (k *kafkaOutput) createConfig() (*sarama.Config, error) {
 
 	cfg.Metadata.Full = false
 
+	// record headers were added in kafka 0.11
+	if k.cfg.Headers != nil && !cfg.Version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil, fmt.Errorf("kafka headers require kafka-version 0.11.0.0 or later, got %s", cfg.Version)
+	}
+
 	switch k.cfg.CompressionCodec {
 	case "gzip":
 		cfg.Producer.Compression = sarama.CompressionGZIP
@@ -581,6 +652,183 @@ func (k *kafkaOutput) createConfig() (*sarama.Config, error) {
 	return cfg, nil
 }
 
+// producerMessages marshals a received message into one or more kafka messages
+// and sets their key and headers.
+func (k *kafkaOutput) producerMessages(m *outputs.ProtoMsg, clientID, workerLogPrefix string) []*sarama.ProducerMessage {
+	meta := m.GetMeta()
+	pmsg, err := outputs.AddSubscriptionTarget(m.GetMsg(), meta, k.cfg.AddTarget, k.targetTpl)
+	if err != nil {
+		k.logger.Printf("failed to add target to the response: %v", err)
+	}
+	topic := k.selectTopic(meta)
+	var bb [][]byte
+	// events are kept to populate the key and headers from their tags
+	var events []*formatters.EventMsg
+	if k.splitEvents() {
+		events, err = outputs.SplitEvents(pmsg, meta, k.evps...)
+		if err == nil {
+			bb = make([][]byte, 0, len(events))
+			for _, ev := range events {
+				var b []byte
+				b, err = k.marshalEvent(ev, topic)
+				if err != nil {
+					break
+				}
+				bb = append(bb, b)
+			}
+		}
+	} else {
+		bb, err = outputs.Marshal(pmsg, meta, k.mo, k.cfg.SplitEvents, k.evps...)
+	}
+	if err != nil {
+		reason := "marshal_error"
+		if errors.Is(err, errSchemaRegistry) {
+			reason = "schema_registry_error"
+			// logged regardless of debug since all the messages fail
+			// until the schema is registered.
+			k.logger.Printf("%s %v", workerLogPrefix, err)
+		} else if k.cfg.Debug {
+			k.logger.Printf("%s failed marshaling proto msg: %v", workerLogPrefix, err)
+		}
+		if k.cfg.EnableMetrics {
+			kafkaNumberOfFailSendMsgs.WithLabelValues(clientID, reason).Inc()
+		}
+		return nil
+	}
+
+	msgs := make([]*sarama.ProducerMessage, 0, len(bb))
+	for i, b := range bb {
+		if k.msgTpl != nil {
+			b, err = outputs.ExecTemplate(b, k.msgTpl)
+			if err != nil {
+				if k.cfg.Debug {
+					k.logger.Printf("failed to execute template: %v", err)
+				}
+				kafkaNumberOfFailSendMsgs.WithLabelValues(clientID, "template_error").Inc()
+				continue
+			}
+		}
+		var ev *formatters.EventMsg
+		if events != nil {
+			ev = events[i]
+		}
+		key, err := k.recordKey(meta, ev)
+		if err != nil {
+			if k.cfg.Debug {
+				k.logger.Printf("failed to execute key template: %v", err)
+			}
+			kafkaNumberOfFailSendMsgs.WithLabelValues(clientID, "template_error").Inc()
+			continue
+		}
+		msgs = append(msgs, &sarama.ProducerMessage{
+			Topic:   topic,
+			Key:     key,
+			Value:   sarama.ByteEncoder(b),
+			Headers: k.recordHeaders(meta, ev),
+		})
+	}
+	return msgs
+}
+
+// splitEvents returns true if each event is sent in a separate message.
+func (k *kafkaOutput) splitEvents() bool {
+	switch k.cfg.Format {
+	case "avro", "protobuf-event":
+		return true
+	case "event":
+		return k.cfg.SplitEvents
+	}
+	return false
+}
+
+// marshalEvent encodes an event according to the output format,
+// the avro and protobuf-event formats use the schema registry wire format.
+func (k *kafkaOutput) marshalEvent(ev *formatters.EventMsg, topic string) ([]byte, error) {
+	switch k.cfg.Format {
+	case "avro":
+		id, err := k.registry.schemaID(topic, avroEventSchema, schemaTypeAvro)
+		if err != nil {
+			return nil, err
+		}
+		return wireFormat(id, schemaTypeAvro, encodeAvroEvent(ev)), nil
+	case "protobuf-event":
+		id, err := k.registry.schemaID(topic, protobufEventSchema, schemaTypeProtobuf)
+		if err != nil {
+			return nil, err
+		}
+		return wireFormat(id, schemaTypeProtobuf, encodeProtobufEvent(ev)), nil
+	default:
+		return json.Marshal(ev)
+	}
+}
+
+// recordKey returns the message key rendered from the key template if set,
+// otherwise the default key if insert-key is true.
+// ev is nil unless the events are sent in separate messages.
+func (k *kafkaOutput) recordKey(meta outputs.Meta, ev *formatters.EventMsg) (sarama.Encoder, error) {
+	if k.keyTpl == nil {
+		if k.cfg.InsertKey {
+			return sarama.ByteEncoder(k.partitionKey(meta)), nil
+		}
+		return nil, nil
+	}
+	data := &keyData{
+		Target:       targetName(meta),
+		Source:       meta["source"],
+		Subscription: meta["subscription-name"],
+		Name:         meta["subscription-name"],
+		Tags:         meta,
+	}
+	if ev != nil {
+		data.Target = targetName(ev.Tags)
+		data.Name = ev.Name
+		data.Tags = ev.Tags
+	}
+	sb := new(strings.Builder)
+	err := k.keyTpl.Execute(sb, data)
+	if err != nil {
+		return nil, err
+	}
+	// an empty key lets the partitioner pick a random partition
+	if sb.Len() == 0 {
+		return nil, nil
+	}
+	return sarama.StringEncoder(sb.String()), nil
+}
+
+// recordHeaders returns the configured message metadata and event tags as headers,
+// the missing ones are skipped.
+// ev is nil unless the events are sent in separate messages.
+func (k *kafkaOutput) recordHeaders(meta outputs.Meta, ev *formatters.EventMsg) []sarama.RecordHeader {
+	if k.cfg.Headers == nil {
+		return nil
+	}
+	hs := make([]sarama.RecordHeader, 0, len(k.cfg.Headers.Meta)+len(k.cfg.Headers.Tags))
+	for _, n := range k.cfg.Headers.Meta {
+		if v, ok := meta[n]; ok {
+			hs = append(hs, sarama.RecordHeader{Key: []byte(n), Value: []byte(v)})
+		}
+	}
+	if ev == nil {
+		return hs
+	}
+	for _, n := range k.cfg.Headers.Tags {
+		if v, ok := ev.Tags[n]; ok {
+			hs = append(hs, sarama.RecordHeader{Key: []byte(n), Value: []byte(v)})
+		}
+	}
+	return hs
+}
+
+// targetName returns the subscription target if set,
+// otherwise the source without the port number.
+func targetName(tags map[string]string) string {
+	if t := tags["subscription-target"]; t != "" {
+		return t
+	}
+	return utils.GetHost(tags["source"])
+}
+
 func (k *kafkaOutput) partitionKey(m outputs.Meta) []byte {
 	b := new(bytes.Buffer)
 	fmt.Fprintf(b, "%s_%s", m["source"], m["subscription-name"])
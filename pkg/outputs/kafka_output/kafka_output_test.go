@@ -0,0 +1,186 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka_output
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+var testMeta = outputs.Meta{
+	"source":            "10.0.0.1:57400",
+	"subscription-name": "port-stats",
+}
+
+var testEvent = &formatters.EventMsg{
+	Name: "port-stats",
+	Tags: map[string]string{
+		"source":            "10.0.0.1:57400",
+		"subscription-name": "port-stats",
+		"interface_name":    "ethernet-1/1",
+	},
+}
+
+var recordKeySet = map[string]struct {
+	cfg  *config
+	ev   *formatters.EventMsg
+	want string
+}{
+	"no_key": {
+		cfg: &config{},
+	},
+	"insert_key": {
+		cfg:  &config{InsertKey: true},
+		want: "10.0.0.1:57400_port-stats",
+	},
+	"template_event_tags": {
+		cfg:  &config{KeyTemplate: `{{ .Target }}_{{ index .Tags "interface_name" }}`},
+		ev:   testEvent,
+		want: "10.0.0.1_ethernet-1/1",
+	},
+	"template_meta": {
+		cfg:  &config{KeyTemplate: `{{ .Subscription }}/{{ index .Tags "source" }}`},
+		want: "port-stats/10.0.0.1:57400",
+	},
+	"template_empty": {
+		cfg: &config{KeyTemplate: `{{ index .Tags "interface_name" }}`},
+	},
+}
+
+func TestRecordKey(t *testing.T) {
+	for name, tc := range recordKeySet {
+		t.Run(name, func(t *testing.T) {
+			k := &kafkaOutput{cfg: tc.cfg}
+			if tc.cfg.KeyTemplate != "" {
+				var err error
+				k.keyTpl, err = gtemplate.CreateTemplate("key-template", tc.cfg.KeyTemplate)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			key, err := k.recordKey(testMeta, tc.ev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.want == "" {
+				if key != nil {
+					t.Fatalf("expected no key, got %v", key)
+				}
+				return
+			}
+			if key == nil {
+				t.Fatalf("expected key %q, got none", tc.want)
+			}
+			b, _ := key.Encode()
+			if string(b) != tc.want {
+				t.Errorf("got key %q, expected %q", b, tc.want)
+			}
+		})
+	}
+}
+
+func TestRecordHeaders(t *testing.T) {
+	k := &kafkaOutput{cfg: &config{
+		Headers: &headersConfig{
+			Meta: []string{"source", "subscription-name", "subscription-target"},
+			Tags: []string{"interface_name", "neighbor_address"},
+		},
+	}}
+	want := []sarama.RecordHeader{
+		{Key: []byte("source"), Value: []byte("10.0.0.1:57400")},
+		{Key: []byte("subscription-name"), Value: []byte("port-stats")},
+		{Key: []byte("interface_name"), Value: []byte("ethernet-1/1")},
+	}
+	got := k.recordHeaders(testMeta, testEvent)
+	if len(got) != len(want) {
+		t.Fatalf("got %d headers, expected %d", len(got), len(want))
+	}
+	for i := range got {
+		if string(got[i].Key) != string(want[i].Key) || string(got[i].Value) != string(want[i].Value) {
+			t.Errorf("header %d: got %s=%s, expected %s=%s", i, got[i].Key, got[i].Value, want[i].Key, want[i].Value)
+		}
+	}
+	// without the event, only the metadata headers are added
+	got = k.recordHeaders(testMeta, nil)
+	if len(got) != 2 {
+		t.Errorf("got %d headers without event, expected 2", len(got))
+	}
+}
+
+func TestWriteSyncRetry(t *testing.T) {
+	k := &kafkaOutput{
+		cfg:        &config{Topic: "telemetry", Format: "event", SplitEvents: true},
+		logger:     log.New(io.Discard, "", 0),
+		mo:         &formatters.MarshalOptions{Format: "event"},
+		syncConfig: sarama.NewConfig(),
+	}
+	rsp := &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{
+		Update: &gnmi.Notification{
+			Timestamp: 42,
+			Update: []*gnmi.Update{
+				{
+					Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interface", Key: map[string]string{"name": "ethernet-1/1"}}, {Name: "mtu"}}},
+					Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 1500}},
+				},
+				{
+					Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interface", Key: map[string]string{"name": "ethernet-1/2"}}, {Name: "mtu"}}},
+					Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 9000}},
+				},
+			},
+		},
+	}}
+	sent := make([]string, 0)
+	record := func(msg *sarama.ProducerMessage) error {
+		b, _ := msg.Value.Encode()
+		sent = append(sent, string(b))
+		return nil
+	}
+	// the first message is acknowledged, the second one fails
+	p := mocks.NewSyncProducer(t, nil)
+	p.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(record)
+	p.ExpectSendMessageAndFail(errors.New("broker unavailable"))
+	k.syncProducer = p
+	err := k.WriteSync(context.Background(), rsp, testMeta)
+	if err == nil {
+		t.Fatal("expected the write to fail")
+	}
+	// the retry sends the second message only
+	p = mocks.NewSyncProducer(t, nil)
+	p.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(record)
+	k.syncProducer = p
+	err = k.WriteSync(context.Background(), rsp, testMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 || sent[0] == sent[1] {
+		t.Fatalf("expected 2 distinct messages to be sent, got %v", sent)
+	}
+}
+
+func TestWriteEventSync(t *testing.T) {
+	k := &kafkaOutput{cfg: &config{}}
+	err := k.WriteEventSync(context.Background(), testEvent)
+	if !outputs.IsPermanent(err) {
+		t.Fatalf("expected a permanent error, got %v", err)
+	}
+}
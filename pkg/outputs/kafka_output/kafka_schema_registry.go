@@ -0,0 +1,180 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka_output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+)
+
+const (
+	defaultSchemaRegistryTimeout = 10 * time.Second
+	schemaRegistryContentType    = "application/vnd.schemaregistry.v1+json"
+)
+
+var errSchemaRegistry = errors.New("schema registry error")
+
+type schemaRegistryConfig struct {
+	URL string `mapstructure:"url,omitempty"`
+	// Subject defaults to `<topic>-value`.
+	Subject        string           `mapstructure:"subject,omitempty"`
+	AutoRegister   *bool            `mapstructure:"auto-register,omitempty"`
+	Authentication *types.BasicAuth `mapstructure:"authentication,omitempty"`
+	TLS            *types.TLSConfig `mapstructure:"tls,omitempty"`
+	Timeout        time.Duration    `mapstructure:"timeout,omitempty"`
+}
+
+// schemaRegistry registers or looks up the events schema in a
+// Confluent compatible schema registry and caches the schema IDs per subject.
+type schemaRegistry struct {
+	cfg    *schemaRegistryConfig
+	client *http.Client
+
+	m   sync.Mutex
+	ids map[string]int
+}
+
+type registrySchemaRequest struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+type registrySchemaResponse struct {
+	ID int `json:"id"`
+}
+
+type registryErrorResponse struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+func (c *schemaRegistryConfig) setDefaults() error {
+	if c.URL == "" {
+		return errors.New("missing schema-registry url")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid schema-registry url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid schema-registry url %q: scheme must be http or https", c.URL)
+	}
+	c.URL = strings.TrimSuffix(c.URL, "/")
+	if c.AutoRegister == nil {
+		ar := true
+		c.AutoRegister = &ar
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultSchemaRegistryTimeout
+	}
+	return nil
+}
+
+func newSchemaRegistry(cfg *schemaRegistryConfig) (*schemaRegistry, error) {
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+	}
+	if cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			cfg.TLS.CaFile,
+			cfg.TLS.CertFile,
+			cfg.TLS.KeyFile,
+			"",
+			cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &http.Transport{
+			TLSClientConfig: tlsCfg,
+		}
+	}
+	return &schemaRegistry{
+		cfg:    cfg,
+		client: client,
+		ids:    make(map[string]int),
+	}, nil
+}
+
+// subject returns the subject of the topic messages values,
+// following the default topic name strategy unless a subject is configured.
+func (r *schemaRegistry) subject(topic string) string {
+	if r.cfg.Subject != "" {
+		return r.cfg.Subject
+	}
+	return topic + "-value"
+}
+
+// schemaID returns the ID of the schema under the topic subject.
+// The schema is registered if auto-register is true, otherwise it is looked up.
+func (r *schemaRegistry) schemaID(topic, schema, schemaType string) (int, error) {
+	subject := r.subject(topic)
+	r.m.Lock()
+	defer r.m.Unlock()
+	if id, ok := r.ids[subject]; ok {
+		return id, nil
+	}
+	reqURL := r.cfg.URL + "/subjects/" + url.PathEscape(subject)
+	if *r.cfg.AutoRegister {
+		reqURL += "/versions"
+	}
+	sreq := &registrySchemaRequest{Schema: schema}
+	// the schema type defaults to AVRO
+	if schemaType != schemaTypeAvro {
+		sreq.SchemaType = schemaType
+	}
+	body, err := json.Marshal(sreq)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, reqURL, bytes.NewBuffer(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", schemaRegistryContentType)
+	req.Header.Set("Accept", schemaRegistryContentType)
+	if r.cfg.Authentication != nil {
+		req.SetBasicAuth(r.cfg.Authentication.Username, r.cfg.Authentication.Password)
+	}
+	rsp, err := r.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errSchemaRegistry, err)
+	}
+	defer rsp.Body.Close()
+	rb, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errSchemaRegistry, err)
+	}
+	if rsp.StatusCode >= 300 {
+		rerr := new(registryErrorResponse)
+		if json.Unmarshal(rb, rerr) == nil && rerr.Message != "" {
+			return 0, fmt.Errorf("%w: subject %q: code=%d: %s", errSchemaRegistry, subject, rerr.ErrorCode, rerr.Message)
+		}
+		return 0, fmt.Errorf("%w: subject %q: status=%d: %s", errSchemaRegistry, subject, rsp.StatusCode, string(rb))
+	}
+	srsp := new(registrySchemaResponse)
+	err = json.Unmarshal(rb, srsp)
+	if err != nil {
+		return 0, fmt.Errorf("%w: subject %q: failed to decode response: %v", errSchemaRegistry, subject, err)
+	}
+	r.ids[subject] = srsp.ID
+	return srsp.ID, nil
+}
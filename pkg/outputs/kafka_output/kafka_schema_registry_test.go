@@ -0,0 +1,109 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka_output
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+)
+
+func TestSchemaRegistry(t *testing.T) {
+	var numRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		numRequests.Add(1)
+		user, pass, _ := r.BasicAuth()
+		if user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error_code":401,"message":"Unauthorized"}`))
+			return
+		}
+		req := new(registrySchemaRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil || req.Schema == "" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		switch r.URL.Path {
+		case "/subjects/telemetry-value/versions":
+			if req.SchemaType != "" {
+				t.Errorf("unexpected schema type for an avro schema: %q", req.SchemaType)
+			}
+			w.Write([]byte(`{"id":1}`))
+		case "/subjects/events/versions":
+			if req.SchemaType != schemaTypeProtobuf {
+				t.Errorf("unexpected schema type: %q", req.SchemaType)
+			}
+			w.Write([]byte(`{"id":2}`))
+		case "/subjects/telemetry-value":
+			// lookup without registration
+			w.Write([]byte(`{"subject":"telemetry-value","version":1,"id":3}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":40401,"message":"Subject not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	newRegistry := func(cfg *schemaRegistryConfig) *schemaRegistry {
+		cfg.URL = srv.URL + "/"
+		if cfg.Authentication == nil {
+			cfg.Authentication = &types.BasicAuth{Username: "admin", Password: "secret"}
+		}
+		if err := cfg.setDefaults(); err != nil {
+			t.Fatal(err)
+		}
+		r, err := newSchemaRegistry(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	r := newRegistry(&schemaRegistryConfig{})
+	for i := 0; i < 2; i++ {
+		id, err := r.schemaID("telemetry", avroEventSchema, schemaTypeAvro)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != 1 {
+			t.Errorf("got schema ID %d, expected 1", id)
+		}
+	}
+	if numRequests.Load() != 1 {
+		t.Errorf("expected the schema ID to be cached, got %d requests", numRequests.Load())
+	}
+
+	r = newRegistry(&schemaRegistryConfig{Subject: "events"})
+	id, err := r.schemaID("telemetry", protobufEventSchema, schemaTypeProtobuf)
+	if err != nil || id != 2 {
+		t.Errorf("got schema ID %d, err=%v, expected 2", id, err)
+	}
+
+	autoRegister := false
+	r = newRegistry(&schemaRegistryConfig{AutoRegister: &autoRegister})
+	id, err = r.schemaID("telemetry", avroEventSchema, schemaTypeAvro)
+	if err != nil || id != 3 {
+		t.Errorf("got schema ID %d, err=%v, expected 3", id, err)
+	}
+	_, err = r.schemaID("unknown", avroEventSchema, schemaTypeAvro)
+	if !errors.Is(err, errSchemaRegistry) {
+		t.Errorf("expected a schema registry error, got %v", err)
+	}
+
+	r = newRegistry(&schemaRegistryConfig{Authentication: &types.BasicAuth{Username: "admin"}})
+	_, err = r.schemaID("telemetry", avroEventSchema, schemaTypeAvro)
+	if !errors.Is(err, errSchemaRegistry) {
+		t.Errorf("expected a schema registry error, got %v", err)
+	}
+}
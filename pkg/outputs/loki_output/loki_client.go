@@ -0,0 +1,215 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package loki_output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const userAgent = "gNMIc loki"
+
+// pushRequest is the JSON body of a Loki push API request.
+type pushRequest struct {
+	Streams []*stream `json:"streams"`
+}
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type pushClient struct {
+	url     string
+	client  *http.Client
+	headers map[string]string
+	auth    func(*http.Request)
+}
+
+func newPushClient(cfg *config) (*pushClient, error) {
+	c := &http.Client{
+		Timeout: cfg.Timeout,
+	}
+	if cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			cfg.TLS.CaFile,
+			cfg.TLS.CertFile,
+			cfg.TLS.KeyFile,
+			"",
+			cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		c.Transport = &http.Transport{
+			TLSClientConfig: tlsCfg,
+		}
+	}
+	pc := &pushClient{
+		url:     cfg.URL,
+		client:  c,
+		headers: make(map[string]string, len(cfg.Headers)+1),
+	}
+	for k, v := range cfg.Headers {
+		pc.headers[k] = v
+	}
+	if cfg.TenantID != "" {
+		pc.headers["X-Scope-OrgID"] = cfg.TenantID
+	}
+	switch {
+	case cfg.Authorization != nil && cfg.Authorization.Type != "":
+		pc.auth = func(r *http.Request) {
+			r.Header.Set("Authorization", fmt.Sprintf("%s %s", cfg.Authorization.Type, cfg.Authorization.Credentials))
+		}
+	case cfg.Authentication != nil:
+		pc.auth = func(r *http.Request) {
+			r.SetBasicAuth(cfg.Authentication.Username, cfg.Authentication.Password)
+		}
+	}
+	return pc, nil
+}
+
+// newPushRequest groups the entries by stream,
+// the values of each stream are sorted by timestamp.
+func newPushRequest(entries []*entry) *pushRequest {
+	streams := make(map[string]*stream)
+	keys := make([]string, 0)
+	for _, e := range entries {
+		s, ok := streams[e.key]
+		if !ok {
+			s = &stream{Stream: e.labels}
+			streams[e.key] = s
+			keys = append(keys, e.key)
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(e.ts, 10), e.line})
+	}
+	req := &pushRequest{Streams: make([]*stream, 0, len(streams))}
+	for _, k := range keys {
+		s := streams[k]
+		sort.SliceStable(s.Values, func(i, j int) bool {
+			ti, _ := strconv.ParseInt(s.Values[i][0], 10, 64)
+			tj, _ := strconv.ParseInt(s.Values[j][0], 10, 64)
+			return ti < tj
+		})
+		req.Streams = append(req.Streams, s)
+	}
+	return req
+}
+
+func (c *pushClient) push(ctx context.Context, req *pushRequest) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return outputs.Permanent(fmt.Errorf("marshal error: %w", err))
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewBuffer(b))
+	if err != nil {
+		return outputs.Permanent(fmt.Errorf("failed to create HTTP request: %w", err))
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", userAgent)
+	if c.auth != nil {
+		c.auth(httpReq)
+	}
+	for k, v := range c.headers {
+		httpReq.Header.Set(k, v)
+	}
+	rsp, err := c.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode < 300 {
+		io.Copy(io.Discard, rsp.Body)
+		return nil
+	}
+	body, _ := io.ReadAll(rsp.Body)
+	err = fmt.Errorf("push failed, code=%d, body=%s", rsp.StatusCode, string(body))
+	if rsp.StatusCode == http.StatusTooManyRequests || rsp.StatusCode >= 500 {
+		return err
+	}
+	return outputs.Permanent(err)
+}
+
+func (c *pushClient) close() {
+	c.client.CloseIdleConnections()
+}
+
+// writer batches the log entries and pushes them when the batch size
+// or the flush interval is reached.
+func (l *lokiOutput) writer(ctx context.Context) {
+	ticker := time.NewTicker(l.cfg.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]*entry, 0, l.cfg.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-l.entriesCh:
+			batch = append(batch, e)
+			if len(batch) < l.cfg.BatchSize {
+				continue
+			}
+			if l.cfg.Debug {
+				l.logger.Printf("batch size reached, pushing")
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+			if l.cfg.Debug {
+				l.logger.Printf("flush interval reached, pushing")
+			}
+		}
+		l.push(ctx, batch)
+		batch = make([]*entry, 0, l.cfg.BatchSize)
+	}
+}
+
+// push sends a batch of entries, retrying with an exponential backoff
+// up to max-retries times unless the error is permanent.
+func (l *lokiOutput) push(ctx context.Context, batch []*entry) {
+	req := newPushRequest(batch)
+	policy := outputs.RetryPolicy{
+		MaxRetries:  l.cfg.MaxRetries,
+		Backoff:     l.cfg.RetryBackoff,
+		Exponential: true,
+	}
+	err := policy.Do(ctx, func() error {
+		start := time.Now()
+		err := l.client.push(ctx, req)
+		if err != nil {
+			l.logger.Printf("failed to push %d entries: %v", len(batch), err)
+			return err
+		}
+		lokiPushDuration.Set(float64(time.Since(start).Nanoseconds()))
+		lokiNumberOfSentEntries.Add(float64(len(batch)))
+		if l.cfg.Debug {
+			l.logger.Printf("pushed %d entries in %d streams", len(batch), len(req.Streams))
+		}
+		return nil
+	})
+	switch {
+	case err == nil, ctx.Err() != nil:
+	case outputs.IsPermanent(err):
+		lokiNumberOfFailedEntries.WithLabelValues("rejected").Add(float64(len(batch)))
+	default:
+		lokiNumberOfFailedEntries.WithLabelValues("max_retries").Add(float64(len(batch)))
+	}
+}
@@ -0,0 +1,161 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package loki_output
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+// entry is a log line and the labels of the stream it belongs to.
+type entry struct {
+	labels map[string]string
+	// key uniquely identifies the stream, it is the labels set in the Loki format.
+	key  string
+	ts   int64
+	line string
+}
+
+// entryFromEvent renders the event as a log line,
+// the line is the event JSON or the result of the msg-template
+// executed with the event as input.
+func (l *lokiOutput) entryFromEvent(ev *formatters.EventMsg) (*entry, error) {
+	if l.cfg.OverrideTimestamps {
+		nev := *ev
+		nev.Timestamp = time.Now().UnixNano()
+		ev = &nev
+	}
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	if l.msgTpl != nil {
+		b, err = outputs.ExecTemplate(b, l.msgTpl)
+		if err != nil {
+			return nil, err
+		}
+	}
+	labels := l.streamLabels(ev)
+	return &entry{
+		labels: labels,
+		key:    labelsKey(labels),
+		ts:     ev.Timestamp,
+		line:   string(b),
+	}, nil
+}
+
+// streamLabels builds the stream labels from the static labels
+// and the configured event tags.
+// A tag is not used as a label once max-label-values distinct values
+// were seen for it, the tag is still part of the log line.
+func (l *lokiOutput) streamLabels(ev *formatters.EventMsg) map[string]string {
+	labels := make(map[string]string, len(l.cfg.StaticLabels)+len(l.cfg.Labels))
+	for k, v := range l.cfg.StaticLabels {
+		labels[k] = v
+	}
+	for _, tn := range l.cfg.Labels {
+		v, ok := ev.Tags[tn]
+		if !ok || v == "" {
+			continue
+		}
+		if !l.allowLabelValue(tn, v) {
+			lokiNumberOfDroppedLabels.WithLabelValues(tn).Inc()
+			if l.cfg.Debug {
+				l.logger.Printf("max label values reached for tag %q, value %q not used as label", tn, v)
+			}
+			continue
+		}
+		labels[sanitizeLabelName(tn)] = v
+	}
+	return labels
+}
+
+// allowLabelValue records the value v of label name and
+// returns false if the label reached its max number of distinct values.
+func (l *lokiOutput) allowLabelValue(name, v string) bool {
+	if l.cfg.MaxLabelValues < 0 {
+		return true
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	vals, ok := l.labelValues[name]
+	if !ok {
+		vals = make(map[string]struct{})
+		l.labelValues[name] = vals
+	}
+	if _, ok := vals[v]; ok {
+		return true
+	}
+	if len(vals) >= l.cfg.MaxLabelValues {
+		return false
+	}
+	vals[v] = struct{}{}
+	return true
+}
+
+// labelsKey returns the labels in the Loki stream selector format,
+// with the labels sorted by name.
+func labelsKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for n := range labels {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	sb := new(strings.Builder)
+	sb.WriteString("{")
+	for i, n := range names {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(sb, "%s=%s", n, strconv.Quote(labels[n]))
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// sanitizeLabelName replaces the characters not allowed
+// in a Loki label name with an underscore.
+func sanitizeLabelName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !isLabelChar(c, i == 0) {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+func validLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isLabelChar(name[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func isLabelChar(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	}
+	return false
+}
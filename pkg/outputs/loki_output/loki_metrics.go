@@ -0,0 +1,68 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package loki_output
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	namespace = "gnmic"
+	subsystem = "loki_output"
+)
+
+var lokiNumberOfSentEntries = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_sent_entries_total",
+	Help:      "Number of log entries successfully pushed by gnmic loki output",
+})
+
+var lokiNumberOfFailedEntries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_failed_entries_total",
+	Help:      "Number of log entries gnmic loki output failed to push",
+}, []string{"reason"})
+
+var lokiNumberOfDroppedLabels = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_dropped_labels_total",
+	Help:      "Number of times a tag was not used as a stream label because it reached max-label-values",
+}, []string{"label"})
+
+var lokiPushDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "push_duration_ns",
+	Help:      "gnmic loki output push request duration in ns",
+})
+
+func initMetrics() {
+	lokiNumberOfSentEntries.Add(0)
+	lokiNumberOfFailedEntries.WithLabelValues("").Add(0)
+	lokiPushDuration.Set(0)
+}
+
+func registerMetrics(reg *prometheus.Registry) error {
+	initMetrics()
+	var err error
+	if err = reg.Register(lokiNumberOfSentEntries); err != nil {
+		return err
+	}
+	if err = reg.Register(lokiNumberOfFailedEntries); err != nil {
+		return err
+	}
+	if err = reg.Register(lokiNumberOfDroppedLabels); err != nil {
+		return err
+	}
+	if err = reg.Register(lokiPushDuration); err != nil {
+		return err
+	}
+	return nil
+}
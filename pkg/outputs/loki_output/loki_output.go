@@ -0,0 +1,384 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package loki_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType            = "loki"
+	loggingPrefix         = "[loki_output:%s] "
+	defaultPushPath       = "/loki/api/v1/push"
+	defaultTimeout        = 10 * time.Second
+	defaultFlushInterval  = 5 * time.Second
+	defaultBatchSize      = 1000
+	defaultBufferSize     = 1000
+	defaultMaxRetries     = 3
+	defaultRetryBackoff   = 500 * time.Millisecond
+	defaultMaxLabelValues = 1000
+	defaultNumWorkers     = 1
+)
+
+var defaultLabels = []string{"source", "subscription-name"}
+
+func init() {
+	outputs.Register(outputType,
+		func() outputs.Output {
+			return &lokiOutput{
+				cfg:       &config{},
+				logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+				eventChan: make(chan *formatters.EventMsg),
+				msgChan:   make(chan *outputs.ProtoMsg),
+				m:         new(sync.Mutex),
+			}
+		})
+}
+
+type lokiOutput struct {
+	cfg    *config
+	logger *log.Logger
+	dryRun bool
+
+	eventChan chan *formatters.EventMsg
+	msgChan   chan *outputs.ProtoMsg
+	entriesCh chan *entry
+	client    *pushClient
+
+	m *sync.Mutex
+	// label name to the set of values seen so far,
+	// used to enforce max-label-values
+	labelValues map[string]map[string]struct{}
+
+	evps      []formatters.EventProcessor
+	msgTpl    *template.Template
+	targetTpl *template.Template
+	cfn       context.CancelFunc
+}
+
+type config struct {
+	Name           string            `mapstructure:"name,omitempty" json:"name,omitempty"`
+	URL            string            `mapstructure:"url,omitempty" json:"url,omitempty"`
+	TenantID       string            `mapstructure:"tenant-id,omitempty" json:"tenant-id,omitempty"`
+	Timeout        time.Duration     `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	Headers        map[string]string `mapstructure:"headers,omitempty" json:"headers,omitempty"`
+	Authentication *auth             `mapstructure:"authentication,omitempty" json:"authentication,omitempty"`
+	Authorization  *authorization    `mapstructure:"authorization,omitempty" json:"authorization,omitempty"`
+	TLS            *types.TLSConfig  `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	FlushInterval  time.Duration     `mapstructure:"flush-interval,omitempty" json:"flush-interval,omitempty"`
+	BatchSize      int               `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	BufferSize     int               `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	MaxRetries     int               `mapstructure:"max-retries,omitempty" json:"max-retries,omitempty"`
+	RetryBackoff   time.Duration     `mapstructure:"retry-backoff,omitempty" json:"retry-backoff,omitempty"`
+	Debug          bool              `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	//
+	Labels             []string          `mapstructure:"labels,omitempty" json:"labels,omitempty"`
+	StaticLabels       map[string]string `mapstructure:"static-labels,omitempty" json:"static-labels,omitempty"`
+	MaxLabelValues     int               `mapstructure:"max-label-values,omitempty" json:"max-label-values,omitempty"`
+	MsgTemplate        string            `mapstructure:"msg-template,omitempty" json:"msg-template,omitempty"`
+	OverrideTimestamps bool              `mapstructure:"override-timestamps,omitempty" json:"override-timestamps,omitempty"`
+	AddTarget          string            `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate     string            `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors    []string          `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers         int               `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	EnableMetrics      bool              `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+}
+
+type auth struct {
+	Username string `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password string `mapstructure:"password,omitempty" json:"-"`
+}
+
+type authorization struct {
+	Type        string `mapstructure:"type,omitempty" json:"type,omitempty"`
+	Credentials string `mapstructure:"credentials,omitempty" json:"-"`
+}
+
+func (l *lokiOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, l.cfg)
+	if err != nil {
+		return err
+	}
+	if l.cfg.URL == "" {
+		return errors.New("missing url field")
+	}
+	u, err := url.Parse(l.cfg.URL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" {
+		return fmt.Errorf("url %q is missing the scheme", l.cfg.URL)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = defaultPushPath
+		l.cfg.URL = u.String()
+	}
+	if l.cfg.Name == "" {
+		l.cfg.Name = name
+	}
+	l.logger.SetPrefix(fmt.Sprintf(loggingPrefix, l.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return err
+		}
+	}
+
+	if l.cfg.TargetTemplate == "" {
+		l.targetTpl = outputs.DefaultTargetTemplate
+	} else if l.cfg.AddTarget != "" {
+		l.targetTpl, err = gtemplate.CreateTemplate("target-template", l.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		l.targetTpl = l.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+	if l.cfg.MsgTemplate != "" {
+		l.msgTpl, err = gtemplate.CreateTemplate("msg-template", l.cfg.MsgTemplate)
+		if err != nil {
+			return err
+		}
+		l.msgTpl = l.msgTpl.Funcs(outputs.TemplateFuncs)
+	}
+
+	l.setDefaults()
+	for n := range l.cfg.StaticLabels {
+		if !validLabelName(n) {
+			return fmt.Errorf("invalid static label name %q", n)
+		}
+	}
+	l.labelValues = make(map[string]map[string]struct{}, len(l.cfg.Labels))
+	l.client, err = newPushClient(l.cfg)
+	if err != nil {
+		return err
+	}
+	if l.dryRun {
+		return nil
+	}
+	l.entriesCh = make(chan *entry, l.cfg.BufferSize)
+
+	ctx, l.cfn = context.WithCancel(ctx)
+	for i := 0; i < l.cfg.NumWorkers; i++ {
+		go l.worker(ctx)
+	}
+	go l.writer(ctx)
+	l.logger.Printf("initialized loki output %s: %s", l.cfg.Name, l.String())
+	return nil
+}
+
+func (l *lokiOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, l.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case l.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if l.cfg.Debug {
+			l.logger.Printf("writing expired after %s", l.cfg.Timeout)
+		}
+		return
+	}
+}
+
+func (l *lokiOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range l.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case l.eventChan <- pev:
+			}
+		}
+	}
+}
+
+func (l *lokiOutput) Close() error {
+	if l.cfn == nil {
+		return nil
+	}
+	l.cfn()
+	if l.client != nil {
+		l.client.close()
+	}
+	return nil
+}
+
+func (l *lokiOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !l.cfg.EnableMetrics {
+		return
+	}
+	if reg == nil {
+		l.logger.Printf("ERR: output metrics enabled but main registry is not initialized, enable main metrics under `api-server`")
+		return
+	}
+	if err := registerMetrics(reg); err != nil {
+		l.logger.Printf("failed to register metric: %v", err)
+	}
+}
+
+func (l *lokiOutput) String() string {
+	b, err := json.Marshal(l.cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (l *lokiOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && l.logger != nil {
+		l.logger.SetOutput(logger.Writer())
+		l.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (l *lokiOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	l.evps, err = formatters.MakeEventProcessors(
+		logger,
+		l.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (l *lokiOutput) SetName(name string) {
+	if l.cfg.Name == "" {
+		l.cfg.Name = name
+	}
+}
+
+func (l *lokiOutput) SetClusterName(_ string) {}
+
+func (l *lokiOutput) SetDryRun(dryRun bool) { l.dryRun = dryRun }
+
+func (l *lokiOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (l *lokiOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-l.eventChan:
+			l.workerHandleEvent(ctx, ev)
+		case m := <-l.msgChan:
+			l.workerHandleProto(ctx, m)
+		}
+	}
+}
+
+func (l *lokiOutput) workerHandleProto(ctx context.Context, m *outputs.ProtoMsg) {
+	pmsg := m.GetMsg()
+	switch pmsg := pmsg.(type) {
+	case *gnmi.SubscribeResponse:
+		meta := m.GetMeta()
+		measName := "default"
+		if subName, ok := meta["subscription-name"]; ok {
+			measName = subName
+		}
+		var err error
+		pmsg, err = outputs.AddSubscriptionTarget(pmsg, m.GetMeta(), l.cfg.AddTarget, l.targetTpl)
+		if err != nil {
+			l.logger.Printf("failed to add target to the response: %v", err)
+		}
+		events, err := formatters.ResponseToEventMsgs(measName, pmsg, meta, l.evps...)
+		if err != nil {
+			l.logger.Printf("failed to convert message to event: %v", err)
+			return
+		}
+		for _, ev := range events {
+			l.workerHandleEvent(ctx, ev)
+		}
+	}
+}
+
+func (l *lokiOutput) workerHandleEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if l.cfg.Debug {
+		l.logger.Printf("got event to buffer: %+v", ev)
+	}
+	e, err := l.entryFromEvent(ev)
+	if err != nil {
+		lokiNumberOfFailedEntries.WithLabelValues("marshal_error").Inc()
+		l.logger.Printf("failed to build log entry: %v", err)
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case l.entriesCh <- e:
+	}
+}
+
+func (l *lokiOutput) setDefaults() {
+	if l.cfg.Timeout <= 0 {
+		l.cfg.Timeout = defaultTimeout
+	}
+	if l.cfg.FlushInterval <= 0 {
+		l.cfg.FlushInterval = defaultFlushInterval
+	}
+	if l.cfg.BatchSize <= 0 {
+		l.cfg.BatchSize = defaultBatchSize
+	}
+	if l.cfg.BufferSize <= 0 {
+		l.cfg.BufferSize = defaultBufferSize
+	}
+	l.cfg.MaxRetries = outputs.MaxRetries(l.cfg.MaxRetries, defaultMaxRetries)
+	if l.cfg.RetryBackoff <= 0 {
+		l.cfg.RetryBackoff = defaultRetryBackoff
+	}
+	if l.cfg.MaxLabelValues == 0 {
+		l.cfg.MaxLabelValues = defaultMaxLabelValues
+	}
+	if l.cfg.Labels == nil {
+		l.cfg.Labels = defaultLabels
+	}
+	if l.cfg.NumWorkers <= 0 {
+		l.cfg.NumWorkers = defaultNumWorkers
+	}
+}
@@ -0,0 +1,140 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package loki_output
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+func TestStreamLabels(t *testing.T) {
+	l := &lokiOutput{
+		cfg: &config{
+			Labels:         []string{"source", "interface_name", "subscription-name"},
+			StaticLabels:   map[string]string{"job": "gnmic"},
+			MaxLabelValues: 2,
+		},
+		m:           new(sync.Mutex),
+		labelValues: make(map[string]map[string]struct{}),
+	}
+	ev := func(intf string) *formatters.EventMsg {
+		return &formatters.EventMsg{
+			Name: "sub1",
+			Tags: map[string]string{
+				"source":            "router1",
+				"subscription-name": "sub1",
+				"interface_name":    intf,
+				"neighbor":          "10.0.0.1",
+			},
+		}
+	}
+	got := l.streamLabels(ev("ethernet-1/1"))
+	want := map[string]string{
+		"job":               "gnmic",
+		"source":            "router1",
+		"interface_name":    "ethernet-1/1",
+		"subscription_name": "sub1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected labels: got %v, want %v", got, want)
+	}
+	if k := labelsKey(got); k != `{interface_name="ethernet-1/1", job="gnmic", source="router1", subscription_name="sub1"}` {
+		t.Fatalf("unexpected labels key: %s", k)
+	}
+	l.streamLabels(ev("ethernet-1/2"))
+	// 3rd distinct interface value exceeds max-label-values
+	got = l.streamLabels(ev("ethernet-1/3"))
+	if _, ok := got["interface_name"]; ok {
+		t.Fatalf("expected interface_name label to be dropped: %v", got)
+	}
+	// known values are still used
+	got = l.streamLabels(ev("ethernet-1/1"))
+	if got["interface_name"] != "ethernet-1/1" {
+		t.Fatalf("expected interface_name label: %v", got)
+	}
+}
+
+func TestLokiOutput(t *testing.T) {
+	reqCh := make(chan *pushRequest, 1)
+	var tenant string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != defaultPushPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		tenant = r.Header.Get("X-Scope-OrgID")
+		req := new(pushRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		reqCh <- req
+	}))
+	defer srv.Close()
+
+	o := outputs.Outputs[outputType]()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := o.Init(ctx, "loki", map[string]interface{}{
+		"url":            srv.URL,
+		"tenant-id":      "noc",
+		"msg-template":   `{{ index .tags "interface_name" }} is {{ index .values "/interface/oper-state" }}`,
+		"flush-interval": "100ms",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	for i, state := range []string{"down", "up"} {
+		o.WriteEvent(ctx, &formatters.EventMsg{
+			Name:      "sub1",
+			Timestamp: int64(2 - i),
+			Tags: map[string]string{
+				"source":            "router1",
+				"subscription-name": "sub1",
+				"interface_name":    "ethernet-1/1",
+			},
+			Values: map[string]interface{}{"/interface/oper-state": state},
+		})
+	}
+	select {
+	case req := <-reqCh:
+		if tenant != "noc" {
+			t.Errorf("unexpected tenant: %q", tenant)
+		}
+		if len(req.Streams) != 1 {
+			t.Fatalf("expected 1 stream, got %d", len(req.Streams))
+		}
+		s := req.Streams[0]
+		wantLabels := map[string]string{"source": "router1", "subscription_name": "sub1"}
+		if !reflect.DeepEqual(s.Stream, wantLabels) {
+			t.Errorf("unexpected stream labels: %v", s.Stream)
+		}
+		wantValues := [][2]string{
+			{"1", "ethernet-1/1 is up"},
+			{"2", "ethernet-1/1 is down"},
+		}
+		if !reflect.DeepEqual(s.Values, wantValues) {
+			t.Errorf("unexpected stream values: %v", s.Values)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for push request")
+	}
+}
@@ -0,0 +1,69 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mqtt_output
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	namespace = "gnmic"
+	subsystem = "mqtt_output"
+)
+
+var mqttNumberOfSentMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_mqtt_msgs_sent_success_total",
+	Help:      "Number of msgs successfully published by gnmic mqtt output",
+}, []string{"publisher_id"})
+
+var mqttNumberOfSentBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_bytes_written_total",
+	Help:      "Number of bytes published by gnmic mqtt output",
+}, []string{"publisher_id"})
+
+var mqttNumberOfFailSendMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_mqtt_msgs_sent_fail_total",
+	Help:      "Number of failed msgs published by gnmic mqtt output",
+}, []string{"publisher_id", "reason"})
+
+var mqttSendDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "msg_send_duration_ns",
+	Help:      "gnmic mqtt output publish duration in ns",
+}, []string{"publisher_id"})
+
+func initMetrics() {
+	mqttNumberOfSentMsgs.WithLabelValues("").Add(0)
+	mqttNumberOfSentBytes.WithLabelValues("").Add(0)
+	mqttNumberOfFailSendMsgs.WithLabelValues("", "").Add(0)
+	mqttSendDuration.WithLabelValues("").Set(0)
+}
+
+func registerMetrics(reg *prometheus.Registry) error {
+	initMetrics()
+	var err error
+	if err = reg.Register(mqttNumberOfSentMsgs); err != nil {
+		return err
+	}
+	if err = reg.Register(mqttNumberOfSentBytes); err != nil {
+		return err
+	}
+	if err = reg.Register(mqttNumberOfFailSendMsgs); err != nil {
+		return err
+	}
+	if err = reg.Register(mqttSendDuration); err != nil {
+		return err
+	}
+	return nil
+}
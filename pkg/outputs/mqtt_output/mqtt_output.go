@@ -0,0 +1,524 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mqtt_output
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType            = "mqtt"
+	loggingPrefix         = "[mqtt_output:%s] "
+	defaultAddress        = "tcp://localhost:1883"
+	defaultTopicTemplate  = "gnmic/{{ .Subscription }}/{{ .Target }}"
+	defaultFormat         = "event"
+	defaultNumWorkers     = 1
+	defaultWriteTimeout   = 5 * time.Second
+	defaultConnectTimeout = 10 * time.Second
+	defaultKeepAlive      = 30 * time.Second
+	defaultReconnectWait  = 2 * time.Second
+)
+
+func init() {
+	outputs.Register(outputType,
+		func() outputs.Output {
+			return &mqttOutput{
+				cfg:       &config{},
+				logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+				eventChan: make(chan *formatters.EventMsg),
+				msgChan:   make(chan *outputs.ProtoMsg),
+			}
+		})
+}
+
+type mqttOutput struct {
+	cfg    *config
+	logger *log.Logger
+	dryRun bool
+
+	eventChan chan *formatters.EventMsg
+	msgChan   chan *outputs.ProtoMsg
+	client    mqtt.Client
+	mo        *formatters.MarshalOptions
+
+	evps      []formatters.EventProcessor
+	topicTpl  *template.Template
+	msgTpl    *template.Template
+	targetTpl *template.Template
+	cfn       context.CancelFunc
+}
+
+type config struct {
+	Name               string           `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Address            string           `mapstructure:"address,omitempty" json:"address,omitempty"`
+	ClientID           string           `mapstructure:"client-id,omitempty" json:"client-id,omitempty"`
+	Username           string           `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password           string           `mapstructure:"password,omitempty" json:"-"`
+	TLS                *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Topic              string           `mapstructure:"topic,omitempty" json:"topic,omitempty"`
+	QoS                byte             `mapstructure:"qos,omitempty" json:"qos,omitempty"`
+	Retain             bool             `mapstructure:"retain,omitempty" json:"retain,omitempty"`
+	CleanSession       *bool            `mapstructure:"clean-session,omitempty" json:"clean-session,omitempty"`
+	KeepAlive          time.Duration    `mapstructure:"keep-alive,omitempty" json:"keep-alive,omitempty"`
+	ConnectTimeout     time.Duration    `mapstructure:"connect-timeout,omitempty" json:"connect-timeout,omitempty"`
+	ReconnectWait      time.Duration    `mapstructure:"reconnect-wait,omitempty" json:"reconnect-wait,omitempty"`
+	WriteTimeout       time.Duration    `mapstructure:"write-timeout,omitempty" json:"write-timeout,omitempty"`
+	Format             string           `mapstructure:"format,omitempty" json:"format,omitempty"`
+	Multiline          bool             `mapstructure:"multiline,omitempty" json:"multiline,omitempty"`
+	Indent             string           `mapstructure:"indent,omitempty" json:"indent,omitempty"`
+	MsgTemplate        string           `mapstructure:"msg-template,omitempty" json:"msg-template,omitempty"`
+	OverrideTimestamps bool             `mapstructure:"override-timestamps,omitempty" json:"override-timestamps,omitempty"`
+	AddTarget          string           `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate     string           `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors    []string         `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers         int              `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	Debug              bool             `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	EnableMetrics      bool             `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+}
+
+// topicData is the input of the topic template.
+type topicData struct {
+	// Target is the subscription target if set,
+	// otherwise the message source without the port number.
+	Target       string
+	Source       string
+	Subscription string
+	// Name is the event name, it is the subscription name
+	// unless changed by an event processor.
+	Name string
+	// Tags are the event tags, or the message metadata
+	// if the format is not `event`.
+	Tags map[string]string
+}
+
+func (m *mqttOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, m.cfg)
+	if err != nil {
+		return err
+	}
+	if m.cfg.Name == "" {
+		m.cfg.Name = name
+	}
+	m.logger.SetPrefix(fmt.Sprintf(loggingPrefix, m.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(m); err != nil {
+			return err
+		}
+	}
+	err = m.setDefaults()
+	if err != nil {
+		return err
+	}
+	m.mo = &formatters.MarshalOptions{
+		Format:     m.cfg.Format,
+		Multiline:  m.cfg.Multiline,
+		Indent:     m.cfg.Indent,
+		OverrideTS: m.cfg.OverrideTimestamps,
+	}
+
+	if m.cfg.TargetTemplate == "" {
+		m.targetTpl = outputs.DefaultTargetTemplate
+	} else if m.cfg.AddTarget != "" {
+		m.targetTpl, err = gtemplate.CreateTemplate("target-template", m.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		m.targetTpl = m.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+	if m.cfg.MsgTemplate != "" {
+		m.msgTpl, err = gtemplate.CreateTemplate("msg-template", m.cfg.MsgTemplate)
+		if err != nil {
+			return err
+		}
+		m.msgTpl = m.msgTpl.Funcs(outputs.TemplateFuncs)
+	}
+	m.topicTpl, err = gtemplate.CreateTemplate("topic", m.cfg.Topic)
+	if err != nil {
+		return fmt.Errorf("invalid topic template: %w", err)
+	}
+
+	m.client, err = m.createClient()
+	if err != nil {
+		return err
+	}
+	if m.dryRun {
+		return nil
+	}
+	ctx, m.cfn = context.WithCancel(ctx)
+	// the client keeps retrying to connect in the background,
+	// the messages published before the connection is established are queued.
+	go func() {
+		token := m.client.Connect()
+		select {
+		case <-ctx.Done():
+		case <-token.Done():
+			if err := token.Error(); err != nil {
+				m.logger.Printf("failed to connect to %s: %v", m.cfg.Address, err)
+			}
+		}
+	}()
+	for i := 0; i < m.cfg.NumWorkers; i++ {
+		go m.worker(ctx)
+	}
+	m.logger.Printf("initialized mqtt output %s: %s", m.cfg.Name, m.String())
+	return nil
+}
+
+func (m *mqttOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, m.cfg.WriteTimeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case m.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if m.cfg.Debug {
+			m.logger.Printf("writing expired after %s", m.cfg.WriteTimeout)
+		}
+		mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "timeout").Inc()
+		return
+	}
+}
+
+func (m *mqttOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range m.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case m.eventChan <- pev:
+			}
+		}
+	}
+}
+
+func (m *mqttOutput) Close() error {
+	if m.cfn == nil {
+		return nil
+	}
+	m.cfn()
+	if m.client != nil {
+		m.client.Disconnect(uint(m.cfg.WriteTimeout.Milliseconds()))
+	}
+	return nil
+}
+
+func (m *mqttOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !m.cfg.EnableMetrics {
+		return
+	}
+	if reg == nil {
+		m.logger.Printf("ERR: output metrics enabled but main registry is not initialized, enable main metrics under `api-server`")
+		return
+	}
+	if err := registerMetrics(reg); err != nil {
+		m.logger.Printf("failed to register metric: %v", err)
+	}
+}
+
+func (m *mqttOutput) String() string {
+	b, err := json.Marshal(m.cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (m *mqttOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && m.logger != nil {
+		m.logger.SetOutput(logger.Writer())
+		m.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (m *mqttOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	m.evps, err = formatters.MakeEventProcessors(
+		logger,
+		m.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *mqttOutput) SetName(name string) {
+	if m.cfg.Name == "" {
+		m.cfg.Name = name
+	}
+}
+
+func (m *mqttOutput) SetClusterName(_ string) {}
+
+func (m *mqttOutput) SetDryRun(dryRun bool) { m.dryRun = dryRun }
+
+func (m *mqttOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (m *mqttOutput) createClient() (mqtt.Client, error) {
+	opts := mqtt.NewClientOptions().
+		AddBroker(m.cfg.Address).
+		SetClientID(m.cfg.ClientID).
+		SetCleanSession(*m.cfg.CleanSession).
+		SetKeepAlive(m.cfg.KeepAlive).
+		SetConnectTimeout(m.cfg.ConnectTimeout).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(m.cfg.ReconnectWait).
+		SetMaxReconnectInterval(m.cfg.ReconnectWait).
+		SetOnConnectHandler(func(mqtt.Client) {
+			m.logger.Printf("connected to %s", m.cfg.Address)
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			m.logger.Printf("connection to %s lost: %v", m.cfg.Address, err)
+		})
+	if m.cfg.Username != "" {
+		opts.SetUsername(m.cfg.Username)
+		opts.SetPassword(m.cfg.Password)
+	}
+	if m.cfg.TLS != nil {
+		tlsConfig, err := utils.NewTLSConfig(
+			m.cfg.TLS.CaFile,
+			m.cfg.TLS.CertFile,
+			m.cfg.TLS.KeyFile,
+			"",
+			m.cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+	return mqtt.NewClient(opts), nil
+}
+
+func (m *mqttOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-m.eventChan:
+			m.publishEvent(ev)
+		case pm := <-m.msgChan:
+			m.handleProto(pm)
+		}
+	}
+}
+
+func (m *mqttOutput) handleProto(pm *outputs.ProtoMsg) {
+	meta := pm.GetMeta()
+	pmsg, err := outputs.AddSubscriptionTarget(pm.GetMsg(), meta, m.cfg.AddTarget, m.targetTpl)
+	if err != nil {
+		m.logger.Printf("failed to add target to the response: %v", err)
+	}
+	if pmsg == nil {
+		return
+	}
+	if m.cfg.Format != "event" {
+		bb, err := outputs.Marshal(pmsg, meta, m.mo, false, m.evps...)
+		if err != nil {
+			m.logger.Printf("failed marshaling proto msg: %v", err)
+			mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "marshal_error").Inc()
+			return
+		}
+		data := &topicData{
+			Target:       targetName(meta),
+			Source:       meta["source"],
+			Subscription: meta["subscription-name"],
+			Name:         meta["subscription-name"],
+			Tags:         meta,
+		}
+		for _, b := range bb {
+			m.publish(data, b)
+		}
+		return
+	}
+	// with the event format, each event is published separately
+	// to a topic rendered using its tags.
+	subName := "default"
+	if sn, ok := meta["subscription-name"]; ok {
+		subName = sn
+	}
+	pmsg, _ = m.mo.OverrideTimestamp(pmsg).(*gnmi.SubscribeResponse)
+	events, err := formatters.ResponseToEventMsgs(subName, pmsg, meta, m.evps...)
+	if err != nil {
+		m.logger.Printf("failed to convert message to event: %v", err)
+		return
+	}
+	for _, ev := range events {
+		m.publishEvent(ev)
+	}
+}
+
+func (m *mqttOutput) publishEvent(ev *formatters.EventMsg) {
+	var b []byte
+	var err error
+	if m.cfg.Multiline {
+		b, err = json.MarshalIndent(ev, "", m.cfg.Indent)
+	} else {
+		b, err = json.Marshal(ev)
+	}
+	if err != nil {
+		m.logger.Printf("failed marshaling event: %v", err)
+		mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "marshal_error").Inc()
+		return
+	}
+	m.publish(&topicData{
+		Target:       targetName(ev.Tags),
+		Source:       ev.Tags["source"],
+		Subscription: ev.Tags["subscription-name"],
+		Name:         ev.Name,
+		Tags:         ev.Tags,
+	}, b)
+}
+
+func (m *mqttOutput) publish(data *topicData, b []byte) {
+	var err error
+	if m.msgTpl != nil {
+		b, err = outputs.ExecTemplate(b, m.msgTpl)
+		if err != nil {
+			if m.cfg.Debug {
+				m.logger.Printf("failed to execute template: %v", err)
+			}
+			mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "template_error").Inc()
+			return
+		}
+	}
+	topic, err := m.topic(data)
+	if err != nil {
+		m.logger.Printf("failed to render topic: %v", err)
+		mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "template_error").Inc()
+		return
+	}
+	start := time.Now()
+	token := m.client.Publish(topic, m.cfg.QoS, m.cfg.Retain, b)
+	if !token.WaitTimeout(m.cfg.WriteTimeout) {
+		if m.cfg.Debug {
+			m.logger.Printf("publish to topic %q timed out", topic)
+		}
+		mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "timeout").Inc()
+		return
+	}
+	if err := token.Error(); err != nil {
+		if m.cfg.Debug {
+			m.logger.Printf("failed to publish to topic %q: %v", topic, err)
+		}
+		mqttNumberOfFailSendMsgs.WithLabelValues(m.cfg.Name, "publish_error").Inc()
+		return
+	}
+	mqttSendDuration.WithLabelValues(m.cfg.Name).Set(float64(time.Since(start).Nanoseconds()))
+	mqttNumberOfSentMsgs.WithLabelValues(m.cfg.Name).Inc()
+	mqttNumberOfSentBytes.WithLabelValues(m.cfg.Name).Add(float64(len(b)))
+}
+
+// topic renders the topic template, the wildcard characters
+// not allowed in a topic name are replaced with an underscore.
+func (m *mqttOutput) topic(data *topicData) (string, error) {
+	sb := new(strings.Builder)
+	err := m.topicTpl.Execute(sb, data)
+	if err != nil {
+		return "", err
+	}
+	topic := strings.TrimSpace(sb.String())
+	if topic == "" {
+		return "", fmt.Errorf("topic template rendered an empty topic")
+	}
+	return strings.NewReplacer("+", "_", "#", "_", " ", "_").Replace(topic), nil
+}
+
+// targetName returns the subscription target if set,
+// otherwise the source without the port number.
+func targetName(tags map[string]string) string {
+	if t := tags["subscription-target"]; t != "" {
+		return t
+	}
+	return utils.GetHost(tags["source"])
+}
+
+func (m *mqttOutput) setDefaults() error {
+	if m.cfg.Format == "" {
+		m.cfg.Format = defaultFormat
+	}
+	if !(m.cfg.Format == "event" || m.cfg.Format == "protojson" || m.cfg.Format == "prototext" || m.cfg.Format == "proto" || m.cfg.Format == "json") {
+		return fmt.Errorf("unsupported output format '%s' for output type MQTT", m.cfg.Format)
+	}
+	if m.cfg.QoS > 2 {
+		return fmt.Errorf("invalid qos %d, expected 0, 1 or 2", m.cfg.QoS)
+	}
+	if m.cfg.Address == "" {
+		m.cfg.Address = defaultAddress
+	}
+	if m.cfg.ClientID == "" {
+		m.cfg.ClientID = "gnmic-" + uuid.New().String()
+	}
+	if m.cfg.Topic == "" {
+		m.cfg.Topic = defaultTopicTemplate
+	}
+	if m.cfg.CleanSession == nil {
+		cs := true
+		m.cfg.CleanSession = &cs
+	}
+	if m.cfg.KeepAlive <= 0 {
+		m.cfg.KeepAlive = defaultKeepAlive
+	}
+	if m.cfg.ConnectTimeout <= 0 {
+		m.cfg.ConnectTimeout = defaultConnectTimeout
+	}
+	if m.cfg.ReconnectWait <= 0 {
+		m.cfg.ReconnectWait = defaultReconnectWait
+	}
+	if m.cfg.WriteTimeout <= 0 {
+		m.cfg.WriteTimeout = defaultWriteTimeout
+	}
+	if m.cfg.NumWorkers <= 0 {
+		m.cfg.NumWorkers = defaultNumWorkers
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mqtt_output
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+func TestTopic(t *testing.T) {
+	tests := []struct {
+		name string
+		tpl  string
+		data *topicData
+		want string
+		err  bool
+	}{
+		{
+			name: "default",
+			tpl:  defaultTopicTemplate,
+			data: &topicData{Target: "router1", Subscription: "sub1"},
+			want: "gnmic/sub1/router1",
+		},
+		{
+			name: "tags",
+			tpl:  `{{ .Subscription }}/{{ index .Tags "interface_name" }}`,
+			data: &topicData{
+				Subscription: "sub1",
+				Tags:         map[string]string{"interface_name": "ethernet-1/1"},
+			},
+			want: "sub1/ethernet-1/1",
+		},
+		{
+			name: "wildcards",
+			tpl:  `{{ .Target }}/{{ .Name }}`,
+			data: &topicData{Target: "r#1", Name: "sub +1"},
+			want: "r_1/sub__1",
+		},
+		{
+			name: "empty",
+			tpl:  `{{ .Target }}`,
+			data: &topicData{},
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := gtemplate.CreateTemplate("topic", tt.tpl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := &mqttOutput{topicTpl: tpl}
+			got, err := m.topic(tt.data)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected an error, got topic %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetName(t *testing.T) {
+	tags := map[string]string{"source": "router1", "subscription-target": "r1"}
+	if got := targetName(tags); got != "r1" {
+		t.Errorf("got %q, want %q", got, "r1")
+	}
+	delete(tags, "subscription-target")
+	if got := targetName(tags); got != "router1" {
+		t.Errorf("got %q, want %q", got, "router1")
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	m := &mqttOutput{cfg: &config{}}
+	if err := m.setDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	if m.cfg.Format != defaultFormat || m.cfg.Address != defaultAddress ||
+		m.cfg.Topic != defaultTopicTemplate || m.cfg.ClientID == "" ||
+		m.cfg.CleanSession == nil || !*m.cfg.CleanSession {
+		t.Errorf("unexpected defaults: %+v", m.cfg)
+	}
+	m = &mqttOutput{cfg: &config{QoS: 3}}
+	if err := m.setDefaults(); err == nil {
+		t.Error("expected an error for qos 3")
+	}
+	m = &mqttOutput{cfg: &config{Format: "csv"}}
+	if err := m.setDefaults(); err == nil {
+		t.Error("expected an error for format csv")
+	}
+}
+
+type testToken struct {
+	done chan struct{}
+	err  error
+}
+
+func newTestToken(err error) *testToken {
+	t := &testToken{done: make(chan struct{}), err: err}
+	close(t.done)
+	return t
+}
+
+func (t *testToken) Wait() bool { <-t.done; return true }
+
+func (t *testToken) WaitTimeout(d time.Duration) bool {
+	select {
+	case <-t.done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+func (t *testToken) Done() <-chan struct{} { return t.done }
+func (t *testToken) Error() error          { return t.err }
+
+type testPublish struct {
+	topic    string
+	qos      byte
+	retained bool
+	payload  []byte
+}
+
+// testClient records the published messages,
+// the methods not used by the output are left unimplemented.
+type testClient struct {
+	mqtt.Client
+	mu    sync.Mutex
+	msgs  []testPublish
+	token mqtt.Token
+}
+
+func (c *testClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.msgs = append(c.msgs, testPublish{
+		topic:    topic,
+		qos:      qos,
+		retained: retained,
+		payload:  payload.([]byte),
+	})
+	if c.token != nil {
+		return c.token
+	}
+	return newTestToken(nil)
+}
+
+func (c *testClient) Disconnect(uint) {}
+
+// newTestOutput sets up an output the way Init does,
+// without connecting its client nor starting its workers.
+func newTestOutput(t *testing.T, cfg map[string]interface{}) (*mqttOutput, *testClient) {
+	t.Helper()
+	o := outputs.Outputs[outputType]().(*mqttOutput)
+	err := outputs.DecodeConfig(cfg, o.cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o.setDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.mo = &formatters.MarshalOptions{Format: o.cfg.Format}
+	o.targetTpl = outputs.DefaultTargetTemplate
+	o.topicTpl, err = gtemplate.CreateTemplate("topic", o.cfg.Topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := new(testClient)
+	o.client = c
+	return o, c
+}
+
+func testEvent() *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 42,
+		Tags: map[string]string{
+			"source":            "router1:57400",
+			"subscription-name": "sub1",
+		},
+		Values: map[string]interface{}{"counter": 1},
+	}
+}
+
+func TestPublishQoSRetain(t *testing.T) {
+	tests := []struct {
+		name   string
+		qos    byte
+		retain bool
+	}{
+		{name: "qos0", qos: 0},
+		{name: "qos1_retain", qos: 1, retain: true},
+		{name: "qos2", qos: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, c := newTestOutput(t, map[string]interface{}{
+				"qos":    tt.qos,
+				"retain": tt.retain,
+			})
+			o.publishEvent(testEvent())
+			if len(c.msgs) != 1 {
+				t.Fatalf("got %d published messages, want 1", len(c.msgs))
+			}
+			msg := c.msgs[0]
+			if msg.topic != "gnmic/sub1/router1" {
+				t.Errorf("got topic %q, want %q", msg.topic, "gnmic/sub1/router1")
+			}
+			if msg.qos != tt.qos {
+				t.Errorf("got qos %d, want %d", msg.qos, tt.qos)
+			}
+			if msg.retained != tt.retain {
+				t.Errorf("got retained %v, want %v", msg.retained, tt.retain)
+			}
+			ev := new(formatters.EventMsg)
+			if err := json.Unmarshal(msg.payload, ev); err != nil {
+				t.Fatalf("failed to unmarshal payload %s: %v", msg.payload, err)
+			}
+			if ev.Name != "sub1" || ev.Timestamp != 42 {
+				t.Errorf("unexpected event: %+v", ev)
+			}
+		})
+	}
+}
+
+func TestPublishFailure(t *testing.T) {
+	o, c := newTestOutput(t, map[string]interface{}{
+		"write-timeout": "10ms",
+	})
+	// a token that never completes times out
+	c.token = &testToken{done: make(chan struct{})}
+	o.publishEvent(testEvent())
+	c.token = newTestToken(mqtt.ErrNotConnected)
+	o.publishEvent(testEvent())
+	if len(c.msgs) != 2 {
+		t.Fatalf("got %d publish calls, want 2", len(c.msgs))
+	}
+}
+
+func TestHandleProto(t *testing.T) {
+	rsp := &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 42,
+				Update: []*gnmi.Update{
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "counter"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1}},
+					},
+				},
+			},
+		},
+	}
+	meta := outputs.Meta{
+		"source":            "router1:57400",
+		"subscription-name": "sub1",
+	}
+	for _, format := range []string{"event", "json"} {
+		t.Run(format, func(t *testing.T) {
+			o, c := newTestOutput(t, map[string]interface{}{
+				"format": format,
+				"topic":  `telemetry/{{ .Subscription }}/{{ .Target }}`,
+				"qos":    1,
+			})
+			o.handleProto(&outputs.ProtoMsg{})
+			if len(c.msgs) != 0 {
+				t.Fatalf("got %d published messages for an empty message", len(c.msgs))
+			}
+			o.handleProto(outputs.NewProtoMsg(rsp, meta))
+			if len(c.msgs) != 1 {
+				t.Fatalf("got %d published messages, want 1", len(c.msgs))
+			}
+			if c.msgs[0].topic != "telemetry/sub1/router1" {
+				t.Errorf("got topic %q, want %q", c.msgs[0].topic, "telemetry/sub1/router1")
+			}
+			if c.msgs[0].qos != 1 {
+				t.Errorf("got qos %d, want 1", c.msgs[0].qos)
+			}
+			if !json.Valid(c.msgs[0].payload) {
+				t.Errorf("invalid payload: %s", c.msgs[0].payload)
+			}
+		})
+	}
+}
@@ -109,6 +109,7 @@ type jetstreamOutput struct {
 	msgChan  chan *outputs.ProtoMsg
 	wg       *sync.WaitGroup
 	logger   *log.Logger
+	dryRun   bool
 	mo       *formatters.MarshalOptions
 	evps     []formatters.EventProcessor
 
@@ -160,6 +161,9 @@ func (n *jetstreamOutput) Init(ctx context.Context, name string, cfg map[string]
 		n.msgTpl = n.msgTpl.Funcs(outputs.TemplateFuncs)
 	}
 
+	if n.dryRun {
+		return nil
+	}
 	n.ctx, n.cancelFn = context.WithCancel(ctx)
 
 	n.wg.Add(n.Cfg.NumWorkers)
@@ -324,6 +328,8 @@ func (n *jetstreamOutput) SetName(name string) {
 
 func (n *jetstreamOutput) SetClusterName(string) {}
 
+func (n *jetstreamOutput) SetDryRun(dryRun bool) { n.dryRun = dryRun }
+
 func (n *jetstreamOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
 
 func (n *jetstreamOutput) worker(ctx context.Context, i int, cfg *config) {
@@ -61,6 +61,7 @@ type NatsOutput struct {
 	msgChan  chan *outputs.ProtoMsg
 	wg       *sync.WaitGroup
 	logger   *log.Logger
+	dryRun   bool
 	mo       *formatters.MarshalOptions
 	evps     []formatters.EventProcessor
 
@@ -169,6 +170,9 @@ func (n *NatsOutput) Init(ctx context.Context, name string, cfg map[string]inter
 		n.msgTpl = n.msgTpl.Funcs(outputs.TemplateFuncs)
 	}
 
+	if n.dryRun {
+		return nil
+	}
 	n.ctx, n.cancelFn = context.WithCancel(ctx)
 	n.wg.Add(n.Cfg.NumWorkers)
 	for i := 0; i < n.Cfg.NumWorkers; i++ {
@@ -438,4 +442,6 @@ func (n *NatsOutput) SetName(name string) {
 
 func (n *NatsOutput) SetClusterName(name string) {}
 
+func (n *NatsOutput) SetDryRun(dryRun bool) { n.dryRun = dryRun }
+
 func (n *NatsOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
@@ -61,6 +61,7 @@ type StanOutput struct {
 	Cfg      *Config
 	cancelFn context.CancelFunc
 	logger   *log.Logger
+	dryRun   bool
 	msgChan  chan *outputs.ProtoMsg
 	wg       *sync.WaitGroup
 	mo       *formatters.MarshalOptions
@@ -159,6 +160,9 @@ func (s *StanOutput) Init(ctx context.Context, name string, cfg map[string]inter
 		}
 		s.targetTpl = s.targetTpl.Funcs(outputs.TemplateFuncs)
 	}
+	if s.dryRun {
+		return nil
+	}
 	ctx, s.cancelFn = context.WithCancel(ctx)
 	s.wg.Add(s.Cfg.NumWorkers)
 	for i := 0; i < s.Cfg.NumWorkers; i++ {
@@ -386,5 +390,6 @@ func (s *StanOutput) SetName(name string) {
 	s.Cfg.Name = sb.String()
 }
 
+func (s *StanOutput) SetDryRun(dryRun bool)                           { s.dryRun = dryRun }
 func (s *StanOutput) SetClusterName(name string)                      {}
 func (s *StanOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
@@ -60,3 +60,13 @@ func WithTargetsConfig(tcs map[string]*types.TargetConfig) Option {
 		return nil
 	}
 }
+
+// WithDryRun makes Init check the output configuration
+// without connecting to any external system or starting any worker.
+// The initialized output must not be used.
+func WithDryRun() Option {
+	return func(o Output) error {
+		o.SetDryRun(true)
+		return nil
+	}
+}
@@ -0,0 +1,259 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+package otlp_output
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	defaultHTTPPath = "/v1/metrics"
+	userAgent       = "gNMIc otlp"
+)
+
+// exporter sends an export request to an OTLP receiver.
+type exporter interface {
+	export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) error
+	close() error
+}
+
+type grpcExporter struct {
+	conn    *grpc.ClientConn
+	client  colmetricspb.MetricsServiceClient
+	headers metadata.MD
+	timeout time.Duration
+}
+
+func newGRPCExporter(ctx context.Context, cfg *config) (*grpcExporter, error) {
+	opts := []grpc.DialOption{
+		grpc.WithUserAgent(userAgent),
+	}
+	if cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			cfg.TLS.CaFile,
+			cfg.TLS.CertFile,
+			cfg.TLS.KeyFile,
+			"",
+			cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	// the connection is established in the background and
+	// re-established by the grpc client if it breaks.
+	conn, err := grpc.DialContext(ctx, cfg.Endpoint, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcExporter{
+		conn:    conn,
+		client:  colmetricspb.NewMetricsServiceClient(conn),
+		headers: metadata.New(cfg.Headers),
+		timeout: cfg.Timeout,
+	}, nil
+}
+
+func (e *grpcExporter) export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	if len(e.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, e.headers)
+	}
+	rsp, err := e.client.Export(ctx, req)
+	if err != nil {
+		switch status.Code(err) {
+		case codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied, codes.Unimplemented:
+			return outputs.Permanent(err)
+		}
+		return err
+	}
+	if ps := rsp.GetPartialSuccess(); ps != nil && ps.GetRejectedDataPoints() > 0 {
+		return outputs.Permanent(fmt.Errorf("%d data points rejected: %s", ps.GetRejectedDataPoints(), ps.GetErrorMessage()))
+	}
+	return nil
+}
+
+func (e *grpcExporter) close() error {
+	return e.conn.Close()
+}
+
+type httpExporter struct {
+	url     string
+	client  *http.Client
+	headers map[string]string
+}
+
+func newHTTPExporter(cfg *config) (*httpExporter, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" {
+		return nil, fmt.Errorf("endpoint %q is missing the URL scheme", cfg.Endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = defaultHTTPPath
+	}
+	c := &http.Client{
+		Timeout: cfg.Timeout,
+	}
+	if cfg.TLS != nil {
+		tlsCfg, err := utils.NewTLSConfig(
+			cfg.TLS.CaFile,
+			cfg.TLS.CertFile,
+			cfg.TLS.KeyFile,
+			"",
+			cfg.TLS.SkipVerify,
+			false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		c.Transport = &http.Transport{
+			TLSClientConfig: tlsCfg,
+		}
+	}
+	return &httpExporter{
+		url:     u.String(),
+		client:  c,
+		headers: cfg.Headers,
+	}, nil
+}
+
+func (e *httpExporter) export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) error {
+	b, err := proto.Marshal(req)
+	if err != nil {
+		return outputs.Permanent(fmt.Errorf("marshal error: %w", err))
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewBuffer(b))
+	if err != nil {
+		return outputs.Permanent(fmt.Errorf("failed to create HTTP request: %w", err))
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("User-Agent", userAgent)
+	for k, v := range e.headers {
+		httpReq.Header.Set(k, v)
+	}
+	rsp, err := e.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	body, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return err
+	}
+	if rsp.StatusCode >= 300 {
+		err = fmt.Errorf("export failed, code=%d, body=%s", rsp.StatusCode, string(body))
+		switch {
+		case rsp.StatusCode == http.StatusTooManyRequests,
+			rsp.StatusCode == http.StatusRequestTimeout,
+			rsp.StatusCode >= 500:
+			return err
+		}
+		return outputs.Permanent(err)
+	}
+	exportRsp := new(colmetricspb.ExportMetricsServiceResponse)
+	if proto.Unmarshal(body, exportRsp) == nil {
+		if ps := exportRsp.GetPartialSuccess(); ps != nil && ps.GetRejectedDataPoints() > 0 {
+			return outputs.Permanent(fmt.Errorf("%d data points rejected: %s", ps.GetRejectedDataPoints(), ps.GetErrorMessage()))
+		}
+	}
+	return nil
+}
+
+func (e *httpExporter) close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// writer batches the data points and exports them when the batch size
+// is reached or every interval.
+func (o *otlpOutput) writer(ctx context.Context) {
+	ticker := time.NewTicker(o.cfg.Interval)
+	defer ticker.Stop()
+	batch := make([]*point, 0, o.cfg.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case p := <-o.pointsCh:
+			batch = append(batch, p)
+			if len(batch) < o.cfg.BatchSize {
+				continue
+			}
+			if o.cfg.Debug {
+				o.logger.Printf("batch size reached, exporting")
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+			if o.cfg.Debug {
+				o.logger.Printf("export interval reached, exporting")
+			}
+		}
+		o.export(ctx, batch)
+		batch = make([]*point, 0, o.cfg.BatchSize)
+	}
+}
+
+// export sends a batch of data points, retrying with an exponential backoff
+// up to max-retries times unless the error is permanent.
+func (o *otlpOutput) export(ctx context.Context, batch []*point) {
+	req := o.exportRequest(batch)
+	policy := outputs.RetryPolicy{
+		MaxRetries:  o.cfg.MaxRetries,
+		Backoff:     defaultRetryBackoff,
+		Exponential: true,
+	}
+	err := policy.Do(ctx, func() error {
+		start := time.Now()
+		err := o.exp.export(ctx, req)
+		if err != nil {
+			o.logger.Printf("failed to export %d data points: %v", len(batch), err)
+			return err
+		}
+		otlpSendDuration.Set(float64(time.Since(start).Nanoseconds()))
+		otlpNumberOfSentDataPoints.Add(float64(len(batch)))
+		if o.cfg.Debug {
+			o.logger.Printf("exported %d data points", len(batch))
+		}
+		return nil
+	})
+	switch {
+	case err == nil, ctx.Err() != nil:
+	case outputs.IsPermanent(err):
+		otlpNumberOfFailedDataPoints.WithLabelValues("rejected").Add(float64(len(batch)))
+	default:
+		otlpNumberOfFailedDataPoints.WithLabelValues("max_retries").Add(float64(len(batch)))
+	}
+}
@@ -0,0 +1,203 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+package otlp_output
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+// point is a single metric data point
+// along with its instrumentation scope name.
+type point struct {
+	scope  string
+	metric *metricspb.Metric
+}
+
+// pointsFromEvent converts the numeric values of an event message
+// into OTLP metrics with a single data point each.
+// The event tags are set as the data point attributes and the event name,
+// i.e the subscription name, is used as the instrumentation scope.
+func (o *otlpOutput) pointsFromEvent(ev *formatters.EventMsg) []*point {
+	attrs := make([]*commonpb.KeyValue, 0, len(ev.Tags))
+	for k, v := range ev.Tags {
+		attrs = append(attrs, stringKeyValue(k, v))
+	}
+	if o.cfg.StringsAsAttributes {
+		for k, v := range ev.Values {
+			if vs, ok := v.(string); ok {
+				if _, err := strconv.ParseFloat(vs, 64); err != nil {
+					attrs = append(attrs, stringKeyValue(k, vs))
+				}
+			}
+		}
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].Key < attrs[j].Key
+	})
+	points := make([]*point, 0, len(ev.Values))
+	for k, v := range ev.Values {
+		dp, ok := numberDataPoint(v)
+		if !ok {
+			if o.cfg.Debug {
+				o.logger.Printf("skipping non numeric value %q: %v", k, v)
+			}
+			continue
+		}
+		dp.Attributes = attrs
+		dp.TimeUnixNano = uint64(ev.Timestamp)
+		m := &metricspb.Metric{
+			Name: o.mb.MetricName(ev.Name, k),
+		}
+		if o.isCounter(k) {
+			dp.StartTimeUnixNano = uint64(o.startTime.UnixNano())
+			m.Data = &metricspb.Metric_Sum{
+				Sum: &metricspb.Sum{
+					DataPoints:             []*metricspb.NumberDataPoint{dp},
+					AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+					IsMonotonic:            true,
+				},
+			}
+		} else {
+			m.Data = &metricspb.Metric_Gauge{
+				Gauge: &metricspb.Gauge{
+					DataPoints: []*metricspb.NumberDataPoint{dp},
+				},
+			}
+		}
+		points = append(points, &point{scope: ev.Name, metric: m})
+	}
+	return points
+}
+
+func (o *otlpOutput) isCounter(valueName string) bool {
+	for _, re := range o.counters {
+		if re.MatchString(valueName) {
+			return true
+		}
+	}
+	return false
+}
+
+// exportRequest builds an export request from a batch of points,
+// grouping them by instrumentation scope.
+func (o *otlpOutput) exportRequest(batch []*point) *colmetricspb.ExportMetricsServiceRequest {
+	rm := &metricspb.ResourceMetrics{
+		Resource: &resourcepb.Resource{
+			Attributes: make([]*commonpb.KeyValue, 0, len(o.cfg.ResourceAttributes)),
+		},
+	}
+	for k, v := range o.cfg.ResourceAttributes {
+		rm.Resource.Attributes = append(rm.Resource.Attributes, stringKeyValue(k, v))
+	}
+	sort.Slice(rm.Resource.Attributes, func(i, j int) bool {
+		return rm.Resource.Attributes[i].Key < rm.Resource.Attributes[j].Key
+	})
+	scopes := make(map[string]*metricspb.ScopeMetrics)
+	for _, p := range batch {
+		sm, ok := scopes[p.scope]
+		if !ok {
+			sm = &metricspb.ScopeMetrics{
+				Scope: &commonpb.InstrumentationScope{Name: p.scope},
+			}
+			scopes[p.scope] = sm
+			rm.ScopeMetrics = append(rm.ScopeMetrics, sm)
+		}
+		sm.Metrics = append(sm.Metrics, p.metric)
+	}
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{rm},
+	}
+}
+
+func stringKeyValue(k, v string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key: k,
+		Value: &commonpb.AnyValue{
+			Value: &commonpb.AnyValue_StringValue{StringValue: v},
+		},
+	}
+}
+
+// numberDataPoint returns a data point holding the value v
+// as an integer or a double, numeric strings are parsed.
+func numberDataPoint(v interface{}) (*metricspb.NumberDataPoint, bool) {
+	dp := new(metricspb.NumberDataPoint)
+	switch v := v.(type) {
+	case int:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int8:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int16:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int32:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case int64:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: v}
+	case uint:
+		setUint(dp, uint64(v))
+	case uint8:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case uint16:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case uint32:
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(v)}
+	case uint64:
+		setUint(dp, v)
+	case float32:
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: float64(v)}
+	case float64:
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: v}
+	case bool:
+		if v {
+			dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: 1}
+		} else {
+			dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: 0}
+		}
+	case string:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: i}
+			return dp, true
+		}
+		if u, err := strconv.ParseUint(v, 10, 64); err == nil {
+			setUint(dp, u)
+			return dp, true
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, false
+		}
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: f}
+	//lint:ignore SA1019 still need DecimalVal for backward compatibility
+	case *gnmi.Decimal64:
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: float64(v.Digits) / math.Pow10(int(v.Precision))}
+	default:
+		return nil, false
+	}
+	return dp, true
+}
+
+// setUint sets the data point value to u as an integer
+// if it fits in an int64, as a double otherwise.
+func setUint(dp *metricspb.NumberDataPoint, u uint64) {
+	if u > math.MaxInt64 {
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: float64(u)}
+		return
+	}
+	dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: int64(u)}
+}
@@ -0,0 +1,109 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+package otlp_output
+
+import (
+	"io"
+	"log"
+	"regexp"
+	"testing"
+	"time"
+
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	promcom "github.com/openconfig/gnmic/pkg/outputs/prometheus_output"
+)
+
+func TestPointsFromEvent(t *testing.T) {
+	o := &otlpOutput{
+		cfg:       &config{StringsAsAttributes: true},
+		logger:    log.New(io.Discard, "", 0),
+		mb:        &promcom.MetricBuilder{Prefix: "gnmic"},
+		counters:  []*regexp.Regexp{regexp.MustCompile("counters/")},
+		startTime: time.Unix(0, 1),
+	}
+	ev := &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 42,
+		Tags: map[string]string{
+			"source":         "router1",
+			"interface_name": "ethernet-1/1",
+		},
+		Values: map[string]interface{}{
+			"/interface/statistics/counters/in-octets": "100",
+			"/interface/statistics/cpu-load":           float64(1.5),
+			"/interface/oper-state":                    "up",
+		},
+	}
+	points := o.pointsFromEvent(ev)
+	if len(points) != 2 {
+		t.Fatalf("expected 2 points, got %d", len(points))
+	}
+	for _, p := range points {
+		if p.scope != "sub1" {
+			t.Errorf("unexpected scope %q", p.scope)
+		}
+		switch p.metric.GetName() {
+		case "gnmic_interface_statistics_counters_in_octets":
+			sum := p.metric.GetSum()
+			if sum == nil || !sum.GetIsMonotonic() {
+				t.Fatalf("expected a monotonic sum, got %v", p.metric)
+			}
+			dp := sum.GetDataPoints()[0]
+			if dp.GetAsInt() != 100 || dp.GetTimeUnixNano() != 42 || dp.GetStartTimeUnixNano() != 1 {
+				t.Errorf("unexpected data point: %v", dp)
+			}
+			// 2 tags and the oper-state string value
+			if len(dp.GetAttributes()) != 3 {
+				t.Errorf("unexpected attributes: %v", dp.GetAttributes())
+			}
+		case "gnmic_interface_statistics_cpu_load":
+			g := p.metric.GetGauge()
+			if g == nil {
+				t.Fatalf("expected a gauge, got %v", p.metric)
+			}
+			if g.GetDataPoints()[0].GetAsDouble() != 1.5 {
+				t.Errorf("unexpected data point: %v", g.GetDataPoints()[0])
+			}
+		default:
+			t.Errorf("unexpected metric %q", p.metric.GetName())
+		}
+	}
+}
+
+func TestExportRequest(t *testing.T) {
+	o := &otlpOutput{
+		cfg: &config{
+			ResourceAttributes: map[string]string{"service.name": "gnmic"},
+		},
+	}
+	batch := []*point{
+		{scope: "sub1", metric: &metricspb.Metric{Name: "m1"}},
+		{scope: "sub2", metric: &metricspb.Metric{Name: "m2"}},
+		{scope: "sub1", metric: &metricspb.Metric{Name: "m3"}},
+	}
+	req := o.exportRequest(batch)
+	if len(req.GetResourceMetrics()) != 1 {
+		t.Fatalf("expected a single resource, got %d", len(req.GetResourceMetrics()))
+	}
+	rm := req.GetResourceMetrics()[0]
+	if len(rm.GetResource().GetAttributes()) != 1 {
+		t.Errorf("unexpected resource attributes: %v", rm.GetResource().GetAttributes())
+	}
+	sms := rm.GetScopeMetrics()
+	if len(sms) != 2 {
+		t.Fatalf("expected 2 scopes, got %d", len(sms))
+	}
+	if sms[0].GetScope().GetName() != "sub1" || len(sms[0].GetMetrics()) != 2 {
+		t.Errorf("unexpected scope metrics: %v", sms[0])
+	}
+	if sms[1].GetScope().GetName() != "sub2" || len(sms[1].GetMetrics()) != 1 {
+		t.Errorf("unexpected scope metrics: %v", sms[1])
+	}
+}
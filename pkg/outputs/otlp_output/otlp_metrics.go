@@ -0,0 +1,57 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+package otlp_output
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	namespace = "gnmic"
+	subsystem = "otlp_output"
+)
+
+var otlpNumberOfSentDataPoints = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_sent_data_points_total",
+	Help:      "Number of data points successfully exported by gnmic otlp output",
+})
+
+var otlpNumberOfFailedDataPoints = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_failed_data_points_total",
+	Help:      "Number of data points gnmic otlp output failed to export",
+}, []string{"reason"})
+
+var otlpSendDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "export_duration_ns",
+	Help:      "gnmic otlp output export duration in ns",
+})
+
+func initMetrics() {
+	otlpNumberOfSentDataPoints.Add(0)
+	otlpNumberOfFailedDataPoints.WithLabelValues("").Add(0)
+	otlpSendDuration.Set(0)
+}
+
+func registerMetrics(reg *prometheus.Registry) error {
+	initMetrics()
+	var err error
+	if err = reg.Register(otlpNumberOfSentDataPoints); err != nil {
+		return err
+	}
+	if err = reg.Register(otlpNumberOfFailedDataPoints); err != nil {
+		return err
+	}
+	if err = reg.Register(otlpSendDuration); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+package otlp_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"text/template"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+	promcom "github.com/openconfig/gnmic/pkg/outputs/prometheus_output"
+)
+
+const (
+	outputType          = "otlp"
+	loggingPrefix       = "[otlp_output:%s] "
+	protocolGRPC        = "grpc"
+	protocolHTTP        = "http"
+	defaultTimeout      = 10 * time.Second
+	defaultInterval     = 10 * time.Second
+	defaultBatchSize    = 1000
+	defaultBufferSize   = 1000
+	defaultMaxRetries   = 3
+	defaultNumWorkers   = 1
+	defaultServiceName  = "gnmic"
+	defaultRetryBackoff = 100 * time.Millisecond
+)
+
+func init() {
+	outputs.Register(outputType,
+		func() outputs.Output {
+			return &otlpOutput{
+				cfg:       &config{},
+				logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+				eventChan: make(chan *formatters.EventMsg),
+				msgChan:   make(chan *outputs.ProtoMsg),
+			}
+		})
+}
+
+type otlpOutput struct {
+	cfg    *config
+	logger *log.Logger
+	dryRun bool
+
+	eventChan chan *formatters.EventMsg
+	msgChan   chan *outputs.ProtoMsg
+	pointsCh  chan *point
+	exp       exporter
+	mb        *promcom.MetricBuilder
+	counters  []*regexp.Regexp
+	startTime time.Time
+
+	evps      []formatters.EventProcessor
+	targetTpl *template.Template
+	cfn       context.CancelFunc
+}
+
+type config struct {
+	Name       string            `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Endpoint   string            `mapstructure:"endpoint,omitempty" json:"endpoint,omitempty"`
+	Protocol   string            `mapstructure:"protocol,omitempty" json:"protocol,omitempty"`
+	Timeout    time.Duration     `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	Headers    map[string]string `mapstructure:"headers,omitempty" json:"headers,omitempty"`
+	TLS        *types.TLSConfig  `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Interval   time.Duration     `mapstructure:"interval,omitempty" json:"interval,omitempty"`
+	BatchSize  int               `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	BufferSize int               `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	MaxRetries int               `mapstructure:"max-retries,omitempty" json:"max-retries,omitempty"`
+	Debug      bool              `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	//
+	ResourceAttributes     map[string]string `mapstructure:"resource-attributes,omitempty" json:"resource-attributes,omitempty"`
+	MetricPrefix           string            `mapstructure:"metric-prefix,omitempty" json:"metric-prefix,omitempty"`
+	AppendSubscriptionName bool              `mapstructure:"append-subscription-name,omitempty" json:"append-subscription-name,omitempty"`
+	CounterPatterns        []string          `mapstructure:"counter-patterns,omitempty" json:"counter-patterns,omitempty"`
+	StringsAsAttributes    bool              `mapstructure:"strings-as-attributes,omitempty" json:"strings-as-attributes,omitempty"`
+	AddTarget              string            `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate         string            `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors        []string          `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers             int               `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	EnableMetrics          bool              `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+}
+
+func (o *otlpOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, o.cfg)
+	if err != nil {
+		return err
+	}
+	if o.cfg.Endpoint == "" {
+		return errors.New("missing endpoint field")
+	}
+	if o.cfg.Name == "" {
+		o.cfg.Name = name
+	}
+	o.logger.SetPrefix(fmt.Sprintf(loggingPrefix, o.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return err
+		}
+	}
+
+	if o.cfg.TargetTemplate == "" {
+		o.targetTpl = outputs.DefaultTargetTemplate
+	} else if o.cfg.AddTarget != "" {
+		o.targetTpl, err = gtemplate.CreateTemplate("target-template", o.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		o.targetTpl = o.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+
+	err = o.setDefaults()
+	if err != nil {
+		return err
+	}
+	o.counters = make([]*regexp.Regexp, 0, len(o.cfg.CounterPatterns))
+	for _, p := range o.cfg.CounterPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("invalid counter pattern %q: %w", p, err)
+		}
+		o.counters = append(o.counters, re)
+	}
+	o.mb = &promcom.MetricBuilder{
+		Prefix:                 o.cfg.MetricPrefix,
+		AppendSubscriptionName: o.cfg.AppendSubscriptionName,
+	}
+	o.startTime = time.Now()
+	o.pointsCh = make(chan *point, o.cfg.BufferSize)
+
+	if o.dryRun {
+		return nil
+	}
+	switch o.cfg.Protocol {
+	case protocolGRPC:
+		o.exp, err = newGRPCExporter(ctx, o.cfg)
+	case protocolHTTP:
+		o.exp, err = newHTTPExporter(o.cfg)
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, o.cfn = context.WithCancel(ctx)
+	for i := 0; i < o.cfg.NumWorkers; i++ {
+		go o.worker(ctx)
+	}
+	go o.writer(ctx)
+	o.logger.Printf("initialized otlp output %s: %s", o.cfg.Name, o.String())
+	return nil
+}
+
+func (o *otlpOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, o.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case o.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if o.cfg.Debug {
+			o.logger.Printf("writing expired after %s", o.cfg.Timeout)
+		}
+		return
+	}
+}
+
+func (o *otlpOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range o.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case o.eventChan <- pev:
+			}
+		}
+	}
+}
+
+func (o *otlpOutput) Close() error {
+	if o.cfn == nil {
+		return nil
+	}
+	o.cfn()
+	if o.exp != nil {
+		return o.exp.close()
+	}
+	return nil
+}
+
+func (o *otlpOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !o.cfg.EnableMetrics {
+		return
+	}
+	if reg == nil {
+		o.logger.Printf("ERR: output metrics enabled but main registry is not initialized, enable main metrics under `api-server`")
+		return
+	}
+	if err := registerMetrics(reg); err != nil {
+		o.logger.Printf("failed to register metric: %v", err)
+	}
+}
+
+func (o *otlpOutput) String() string {
+	b, err := json.Marshal(o.cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (o *otlpOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && o.logger != nil {
+		o.logger.SetOutput(logger.Writer())
+		o.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (o *otlpOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	o.evps, err = formatters.MakeEventProcessors(
+		logger,
+		o.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (o *otlpOutput) SetName(name string) {
+	if o.cfg.Name == "" {
+		o.cfg.Name = name
+	}
+}
+
+func (o *otlpOutput) SetClusterName(_ string) {}
+
+func (o *otlpOutput) SetDryRun(dryRun bool) { o.dryRun = dryRun }
+
+func (o *otlpOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (o *otlpOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-o.eventChan:
+			o.workerHandleEvent(ctx, ev)
+		case m := <-o.msgChan:
+			o.workerHandleProto(ctx, m)
+		}
+	}
+}
+
+func (o *otlpOutput) workerHandleProto(ctx context.Context, m *outputs.ProtoMsg) {
+	pmsg := m.GetMsg()
+	switch pmsg := pmsg.(type) {
+	case *gnmi.SubscribeResponse:
+		meta := m.GetMeta()
+		measName := "default"
+		if subName, ok := meta["subscription-name"]; ok {
+			measName = subName
+		}
+		var err error
+		pmsg, err = outputs.AddSubscriptionTarget(pmsg, m.GetMeta(), o.cfg.AddTarget, o.targetTpl)
+		if err != nil {
+			o.logger.Printf("failed to add target to the response: %v", err)
+		}
+		events, err := formatters.ResponseToEventMsgs(measName, pmsg, meta, o.evps...)
+		if err != nil {
+			o.logger.Printf("failed to convert message to event: %v", err)
+			return
+		}
+		for _, ev := range events {
+			o.workerHandleEvent(ctx, ev)
+		}
+	}
+}
+
+func (o *otlpOutput) workerHandleEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if o.cfg.Debug {
+		o.logger.Printf("got event to buffer: %+v", ev)
+	}
+	for _, p := range o.pointsFromEvent(ev) {
+		select {
+		case <-ctx.Done():
+			return
+		case o.pointsCh <- p:
+		}
+	}
+}
+
+func (o *otlpOutput) setDefaults() error {
+	switch o.cfg.Protocol {
+	case "":
+		o.cfg.Protocol = protocolGRPC
+	case protocolGRPC, protocolHTTP:
+	default:
+		return fmt.Errorf("unknown protocol %q, expected %q or %q", o.cfg.Protocol, protocolGRPC, protocolHTTP)
+	}
+	if o.cfg.Timeout <= 0 {
+		o.cfg.Timeout = defaultTimeout
+	}
+	if o.cfg.Interval <= 0 {
+		o.cfg.Interval = defaultInterval
+	}
+	if o.cfg.BatchSize <= 0 {
+		o.cfg.BatchSize = defaultBatchSize
+	}
+	if o.cfg.BufferSize <= 0 {
+		o.cfg.BufferSize = defaultBufferSize
+	}
+	o.cfg.MaxRetries = outputs.MaxRetries(o.cfg.MaxRetries, defaultMaxRetries)
+	if o.cfg.NumWorkers <= 0 {
+		o.cfg.NumWorkers = defaultNumWorkers
+	}
+	if o.cfg.ResourceAttributes == nil {
+		o.cfg.ResourceAttributes = make(map[string]string)
+	}
+	if _, ok := o.cfg.ResourceAttributes["service.name"]; !ok {
+		o.cfg.ResourceAttributes["service.name"] = defaultServiceName
+	}
+	return nil
+}
@@ -42,6 +42,18 @@ type Output interface {
 	SetName(string)
 	SetClusterName(string)
 	SetTargetsConfig(map[string]*types.TargetConfig)
+	SetDryRun(bool)
+}
+
+// DeliveryReporter is implemented by the outputs able to report
+// whether a message was delivered to its destination.
+// WriteSync and WriteEventSync return once the message is delivered,
+// or with the error that prevented its delivery.
+// A PermanentError means the message will never be delivered.
+// A failed write is retried with the same message.
+type DeliveryReporter interface {
+	WriteSync(context.Context, proto.Message, Meta) error
+	WriteEventSync(context.Context, *formatters.EventMsg) error
 }
 
 type Initializer func() Output
@@ -62,6 +74,14 @@ var OutputTypes = map[string]struct{}{
 	"jetstream":        {},
 	"snmp":             {},
 	"asciigraph":       {},
+	"otlp":             {},
+	"elasticsearch":    {},
+	"loki":             {},
+	"sql":              {},
+	"mqtt":             {},
+	"http":             {},
+	"graphite":         {},
+	"statsd":           {},
 }
 
 func Register(name string, initFn Initializer) {
@@ -177,6 +197,34 @@ func Marshal(pmsg protoreflect.ProtoMessage, meta map[string]string, mo *formatt
 }
 
 func marshalSplit(pmsg protoreflect.ProtoMessage, meta map[string]string, mo *formatters.MarshalOptions, evps ...formatters.EventProcessor) ([][]byte, error) {
+	events, err := SplitEvents(pmsg, meta, evps...)
+	if err != nil {
+		return nil, err
+	}
+	numEvents := len(events)
+	if numEvents == 0 {
+		return nil, nil
+	}
+	rs := make([][]byte, 0, numEvents)
+	marshalFn := json.Marshal
+	if mo.Multiline {
+		marshalFn = func(v any) ([]byte, error) {
+			return json.MarshalIndent(v, "", mo.Indent)
+		}
+	}
+	for _, ev := range events {
+		b, err := marshalFn(ev)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, b)
+	}
+	return rs, nil
+}
+
+// SplitEvents converts a subscribe response update into events
+// and applies the event processors to them.
+func SplitEvents(pmsg protoreflect.ProtoMessage, meta map[string]string, evps ...formatters.EventProcessor) ([]*formatters.EventMsg, error) {
 	var subscriptionName string
 	var ok bool
 	if subscriptionName, ok = meta["subscription-name"]; !ok {
@@ -190,25 +238,7 @@ func marshalSplit(pmsg protoreflect.ProtoMessage, meta map[string]string, mo *fo
 			if err != nil {
 				return nil, fmt.Errorf("failed converting response to events: %v", err)
 			}
-			numEvents := len(events)
-			if numEvents == 0 {
-				return nil, nil
-			}
-			rs := make([][]byte, 0, numEvents)
-			marshalFn := json.Marshal
-			if mo.Multiline {
-				marshalFn = func(v any) ([]byte, error) {
-					return json.MarshalIndent(v, "", mo.Indent)
-				}
-			}
-			for _, ev := range events {
-				b, err := marshalFn(ev)
-				if err != nil {
-					return nil, err
-				}
-				rs = append(rs, b)
-			}
-			return rs, nil
+			return events, nil
 		default:
 			return nil, fmt.Errorf("unexpected message type: %T", msg)
 		}
@@ -68,6 +68,7 @@ func init() {
 type prometheusOutput struct {
 	cfg       *config
 	logger    *log.Logger
+	dryRun    bool
 	eventChan chan *formatters.EventMsg
 	msgChan   chan *outputs.ProtoMsg
 
@@ -183,6 +184,9 @@ func (p *prometheusOutput) Init(ctx context.Context, name string, cfg map[string
 		ExportTimestamps:       p.cfg.ExportTimestamps,
 	}
 
+	if p.dryRun {
+		return nil
+	}
 	if p.cfg.CacheConfig != nil {
 		p.gnmiCache, err = cache.New(
 			p.cfg.CacheConfig,
@@ -558,4 +562,6 @@ func (p *prometheusOutput) SetClusterName(name string) {
 	}
 }
 
+func (p *prometheusOutput) SetDryRun(dryRun bool) { p.dryRun = dryRun }
+
 func (p *prometheusOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
@@ -23,6 +23,8 @@ import (
 	"github.com/prometheus/prometheus/prompb"
 
 	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
 )
 
 var (
@@ -141,14 +143,45 @@ WRITE:
 	}
 }
 
+// writeEvents sends the time series of the events in a single write request.
+func (p *promWriteOutput) writeEvents(ctx context.Context, evs []*formatters.EventMsg) error {
+	pts := make([]prompb.TimeSeries, 0, len(evs))
+	for _, ev := range evs {
+		for _, nts := range p.mb.TimeSeriesFromEvent(ev) {
+			p.saveMetadata(nts.Name)
+			pts = append(pts, *nts.TS)
+		}
+	}
+	if len(pts) == 0 {
+		return nil
+	}
+	if p.httpClient == nil {
+		return errors.New("prometheus write output not initialized")
+	}
+	sort.Slice(pts, func(i, j int) bool {
+		return pts[i].Samples[0].Timestamp < pts[j].Samples[0].Timestamp
+	})
+	start := time.Now()
+	err := p.writeRequest(ctx, &prompb.WriteRequest{
+		Timeseries: pts,
+	})
+	if err != nil {
+		return err
+	}
+	prometheusWriteSendDuration.Set(float64(time.Since(start).Nanoseconds()))
+	prometheusWriteNumberOfSentMsgs.Add(float64(len(pts)))
+	return nil
+}
+
 // writeRequest marshals the supplied prompb.WriteRequest,
 // creates an HTTP request with the proper configured options (Authentication, Headers,...),
 // sends the request and checks the returned response status code.
-// It returns an error if the status code is >=300.
+// It returns an error if the status code is >=300,
+// the requests rejected by the remote are not worth retrying.
 func (p *promWriteOutput) writeRequest(ctx context.Context, wr *prompb.WriteRequest) error {
 	httpReq, err := p.makeHTTPRequest(ctx, wr)
 	if err != nil {
-		return err
+		return outputs.Permanent(err)
 	}
 
 	// send request with retries
@@ -177,7 +210,14 @@ RETRY:
 		if err != nil {
 			return err
 		}
-		return fmt.Errorf("write response failed, code=%d, body=%s", rsp.StatusCode, string(msg))
+		err = fmt.Errorf("write response failed, code=%d, body=%s", rsp.StatusCode, string(msg))
+		switch {
+		case rsp.StatusCode == http.StatusTooManyRequests,
+			rsp.StatusCode == http.StatusRequestTimeout,
+			rsp.StatusCode >= 500:
+			return err
+		}
+		return outputs.Permanent(err)
 	}
 	return nil
 }
@@ -68,6 +68,7 @@ func init() {
 type promWriteOutput struct {
 	cfg    *config
 	logger *log.Logger
+	dryRun bool
 
 	httpClient   *http.Client
 	eventChan    chan *formatters.EventMsg
@@ -172,6 +173,9 @@ func (p *promWriteOutput) Init(ctx context.Context, name string, cfg map[string]
 		StringsAsLabels:        p.cfg.StringsAsLabels,
 	}
 
+	if p.dryRun {
+		return nil
+	}
 	// initialize buffer chan
 	p.timeSeriesCh = make(chan *prompb.TimeSeries, p.cfg.BufferSize)
 	err = p.createHTTPClient()
@@ -226,6 +230,45 @@ func (p *promWriteOutput) WriteEvent(ctx context.Context, ev *formatters.EventMs
 	}
 }
 
+// WriteSync sends the message time series in a single write request,
+// without buffering them, and returns once they are written or failed.
+func (p *promWriteOutput) WriteSync(ctx context.Context, rsp proto.Message, meta outputs.Meta) error {
+	if rsp == nil {
+		return nil
+	}
+	var err error
+	rsp, err = outputs.AddSubscriptionTarget(rsp, meta, p.cfg.AddTarget, p.targetTpl)
+	if err != nil {
+		p.logger.Printf("failed to add target to the response: %v", err)
+	}
+	sr, ok := rsp.(*gnmi.SubscribeResponse)
+	if !ok {
+		return nil
+	}
+	measName := "default"
+	if subName, ok := meta["subscription-name"]; ok {
+		measName = subName
+	}
+	events, err := formatters.ResponseToEventMsgs(measName, sr, meta, p.evps...)
+	if err != nil {
+		return outputs.Permanent(fmt.Errorf("failed to convert message to event: %w", err))
+	}
+	return p.writeEvents(ctx, events)
+}
+
+// WriteEventSync sends the event time series in a single write request,
+// without buffering them, and returns once they are written or failed.
+func (p *promWriteOutput) WriteEventSync(ctx context.Context, ev *formatters.EventMsg) error {
+	if ev == nil {
+		return nil
+	}
+	evs := []*formatters.EventMsg{ev}
+	for _, proc := range p.evps {
+		evs = proc.Apply(evs...)
+	}
+	return p.writeEvents(ctx, evs)
+}
+
 func (p *promWriteOutput) Close() error {
 	if p.cfn == nil {
 		return nil
@@ -284,6 +327,8 @@ func (p *promWriteOutput) SetName(name string) {
 
 func (p *promWriteOutput) SetClusterName(_ string) {}
 
+func (p *promWriteOutput) SetDryRun(dryRun bool) { p.dryRun = dryRun }
+
 func (p *promWriteOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
 
 //
@@ -338,16 +383,7 @@ func (p *promWriteOutput) workerHandleEvent(ev *formatters.EventMsg) {
 			p.buffDrainCh <- struct{}{}
 		}
 		// populate metadata cache
-		p.m.Lock()
-		if p.cfg.Debug {
-			p.logger.Printf("saving metrics metadata")
-		}
-		p.metadataCache[pts.Name] = prompb.MetricMetadata{
-			Type:             prompb.MetricMetadata_COUNTER,
-			MetricFamilyName: pts.Name,
-			Help:             defaultMetricHelp,
-		}
-		p.m.Unlock()
+		p.saveMetadata(pts.Name)
 		// write time series to buffer
 		if p.cfg.Debug {
 			p.logger.Printf("writing TimeSeries to buffer")
@@ -356,6 +392,19 @@ func (p *promWriteOutput) workerHandleEvent(ev *formatters.EventMsg) {
 	}
 }
 
+func (p *promWriteOutput) saveMetadata(name string) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.cfg.Debug {
+		p.logger.Printf("saving metrics metadata")
+	}
+	p.metadataCache[name] = prompb.MetricMetadata{
+		Type:             prompb.MetricMetadata_COUNTER,
+		MetricFamilyName: name,
+		Help:             defaultMetricHelp,
+	}
+}
+
 func (p *promWriteOutput) setDefaults() error {
 	if p.cfg.Timeout <= 0 {
 		p.cfg.Timeout = defaultTimeout
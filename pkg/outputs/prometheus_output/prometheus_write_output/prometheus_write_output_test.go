@@ -0,0 +1,93 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package prometheus_write_output
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	gogoproto "github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/openconfig/gnmic/pkg/outputs"
+	promcom "github.com/openconfig/gnmic/pkg/outputs/prometheus_output"
+)
+
+func TestWriteSync(t *testing.T) {
+	rsp := &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{
+		Update: &gnmi.Notification{
+			Timestamp: 42000000,
+			Update: []*gnmi.Update{
+				{
+					Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interface", Key: map[string]string{"name": "ethernet-1/1"}}, {Name: "mtu"}}},
+					Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 1500}},
+				},
+				{
+					Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interface", Key: map[string]string{"name": "ethernet-1/2"}}, {Name: "mtu"}}},
+					Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 9000}},
+				},
+			},
+		},
+	}}
+	tests := []struct {
+		name      string
+		code      int
+		wantErr   bool
+		permanent bool
+	}{
+		{name: "written", code: http.StatusNoContent},
+		{name: "bad_request", code: http.StatusBadRequest, wantErr: true, permanent: true},
+		{name: "unavailable", code: http.StatusServiceUnavailable, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wr := new(prompb.WriteRequest)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				b, err := snappy.Decode(nil, b)
+				if err == nil {
+					err = gogoproto.Unmarshal(b, wr)
+				}
+				if err != nil {
+					t.Errorf("failed to decode write request: %v", err)
+				}
+				w.WriteHeader(tt.code)
+			}))
+			defer srv.Close()
+			p := &promWriteOutput{
+				cfg:           &config{URL: srv.URL},
+				logger:        log.New(io.Discard, "", 0),
+				httpClient:    srv.Client(),
+				mb:            &promcom.MetricBuilder{},
+				m:             new(sync.Mutex),
+				metadataCache: make(map[string]prompb.MetricMetadata),
+			}
+			err := p.WriteSync(context.Background(), rsp, outputs.Meta{"subscription-name": "sub1"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if outputs.IsPermanent(err) != tt.permanent {
+				t.Fatalf("expected permanent=%v, got %v", tt.permanent, err)
+			}
+			if len(wr.Timeseries) != 2 {
+				t.Fatalf("expected 2 time series in a single request, got %d", len(wr.Timeseries))
+			}
+			if len(p.metadataCache) != 1 {
+				t.Fatalf("expected the metric metadata to be cached, got %v", p.metadataCache)
+			}
+		})
+	}
+}
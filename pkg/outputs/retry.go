@@ -0,0 +1,85 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package outputs
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// PermanentError wraps the errors that should not be retried,
+// e.g: a request rejected by the receiver.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Permanent wraps err in a PermanentError.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent returns true if err wraps a PermanentError.
+func IsPermanent(err error) bool {
+	var perr *PermanentError
+	return errors.As(err, &perr)
+}
+
+// MaxRetries returns the max-retries value to use for a configured value n:
+// def if n is 0, no retries if n is negative.
+func MaxRetries(n, def int) int {
+	switch {
+	case n < 0:
+		return 0
+	case n == 0:
+		return def
+	}
+	return n
+}
+
+// RetryPolicy defines how a failed send is retried.
+type RetryPolicy struct {
+	// number of retries after the first attempt,
+	// retry until success or a permanent error if negative
+	MaxRetries int
+	// wait time before the first retry
+	Backoff time.Duration
+	// double the wait time after each retry
+	Exponential bool
+	// upper bound of the wait time between retries, if set
+	MaxBackoff time.Duration
+}
+
+// Do calls fn until it succeeds, returns a PermanentError,
+// the retries are exhausted or ctx is done.
+// It returns the last error returned by fn, or the ctx error.
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	backoff := p.Backoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || IsPermanent(err) || (p.MaxRetries >= 0 && attempt >= p.MaxRetries) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if p.Exponential {
+			backoff *= 2
+		}
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package outputs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMaxRetries(t *testing.T) {
+	tests := []struct {
+		n, def, want int
+	}{
+		{n: 0, def: 3, want: 3},
+		{n: -1, def: 3, want: 0},
+		{n: 5, def: 3, want: 5},
+	}
+	for _, tt := range tests {
+		if got := MaxRetries(tt.n, tt.def); got != tt.want {
+			t.Errorf("MaxRetries(%d, %d) = %d, want %d", tt.n, tt.def, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	errTemp := errors.New("temporary")
+	tests := []struct {
+		name      string
+		policy    RetryPolicy
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "success",
+			policy:    RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond},
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "success_after_retries",
+			policy:    RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond, Exponential: true},
+			errs:      []error{errTemp, errTemp, nil},
+			wantCalls: 3,
+		},
+		{
+			name:      "retries_exhausted",
+			policy:    RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond},
+			errs:      []error{errTemp, errTemp, errTemp, nil},
+			wantCalls: 3,
+			wantErr:   errTemp,
+		},
+		{
+			name:      "no_retries",
+			policy:    RetryPolicy{Backoff: time.Millisecond},
+			errs:      []error{errTemp, nil},
+			wantCalls: 1,
+			wantErr:   errTemp,
+		},
+		{
+			name:      "unlimited_retries",
+			policy:    RetryPolicy{MaxRetries: -1, Backoff: time.Millisecond, Exponential: true, MaxBackoff: 2 * time.Millisecond},
+			errs:      []error{errTemp, errTemp, errTemp, errTemp, errTemp, nil},
+			wantCalls: 6,
+		},
+		{
+			name:      "permanent",
+			policy:    RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond},
+			errs:      []error{Permanent(fmt.Errorf("rejected: %w", errTemp)), nil},
+			wantCalls: 1,
+			wantErr:   errTemp,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := tt.policy.Do(context.Background(), func() error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := RetryPolicy{MaxRetries: 10, Backoff: time.Hour}.Do(ctx, func() error {
+		calls++
+		cancel()
+		return errors.New("temporary")
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, want 1", calls)
+	}
+}
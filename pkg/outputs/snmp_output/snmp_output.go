@@ -58,6 +58,7 @@ type snmpOutput struct {
 	name       string
 	cfg        *Config
 	logger     *log.Logger
+	dryRun     bool
 	cancelFn   context.CancelFunc
 	snmpClient g.Handler
 	eventChan  chan *formatters.EventMsg
@@ -182,6 +183,9 @@ func (s *snmpOutput) Init(ctx context.Context, name string, cfg map[string]inter
 		}
 		s.targetTpl = s.targetTpl.Funcs(outputs.TemplateFuncs)
 	}
+	if s.dryRun {
+		return nil
+	}
 	s.cache, err = cache.New(&cache.Config{Expiration: -1}, cache.WithLogger(s.logger))
 	if err != nil {
 		return err
@@ -289,6 +293,7 @@ func (s *snmpOutput) start(ctx context.Context) {
 }
 
 func (s *snmpOutput) SetName(name string)                               {}
+func (s *snmpOutput) SetDryRun(dryRun bool)                             { s.dryRun = dryRun }
 func (s *snmpOutput) SetClusterName(name string)                        {}
 func (s *snmpOutput) SetTargetsConfig(c map[string]*types.TargetConfig) {}
 
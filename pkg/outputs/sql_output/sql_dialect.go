@@ -0,0 +1,178 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_output
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+const (
+	driverPostgres   = "postgres"
+	driverClickHouse = "clickhouse"
+	// maximum identifier length, PostgreSQL truncates longer identifiers.
+	maxIdentLength = 63
+)
+
+// sqlDrivers maps the output driver to the database/sql driver name.
+var sqlDrivers = map[string]string{
+	driverPostgres:   "pgx",
+	driverClickHouse: "clickhouse",
+}
+
+var dialects = map[string]dialect{
+	driverPostgres:   postgresDialect{},
+	driverClickHouse: clickhouseDialect{},
+}
+
+// kind is the type of a column as seen by the output.
+type kind int
+
+const (
+	kindText kind = iota
+	kindNumber
+	kindBool
+	kindTime
+)
+
+// dialect holds the database specific parts of the SQL statements.
+type dialect interface {
+	// quote returns the quoted identifier.
+	quote(ident string) string
+	// placeholder returns the n-th (1-based) bind parameter placeholder.
+	placeholder(n int) string
+	// columnType returns the SQL type of a nullable column of kind k,
+	// the time kind is used for the non nullable timestamp column.
+	columnType(k kind) string
+	// kindOf returns the kind of an existing column based on its SQL type.
+	kindOf(sqlType string) kind
+	// columnsQuery returns a query listing the name and type
+	// of a table columns, it takes the table name as parameter.
+	columnsQuery() string
+	defaultTableOptions() string
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) quote(ident string) string {
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+func (postgresDialect) placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (postgresDialect) columnType(k kind) string {
+	switch k {
+	case kindNumber:
+		return "DOUBLE PRECISION"
+	case kindBool:
+		return "BOOLEAN"
+	case kindTime:
+		return "TIMESTAMPTZ NOT NULL"
+	default:
+		return "TEXT"
+	}
+}
+
+func (postgresDialect) kindOf(sqlType string) kind {
+	t := strings.ToLower(sqlType)
+	switch {
+	case strings.HasPrefix(t, "timestamp"):
+		return kindTime
+	case t == "boolean":
+		return kindBool
+	case strings.Contains(t, "double"), strings.Contains(t, "int"),
+		t == "real", t == "numeric":
+		return kindNumber
+	default:
+		return kindText
+	}
+}
+
+func (postgresDialect) columnsQuery() string {
+	return "SELECT column_name, data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1"
+}
+
+func (postgresDialect) defaultTableOptions() string { return "" }
+
+type clickhouseDialect struct{}
+
+func (clickhouseDialect) quote(ident string) string {
+	return "`" + strings.ReplaceAll(ident, "`", "\\`") + "`"
+}
+
+func (clickhouseDialect) placeholder(int) string { return "?" }
+
+func (clickhouseDialect) columnType(k kind) string {
+	switch k {
+	case kindNumber:
+		return "Nullable(Float64)"
+	case kindBool:
+		return "Nullable(Bool)"
+	case kindTime:
+		return "DateTime64(9, 'UTC')"
+	default:
+		return "Nullable(String)"
+	}
+}
+
+func (clickhouseDialect) kindOf(sqlType string) kind {
+	switch {
+	case strings.Contains(sqlType, "DateTime"):
+		return kindTime
+	case strings.Contains(sqlType, "Bool"):
+		return kindBool
+	case strings.Contains(sqlType, "Float"), strings.Contains(sqlType, "Int"),
+		strings.Contains(sqlType, "Decimal"):
+		return kindNumber
+	default:
+		return kindText
+	}
+}
+
+func (clickhouseDialect) columnsQuery() string {
+	return "SELECT name, type FROM system.columns WHERE database = currentDatabase() AND table = ?"
+}
+
+func (clickhouseDialect) defaultTableOptions() string {
+	return "ENGINE = MergeTree ORDER BY timestamp"
+}
+
+// identifier turns a tag name, a value name or a measurement name
+// into a lower case SQL identifier made of letters, digits and underscores.
+// Identifiers longer than 63 characters are truncated and suffixed
+// with a hash of the original name to keep them unique.
+func identifier(name string) string {
+	sb := new(strings.Builder)
+	sep := false
+	for _, c := range strings.ToLower(name) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+			if sep && sb.Len() > 0 {
+				sb.WriteByte('_')
+			}
+			sep = false
+			sb.WriteRune(c)
+		default:
+			sep = true
+		}
+	}
+	id := sb.String()
+	if id == "" || (id[0] >= '0' && id[0] <= '9') {
+		id = "_" + id
+	}
+	if len(id) > maxIdentLength {
+		h := fnv.New32a()
+		h.Write([]byte(name))
+		id = fmt.Sprintf("%s_%08x", id[:maxIdentLength-9], h.Sum32())
+	}
+	return id
+}
@@ -0,0 +1,69 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_output
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	namespace = "gnmic"
+	subsystem = "sql_output"
+)
+
+var sqlNumberOfSentRows = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_inserted_rows_total",
+	Help:      "Number of rows successfully inserted by gnmic sql output",
+})
+
+var sqlNumberOfFailedRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_failed_rows_total",
+	Help:      "Number of rows gnmic sql output failed to insert",
+}, []string{"reason"})
+
+var sqlNumberOfSchemaChanges = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "number_of_schema_changes_total",
+	Help:      "Number of tables created and columns added by gnmic sql output",
+})
+
+var sqlInsertDuration = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Subsystem: subsystem,
+	Name:      "insert_duration_ns",
+	Help:      "gnmic sql output batch insert duration in ns",
+})
+
+func initMetrics() {
+	sqlNumberOfSentRows.Add(0)
+	sqlNumberOfFailedRows.WithLabelValues("").Add(0)
+	sqlNumberOfSchemaChanges.Add(0)
+	sqlInsertDuration.Set(0)
+}
+
+func registerMetrics(reg *prometheus.Registry) error {
+	initMetrics()
+	var err error
+	if err = reg.Register(sqlNumberOfSentRows); err != nil {
+		return err
+	}
+	if err = reg.Register(sqlNumberOfFailedRows); err != nil {
+		return err
+	}
+	if err = reg.Register(sqlNumberOfSchemaChanges); err != nil {
+		return err
+	}
+	if err = reg.Register(sqlInsertDuration); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,371 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_output
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	outputType           = "sql"
+	loggingPrefix        = "[sql_output:%s] "
+	modeWide             = "wide"
+	modeLong             = "long"
+	defaultLongTable     = "gnmic_events"
+	defaultTimeout       = 10 * time.Second
+	defaultFlushInterval = 5 * time.Second
+	defaultBatchSize     = 1000
+	defaultBufferSize    = 1000
+	defaultNumWorkers    = 1
+)
+
+func init() {
+	outputs.Register(outputType,
+		func() outputs.Output {
+			return &sqlOutput{
+				cfg:       &config{},
+				logger:    log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+				eventChan: make(chan *formatters.EventMsg),
+				msgChan:   make(chan *outputs.ProtoMsg),
+				m:         new(sync.Mutex),
+				tables:    make(map[string]map[string]kind),
+			}
+		})
+}
+
+type sqlOutput struct {
+	cfg    *config
+	logger *log.Logger
+	dryRun bool
+
+	eventChan chan *formatters.EventMsg
+	msgChan   chan *outputs.ProtoMsg
+	rowsCh    chan *row
+	db        *sql.DB
+	dialect   dialect
+
+	m *sync.Mutex
+	// known tables columns, table name to column name to column kind.
+	tables map[string]map[string]kind
+
+	evps      []formatters.EventProcessor
+	targetTpl *template.Template
+	cfn       context.CancelFunc
+}
+
+type config struct {
+	Name          string        `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Driver        string        `mapstructure:"driver,omitempty" json:"driver,omitempty"`
+	DSN           string        `mapstructure:"dsn,omitempty" json:"-"`
+	Mode          string        `mapstructure:"mode,omitempty" json:"mode,omitempty"`
+	Table         string        `mapstructure:"table,omitempty" json:"table,omitempty"`
+	TablePrefix   string        `mapstructure:"table-prefix,omitempty" json:"table-prefix,omitempty"`
+	TableOptions  string        `mapstructure:"table-options,omitempty" json:"table-options,omitempty"`
+	Timescale     bool          `mapstructure:"timescaledb,omitempty" json:"timescaledb,omitempty"`
+	Timeout       time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	FlushInterval time.Duration `mapstructure:"flush-interval,omitempty" json:"flush-interval,omitempty"`
+	BatchSize     int           `mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	BufferSize    int           `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+	MaxOpenConns  int           `mapstructure:"max-open-conns,omitempty" json:"max-open-conns,omitempty"`
+	Debug         bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	//
+	AddTarget       string   `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate  string   `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	EventProcessors []string `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	NumWorkers      int      `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	EnableMetrics   bool     `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+}
+
+func (s *sqlOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, s.cfg)
+	if err != nil {
+		return err
+	}
+	if s.cfg.DSN == "" {
+		return errors.New("missing dsn field")
+	}
+	if s.cfg.Name == "" {
+		s.cfg.Name = name
+	}
+	s.logger.SetPrefix(fmt.Sprintf(loggingPrefix, s.cfg.Name))
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return err
+		}
+	}
+
+	if s.cfg.TargetTemplate == "" {
+		s.targetTpl = outputs.DefaultTargetTemplate
+	} else if s.cfg.AddTarget != "" {
+		s.targetTpl, err = gtemplate.CreateTemplate("target-template", s.cfg.TargetTemplate)
+		if err != nil {
+			return err
+		}
+		s.targetTpl = s.targetTpl.Funcs(outputs.TemplateFuncs)
+	}
+
+	err = s.setDefaults()
+	if err != nil {
+		return err
+	}
+	if s.dryRun {
+		return nil
+	}
+	s.db, err = sql.Open(sqlDrivers[s.cfg.Driver], s.cfg.DSN)
+	if err != nil {
+		return err
+	}
+	if s.cfg.MaxOpenConns > 0 {
+		s.db.SetMaxOpenConns(s.cfg.MaxOpenConns)
+	}
+	pctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	err = s.db.PingContext(pctx)
+	if err != nil {
+		s.db.Close()
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	s.rowsCh = make(chan *row, s.cfg.BufferSize)
+
+	ctx, s.cfn = context.WithCancel(ctx)
+	for i := 0; i < s.cfg.NumWorkers; i++ {
+		go s.worker(ctx)
+	}
+	go s.writer(ctx)
+	s.logger.Printf("initialized sql output %s: %s", s.cfg.Name, s.String())
+	return nil
+}
+
+func (s *sqlOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+
+	wctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		return
+	case s.msgChan <- outputs.NewProtoMsg(rsp, meta):
+	case <-wctx.Done():
+		if s.cfg.Debug {
+			s.logger.Printf("writing expired after %s", s.cfg.Timeout)
+		}
+		return
+	}
+}
+
+func (s *sqlOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		var evs = []*formatters.EventMsg{ev}
+		for _, proc := range s.evps {
+			evs = proc.Apply(evs...)
+		}
+		for _, pev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case s.eventChan <- pev:
+			}
+		}
+	}
+}
+
+func (s *sqlOutput) Close() error {
+	if s.cfn == nil {
+		return nil
+	}
+	s.cfn()
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
+
+func (s *sqlOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !s.cfg.EnableMetrics {
+		return
+	}
+	if reg == nil {
+		s.logger.Printf("ERR: output metrics enabled but main registry is not initialized, enable main metrics under `api-server`")
+		return
+	}
+	if err := registerMetrics(reg); err != nil {
+		s.logger.Printf("failed to register metric: %v", err)
+	}
+}
+
+func (s *sqlOutput) String() string {
+	b, err := json.Marshal(s.cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (s *sqlOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && s.logger != nil {
+		s.logger.SetOutput(logger.Writer())
+		s.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (s *sqlOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	s.evps, err = formatters.MakeEventProcessors(
+		logger,
+		s.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *sqlOutput) SetName(name string) {
+	if s.cfg.Name == "" {
+		s.cfg.Name = name
+	}
+}
+
+func (s *sqlOutput) SetClusterName(_ string) {}
+
+func (s *sqlOutput) SetDryRun(dryRun bool) { s.dryRun = dryRun }
+
+func (s *sqlOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+
+//
+
+func (s *sqlOutput) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-s.eventChan:
+			s.workerHandleEvent(ctx, ev)
+		case m := <-s.msgChan:
+			s.workerHandleProto(ctx, m)
+		}
+	}
+}
+
+func (s *sqlOutput) workerHandleProto(ctx context.Context, m *outputs.ProtoMsg) {
+	pmsg := m.GetMsg()
+	switch pmsg := pmsg.(type) {
+	case *gnmi.SubscribeResponse:
+		meta := m.GetMeta()
+		measName := "default"
+		if subName, ok := meta["subscription-name"]; ok {
+			measName = subName
+		}
+		var err error
+		pmsg, err = outputs.AddSubscriptionTarget(pmsg, m.GetMeta(), s.cfg.AddTarget, s.targetTpl)
+		if err != nil {
+			s.logger.Printf("failed to add target to the response: %v", err)
+		}
+		events, err := formatters.ResponseToEventMsgs(measName, pmsg, meta, s.evps...)
+		if err != nil {
+			s.logger.Printf("failed to convert message to event: %v", err)
+			return
+		}
+		for _, ev := range events {
+			s.workerHandleEvent(ctx, ev)
+		}
+	}
+}
+
+func (s *sqlOutput) workerHandleEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if s.cfg.Debug {
+		s.logger.Printf("got event to buffer: %+v", ev)
+	}
+	var rows []*row
+	switch s.cfg.Mode {
+	case modeLong:
+		rows = s.longRows(ev)
+	default:
+		rows = s.wideRows(ev)
+	}
+	for _, r := range rows {
+		select {
+		case <-ctx.Done():
+			return
+		case s.rowsCh <- r:
+		}
+	}
+}
+
+func (s *sqlOutput) setDefaults() error {
+	var ok bool
+	s.dialect, ok = dialects[s.cfg.Driver]
+	if !ok {
+		return fmt.Errorf("unknown driver %q, expected %q or %q", s.cfg.Driver, driverPostgres, driverClickHouse)
+	}
+	switch s.cfg.Mode {
+	case "":
+		s.cfg.Mode = modeWide
+	case modeWide, modeLong:
+	default:
+		return fmt.Errorf("unknown mode %q, expected %q or %q", s.cfg.Mode, modeWide, modeLong)
+	}
+	if s.cfg.Timescale && s.cfg.Driver != driverPostgres {
+		return fmt.Errorf("timescaledb requires the %q driver", driverPostgres)
+	}
+	if s.cfg.Table == "" {
+		s.cfg.Table = defaultLongTable
+	}
+	if s.cfg.TableOptions == "" {
+		s.cfg.TableOptions = s.dialect.defaultTableOptions()
+	}
+	if s.cfg.Timeout <= 0 {
+		s.cfg.Timeout = defaultTimeout
+	}
+	if s.cfg.FlushInterval <= 0 {
+		s.cfg.FlushInterval = defaultFlushInterval
+	}
+	if s.cfg.BatchSize <= 0 {
+		s.cfg.BatchSize = defaultBatchSize
+	}
+	if s.cfg.BufferSize <= 0 {
+		s.cfg.BufferSize = defaultBufferSize
+	}
+	if s.cfg.NumWorkers <= 0 {
+		s.cfg.NumWorkers = defaultNumWorkers
+	}
+	return nil
+}
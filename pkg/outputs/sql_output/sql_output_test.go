@@ -0,0 +1,211 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_output
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+// recorder is a database/sql driver recording the executed statements,
+// queries return no rows.
+type recorder struct {
+	m     sync.Mutex
+	stmts []string
+}
+
+func (r *recorder) Open(string) (driver.Conn, error) { return &recConn{r: r}, nil }
+
+func (r *recorder) statements() []string {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return append([]string(nil), r.stmts...)
+}
+
+type recConn struct{ r *recorder }
+
+func (c *recConn) Prepare(query string) (driver.Stmt, error) {
+	return &recStmt{r: c.r, query: query}, nil
+}
+func (c *recConn) Close() error              { return nil }
+func (c *recConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *recConn) Commit() error             { return nil }
+func (c *recConn) Rollback() error           { return nil }
+
+type recStmt struct {
+	r     *recorder
+	query string
+}
+
+func (s *recStmt) Close() error  { return nil }
+func (s *recStmt) NumInput() int { return -1 }
+
+func (s *recStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.r.m.Lock()
+	defer s.r.m.Unlock()
+	s.r.stmts = append(s.r.stmts, s.query)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *recStmt) Query([]driver.Value) (driver.Rows, error) { return &recRows{}, nil }
+
+type recRows struct{}
+
+func (*recRows) Columns() []string         { return []string{"name", "type"} }
+func (*recRows) Close() error              { return nil }
+func (*recRows) Next([]driver.Value) error { return io.EOF }
+
+var rec = &recorder{}
+
+func init() {
+	sql.Register("sqltest", rec)
+}
+
+func TestSQLOutputSchemaEvolution(t *testing.T) {
+	sqlDrivers[driverPostgres] = "sqltest"
+	defer func() { sqlDrivers[driverPostgres] = "pgx" }()
+
+	o := outputs.Outputs[outputType]()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := o.Init(ctx, "sql", map[string]interface{}{
+		"driver":     "postgres",
+		"dsn":        "test",
+		"batch-size": 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	o.WriteEvent(ctx, &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 1,
+		Tags:      map[string]string{"source": "router1", "interface_name": "ethernet-1/1"},
+		Values:    map[string]interface{}{"/interface/statistics/in-octets": uint64(42)},
+	})
+	o.WriteEvent(ctx, &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 2,
+		Tags:      map[string]string{"source": "router1", "interface_name": "ethernet-1/1"},
+		Values:    map[string]interface{}{"/interface/oper-state": "up"},
+	})
+	want := []string{
+		`CREATE TABLE IF NOT EXISTS "sub1" ("timestamp" TIMESTAMPTZ NOT NULL, "interface_name" TEXT, "interface_statistics_in_octets" DOUBLE PRECISION, "source" TEXT)`,
+		`INSERT INTO "sub1" ("timestamp", "interface_name", "interface_statistics_in_octets", "source") VALUES ($1, $2, $3, $4)`,
+		`ALTER TABLE "sub1" ADD COLUMN IF NOT EXISTS "interface_oper_state" TEXT`,
+		`INSERT INTO "sub1" ("timestamp", "interface_name", "interface_oper_state", "source") VALUES ($1, $2, $3, $4)`,
+	}
+	deadline := time.After(5 * time.Second)
+	for {
+		got := rec.statements()
+		if len(got) >= len(want) {
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("statement %d:\ngot:  %s\nwant: %s", i, got[i], want[i])
+				}
+			}
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("timeout waiting for statements, got: %s", strings.Join(got, "\n"))
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"/interfaces/interface/state/counters/in-octets": "interfaces_interface_state_counters_in_octets",
+		"subscription-name": "subscription_name",
+		"Interface_Name":    "interface_name",
+		"1st":               "_1st",
+		"":                  "_",
+	}
+	for in, want := range tests {
+		if got := identifier(in); got != want {
+			t.Errorf("identifier(%q) = %q, want %q", in, got, want)
+		}
+	}
+	long := identifier("/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor/state/session-state")
+	if len(long) != maxIdentLength {
+		t.Errorf("expected a %d characters identifier, got %q", maxIdentLength, long)
+	}
+}
+
+func TestWideRowsColumns(t *testing.T) {
+	s := &sqlOutput{cfg: &config{}}
+	rows := s.wideRows(&formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 1,
+		Tags: map[string]string{
+			"timestamp":      "t",
+			"interface-name": "ethernet-1/1",
+			"interface_name": "ethernet-1/2",
+			"state":          "tag",
+		},
+		Values: map[string]interface{}{
+			"state":     "up",
+			"timestamp": int64(42),
+		},
+	})
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	want := map[string]interface{}{
+		"interface_name":     "ethernet-1/1",
+		"interface_name_tag": "ethernet-1/2",
+		"state":              "tag",
+		"state_value":        "up",
+		"timestamp_tag":      "t",
+		"timestamp_value":    int64(42),
+	}
+	got := rows[0].values
+	if len(got) != len(want) {
+		t.Fatalf("got columns %v, want %v", got, want)
+	}
+	for c, v := range want {
+		if got[c] != v {
+			t.Errorf("column %q: got %v, want %v", c, got[c], v)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		k    kind
+		want interface{}
+	}{
+		{in: uint64(42), k: kindNumber, want: float64(42)},
+		{in: "42.5", k: kindNumber, want: 42.5},
+		{in: "up", k: kindNumber, want: nil},
+		{in: int32(7), k: kindText, want: "7"},
+		{in: "true", k: kindBool, want: true},
+		{in: []interface{}{"a", "b"}, k: kindText, want: `["a","b"]`},
+	}
+	for _, tt := range tests {
+		if got := convert(tt.in, tt.k); got != tt.want {
+			t.Errorf("convert(%v, %v) = %v, want %v", tt.in, tt.k, got, tt.want)
+		}
+	}
+	if valueKind("12") != kindNumber || valueKind("up") != kindText || valueKind(true) != kindBool {
+		t.Error("unexpected value kinds")
+	}
+}
@@ -0,0 +1,209 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_output
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const timestampColumn = "timestamp"
+
+// row is a set of column values to be inserted in table.
+type row struct {
+	table  string
+	ts     time.Time
+	values map[string]interface{}
+	// kind of each column in values, based on the first value seen.
+	kinds map[string]kind
+}
+
+func (r *row) set(col string, k kind, v interface{}) {
+	r.values[col] = v
+	r.kinds[col] = k
+}
+
+// column returns the column name of a tag or a value, the suffix is appended
+// to it if it is already used in the row or if it is the timestamp column.
+func (r *row) column(name, suffix string) string {
+	col := identifier(name)
+	for {
+		if _, ok := r.values[col]; !ok && col != timestampColumn {
+			return col
+		}
+		col = identifier(col + suffix)
+	}
+}
+
+// wideRows converts an event into a single row of the table named after the event name,
+// with a column per tag and per value.
+// The tags and values are walked in order so that colliding names
+// are always given the same columns.
+func (s *sqlOutput) wideRows(ev *formatters.EventMsg) []*row {
+	if len(ev.Values) == 0 {
+		return nil
+	}
+	r := &row{
+		table:  identifier(s.cfg.TablePrefix + ev.Name),
+		ts:     time.Unix(0, ev.Timestamp).UTC(),
+		values: make(map[string]interface{}, len(ev.Tags)+len(ev.Values)),
+		kinds:  make(map[string]kind, len(ev.Tags)+len(ev.Values)),
+	}
+	for _, k := range sortedKeys(ev.Tags) {
+		r.set(r.column(k, "_tag"), kindText, ev.Tags[k])
+	}
+	for _, k := range sortedKeys(ev.Values) {
+		v := ev.Values[k]
+		r.set(r.column(k, "_value"), valueKind(v), v)
+	}
+	return []*row{r}
+}
+
+// longRows converts an event into a row per value of the fixed schema table:
+// timestamp, name, target, path, tags, value and value_num.
+func (s *sqlOutput) longRows(ev *formatters.EventMsg) []*row {
+	if len(ev.Values) == 0 {
+		return nil
+	}
+	ts := time.Unix(0, ev.Timestamp).UTC()
+	tags, err := json.Marshal(ev.Tags)
+	if err != nil {
+		s.logger.Printf("failed to marshal event tags: %v", err)
+		return nil
+	}
+	rows := make([]*row, 0, len(ev.Values))
+	for k, v := range ev.Values {
+		r := &row{
+			table:  s.cfg.Table,
+			ts:     ts,
+			values: make(map[string]interface{}, 6),
+			kinds:  make(map[string]kind, 6),
+		}
+		r.set("name", kindText, ev.Name)
+		r.set("target", kindText, ev.Tags["source"])
+		r.set("path", kindText, k)
+		r.set("tags", kindText, string(tags))
+		r.set("value", kindText, v)
+		if n, ok := toFloat(v); ok {
+			r.set("value_num", kindNumber, n)
+		} else {
+			r.set("value_num", kindNumber, nil)
+		}
+		rows = append(rows, r)
+	}
+	return rows
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// valueKind returns the column kind used to store v,
+// numeric strings are stored as numbers.
+func valueKind(v interface{}) kind {
+	switch v := v.(type) {
+	case bool:
+		return kindBool
+	case string:
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return kindNumber
+		}
+		return kindText
+	}
+	if _, ok := toFloat(v); ok {
+		return kindNumber
+	}
+	return kindText
+}
+
+// convert returns the value of v to be inserted in a column of kind k,
+// it returns nil if v cannot be converted.
+func convert(v interface{}, k kind) interface{} {
+	if v == nil {
+		return nil
+	}
+	switch k {
+	case kindNumber:
+		if f, ok := toFloat(v); ok {
+			return f
+		}
+		return nil
+	case kindBool:
+		switch v := v.(type) {
+		case bool:
+			return v
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil
+			}
+			return b
+		}
+		return nil
+	case kindTime:
+		return v
+	}
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return string(b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
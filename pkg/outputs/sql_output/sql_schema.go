@@ -0,0 +1,120 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_output
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ensureTable creates the table if it does not exist and adds the columns
+// in cols it is missing.
+// It returns the kinds of the table columns, the kind of an existing column
+// is not changed, the inserted values are converted to it.
+func (s *sqlOutput) ensureTable(ctx context.Context, table string, cols map[string]kind) (map[string]kind, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	known, ok := s.tables[table]
+	if !ok {
+		var err error
+		known, err = s.tableColumns(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		if len(known) == 0 {
+			err = s.createTable(ctx, table, cols)
+			if err != nil {
+				return nil, err
+			}
+			known = make(map[string]kind, len(cols)+1)
+			known[timestampColumn] = kindTime
+			for c, k := range cols {
+				known[c] = k
+			}
+		}
+		s.tables[table] = known
+	}
+	missing := make([]string, 0)
+	for c := range cols {
+		if _, ok := known[c]; !ok {
+			missing = append(missing, c)
+		}
+	}
+	sort.Strings(missing)
+	for _, c := range missing {
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s",
+			s.dialect.quote(table), s.dialect.quote(c), s.dialect.columnType(cols[c]))
+		if s.cfg.Debug {
+			s.logger.Printf("adding column: %s", stmt)
+		}
+		_, err := s.db.ExecContext(ctx, stmt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add column %q to table %q: %w", c, table, err)
+		}
+		sqlNumberOfSchemaChanges.Inc()
+		known[c] = cols[c]
+	}
+	return known, nil
+}
+
+// tableColumns reads the columns of an existing table,
+// it returns an empty map if the table does not exist.
+func (s *sqlOutput) tableColumns(ctx context.Context, table string) (map[string]kind, error) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.columnsQuery(), table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table %q columns: %w", table, err)
+	}
+	defer rows.Close()
+	cols := make(map[string]kind)
+	for rows.Next() {
+		var name, sqlType string
+		err = rows.Scan(&name, &sqlType)
+		if err != nil {
+			return nil, err
+		}
+		cols[name] = s.dialect.kindOf(sqlType)
+	}
+	return cols, rows.Err()
+}
+
+func (s *sqlOutput) createTable(ctx context.Context, table string, cols map[string]kind) error {
+	names := make([]string, 0, len(cols))
+	for c := range cols {
+		names = append(names, c)
+	}
+	sort.Strings(names)
+	defs := make([]string, 0, len(cols)+1)
+	defs = append(defs, fmt.Sprintf("%s %s", s.dialect.quote(timestampColumn), s.dialect.columnType(kindTime)))
+	for _, c := range names {
+		defs = append(defs, fmt.Sprintf("%s %s", s.dialect.quote(c), s.dialect.columnType(cols[c])))
+	}
+	stmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", s.dialect.quote(table), strings.Join(defs, ", "))
+	if s.cfg.TableOptions != "" {
+		stmt += " " + s.cfg.TableOptions
+	}
+	if s.cfg.Debug {
+		s.logger.Printf("creating table: %s", stmt)
+	}
+	_, err := s.db.ExecContext(ctx, stmt)
+	if err != nil {
+		return fmt.Errorf("failed to create table %q: %w", table, err)
+	}
+	sqlNumberOfSchemaChanges.Inc()
+	if s.cfg.Timescale {
+		_, err = s.db.ExecContext(ctx,
+			"SELECT create_hypertable($1::regclass, $2, if_not_exists => TRUE)",
+			s.dialect.quote(table), timestampColumn)
+		if err != nil {
+			return fmt.Errorf("failed to create hypertable %q: %w", table, err)
+		}
+	}
+	return nil
+}
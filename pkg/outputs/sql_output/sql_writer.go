@@ -0,0 +1,135 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_output
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// writer batches the rows and inserts them when the batch size
+// or the flush interval is reached.
+func (s *sqlOutput) writer(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]*row, 0, s.cfg.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case r := <-s.rowsCh:
+			batch = append(batch, r)
+			if len(batch) < s.cfg.BatchSize {
+				continue
+			}
+			if s.cfg.Debug {
+				s.logger.Printf("batch size reached, flushing")
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+			if s.cfg.Debug {
+				s.logger.Printf("flush interval reached, flushing")
+			}
+		}
+		s.flush(ctx, batch)
+		batch = make([]*row, 0, s.cfg.BatchSize)
+	}
+}
+
+// flush inserts the batch rows, table by table.
+func (s *sqlOutput) flush(ctx context.Context, batch []*row) {
+	tables := make([]string, 0)
+	byTable := make(map[string][]*row)
+	for _, r := range batch {
+		if _, ok := byTable[r.table]; !ok {
+			tables = append(tables, r.table)
+		}
+		byTable[r.table] = append(byTable[r.table], r)
+	}
+	for _, table := range tables {
+		rows := byTable[table]
+		start := time.Now()
+		err := s.insert(ctx, table, rows)
+		if err != nil {
+			s.logger.Printf("failed to insert %d rows in table %q: %v", len(rows), table, err)
+			sqlNumberOfFailedRows.WithLabelValues("insert_error").Add(float64(len(rows)))
+			continue
+		}
+		sqlInsertDuration.Set(float64(time.Since(start).Nanoseconds()))
+		sqlNumberOfSentRows.Add(float64(len(rows)))
+		if s.cfg.Debug {
+			s.logger.Printf("inserted %d rows in table %q", len(rows), table)
+		}
+	}
+}
+
+// insert writes rows to table in a single transaction,
+// after creating the table or adding the missing columns if needed.
+func (s *sqlOutput) insert(ctx context.Context, table string, rows []*row) error {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	cols := make(map[string]kind)
+	for _, r := range rows {
+		for c, k := range r.kinds {
+			if _, ok := cols[c]; !ok {
+				cols[c] = k
+			}
+		}
+	}
+	kinds, err := s.ensureTable(ctx, table, cols)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(cols))
+	for c := range cols {
+		names = append(names, c)
+	}
+	sort.Strings(names)
+
+	quoted := make([]string, 0, len(names)+1)
+	placeholders := make([]string, 0, len(names)+1)
+	quoted = append(quoted, s.dialect.quote(timestampColumn))
+	placeholders = append(placeholders, s.dialect.placeholder(1))
+	for i, c := range names {
+		quoted = append(quoted, s.dialect.quote(c))
+		placeholders = append(placeholders, s.dialect.placeholder(i+2))
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		s.dialect.quote(table), strings.Join(quoted, ", "), strings.Join(placeholders, ", "))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	args := make([]interface{}, len(names)+1)
+	for _, r := range rows {
+		args[0] = r.ts
+		for i, c := range names {
+			args[i+1] = convert(r.values[c], kinds[c])
+		}
+		_, err = stmt.ExecContext(ctx, args...)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -51,6 +51,7 @@ type tcpOutput struct {
 	buffer   chan []byte
 	limiter  *time.Ticker
 	logger   *log.Logger
+	dryRun   bool
 	mo       *formatters.MarshalOptions
 	evps     []formatters.EventProcessor
 
@@ -116,10 +117,6 @@ func (t *tcpOutput) Init(ctx context.Context, name string, cfg map[string]interf
 	if err != nil {
 		return fmt.Errorf("wrong address format: %v", err)
 	}
-	t.buffer = make(chan []byte, t.cfg.BufferSize)
-	if t.cfg.Rate > 0 {
-		t.limiter = time.NewTicker(t.cfg.Rate)
-	}
 	if t.cfg.RetryInterval == 0 {
 		t.cfg.RetryInterval = defaultRetryTimer
 	}
@@ -143,6 +140,13 @@ func (t *tcpOutput) Init(ctx context.Context, name string, cfg map[string]interf
 		}
 		t.targetTpl = t.targetTpl.Funcs(outputs.TemplateFuncs)
 	}
+	if t.dryRun {
+		return nil
+	}
+	t.buffer = make(chan []byte, t.cfg.BufferSize)
+	if t.cfg.Rate > 0 {
+		t.limiter = time.NewTicker(t.cfg.Rate)
+	}
 	go func() {
 		<-ctx.Done()
 		t.Close()
@@ -240,5 +244,6 @@ START:
 }
 
 func (t *tcpOutput) SetName(name string)                             {}
+func (t *tcpOutput) SetDryRun(dryRun bool)                           { t.dryRun = dryRun }
 func (t *tcpOutput) SetClusterName(name string)                      {}
 func (s *tcpOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
@@ -51,6 +51,7 @@ type UDPSock struct {
 	buffer   chan []byte
 	limiter  *time.Ticker
 	logger   *log.Logger
+	dryRun   bool
 	mo       *formatters.MarshalOptions
 	evps     []formatters.EventProcessor
 
@@ -116,15 +117,6 @@ func (u *UDPSock) Init(ctx context.Context, name string, cfg map[string]interfac
 		u.Cfg.RetryInterval = defaultRetryTimer
 	}
 
-	u.buffer = make(chan []byte, u.Cfg.BufferSize)
-	if u.Cfg.Rate > 0 {
-		u.limiter = time.NewTicker(u.Cfg.Rate)
-	}
-	go func() {
-		<-ctx.Done()
-		u.Close()
-	}()
-	ctx, u.cancelFn = context.WithCancel(ctx)
 	u.mo = &formatters.MarshalOptions{
 		Format:     u.Cfg.Format,
 		OverrideTS: u.Cfg.OverrideTimestamps,
@@ -138,6 +130,18 @@ func (u *UDPSock) Init(ctx context.Context, name string, cfg map[string]interfac
 		}
 		u.targetTpl = u.targetTpl.Funcs(outputs.TemplateFuncs)
 	}
+	if u.dryRun {
+		return nil
+	}
+	u.buffer = make(chan []byte, u.Cfg.BufferSize)
+	if u.Cfg.Rate > 0 {
+		u.limiter = time.NewTicker(u.Cfg.Rate)
+	}
+	go func() {
+		<-ctx.Done()
+		u.Close()
+	}()
+	ctx, u.cancelFn = context.WithCancel(ctx)
 	go u.start(ctx)
 	return nil
 }
@@ -226,5 +230,6 @@ DIAL:
 }
 
 func (u *UDPSock) SetName(name string)                             {}
+func (u *UDPSock) SetDryRun(dryRun bool)                           { u.dryRun = dryRun }
 func (u *UDPSock) SetClusterName(name string)                      {}
 func (u *UDPSock) SetTargetsConfig(map[string]*types.TargetConfig) {}